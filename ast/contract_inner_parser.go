@@ -860,6 +860,15 @@ func parsePotentialAssignmentOrBranchingCase(r *strings.Reader, eot rune, allowB
 			// Drain our local buffer and return.
 			return drain()
 		case '=':
+			// If this is followed by another '=', this is a equality check on the reference
+			// rather than a assignment.
+			if c2, _, err := r.ReadRune(); err == nil {
+				if c2 == '=' && refPos != -1 {
+					return drain()
+				}
+				_ = r.UnreadRune()
+			}
+
 			// Handle if the reference buffer is blank.
 			if refPos == -1 {
 				return nil, &ParserError{
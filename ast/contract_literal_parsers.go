@@ -173,6 +173,11 @@ numberGetLoop:
 			content = "0" + content
 		}
 
+		// Add the sign back if this is negative.
+		if state == 2 {
+			content = "-" + content
+		}
+
 		// Parse the float as a float64.
 		float, err := strconv.ParseFloat(content, 64)
 		if err != nil {
@@ -214,6 +219,11 @@ numberGetLoop:
 		}
 	}
 
+	// Add the sign back if this is negative.
+	if state == 2 {
+		content = "-" + content
+	}
+
 	// Check if the content fits in a 64 bit integer.
 	v, err := strconv.ParseInt(content, base, 64)
 	if err == nil {
@@ -229,7 +239,7 @@ numberGetLoop:
 	if x != nil {
 		// Return the big integer literal.
 		return BigIntLiteralToken{
-			Value:    content,
+			Value:    x.String(),
 			Position: pos,
 		}, nil
 	}
@@ -505,8 +505,24 @@ func ensureClosingBracket(r *strings.Reader) *ParserError {
 	return nil
 }
 
+// Defines the precedence of each operator. Higher values bind tighter.
+var opPrecedence = map[rune]int{
+	'|': 1,
+	'&': 2,
+	'=': 3, '!': 3, '<': 3, '>': 3, '≤': 3, '≥': 3,
+	'+': 4, '-': 4,
+	'*': 5, '/': 5, '%': 5,
+	'^': 6,
+}
+
 // Parses a token and looks ahead for a group that should group with the token.
 func parseInnerContractTokenWithOpGrouping(r *strings.Reader, eot rune) (any, *ParserError) {
+	return parseInnerContractTokenWithPrecedence(r, eot, 0)
+}
+
+// Parses a token and groups it with any operators that bind at least as tightly as the
+// minimum precedence specified.
+func parseInnerContractTokenWithPrecedence(r *strings.Reader, eot rune, minPrecedence int) (any, *ParserError) {
 	// Consume all of the whitespace.
 	gulpWhitespace(r)
 
@@ -590,106 +606,70 @@ func parseInnerContractTokenWithOpGrouping(r *strings.Reader, eot rune) (any, *P
 		return nil, nil
 	}
 
-	// Look ahead for a operator.
-	pos := getReaderPos(r)
-	op := parseMathOrBoolOp(r)
-	if op == 0 {
-		// No operator.
-		return res, nil
-	}
+	// Handle any operators which follow.
+	for {
+		// Look ahead for a operator.
+		pos := getReaderPos(r)
+		op := parseMathOrBoolOp(r)
+		if op == 0 {
+			// No operator.
+			return res, nil
+		}
 
-	// Parse the next token.
-	next, perr := parseInnerContractTokenWithOpGrouping(r, eot)
-	if perr != nil {
-		return nil, perr
+		// If the operator binds looser than our caller, rewind and let the caller handle it.
+		precedence := opPrecedence[op]
+		if precedence < minPrecedence {
+			_, _ = r.Seek(int64(pos), io.SeekStart)
+			return res, nil
+		}
+
+		// Parse the next token. Exponents are right associative, everything else is left
+		// associative.
+		nextPrecedence := precedence + 1
+		if op == '^' {
+			nextPrecedence = precedence
+		}
+		next, perr := parseInnerContractTokenWithPrecedence(r, eot, nextPrecedence)
+		if perr != nil {
+			return nil, perr
+		}
+
+		// Group the operation.
+		res = createOpToken(op, res, next, pos)
 	}
+}
 
-	// Return the math operation.
+// Creates the token for the math or boolean operator specified.
+func createOpToken(op rune, left, right any, pos int) any {
 	switch op {
 	case '+':
-		return AddToken{
-			Left:     res,
-			Right:    next,
-			Position: pos,
-		}, nil
+		return AddToken{Left: left, Right: right, Position: pos}
 	case '-':
-		return SubtractToken{
-			Left:     res,
-			Right:    next,
-			Position: pos,
-		}, nil
+		return SubtractToken{Left: left, Right: right, Position: pos}
 	case '*':
-		return MultiplyToken{
-			Left:     res,
-			Right:    next,
-			Position: pos,
-		}, nil
+		return MultiplyToken{Left: left, Right: right, Position: pos}
 	case '/':
-		return DivideToken{
-			Left:     res,
-			Right:    next,
-			Position: pos,
-		}, nil
+		return DivideToken{Left: left, Right: right, Position: pos}
 	case '%':
-		return ModuloToken{
-			Left:     res,
-			Right:    next,
-			Position: pos,
-		}, nil
+		return ModuloToken{Left: left, Right: right, Position: pos}
 	case '^':
-		return ExponentToken{
-			Left:     res,
-			Right:    next,
-			Position: pos,
-		}, nil
+		return ExponentToken{Left: left, Right: right, Position: pos}
 	case '<':
-		return LessThanToken{
-			Left:     res,
-			Right:    next,
-			Position: pos,
-		}, nil
+		return LessThanToken{Left: left, Right: right, Position: pos}
 	case '>':
-		return GreaterThanToken{
-			Left:     res,
-			Right:    next,
-			Position: pos,
-		}, nil
+		return GreaterThanToken{Left: left, Right: right, Position: pos}
 	case '=':
-		return EqualToken{
-			Left:     res,
-			Right:    next,
-			Position: pos,
-		}, nil
+		return EqualToken{Left: left, Right: right, Position: pos}
 	case '!':
-		return NotEqualToken{
-			Left:     res,
-			Right:    next,
-			Position: pos,
-		}, nil
+		return NotEqualToken{Left: left, Right: right, Position: pos}
 	case '&':
-		return AndToken{
-			Left:     res,
-			Right:    next,
-			Position: pos,
-		}, nil
+		return AndToken{Left: left, Right: right, Position: pos}
 	case '|':
-		return OrToken{
-			Left:     res,
-			Right:    next,
-			Position: pos,
-		}, nil
+		return OrToken{Left: left, Right: right, Position: pos}
 	case '≤':
-		return LessThanOrEqualToken{
-			Left:     res,
-			Right:    next,
-			Position: pos,
-		}, nil
+		return LessThanOrEqualToken{Left: left, Right: right, Position: pos}
 	case '≥':
-		return GreaterThanOrEqualToken{
-			Left:     res,
-			Right:    next,
-			Position: pos,
-		}, nil
+		return GreaterThanOrEqualToken{Left: left, Right: right, Position: pos}
 	default:
 		// This should never happen.
 		panic("unexpected operator value")
@@ -813,14 +793,15 @@ func parseContractArgument(r *strings.Reader) (*ContractArgumentToken, *ParserEr
 }
 
 // Parses a contract return type.
-func parseContractReturnType(r *strings.Reader) (string, *ParserError) {
+func parseContractReturnType(r *strings.Reader) (string, int, *ParserError) {
 	state := 0
 	content := ""
+	index := -1
 	for {
-		c, _, err := r.ReadRune()
+		c, size, err := r.ReadRune()
 		if err != nil {
 			// End of file.
-			return "", &ParserError{
+			return "", -1, &ParserError{
 				Message:  "unexpected end of file after contract return type bracket",
 				Position: getReaderPos(r),
 			}
@@ -829,15 +810,20 @@ func parseContractReturnType(r *strings.Reader) (string, *ParserError) {
 		switch c {
 		case '-':
 			if state != 0 {
-				return "", &ParserError{
+				return "", -1, &ParserError{
 					Message:  "unexpected '-' after '-'",
 					Position: getReaderPos(r),
 				}
 			}
 			state = 1
 		case '>':
+			if state == 2 {
+				// This closes a generic type such as Cursor<T>.
+				content += string(c)
+				break
+			}
 			if state != 1 {
-				return "", &ParserError{
+				return "", -1, &ParserError{
 					Message:  "unexpected '>' that is not preceded by '-'",
 					Position: getReaderPos(r),
 				}
@@ -845,7 +831,7 @@ func parseContractReturnType(r *strings.Reader) (string, *ParserError) {
 			state = 2
 		case ' ', '\t', '\n', '\r':
 			if state == 1 {
-				return "", &ParserError{
+				return "", -1, &ParserError{
 					Message:  "unexpected whitespace after '-'",
 					Position: getReaderPos(r),
 				}
@@ -856,14 +842,17 @@ func parseContractReturnType(r *strings.Reader) (string, *ParserError) {
 				// Rewind the rune.
 				_ = r.UnreadRune()
 
-				return content, nil
+				return content, index, nil
 			}
 		default:
 			if state == 2 {
+				if content == "" {
+					index = getReaderPos(r) - size
+				}
 				content += string(c)
 			} else {
 				// Unexpected character.
-				return "", &ParserError{
+				return "", -1, &ParserError{
 					Message:  "unexpected '" + string(c) + "' after contract return type",
 					Position: getReaderPos(r),
 				}
@@ -1128,7 +1117,7 @@ nameReader:
 	}
 
 	// Parse the return type of the contract.
-	returnType, perr := parseContractReturnType(r)
+	returnType, returnTypeIndex, perr := parseContractReturnType(r)
 	if perr != nil {
 		return ContractToken{}, perr
 	}
@@ -1177,12 +1166,13 @@ openingBracketFind:
 
 	// Return the contract.
 	return ContractToken{
-		Name:       name,
-		Position:   pos,
-		ReturnType: returnType,
-		Argument:   arg,
-		Throws:     throws,
-		Decorators: decorators,
-		Statements: inner,
+		Name:            name,
+		Position:        pos,
+		ReturnType:      returnType,
+		ReturnTypeIndex: returnTypeIndex,
+		Argument:        arg,
+		Throws:          throws,
+		Decorators:      decorators,
+		Statements:      inner,
 	}, nil
 }
@@ -0,0 +1,88 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package ast
+
+import "strings"
+
+// Gets where a declaration starts, which is its first decorator if it has any.
+func declarationStart(position int, decorators []DecoratorToken) int {
+	if len(decorators) != 0 {
+		return decorators[0].Position
+	}
+	return position
+}
+
+// Gets the doc comment from the comment tokens directly before the token at the index. Each
+// comment must be on its own line, and the lines must be directly above start with no blank
+// lines in between. The lines are joined with a new line, and the space after the slashes is
+// removed.
+func docComment(input string, siblings []any, index, start int) string {
+	var lines []string
+	for i := index - 1; i >= 0; i-- {
+		c, ok := siblings[i].(CommentToken)
+		if !ok {
+			break
+		}
+
+		// Make sure nothing but indentation is before the comment on its line.
+		lineStart := strings.LastIndexByte(input[:c.Position], '\n') + 1
+		if strings.TrimLeft(input[lineStart:c.Position], " \t") != "" {
+			break
+		}
+
+		// Make sure the line after the comment is where the next thing starts.
+		end := strings.IndexByte(input[c.Position:start], '\n')
+		if end == -1 || strings.TrimLeft(input[c.Position+end+1:start], " \t") != "" {
+			break
+		}
+
+		lines = append([]string{strings.TrimRight(strings.TrimPrefix(c.Comment, " "), " \t\r")}, lines...)
+		start = c.Position
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Sets the doc comments of the fields within a struct or exception.
+func attachFieldComments(input string, fields []any) {
+	for i, f := range fields {
+		if field, ok := f.(FieldToken); ok {
+			field.Comment = docComment(input, fields, i, declarationStart(field.Position, field.Decorators))
+			fields[i] = field
+		}
+	}
+}
+
+// Sets the doc comments of the declarations at the root of the document and the fields within
+// them. The tokens are changed in place.
+func attachDocComments(input string, tokens []any) {
+	for i, t := range tokens {
+		switch x := t.(type) {
+		case StructToken:
+			x.Comment = docComment(input, tokens, i, declarationStart(x.Position, x.Decorators))
+			attachFieldComments(input, x.Fields)
+			tokens[i] = x
+		case ExceptionToken:
+			x.Comment = docComment(input, tokens, i, declarationStart(x.Position, x.Decorators))
+			attachFieldComments(input, x.Fields)
+			tokens[i] = x
+		case EnumToken:
+			x.Comment = docComment(input, tokens, i, declarationStart(x.Position, x.Decorators))
+			tokens[i] = x
+		case ContractToken:
+			x.Comment = docComment(input, tokens, i, declarationStart(x.Position, x.Decorators))
+			tokens[i] = x
+		case MappingToken:
+			x.Comment = docComment(input, tokens, i, declarationStart(x.Position, x.Decorators))
+			tokens[i] = x
+		case ExtendsToken:
+			// The decorators of extends are on the struct within it.
+			if s, ok := x.Token.(StructToken); ok {
+				s.Comment = docComment(input, tokens, i, declarationStart(x.Position, s.Decorators))
+				attachFieldComments(input, s.Fields)
+				x.Token = s
+				tokens[i] = x
+			}
+		}
+	}
+}
@@ -0,0 +1,639 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package ast
+
+import (
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Defines the string used for each level of indentation.
+const formatIndent = "    "
+
+// Defines the operator and precedence of each operator token. The precedence matches opPrecedence.
+var formatOperators = map[reflect.Type]struct {
+	op         string
+	precedence int
+}{
+	reflect.TypeOf(OrToken{}):                 {"||", 1},
+	reflect.TypeOf(AndToken{}):                {"&&", 2},
+	reflect.TypeOf(EqualToken{}):              {"==", 3},
+	reflect.TypeOf(NotEqualToken{}):           {"!=", 3},
+	reflect.TypeOf(LessThanToken{}):           {"<", 3},
+	reflect.TypeOf(GreaterThanToken{}):        {">", 3},
+	reflect.TypeOf(LessThanOrEqualToken{}):    {"<=", 3},
+	reflect.TypeOf(GreaterThanOrEqualToken{}): {">=", 3},
+	reflect.TypeOf(AddToken{}):                {"+", 4},
+	reflect.TypeOf(SubtractToken{}):           {"-", 4},
+	reflect.TypeOf(MultiplyToken{}):           {"*", 5},
+	reflect.TypeOf(DivideToken{}):             {"/", 5},
+	reflect.TypeOf(ModuloToken{}):             {"%", 5},
+	reflect.TypeOf(ExponentToken{}):           {"^", 6},
+}
+
+// Gets the operator, precedence, and sides of a operator token. ok is false if this is not one.
+func operatorToken(t any) (op string, precedence int, left, right any, ok bool) {
+	if t == nil {
+		return
+	}
+	info, ok := formatOperators[reflect.TypeOf(t)]
+	if !ok {
+		return
+	}
+	v := reflect.ValueOf(t)
+	return info.op, info.precedence, v.FieldByName("Left").Interface(), v.FieldByName("Right").Interface(), true
+}
+
+// Gets the token that is at the end of a expression when it is formatted. chained is true if it is
+// a chained reference such as `.first`, which is ended by whitespace rather than the next token.
+func rightEdge(t any) (leaf any, chained bool) {
+	for {
+		switch x := t.(type) {
+		case AssignmentToken:
+			t = x.Value
+		case ReturnToken:
+			if x.Token == nil {
+				return x, false
+			}
+			t = x.Token
+		case ThrowLiteralToken:
+			t = x.Token
+		case InlineIfToken:
+			t = x.Condition
+		case InlineUnlessToken:
+			t = x.Condition
+		case NotToken:
+			if !isNotOperand(x.Token) {
+				return x, false
+			}
+			t = x.Token
+		case MethodCallToken:
+			if x.ChainedCall == nil {
+				return x, false
+			}
+			if ref, ok := x.ChainedCall.(ReferenceToken); ok {
+				return ref, true
+			}
+			t = x.ChainedCall
+		default:
+			if _, _, _, right, ok := operatorToken(t); ok {
+				t = right
+				continue
+			}
+			return t, false
+		}
+	}
+}
+
+// Formats the expression so that it can be directly followed by a bracket or comma. Chained
+// references are ended by whitespace, so a space is added after them.
+func (p *printer) exprBeforeDelimiter(t any) string {
+	s := p.expr(t)
+	if _, chained := rightEdge(t); chained {
+		s += " "
+	}
+	return s
+}
+
+// Checks if the expression ends with a inline if or unless. The condition of these takes in any
+// operators after it, so they need to be in brackets when they are on the left of a operator.
+func endsWithInline(t any) bool {
+	for {
+		switch x := t.(type) {
+		case InlineIfToken, InlineUnlessToken:
+			return true
+		case MethodCallToken:
+			if x.ChainedCall == nil {
+				return false
+			}
+			t = x.ChainedCall
+		default:
+			if _, _, _, right, ok := operatorToken(t); ok {
+				t = right
+				continue
+			}
+			return false
+		}
+	}
+}
+
+// Defines the printer used to format tokens.
+type printer struct {
+	b      strings.Builder
+	indent int
+}
+
+// Writes a line at the current indentation. Blank lines are not indented.
+func (p *printer) line(s string) {
+	if s == "" {
+		p.b.WriteByte('\n')
+		return
+	}
+	p.b.WriteString(strings.Repeat(formatIndent, p.indent))
+	p.b.WriteString(s)
+	p.b.WriteByte('\n')
+}
+
+// Formats a comment.
+func formatComment(c CommentToken) string {
+	return "//" + c.Comment
+}
+
+// Formats a decorator. The values are used if they were parsed, otherwise the source of the
+// arguments is used as is.
+func (p *printer) decorator(d DecoratorToken) string {
+	if d.Values == nil {
+		if d.Arguments == "" {
+			return "@" + d.Method
+		}
+		return "@" + d.Method + "(" + d.Arguments + ")"
+	}
+	args := make([]string, len(d.Values))
+	for i, v := range d.Values {
+		args[i] = p.expr(v)
+	}
+	return "@" + d.Method + "(" + strings.Join(args, ", ") + ")"
+}
+
+// Writes the decorators on their own lines.
+func (p *printer) decorators(decorators []DecoratorToken) {
+	for _, d := range decorators {
+		p.line(p.decorator(d))
+	}
+}
+
+// Quotes a string with single quotes.
+func quoteString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+var bareObjectKeyRegex = regexp.MustCompile("^[A-Za-z_][A-Za-z0-9_]*$")
+
+// Formats the key of a object literal, quoting it if it cannot be written bare.
+func formatObjectKey(key string) string {
+	if bareObjectKeyRegex.MatchString(key) {
+		return key
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(key) + `"`
+}
+
+// Formats a float so that it is always parsed as a float.
+func formatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s
+}
+
+// Formats the side of a operator, adding brackets if they are needed to keep the grouping.
+func (p *printer) operand(t any, precedence int, left, rightAssociative bool) string {
+	needsBrackets := left && endsWithInline(t)
+	if _, childPrecedence, _, _, ok := operatorToken(t); ok {
+		if childPrecedence == precedence {
+			// Left associative operators group to the left, so only the right needs brackets.
+			needsBrackets = needsBrackets || left == rightAssociative
+		} else {
+			needsBrackets = needsBrackets || childPrecedence < precedence
+		}
+	}
+	if needsBrackets {
+		return "(" + p.exprBeforeDelimiter(t) + ")"
+	}
+	return p.expr(t)
+}
+
+// Formats a list of expressions separated by commas. A list with comments in it is written across
+// multiple lines since a comment ends at the end of the line.
+func (p *printer) list(items []any, open, close string) string {
+	hasComment := false
+	for _, item := range items {
+		if _, ok := item.(CommentToken); ok {
+			hasComment = true
+			break
+		}
+	}
+
+	if !hasComment {
+		parts := make([]string, len(items))
+		for i, item := range items {
+			parts[i] = p.exprBeforeDelimiter(item)
+		}
+		return open + strings.Join(parts, ", ") + close
+	}
+
+	var b strings.Builder
+	b.WriteString(open + "\n")
+	p.indent++
+	for i, item := range items {
+		b.WriteString(strings.Repeat(formatIndent, p.indent) + p.exprBeforeDelimiter(item))
+		if i != len(items)-1 {
+			if _, ok := item.(CommentToken); ok {
+				// The comma cannot go on the same line as a comment.
+				b.WriteString("\n" + strings.Repeat(formatIndent, p.indent))
+			}
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	p.indent--
+	b.WriteString(strings.Repeat(formatIndent, p.indent) + close)
+	return b.String()
+}
+
+// Formats a object literal. Comments are written first since their position within the object is
+// not kept, and the keys are sorted.
+func (p *printer) object(o ObjectLiteralToken) string {
+	if len(o.Values) == 0 && len(o.Comments) == 0 {
+		return "{}"
+	}
+
+	keys := make([]string, 0, len(o.Values))
+	for k := range o.Values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString("{\n")
+	p.indent++
+	for _, c := range o.Comments {
+		b.WriteString(strings.Repeat(formatIndent, p.indent) + formatComment(c) + "\n")
+	}
+	for _, k := range keys {
+		b.WriteString(strings.Repeat(formatIndent, p.indent) + formatObjectKey(k) + " = " + p.expr(o.Values[k]) + "\n")
+	}
+	p.indent--
+	b.WriteString(strings.Repeat(formatIndent, p.indent) + "}")
+	return b.String()
+}
+
+// Checks if a token can follow a '!' without brackets.
+func isNotOperand(t any) bool {
+	switch t.(type) {
+	case ReferenceToken, MethodCallToken, StringLiteralToken, NumberLiteralToken, FloatLiteralToken,
+		BigIntLiteralToken, BooleanLiteralToken, NullLiteralToken, ArrayLiteralToken, ObjectLiteralToken:
+		return true
+	}
+	return false
+}
+
+// Formats a expression. Statements which can be inside expressions, such as a return within a
+// inline if, are also handled here.
+func (p *printer) expr(t any) string {
+	switch x := t.(type) {
+	case nil:
+		return ""
+	case CommentToken:
+		return formatComment(x)
+	case ReferenceToken:
+		return x.Name
+	case StringLiteralToken:
+		return quoteString(x.Value)
+	case NumberLiteralToken:
+		return strconv.Itoa(x.Value)
+	case FloatLiteralToken:
+		return formatFloat(x.Value)
+	case BigIntLiteralToken:
+		return x.Value
+	case BooleanLiteralToken:
+		return strconv.FormatBool(x.Value)
+	case NullLiteralToken:
+		return "null"
+	case ArrayLiteralToken:
+		return p.list(x.Values, "[", "]")
+	case ObjectLiteralToken:
+		return p.object(x)
+	case MethodCallToken:
+		s := x.Name + p.list(x.Arguments, "(", ")")
+		if x.ChainedCall != nil {
+			s += "." + p.expr(x.ChainedCall)
+		}
+		return s
+	case NotToken:
+		if isNotOperand(x.Token) {
+			return "!" + p.expr(x.Token)
+		}
+		return "!(" + p.exprBeforeDelimiter(x.Token) + ")"
+	case InlineIfToken:
+		return p.expr(x.Token) + " if " + p.expr(x.Condition)
+	case InlineUnlessToken:
+		return p.expr(x.Token) + " unless " + p.expr(x.Condition)
+	case AssignmentToken:
+		return x.Name + " = " + p.expr(x.Value)
+	case ReturnToken:
+		if x.Token == nil {
+			return "return"
+		}
+		return "return " + p.expr(x.Token)
+	case ThrowLiteralToken:
+		return "throw " + p.expr(x.Token)
+	}
+
+	op, precedence, left, right, ok := operatorToken(t)
+	if !ok {
+		panic("ast: cannot format token of type " + reflect.TypeOf(t).String())
+	}
+	rightAssociative := op == "^"
+	return p.operand(left, precedence, true, rightAssociative) + " " + op + " " +
+		p.operand(right, precedence, false, rightAssociative)
+}
+
+// Writes the statements of a block at one more level of indentation.
+func (p *printer) block(statements []any) {
+	p.indent++
+	for _, t := range statements {
+		p.statement(t)
+	}
+	p.indent--
+}
+
+// Writes a else chain followed by the closing bracket of the if or unless statement.
+func (p *printer) elseChain(else_ *ElseToken) {
+	for ; else_ != nil; else_ = else_.Next {
+		if else_.Condition == nil {
+			p.line("} else {")
+		} else {
+			p.line("} elif " + p.expr(else_.Condition) + " {")
+		}
+		p.block(else_.Statements)
+	}
+	p.line("}")
+}
+
+// Formats a part of a for statement followed by a semi-colon. References are ended by whitespace
+// rather than the semi-colon, so a space is put before it if needed.
+func (p *printer) forPart(t any) string {
+	s := p.expr(t)
+	if t == nil {
+		return ";"
+	}
+	if leaf, _ := rightEdge(t); reflect.TypeOf(leaf) == reflect.TypeOf(ReferenceToken{}) {
+		return s + " ;"
+	}
+	return s + ";"
+}
+
+// Checks if a token is a expression rather than a statement.
+func isExpression(t any) bool {
+	if isNotOperand(t) {
+		return true
+	}
+	if _, ok := t.(NotToken); ok {
+		return true
+	}
+	_, _, _, _, ok := operatorToken(t)
+	return ok
+}
+
+// Writes a switch statement.
+func (p *printer) switchStatement(x SwitchToken) {
+	p.line("switch " + p.expr(x.Condition) + " {")
+	p.indent++
+	for _, c := range x.Comments {
+		p.line(formatComment(c))
+	}
+	for _, c := range x.Cases {
+		name := p.expr(c.Name)
+		if len(c.Statements) == 1 && isExpression(c.Statements[0]) {
+			p.line(name + " = " + p.expr(c.Statements[0]))
+			continue
+		}
+		p.line(name + " = {")
+		p.block(c.Statements)
+		p.line("}")
+	}
+	p.indent--
+	p.line("}")
+}
+
+// Writes a try statement and its catches.
+func (p *printer) tryStatement(x TryToken) {
+	p.line("try {")
+	p.block(x.Statements)
+	for c := x.Catch; c != nil; c = c.Next {
+		s := "} catch"
+		if c.Exception != "" {
+			s += " " + c.Exception
+		}
+		if c.Variable != "" {
+			s += " -> " + c.Variable
+		}
+		p.line(s + " {")
+		p.block(c.Statements)
+	}
+	p.line("}")
+}
+
+// Writes a statement within a contract.
+func (p *printer) statement(t any) {
+	switch x := t.(type) {
+	case IfToken:
+		p.line("if " + p.expr(x.Condition) + " {")
+		p.block(x.Statements)
+		p.elseChain(x.Else)
+	case UnlessToken:
+		p.line("unless " + p.expr(x.Condition) + " {")
+		p.block(x.Statements)
+		p.elseChain(x.Else)
+	case WhileToken:
+		p.line("while " + p.expr(x.Condition) + " {")
+		p.block(x.Statements)
+		p.line("}")
+	case ForToken:
+		p.line("for " + p.forPart(x.Assignment) + " " + p.forPart(x.Condition) + " " + p.expr(x.Increment) + " {")
+		p.block(x.Statements)
+		p.line("}")
+	case SwitchToken:
+		p.switchStatement(x)
+	case TryToken:
+		p.tryStatement(x)
+	default:
+		p.line(p.expr(t))
+	}
+}
+
+// Writes the fields of a struct or exception, or the values of a enum. A blank line is put before
+// fields with decorators and before comments that come after a field so that groups stand out.
+func (p *printer) fields(fields []any) {
+	p.indent++
+	var prev any
+	starts := docCommentStarts(fields)
+	for i, f := range fields {
+		if i != 0 {
+			_, prevComment := prev.(CommentToken)
+			switch x := f.(type) {
+			case CommentToken:
+				if !prevComment || starts[i] {
+					p.line("")
+				}
+			case FieldToken:
+				// Comments which are not the doc comment of the field were split from it.
+				if (len(x.Decorators) != 0 && !prevComment) || (prevComment && x.Comment == "") {
+					p.line("")
+				}
+			case ReferenceToken:
+				if len(x.Decorators) != 0 && !prevComment {
+					p.line("")
+				}
+			}
+		}
+		switch x := f.(type) {
+		case CommentToken:
+			p.line(formatComment(x))
+		case FieldToken:
+			p.decorators(x.Decorators)
+			p.line(x.Name + ": " + x.Type)
+		case ReferenceToken:
+			p.decorators(x.Decorators)
+			p.line(x.Name)
+		case EnumValueToken:
+			p.line(x.Name)
+		}
+		prev = f
+	}
+	p.indent--
+	p.line("}")
+}
+
+// Writes the inside of a mapping. Comments are written first since their position is not kept.
+func (p *printer) mapping(m MappingPartialToken) {
+	p.indent++
+	for _, c := range m.Comments {
+		p.line(formatComment(c))
+	}
+	switch x := m.Value.(type) {
+	case MappingPartialToken:
+		p.line(m.Key + " -> {")
+		p.mapping(x)
+	default:
+		p.line(m.Key + " -> " + x.(string))
+	}
+	p.indent--
+	p.line("}")
+}
+
+// Writes a token at the document root.
+func (p *printer) root(t any) {
+	switch x := t.(type) {
+	case CommentToken:
+		p.line(formatComment(x))
+	case ImportToken:
+		p.line("import " + quoteString(x.Path))
+	case StructToken:
+		p.decorators(x.Decorators)
+		p.line("struct " + x.Name + " {")
+		p.fields(x.Fields)
+	case ExceptionToken:
+		p.decorators(x.Decorators)
+		p.line("exception " + x.Name + " {")
+		p.fields(x.Fields)
+	case EnumToken:
+		p.decorators(x.Decorators)
+		p.line("enum " + x.Name + " {")
+		p.fields(x.Values)
+	case ExtendsToken:
+		s := x.Token.(StructToken)
+		p.decorators(s.Decorators)
+		p.line("extends struct " + s.Name + " {")
+		p.fields(s.Fields)
+	case ContractToken:
+		p.decorators(x.Decorators)
+		s := "contract " + x.Name + "("
+		if x.Argument != nil {
+			s += x.Argument.Name + ": " + x.Argument.Type
+		}
+		s += ") -> " + x.ReturnType
+		if len(x.Throws) != 0 {
+			names := make([]string, len(x.Throws))
+			for i, throw := range x.Throws {
+				names[i] = throw.Name
+			}
+			s += " throws " + strings.Join(names, ", ")
+		}
+		p.line(s + " {")
+		p.block(x.Statements)
+		p.line("}")
+	case MappingToken:
+		p.decorators(x.Decorators)
+		p.line("mapping " + x.Name + " using " + strings.Join(x.Using, ", ") + " {")
+		p.mapping(x.MappingPartialToken)
+	default:
+		panic("ast: cannot format token of type " + reflect.TypeOf(t).String() + " at the document root")
+	}
+}
+
+// Gets the doc comment of the token. The boolean is false if the token cannot have one.
+func tokenDocComment(t any) (string, bool) {
+	switch x := t.(type) {
+	case FieldToken:
+		return x.Comment, true
+	case StructToken:
+		return x.Comment, true
+	case ExceptionToken:
+		return x.Comment, true
+	case EnumToken:
+		return x.Comment, true
+	case ContractToken:
+		return x.Comment, true
+	case MappingToken:
+		return x.Comment, true
+	case ExtendsToken:
+		if s, ok := x.Token.(StructToken); ok {
+			return s.Comment, true
+		}
+	}
+	return "", false
+}
+
+// Checks if the token can have a doc comment but does not. Comments before these were split
+// from them by a blank line.
+func isUndocumented(t any) bool {
+	c, ok := tokenDocComment(t)
+	return ok && c == ""
+}
+
+// Gets the indexes of the comments which start a doc comment. Comments before these were split
+// from the doc comment by a blank line.
+func docCommentStarts(tokens []any) map[int]bool {
+	starts := map[int]bool{}
+	for i, t := range tokens {
+		if c, _ := tokenDocComment(t); c != "" {
+			starts[i-strings.Count(c, "\n")-1] = true
+		}
+	}
+	return starts
+}
+
+// Format is used to turn the tokens from Parse back into RQL. The output is formatted the same way
+// no matter how the input was formatted, and parsing it again gives the same tokens other than the
+// positions. Comments are kept, although comments within object literals, switch statements, and
+// mappings are moved to the start since where they were is not stored. Doc comments are kept
+// directly above the token after them, imports are kept together, and everything else is split
+// by a blank line.
+func Format(tokens []any) string {
+	p := &printer{}
+	starts := docCommentStarts(tokens)
+	for i, t := range tokens {
+		if i != 0 {
+			// Imports are kept together.
+			_, prevImport := tokens[i-1].(ImportToken)
+			_, isImport := t.(ImportToken)
+			if _, ok := tokens[i-1].(CommentToken); (!ok && !(prevImport && isImport)) || starts[i] || isUndocumented(t) {
+				p.line("")
+			}
+		}
+		p.root(t)
+	}
+	return p.b.String()
+}
+
+// FormatValue is used to turn a single expression, such as a literal within a decorator, back
+// into RQL. Object literals are split over multiple lines the same way as Format.
+func FormatValue(token any) string {
+	return (&printer{}).expr(token)
+}
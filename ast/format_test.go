@@ -0,0 +1,129 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package ast_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"remixdb.io/ast"
+)
+
+// Removes the positions and the source of decorator arguments from the tokens and makes empty
+// slices nil so tokens from different source text can be compared. The values of decorator
+// arguments are kept, but the positions within them are removed too.
+func withoutPositions(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		res := reflect.New(v.Type()).Elem()
+		res.Set(withoutPositions(v.Elem()))
+		return res
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		res := reflect.New(v.Type().Elem())
+		res.Elem().Set(withoutPositions(v.Elem()))
+		return res
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return reflect.Zero(v.Type())
+		}
+		res := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			res.Index(i).Set(withoutPositions(v.Index(i)))
+		}
+		return res
+	case reflect.Map:
+		res := reflect.MakeMap(v.Type())
+		iter := v.MapRange()
+		for iter.Next() {
+			res.SetMapIndex(iter.Key(), withoutPositions(iter.Value()))
+		}
+		return res
+	case reflect.Struct:
+		res := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.NumField(); i++ {
+			switch v.Type().Field(i).Name {
+			case "Position", "NameIndex", "TypeIndex", "ReturnTypeIndex":
+			case "Arguments":
+				// The source of the arguments of a decorator changes when it is formatted, so only
+				// the values are compared.
+				if v.Type() != reflect.TypeOf(ast.DecoratorToken{}) {
+					res.Field(i).Set(withoutPositions(v.Field(i)))
+				}
+			default:
+				res.Field(i).Set(withoutPositions(v.Field(i)))
+			}
+		}
+		return res
+	}
+	return v
+}
+
+func TestFormat_roundTrip(t *testing.T) {
+	// The fixtures within the errors folder do not parse, so they cannot be formatted. Every
+	// other fixture must parse.
+	errorsFolder := filepath.Join(startPrefix, "errors")
+	err := filepath.WalkDir(startPrefix, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && path == errorsFolder {
+			return fs.SkipDir
+		}
+		if d.IsDir() || filepath.Ext(path) != ".rql" {
+			return nil
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		tokens, perr := ast.Parse(strings.ReplaceAll(string(b), "<<R>>", "\r"))
+
+		t.Run(path, func(t *testing.T) {
+			require.Nil(t, perr)
+			formatted := ast.Format(tokens)
+			reparsed, perr := ast.Parse(formatted)
+			require.Nil(t, perr, formatted)
+			assert.Equal(t,
+				withoutPositions(reflect.ValueOf(tokens)).Interface(),
+				withoutPositions(reflect.ValueOf(reparsed)).Interface(), formatted)
+
+			// Formatting again should not change anything.
+			assert.Equal(t, formatted, ast.Format(reparsed))
+		})
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestWithoutPositions(t *testing.T) {
+	tokens, perr := ast.Parse("struct A {\n    @default([1])\n    a: int\n}\n")
+	require.Nil(t, perr)
+	assert.Equal(t, []any{
+		ast.StructToken{
+			Name: "A",
+			Fields: []any{
+				ast.FieldToken{
+					Name: "a",
+					Type: "int",
+					Decorators: []ast.DecoratorToken{{
+						Method: "default",
+						Values: []any{ast.ArrayLiteralToken{Values: []any{ast.NumberLiteralToken{Value: 1}}}},
+					}},
+				},
+			},
+		},
+	}, withoutPositions(reflect.ValueOf(tokens)).Interface())
+}
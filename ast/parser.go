@@ -4,8 +4,11 @@
 package ast
 
 import (
+	"io"
 	"regexp"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 // ParserError is used to define an error that occurred while parsing.
@@ -15,6 +18,84 @@ type ParserError struct {
 
 	// Position is the position of the error.
 	Position int
+
+	// End is the position after the last character that the parser read before it errored. This
+	// is never before Position.
+	End int
+
+	// Line is the line the position is on, starting at 1.
+	Line int
+
+	// Column is the character within the line the position is at, starting at 1.
+	Column int
+}
+
+// Error is used to return the error message with the line and column.
+func (e *ParserError) Error() string {
+	return e.Message + " (line " + strconv.Itoa(e.Line) + ", column " + strconv.Itoa(e.Column) + ")"
+}
+
+// Checks if the error is because the input ended before the parser expected it to.
+func (e *ParserError) endOfFile() bool {
+	return strings.Contains(e.Message, "end of file") || strings.HasSuffix(e.Message, "got EOF")
+}
+
+// Gets the start and end of the line that the position is on. The end does not include the
+// line ending.
+func lineBounds(input string, pos int) (start, end int) {
+	start = strings.LastIndexByte(input[:pos], '\n') + 1
+	end = strings.IndexByte(input[pos:], '\n')
+	if end == -1 {
+		end = len(input)
+	} else {
+		end += pos
+	}
+	if end > start && input[end-1] == '\r' {
+		end--
+	}
+	return
+}
+
+// Sets the line and column of the error from the input.
+func (e *ParserError) setLineColumn(input string) {
+	pos := e.Position
+	if pos > len(input) {
+		pos = len(input)
+	}
+	start, _ := lineBounds(input, pos)
+	e.Line = strings.Count(input[:start], "\n") + 1
+	e.Column = utf8.RuneCountInString(input[start:pos]) + 1
+}
+
+// Render is used to render the error with the line it is on and a caret under the characters
+// from Position to End. The input must be the string that was parsed. Tabs before the caret are
+// kept so that it lines up with the line above it.
+func (e *ParserError) Render(input string) string {
+	pos := e.Position
+	if pos > len(input) {
+		pos = len(input)
+	}
+	start, end := lineBounds(input, pos)
+	line := input[start:end]
+
+	// Build the padding, keeping tabs so the caret is in the same place as the position.
+	var padding strings.Builder
+	for _, c := range input[start:min(pos, end)] {
+		if c == '\t' {
+			padding.WriteByte('\t')
+		} else {
+			padding.WriteByte(' ')
+		}
+	}
+
+	// Underline until the end of the error or the end of the line, whichever is first.
+	carets := 1
+	if e.End > pos && end > pos {
+		carets = max(utf8.RuneCountInString(input[pos:min(e.End, end)]), 1)
+	}
+
+	return "line " + strconv.Itoa(e.Line) + ", column " + strconv.Itoa(e.Column) + ": " + e.Message +
+		"\n" + line + "\n" + padding.String() + strings.Repeat("^", carets)
 }
 
 func getReaderPos(r *strings.Reader) int {
@@ -186,43 +267,136 @@ nameReader:
 		}
 	}
 
-	// Consume everything in the token until the closing bracket.
-	content := ""
-contentReader:
+	// Parse the arguments until the closing bracket.
+	start := getReaderPos(r)
+	values, perr := parseDecoratorValues(r)
+	if perr != nil {
+		return DecoratorToken{}, perr
+	}
+
+	// Get the source of the arguments without the closing bracket.
+	content := make([]byte, getReaderPos(r)-1-start)
+	_, _ = r.ReadAt(content, int64(start))
+
+	// Gobble the whitespace.
+	gulpWhitespace(r)
+
+	// Return the decorator token.
+	return DecoratorToken{
+		Method:    name,
+		Position:  pos,
+		Arguments: strings.TrimSpace(string(content)),
+		Values:    values,
+	}, nil
+}
+
+// Checks if a decorator argument is a literal or a reference. Comments are allowed within object
+// literals since they are stored apart from the values.
+func isDecoratorValue(token any) bool {
+	ok := true
+	Inspect(token, func(t any) bool {
+		switch t.(type) {
+		case StringLiteralToken, NumberLiteralToken, FloatLiteralToken, BigIntLiteralToken,
+			BooleanLiteralToken, NullLiteralToken, ArrayLiteralToken, ObjectLiteralToken,
+			ReferenceToken, CommentToken:
+			return true
+		}
+		ok = false
+		return false
+	})
+	_, comment := token.(CommentToken)
+	return ok && !comment
+}
+
+// Parses the comma separated arguments of a decorator. This assumes the opening bracket has
+// already been read and reads up to and including the closing bracket.
+func parseDecoratorValues(r *strings.Reader) ([]any, *ParserError) {
+	values := []any{}
 	for {
-		// Read the next unicode character.
+		// Gulp the whitespace and check for the closing bracket.
+		gulpWhitespace(r)
+		pos := getReaderPos(r)
 		c, _, err := r.ReadRune()
 		if err != nil {
-			// End of file.
-			return DecoratorToken{}, &ParserError{
+			return nil, &ParserError{
 				Message:  unexpectedEofAfterDeco,
 				Position: pos,
 			}
 		}
+		if c == ')' && len(values) == 0 {
+			return values, nil
+		}
+		_ = r.UnreadRune()
+
+		// Parse the argument.
+		value, perr := parseInnerContractToken(r, ')')
+		if perr != nil {
+			return nil, perr
+		}
+		if value == nil {
+			return nil, &ParserError{
+				Message:  "expected decorator argument",
+				Position: pos,
+			}
+		}
+		if !isDecoratorValue(value) {
+			return nil, &ParserError{
+				Message:  "decorator arguments must be literals or references",
+				Position: pos,
+			}
+		}
+		values = append(values, value)
 
-		// Switch on the character.
+		// Check for a comma or the closing bracket.
+		gulpWhitespace(r)
+		pos = getReaderPos(r)
+		c, _, err = r.ReadRune()
+		if err != nil {
+			return nil, &ParserError{
+				Message:  unexpectedEofAfterDeco,
+				Position: pos,
+			}
+		}
 		switch c {
 		case ')':
-			// We found the end of the decorator.
-			break contentReader
+			return values, nil
+		case ',':
 		default:
-			// Add the character to the content.
-			content += string(c)
+			return nil, &ParserError{
+				Message:  "unexpected '" + string(c) + "' after decorator argument",
+				Position: pos,
+			}
 		}
 	}
+}
 
-	// Trim the content.
-	content = strings.TrimSpace(content)
-
-	// Gobble the whitespace.
-	gulpWhitespace(r)
+// ParseDecoratorArguments is used to parse the source of the arguments of a decorator without
+// the brackets into literal tokens. Positions are within the arguments. This is used for
+// decorators which were created without Values, such as ones stored before it existed.
+func ParseDecoratorArguments(arguments string) ([]any, *ParserError) {
+	r := strings.NewReader(arguments + ")")
+	values, perr := parseDecoratorValues(r)
+	if perr == nil && r.Len() != 0 {
+		perr = &ParserError{
+			Message:  "unexpected ')' after decorator arguments",
+			Position: getReaderPos(r) - 1,
+		}
+	}
+	if perr != nil {
+		perr.End = min(max(getReaderPos(r), perr.Position), len(arguments))
+		perr.setLineColumn(arguments)
+		return nil, perr
+	}
+	return values, nil
+}
 
-	// Return the decorator token.
-	return DecoratorToken{
-		Method:    name,
-		Position:  pos,
-		Arguments: content,
-	}, nil
+// ArgumentValues is used to get the arguments of the decorator as literal tokens. Values is used
+// if it is set, otherwise Arguments is parsed.
+func (d DecoratorToken) ArgumentValues() ([]any, *ParserError) {
+	if d.Values != nil || d.Arguments == "" {
+		return d.Values, nil
+	}
+	return ParseDecoratorArguments(d.Arguments)
 }
 
 // Parses the extends keyword. This assumes the starting e of extends has already been read.
@@ -254,6 +428,65 @@ func parseExtends(r *strings.Reader) *ParserError {
 	return nil
 }
 
+// Parses a import. This assumes the starting i of import has already been read.
+func parseImport(r *strings.Reader) (ImportToken, *ParserError) {
+	// Get the position of the import with 1 subtracted because we already read the character.
+	pos := getReaderPos(r) - 1
+
+	// Make sure the next content is 'mport'.
+	if !peekString(r, "mport") {
+		return ImportToken{}, &ParserError{
+			Message:  "unexpected character 'i' was hit - did you mean import?",
+			Position: pos,
+		}
+	}
+	_, _ = r.Seek(5, io.SeekCurrent)
+
+	// Expect a space or newline.
+	hasSpace, perr := gulpWhitespace(r)
+	if perr != nil {
+		return ImportToken{}, perr
+	}
+	if !hasSpace {
+		return ImportToken{}, &ParserError{
+			Message:  "unexpected lack of a space after 'import' keyword - did you forget a space?",
+			Position: pos,
+		}
+	}
+
+	// Parse the path.
+	c, _, err := r.ReadRune()
+	if err != nil {
+		// End of file.
+		return ImportToken{}, &ParserError{
+			Message:  "unexpected end of file after 'import' keyword",
+			Position: pos,
+		}
+	}
+	var path StringLiteralToken
+	switch c {
+	case '"':
+		path, perr = parseDoubleQuotedString(r)
+	case '\'':
+		path, perr = parseSingleQuotedString(r)
+	default:
+		return ImportToken{}, &ParserError{
+			Message:  "expected a string after 'import' keyword, got '" + string(c) + "'",
+			Position: getReaderPos(r) - 1,
+		}
+	}
+	if perr != nil {
+		return ImportToken{}, perr
+	}
+	if path.Value == "" {
+		return ImportToken{}, &ParserError{
+			Message:  "the path of a import cannot be blank",
+			Position: path.Position,
+		}
+	}
+	return ImportToken{Path: path.Value, Position: pos}, nil
+}
+
 var typeSplitRegex = regexp.MustCompile(":[ \t]*[a-zA-Z]")
 
 // Parses a struct field or reference. c is the initial character that was consumed.
@@ -403,28 +636,47 @@ func parseStruct(r *strings.Reader, decorators []DecoratorToken) (StructToken, *
 		}
 	}
 
+	// Parse the name and fields of the struct.
+	name, perr := parseDeclarationStart(r, pos, "struct")
+	if perr != nil {
+		return StructToken{}, perr
+	}
+	fields, perr := parseInnerStruct(r)
+	if perr != nil {
+		return StructToken{}, perr
+	}
+	return StructToken{
+		Name:       name,
+		Position:   pos,
+		Decorators: decorators,
+		Fields:     fields,
+	}, nil
+}
+
+// Parses everything after the keyword of a struct, exception, or enum up to and including the
+// opening bracket, returning the name. keyword is used within error messages.
+func parseDeclarationStart(r *strings.Reader, pos int, keyword string) (name string, perr *ParserError) {
 	// Expect a space or newline.
 	hasSpace, perr := gulpWhitespace(r)
 	if perr != nil {
-		return StructToken{}, perr
+		return "", perr
 	}
 	if !hasSpace {
-		return StructToken{}, &ParserError{
-			Message:  "unexpected lack of a space after 'struct' keyword - did you forget a space?",
+		return "", &ParserError{
+			Message:  "unexpected lack of a space after '" + keyword + "' keyword - did you forget a space?",
 			Position: pos,
 		}
 	}
 
-	// Get the name of the struct.
-	name := ""
+	// Get the name.
 nameReader:
 	for {
 		// Read the next Unicode character.
 		c, _, err := r.ReadRune()
 		if err != nil {
 			// End of file.
-			return StructToken{}, &ParserError{
-				Message:  "unexpected end of file after start of struct definition",
+			return "", &ParserError{
+				Message:  "unexpected end of file after start of " + keyword + " definition",
 				Position: pos,
 			}
 		}
@@ -441,7 +693,7 @@ nameReader:
 			c, _, err := r.ReadRune()
 			if err != nil {
 				// End of file.
-				return StructToken{}, &ParserError{
+				return "", &ParserError{
 					Message:  "unexpected end of file after '\\r'",
 					Position: pos,
 				}
@@ -453,7 +705,7 @@ nameReader:
 				}
 			} else {
 				// A \r alone is not a valid return character.
-				return StructToken{}, &ParserError{
+				return "", &ParserError{
 					Message:  "unexpected '" + string(c) + "' after '\\r'",
 					Position: pos,
 				}
@@ -466,16 +718,16 @@ nameReader:
 
 	// Make sure the name is not empty.
 	if name == "" {
-		return StructToken{}, &ParserError{
-			Message:  "unexpected end of file after 'struct' keyword - did you forget the struct name?",
+		return "", &ParserError{
+			Message:  "unexpected end of file after '" + keyword + "' keyword - did you forget the " + keyword + " name?",
 			Position: pos,
 		}
 	}
 
 	// Make sure the name starts with a a-z or A-Z.
 	if !(name[0] >= 'a' && name[0] <= 'z') && !(name[0] >= 'A' && name[0] <= 'Z') {
-		return StructToken{}, &ParserError{
-			Message:  "unexpected '" + string(name[0]) + "' as first character of struct name",
+		return "", &ParserError{
+			Message:  "unexpected '" + string(name[0]) + "' as first character of " + keyword + " name",
 			Position: pos,
 		}
 	}
@@ -487,8 +739,8 @@ openingBracketFind:
 		c, _, err := r.ReadRune()
 		if err != nil {
 			// End of file.
-			return StructToken{}, &ParserError{
-				Message:  "unexpected end of file after struct name",
+			return "", &ParserError{
+				Message:  "unexpected end of file after " + keyword + " name",
 				Position: pos,
 			}
 		}
@@ -502,19 +754,33 @@ openingBracketFind:
 			break openingBracketFind
 		default:
 			// Unexpected character.
-			return StructToken{}, &ParserError{
-				Message:  "unexpected '" + string(c) + "' after struct name",
+			return "", &ParserError{
+				Message:  "unexpected '" + string(c) + "' after " + keyword + " name",
 				Position: pos,
 			}
 		}
 	}
 
-	// Parse the inner struct.
+	return name, nil
+}
+
+// Parses an exception. This assumes the e has already been read and that the next content
+// is 'xception'.
+func parseException(r *strings.Reader, decorators []DecoratorToken) (ExceptionToken, *ParserError) {
+	// The position of the exception with 1 subtracted because we already read the character.
+	pos := getReaderPos(r) - 1
+	r.Seek(int64(len("xception")), io.SeekCurrent)
+
+	// Parse the name and fields of the exception.
+	name, perr := parseDeclarationStart(r, pos, "exception")
+	if perr != nil {
+		return ExceptionToken{}, perr
+	}
 	fields, perr := parseInnerStruct(r)
 	if perr != nil {
-		return StructToken{}, perr
+		return ExceptionToken{}, perr
 	}
-	return StructToken{
+	return ExceptionToken{
 		Name:       name,
 		Position:   pos,
 		Decorators: decorators,
@@ -522,8 +788,119 @@ openingBracketFind:
 	}, nil
 }
 
-// Parses tokens at the document root.
-func parseDocRootToken(r *strings.Reader, tokens *[]any, c rune) *ParserError {
+// Checks if the character can be used within the name of a enum value.
+func isEnumValueChar(c rune) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// Parses the values of a enum up to and including the closing bracket. Values are separated by
+// commas or new lines.
+func parseInnerEnum(r *strings.Reader, pos int) ([]any, *ParserError) {
+	values := []any{}
+	seen := map[string]struct{}{}
+	for {
+		// Read the next Unicode character.
+		c, _, err := r.ReadRune()
+		if err != nil {
+			// End of file before closing bracket.
+			return nil, &ParserError{
+				Message:  "unexpected end of file before closing bracket",
+				Position: pos,
+			}
+		}
+
+		// Switch on the character.
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			// Ignore whitespace and separators.
+		case c == '/':
+			// Parse a comment.
+			if perr := parseComment(r, &values); perr != nil {
+				return nil, perr
+			}
+		case c == '}':
+			// Make sure the enum is not empty.
+			for _, v := range values {
+				if _, ok := v.(EnumValueToken); ok {
+					return values, nil
+				}
+			}
+			return nil, &ParserError{
+				Message:  "unexpected empty enum - enums must have at least one value",
+				Position: pos,
+			}
+		case isEnumValueChar(c):
+			// Read the rest of the value.
+			valuePos := getReaderPos(r) - 1
+			name := string(c)
+			for {
+				c, _, err = r.ReadRune()
+				if err != nil {
+					break
+				}
+				if !isEnumValueChar(c) {
+					_ = r.UnreadRune()
+					break
+				}
+				name += string(c)
+			}
+
+			// Make sure the value is not a duplicate.
+			if _, ok := seen[name]; ok {
+				return nil, &ParserError{
+					Message:  "unexpected duplicate enum value '" + name + "'",
+					Position: valuePos,
+				}
+			}
+			seen[name] = struct{}{}
+			values = append(values, EnumValueToken{
+				Name:     name,
+				Position: valuePos,
+			})
+		default:
+			// Unexpected character.
+			return nil, &ParserError{
+				Message:  "unexpected character '" + string(c) + "' within enum",
+				Position: getReaderPos(r) - 1,
+			}
+		}
+	}
+}
+
+// Parses a enum. This assumes the e has already been read and that the next content is 'num'.
+func parseEnum(r *strings.Reader, decorators []DecoratorToken) (EnumToken, *ParserError) {
+	// The position of the enum with 1 subtracted because we already read the character.
+	pos := getReaderPos(r) - 1
+	r.Seek(int64(len("num")), io.SeekCurrent)
+
+	// Parse the name and values of the enum.
+	name, perr := parseDeclarationStart(r, pos, "enum")
+	if perr != nil {
+		return EnumToken{}, perr
+	}
+	values, perr := parseInnerEnum(r, pos)
+	if perr != nil {
+		return EnumToken{}, perr
+	}
+	return EnumToken{
+		Name:       name,
+		Position:   pos,
+		Decorators: decorators,
+		Values:     values,
+	}, nil
+}
+
+// Checks if the next content is the string without consuming it.
+func peekString(r *strings.Reader, s string) bool {
+	b := make([]byte, len(s))
+	n, _ := r.Read(b)
+	r.Seek(int64(-n), io.SeekCurrent)
+	return string(b[:n]) == s
+}
+
+// Parses tokens at the document root. declPos is set to the position of the last thing the
+// parser started on, which is used to know where to recover from on an error.
+func parseDocRootToken(r *strings.Reader, tokens *[]any, c rune, declPos *int) *ParserError {
 	// Defines all of the decorators that should be applied to the next non-decorator token.
 	decorators := []DecoratorToken{}
 
@@ -533,6 +910,7 @@ func parseDocRootToken(r *strings.Reader, tokens *[]any, c rune) *ParserError {
 parseStart:
 	// Get the position of the token. It is with 1 subtracted because we already read the character.
 	pos := getReaderPos(r) - 1
+	*declPos = pos
 
 	// Switch on the next character.
 	switch c {
@@ -574,6 +952,36 @@ parseStart:
 		}
 		goto parseStart
 	case 'e':
+		// Exceptions, enums, and extends all start with 'e', so check which one this is.
+		if peekString(r, "num") {
+			e, err := parseEnum(r, decorators)
+			if err != nil {
+				return err
+			}
+			if extends != -1 {
+				return &ParserError{
+					Message:  "unexpected 'enum' keyword after 'extends' keyword",
+					Position: pos,
+				}
+			}
+			*tokens = append(*tokens, e)
+			return nil
+		}
+		if peekString(r, "xception") {
+			e, err := parseException(r, decorators)
+			if err != nil {
+				return err
+			}
+			if extends != -1 {
+				return &ParserError{
+					Message:  "unexpected 'exception' keyword after 'extends' keyword",
+					Position: pos,
+				}
+			}
+			*tokens = append(*tokens, e)
+			return nil
+		}
+
 		// Parse an extends.
 		err := parseExtends(r)
 		if err != nil {
@@ -605,6 +1013,26 @@ parseStart:
 
 		// Go to the start of the loop.
 		goto parseStart
+	case 'i':
+		// Parse a import. These cannot have decorators or be extended.
+		i, err := parseImport(r)
+		if err != nil {
+			return err
+		}
+		if extends != -1 {
+			return &ParserError{
+				Message:  "unexpected 'import' keyword after 'extends' keyword",
+				Position: pos,
+			}
+		}
+		if len(decorators) != 0 {
+			return &ParserError{
+				Message:  "decorators cannot be used on a import",
+				Position: decorators[0].Position,
+			}
+		}
+		*tokens = append(*tokens, i)
+		return nil
 	case 's':
 		// Parse a struct.
 		s, err := parseStruct(r, decorators)
@@ -775,17 +1203,58 @@ func handleInlineIfElse(r *strings.Reader, readToken any) (any, *ParserError) {
 	}
 }
 
-// Parse is used to parse a string into an AST. The any is all the types in tokens.go.
-func Parse(input string) ([]any, *ParserError) {
+// Defines the keywords which start a declaration at the document root.
+var declarationKeywords = []string{"struct", "contract", "mapping", "exception", "enum", "extends", "import"}
+
+// Checks if the line is the start of a declaration at the document root. Declarations at the root
+// are not indented, so this is only true if the line starts with a keyword, decorator, or comment.
+func isDeclarationLine(line string) bool {
+	if strings.HasPrefix(line, "@") || strings.HasPrefix(line, "//") {
+		return true
+	}
+	for _, keyword := range declarationKeywords {
+		if strings.HasPrefix(line, keyword) && len(line) > len(keyword) {
+			switch line[len(keyword)] {
+			case ' ', '\t', '\r', '\n':
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Finds the position to continue parsing from after an error in the declaration at pos. This is
+// the start of the next line which starts a declaration, or the end of the input.
+func findRecoveryPoint(input string, pos int) int {
+	for {
+		i := strings.IndexByte(input[pos:], '\n')
+		if i == -1 {
+			return len(input)
+		}
+		pos += i + 1
+		if isDeclarationLine(input[pos:]) {
+			return pos
+		}
+	}
+}
+
+// ParseAll is used to parse a string into an AST and return every error within it. When a
+// declaration fails to parse, the parser skips to the next line that starts a declaration at
+// the document root and carries on from there. The tokens for the declarations that did parse
+// are returned along with the errors in the order they are within the input. Comments directly
+// above a declaration or field are set as its doc comment as well as being returned as tokens.
+func ParseAll(input string) ([]any, []*ParserError) {
 	r := strings.NewReader(input)
 	tokens := []any{}
+	var errs []*ParserError
 
 	for {
 		// Read the next Unicode character.
 		c, _, err := r.ReadRune()
 		if err != nil {
 			// End of file.
-			return tokens, nil
+			attachDocComments(input, tokens)
+			return tokens, errs
 		}
 
 		switch c {
@@ -793,9 +1262,32 @@ func Parse(input string) ([]any, *ParserError) {
 			// Ignore whitespace.
 		default:
 			// Parse tokens.
-			if perr := parseDocRootToken(r, &tokens, c); perr != nil {
-				return nil, perr
+			declPos := 0
+			if perr := parseDocRootToken(r, &tokens, c, &declPos); perr != nil {
+				// Set where the error ends and where it is within the input. Errors for the end of
+				// the file are put at the end of the last line which is not blank, since that is
+				// where something is missing.
+				perr.End = max(getReaderPos(r), perr.Position)
+				if perr.endOfFile() {
+					perr.Position = len(strings.TrimRight(input, " \t\r\n"))
+					perr.End = perr.Position
+				}
+				perr.setLineColumn(input)
+				errs = append(errs, perr)
+
+				// Skip to the next declaration.
+				_, _ = r.Seek(int64(findRecoveryPoint(input, declPos)), io.SeekStart)
 			}
 		}
 	}
 }
+
+// Parse is used to parse a string into an AST. The any is all the types in tokens.go. If there
+// are any errors, the first one is returned. Use ParseAll to get every error.
+func Parse(input string) ([]any, *ParserError) {
+	tokens, errs := ParseAll(input)
+	if errs != nil {
+		return nil, errs[0]
+	}
+	return tokens, nil
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"remixdb.io/ast"
 	"remixdb.io/internal/utils"
 )
@@ -39,13 +40,13 @@ func processFile(fp string, t *testing.T) {
 		t.Fatal(err)
 	}
 
-	// Parse the file.
-	r, perr := ast.Parse(strings.ReplaceAll(string(b), "<<R>>", "\r"))
+	// Parse the file. Every error is included so that recovery is tested.
+	r, errs := ast.ParseAll(strings.ReplaceAll(string(b), "<<R>>", "\r"))
 	var toString any
-	if perr == nil {
+	if errs == nil {
 		toString = r
 	} else {
-		toString = perr
+		toString = errs
 	}
 
 	// Turn it into a spew string.
@@ -113,6 +114,7 @@ func TestParse(t *testing.T) {
 		// Setup spew.
 		spew.Config.DisablePointerAddresses = true
 		spew.Config.SortKeys = true
+		spew.Config.DisableMethods = true
 
 		// If GOLDEN_UPDATE is set to 1, we should delete the testdata/results directory.
 		if os.Getenv("GOLDEN_UPDATE") == "1" {
@@ -127,6 +129,92 @@ func TestParse(t *testing.T) {
 	})
 }
 
+func TestParseAll_recovery(t *testing.T) {
+	tokens, errs := ast.ParseAll(`struct User {
+    name: string
+}
+
+struct Broken {
+    @primary
+}
+
+contract Fine() -> void {
+    return
+}
+`)
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "unexpected decorators pointing to nothing", errs[0].Message)
+
+	// The declarations either side of the broken struct are still parsed.
+	assert.Len(t, tokens, 2)
+	assert.IsType(t, ast.StructToken{}, tokens[0])
+	assert.IsType(t, ast.ContractToken{}, tokens[1])
+}
+
+func TestParserError_Render(t *testing.T) {
+	input := "struct User {\n\tname: string\n}\n\nenum Status {\n\tActive: 1\n}\n"
+	_, perr := ast.Parse(input)
+	assert.Equal(t, 6, perr.Line)
+	assert.Equal(t, 8, perr.Column)
+	assert.Equal(t, "unexpected character ':' within enum (line 6, column 8)", perr.Error())
+	assert.Equal(t, "line 6, column 8: unexpected character ':' within enum\n\tActive: 1\n\t      ^", perr.Render(input))
+
+	// Errors at the end of the file point after the last line which is not blank.
+	input = "struct E { q: int\n\n"
+	_, perr = ast.Parse(input)
+	assert.Equal(t, "line 1, column 18: unexpected end of file before closing bracket\nstruct E { q: int\n                 ^", perr.Render(input))
+}
+
+func TestParseDecoratorArguments(t *testing.T) {
+	values, perr := ast.ParseDecoratorArguments(`1, 'a)', [true], days`)
+	require.Nil(t, perr)
+	assert.Equal(t, []any{
+		ast.NumberLiteralToken{Value: 1, Position: 0},
+		ast.StringLiteralToken{Value: "a)", Position: 3},
+		ast.ArrayLiteralToken{Values: []any{ast.BooleanLiteralToken{Value: true, Position: 10}}, Position: 9},
+		ast.ReferenceToken{Name: "days", Position: 17},
+	}, values)
+
+	values, perr = ast.ParseDecoratorArguments("")
+	require.Nil(t, perr)
+	assert.Empty(t, values)
+
+	for arguments, message := range map[string]string{
+		"1,":       "expected decorator argument",
+		"1 2":      "unexpected '2' after decorator argument",
+		"now()":    "decorator arguments must be literals or references",
+		"[now()]":  "decorator arguments must be literals or references",
+		"1), (2":   "unexpected ')' after decorator arguments",
+		"'closing": "unexpected end of file after single quoted string",
+	} {
+		_, perr = ast.ParseDecoratorArguments(arguments)
+		if assert.NotNil(t, perr, arguments) {
+			assert.Equal(t, message, perr.Message, arguments)
+		}
+	}
+}
+
+func TestDecoratorToken_ArgumentValues(t *testing.T) {
+	// Values are used when they are set.
+	values := []any{ast.NumberLiteralToken{Value: 1, Position: 9}}
+	v, perr := ast.DecoratorToken{Method: "length", Arguments: "1", Values: values}.ArgumentValues()
+	require.Nil(t, perr)
+	assert.Equal(t, values, v)
+
+	// Otherwise the arguments are parsed.
+	v, perr = ast.DecoratorToken{Method: "length", Arguments: "1, 64"}.ArgumentValues()
+	require.Nil(t, perr)
+	assert.Equal(t, []any{
+		ast.NumberLiteralToken{Value: 1, Position: 0},
+		ast.NumberLiteralToken{Value: 64, Position: 3},
+	}, v)
+
+	// Decorators without brackets have no values.
+	v, perr = ast.DecoratorToken{Method: "primary"}.ArgumentValues()
+	require.Nil(t, perr)
+	assert.Nil(t, v)
+}
+
 //go:embed testdata/tests/misc/combo.rql
 var combo string
 
@@ -14,9 +14,14 @@ type DecoratorToken struct {
 	// Method is the method name.
 	Method string
 
-	// Arguments are the arguments of the decorator.
+	// Arguments is the source of the arguments of the decorator without the brackets.
 	Arguments string
 
+	// Values are the arguments of the decorator parsed into literal and reference tokens. This is
+	// nil if the decorator has no brackets. Use ArgumentValues to also handle decorators which
+	// only have Arguments set.
+	Values []any
+
 	// Position is the position of the decorator.
 	Position int
 }
@@ -34,6 +39,10 @@ type FieldToken struct {
 
 	// Decorators are the decorators of the field.
 	Decorators []DecoratorToken
+
+	// Comment is the doc comment from the comments directly above the field and its decorators.
+	// Can be blank.
+	Comment string
 }
 
 // ReferenceToken is used to define a reference to something that is implied to be a variable or
@@ -63,6 +72,59 @@ type StructToken struct {
 	// Fields are the fields of the struct. They can be any of
 	// CommentToken, FieldToken, or ReferenceToken.
 	Fields []any
+
+	// Comment is the doc comment from the comments directly above the struct and its decorators.
+	// Can be blank.
+	Comment string
+}
+
+// ExceptionToken is used to define an exception. Exceptions are declared like structs, but
+// are only used to describe the body of errors thrown by contracts.
+type ExceptionToken struct {
+	// Name is the name of the exception.
+	Name string
+
+	// Position is the position of the exception.
+	Position int
+
+	// Decorators are the decorators of the exception.
+	Decorators []DecoratorToken
+
+	// Fields are the fields of the exception. They can be any of
+	// CommentToken, FieldToken, or ReferenceToken.
+	Fields []any
+
+	// Comment is the doc comment from the comments directly above the exception and its decorators.
+	// Can be blank.
+	Comment string
+}
+
+// EnumToken is used to define a enum. A enum is a string which can only be one of the values.
+type EnumToken struct {
+	// Name is the name of the enum.
+	Name string
+
+	// Position is the position of the enum.
+	Position int
+
+	// Decorators are the decorators of the enum.
+	Decorators []DecoratorToken
+
+	// Values are the values of the enum. They can be any of CommentToken or EnumValueToken.
+	Values []any
+
+	// Comment is the doc comment from the comments directly above the enum and its decorators.
+	// Can be blank.
+	Comment string
+}
+
+// EnumValueToken is used to define a value within a enum.
+type EnumValueToken struct {
+	// Name is the name of the value.
+	Name string
+
+	// Position is the position of the value.
+	Position int
 }
 
 // ExtendsToken is used to define something that extends another thing.
@@ -74,6 +136,15 @@ type ExtendsToken struct {
 	Position int
 }
 
+// ImportToken is used to define a import of another RQL file.
+type ImportToken struct {
+	// Path is the path of the file being imported. This is relative to the file with the import.
+	Path string
+
+	// Position is the position of the import.
+	Position int
+}
+
 // ReturnToken is used to define a return statement.
 type ReturnToken struct {
 	// Token is the token that is being returned.
@@ -127,7 +198,7 @@ type FloatLiteralToken struct {
 
 // BigIntLiteralToken is used to define a big integer literal.
 type BigIntLiteralToken struct {
-	// Value is the value of the big integer literal.
+	// Value is the value of the big integer literal in base 10.
 	Value string
 
 	// Position is the position of the big integer literal.
@@ -403,6 +474,9 @@ type ContractToken struct {
 	// ReturnType is the return type of the contract.
 	ReturnType string
 
+	// ReturnTypeIndex is the index of the return type in the file.
+	ReturnTypeIndex int
+
 	// Position is the position of the contract.
 	Position int
 
@@ -414,6 +488,10 @@ type ContractToken struct {
 
 	// Statements are the statements of the contract.
 	Statements []any
+
+	// Comment is the doc comment from the comments directly above the contract and its decorators.
+	// Can be blank.
+	Comment string
 }
 
 // MappingPartialToken is used to define a partial mapping.
@@ -444,6 +522,10 @@ type MappingToken struct {
 
 	// Decorators are the decorators of the mapping.
 	Decorators []DecoratorToken
+
+	// Comment is the doc comment from the comments directly above the mapping and its decorators.
+	// Can be blank.
+	Comment string
 }
 
 // ElseToken is used to define an else statement.
@@ -0,0 +1,442 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package ast
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Visitor is used to visit tokens with Walk. Visit is called for each token. If the visitor it
+// returns is not nil, Walk visits each child of the token with it followed by a call of
+// w.Visit(nil).
+type Visitor interface {
+	Visit(token any) (w Visitor)
+}
+
+// Cursor is used to describe the token being visited by Apply and to replace or delete it.
+type Cursor struct {
+	token     any
+	parent    any
+	field     string
+	index     int
+	key       string
+	deletable bool
+	replaced  bool
+	deleted   bool
+}
+
+// Token is used to get the token being visited. This is the replacement if Replace was called.
+func (c *Cursor) Token() any { return c.token }
+
+// Parent is used to get the token which contains the token being visited as it was before any
+// of its children were replaced. This is nil for the tokens passed to Apply.
+func (c *Cursor) Parent() any { return c.parent }
+
+// Field is used to get the name of the field within the parent which contains the token, such as
+// Statements or Left. This is blank for the tokens passed to Apply.
+func (c *Cursor) Field() string { return c.field }
+
+// Index is used to get the index of the token within the slice it is in, or -1 if it is not
+// within a slice.
+func (c *Cursor) Index() int { return c.index }
+
+// Key is used to get the key of the token within the Values of a ObjectLiteralToken, or blank if
+// it is not within one.
+func (c *Cursor) Key() string { return c.key }
+
+// Replace is used to replace the token being visited. If this is called from the pre function,
+// the children of the replacement are visited. Panics if the field cannot hold the token.
+func (c *Cursor) Replace(token any) {
+	c.token = token
+	c.replaced = true
+}
+
+// Delete is used to remove the token being visited from the slice or map it is within. Panics if
+// the token is not within a slice or map.
+func (c *Cursor) Delete() {
+	if !c.deletable {
+		panic("ast: Delete called on a token which is not within a slice or map")
+	}
+	c.deleted = true
+	c.replaced = true
+}
+
+// ApplyFunc is called by Apply for each token. See Apply for what the result means.
+type ApplyFunc func(c *Cursor) bool
+
+// Defines the state of a traversal.
+type applier struct {
+	pre, post ApplyFunc
+	stopped   bool
+}
+
+// Visits a token and its children. Returns the token with any replacements, if anything was
+// replaced and if the token was deleted.
+func (a *applier) apply(parent any, field string, index int, key string, deletable bool, token any) (any, bool, bool) {
+	c := &Cursor{
+		token: token, parent: parent, field: field, index: index, key: key, deletable: deletable,
+	}
+	if a.pre != nil && !a.pre(c) {
+		return c.token, c.replaced, c.deleted
+	}
+	if c.deleted {
+		return nil, true, true
+	}
+
+	if t, changed := a.children(c.token); changed {
+		c.token = t
+		c.replaced = true
+	}
+
+	if a.post != nil && !a.stopped && !a.post(c) {
+		a.stopped = true
+	}
+	if c.deleted {
+		return nil, true, true
+	}
+	return c.token, c.replaced, false
+}
+
+// Casts a replacement into the type the field holds.
+func cast[T any](token any, field string) T {
+	t, ok := token.(T)
+	if !ok {
+		panic(fmt.Sprintf("ast: cannot put a %T into %s", token, field))
+	}
+	return t
+}
+
+// Visits a field which holds any token. Nil is not visited.
+func (a *applier) node(parent any, field string, token any) (any, bool) {
+	if token == nil || a.stopped {
+		return token, false
+	}
+	t, changed, _ := a.apply(parent, field, -1, "", false, token)
+	return t, changed
+}
+
+// Visits a field which holds a pointer to a token. The token is visited rather than the pointer.
+func applyPointer[T any](a *applier, parent any, field string, token *T) (*T, bool) {
+	if token == nil || a.stopped {
+		return token, false
+	}
+	t, changed, _ := a.apply(parent, field, -1, "", false, *token)
+	if !changed {
+		return token, false
+	}
+	v := cast[T](t, field)
+	return &v, true
+}
+
+// Visits each token within a slice. The slice is only copied if something within it changes.
+func applySlice[T any](a *applier, parent any, field string, tokens []T) ([]T, bool) {
+	var out []T
+	for i, token := range tokens {
+		var t any = token
+		changed, deleted := false, false
+		if !a.stopped {
+			t, changed, deleted = a.apply(parent, field, i, "", true, token)
+		}
+		if changed && out == nil {
+			out = append(make([]T, 0, len(tokens)), tokens[:i]...)
+		}
+		if out != nil && !deleted {
+			out = append(out, cast[T](t, field))
+		}
+	}
+	if out == nil {
+		return tokens, false
+	}
+	return out, true
+}
+
+// Visits the values of a object literal in the order of their keys. The map is only copied if
+// something within it changes.
+func (a *applier) values(parent any, values map[string]any) (map[string]any, bool) {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var out map[string]any
+	for _, k := range keys {
+		if a.stopped {
+			break
+		}
+		t, changed, deleted := a.apply(parent, "Values", -1, k, true, values[k])
+		if !changed {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]any, len(values))
+			for k, v := range values {
+				out[k] = v
+			}
+		}
+		if deleted {
+			delete(out, k)
+		} else {
+			out[k] = t
+		}
+	}
+	if out == nil {
+		return values, false
+	}
+	return out, true
+}
+
+// Visits the value of a mapping if it is a nested mapping rather than a string.
+func (a *applier) mappingValue(parent any, value any) (any, bool) {
+	if _, ok := value.(MappingPartialToken); !ok {
+		return value, false
+	}
+	return a.node(parent, "Value", value)
+}
+
+// Checks if any of the values are true.
+func anyOf(changed ...bool) bool {
+	for _, c := range changed {
+		if c {
+			return true
+		}
+	}
+	return false
+}
+
+// Visits the children of a token in the order they are within the source. Comments which are
+// stored apart from what they are next to are visited after the rest of the children. Returns
+// a copy of the token with any replacements and if anything changed.
+func (a *applier) children(token any) (any, bool) {
+	var c [4]bool
+	switch x := token.(type) {
+	case []any:
+		return applySlice(a, nil, "", x)
+	case DecoratorToken:
+		x.Values, c[0] = applySlice(a, token, "Values", x.Values)
+		return x, c[0]
+	case FieldToken:
+		x.Decorators, c[0] = applySlice(a, token, "Decorators", x.Decorators)
+		return x, c[0]
+	case ReferenceToken:
+		x.Decorators, c[0] = applySlice(a, token, "Decorators", x.Decorators)
+		return x, c[0]
+	case StructToken:
+		x.Decorators, c[0] = applySlice(a, token, "Decorators", x.Decorators)
+		x.Fields, c[1] = applySlice(a, token, "Fields", x.Fields)
+		return x, anyOf(c[:]...)
+	case ExceptionToken:
+		x.Decorators, c[0] = applySlice(a, token, "Decorators", x.Decorators)
+		x.Fields, c[1] = applySlice(a, token, "Fields", x.Fields)
+		return x, anyOf(c[:]...)
+	case EnumToken:
+		x.Decorators, c[0] = applySlice(a, token, "Decorators", x.Decorators)
+		x.Values, c[1] = applySlice(a, token, "Values", x.Values)
+		return x, anyOf(c[:]...)
+	case ExtendsToken:
+		x.Token, c[0] = a.node(token, "Token", x.Token)
+		return x, c[0]
+	case ReturnToken:
+		x.Token, c[0] = a.node(token, "Token", x.Token)
+		return x, c[0]
+	case ArrayLiteralToken:
+		x.Values, c[0] = applySlice(a, token, "Values", x.Values)
+		return x, c[0]
+	case ObjectLiteralToken:
+		x.Values, c[0] = a.values(token, x.Values)
+		x.Comments, c[1] = applySlice(a, token, "Comments", x.Comments)
+		return x, anyOf(c[:]...)
+	case MethodCallToken:
+		x.Arguments, c[0] = applySlice(a, token, "Arguments", x.Arguments)
+		x.ChainedCall, c[1] = a.node(token, "ChainedCall", x.ChainedCall)
+		return x, anyOf(c[:]...)
+	case AssignmentToken:
+		x.Value, c[0] = a.node(token, "Value", x.Value)
+		return x, c[0]
+	case AddToken:
+		x.Left, c[0] = a.node(token, "Left", x.Left)
+		x.Right, c[1] = a.node(token, "Right", x.Right)
+		return x, anyOf(c[:]...)
+	case LessThanToken:
+		x.Left, c[0] = a.node(token, "Left", x.Left)
+		x.Right, c[1] = a.node(token, "Right", x.Right)
+		return x, anyOf(c[:]...)
+	case GreaterThanToken:
+		x.Left, c[0] = a.node(token, "Left", x.Left)
+		x.Right, c[1] = a.node(token, "Right", x.Right)
+		return x, anyOf(c[:]...)
+	case LessThanOrEqualToken:
+		x.Left, c[0] = a.node(token, "Left", x.Left)
+		x.Right, c[1] = a.node(token, "Right", x.Right)
+		return x, anyOf(c[:]...)
+	case GreaterThanOrEqualToken:
+		x.Left, c[0] = a.node(token, "Left", x.Left)
+		x.Right, c[1] = a.node(token, "Right", x.Right)
+		return x, anyOf(c[:]...)
+	case EqualToken:
+		x.Left, c[0] = a.node(token, "Left", x.Left)
+		x.Right, c[1] = a.node(token, "Right", x.Right)
+		return x, anyOf(c[:]...)
+	case NotEqualToken:
+		x.Left, c[0] = a.node(token, "Left", x.Left)
+		x.Right, c[1] = a.node(token, "Right", x.Right)
+		return x, anyOf(c[:]...)
+	case AndToken:
+		x.Left, c[0] = a.node(token, "Left", x.Left)
+		x.Right, c[1] = a.node(token, "Right", x.Right)
+		return x, anyOf(c[:]...)
+	case OrToken:
+		x.Left, c[0] = a.node(token, "Left", x.Left)
+		x.Right, c[1] = a.node(token, "Right", x.Right)
+		return x, anyOf(c[:]...)
+	case MultiplyToken:
+		x.Left, c[0] = a.node(token, "Left", x.Left)
+		x.Right, c[1] = a.node(token, "Right", x.Right)
+		return x, anyOf(c[:]...)
+	case SubtractToken:
+		x.Left, c[0] = a.node(token, "Left", x.Left)
+		x.Right, c[1] = a.node(token, "Right", x.Right)
+		return x, anyOf(c[:]...)
+	case DivideToken:
+		x.Left, c[0] = a.node(token, "Left", x.Left)
+		x.Right, c[1] = a.node(token, "Right", x.Right)
+		return x, anyOf(c[:]...)
+	case ModuloToken:
+		x.Left, c[0] = a.node(token, "Left", x.Left)
+		x.Right, c[1] = a.node(token, "Right", x.Right)
+		return x, anyOf(c[:]...)
+	case ExponentToken:
+		x.Left, c[0] = a.node(token, "Left", x.Left)
+		x.Right, c[1] = a.node(token, "Right", x.Right)
+		return x, anyOf(c[:]...)
+	case ThrowLiteralToken:
+		x.Token, c[0] = a.node(token, "Token", x.Token)
+		return x, c[0]
+	case ContractToken:
+		x.Decorators, c[0] = applySlice(a, token, "Decorators", x.Decorators)
+		x.Argument, c[1] = applyPointer(a, token, "Argument", x.Argument)
+		x.Throws, c[2] = applySlice(a, token, "Throws", x.Throws)
+		x.Statements, c[3] = applySlice(a, token, "Statements", x.Statements)
+		return x, anyOf(c[:]...)
+	case MappingPartialToken:
+		x.Value, c[0] = a.mappingValue(token, x.Value)
+		x.Comments, c[1] = applySlice(a, token, "Comments", x.Comments)
+		return x, anyOf(c[:]...)
+	case MappingToken:
+		x.Decorators, c[0] = applySlice(a, token, "Decorators", x.Decorators)
+		x.Value, c[1] = a.mappingValue(token, x.Value)
+		x.Comments, c[2] = applySlice(a, token, "Comments", x.Comments)
+		return x, anyOf(c[:]...)
+	case ElseToken:
+		x.Condition, c[0] = a.node(token, "Condition", x.Condition)
+		x.Statements, c[1] = applySlice(a, token, "Statements", x.Statements)
+		x.Next, c[2] = applyPointer(a, token, "Next", x.Next)
+		return x, anyOf(c[:]...)
+	case UnlessToken:
+		x.Condition, c[0] = a.node(token, "Condition", x.Condition)
+		x.Statements, c[1] = applySlice(a, token, "Statements", x.Statements)
+		x.Else, c[2] = applyPointer(a, token, "Else", x.Else)
+		return x, anyOf(c[:]...)
+	case IfToken:
+		x.Condition, c[0] = a.node(token, "Condition", x.Condition)
+		x.Statements, c[1] = applySlice(a, token, "Statements", x.Statements)
+		x.Else, c[2] = applyPointer(a, token, "Else", x.Else)
+		return x, anyOf(c[:]...)
+	case ForToken:
+		x.Assignment, c[0] = a.node(token, "Assignment", x.Assignment)
+		x.Condition, c[1] = a.node(token, "Condition", x.Condition)
+		x.Increment, c[2] = a.node(token, "Increment", x.Increment)
+		x.Statements, c[3] = applySlice(a, token, "Statements", x.Statements)
+		return x, anyOf(c[:]...)
+	case WhileToken:
+		x.Condition, c[0] = a.node(token, "Condition", x.Condition)
+		x.Statements, c[1] = applySlice(a, token, "Statements", x.Statements)
+		return x, anyOf(c[:]...)
+	case InlineIfToken:
+		x.Condition, c[0] = a.node(token, "Condition", x.Condition)
+		x.Token, c[1] = a.node(token, "Token", x.Token)
+		return x, anyOf(c[:]...)
+	case InlineUnlessToken:
+		x.Condition, c[0] = a.node(token, "Condition", x.Condition)
+		x.Token, c[1] = a.node(token, "Token", x.Token)
+		return x, anyOf(c[:]...)
+	case CatchToken:
+		x.Statements, c[0] = applySlice(a, token, "Statements", x.Statements)
+		x.Next, c[1] = applyPointer(a, token, "Next", x.Next)
+		return x, anyOf(c[:]...)
+	case TryToken:
+		x.Statements, c[0] = applySlice(a, token, "Statements", x.Statements)
+		x.Catch, c[1] = applyPointer(a, token, "Catch", x.Catch)
+		return x, anyOf(c[:]...)
+	case SwitchCaseToken:
+		x.Name, c[0] = a.node(token, "Name", x.Name)
+		x.Statements, c[1] = applySlice(a, token, "Statements", x.Statements)
+		return x, anyOf(c[:]...)
+	case SwitchToken:
+		x.Condition, c[0] = a.node(token, "Condition", x.Condition)
+		x.Cases, c[1] = applySlice(a, token, "Cases", x.Cases)
+		x.Comments, c[2] = applySlice(a, token, "Comments", x.Comments)
+		return x, anyOf(c[:]...)
+	case NotToken:
+		x.Token, c[0] = a.node(token, "Token", x.Token)
+		return x, c[0]
+	}
+
+	// The rest of the tokens do not have children.
+	return token, false
+}
+
+// Apply is used to traverse a token and its children, calling pre before the children of each
+// token are visited and post after. Either function can be nil. The token can also be a slice of
+// tokens such as the result of Parse, in which case each token is visited.
+//
+// If pre returns false, the children of the token and post are skipped. If post returns false,
+// the traversal stops. Tokens can be replaced or deleted with the cursor. The tokens passed in
+// are never changed; instead, the tokens which contain a replacement are copied and the result
+// is returned. If nothing is replaced, the token passed in is returned.
+//
+// Every token within tokens.go is visited, including decorators, comments, contract arguments,
+// else and catch chains, and switch cases. Pointers to tokens are visited as the token they point
+// to. Children are visited in the order they are within the source, except comments which are
+// stored apart from the tokens they are next to and are visited after the other children.
+func Apply(token any, pre, post ApplyFunc) any {
+	a := &applier{pre: pre, post: post}
+	if tokens, ok := token.([]any); ok {
+		t, _ := a.children(tokens)
+		return t
+	}
+	if token == nil {
+		return nil
+	}
+	t, _, _ := a.apply(nil, "", -1, "", false, token)
+	return t
+}
+
+// Inspect is used to traverse a token and its children in the same order as Apply. If f returns
+// false for a token, its children are skipped. The token can also be a slice of tokens.
+func Inspect(token any, f func(token any) bool) {
+	Apply(token, func(c *Cursor) bool {
+		return f(c.Token())
+	}, nil)
+}
+
+// Walk is used to traverse a token and its children in the same order as Apply with a visitor.
+// The token can also be a slice of tokens. See Visitor for how the visitor is called.
+func Walk(v Visitor, token any) {
+	var stack []Visitor
+	Apply(token, func(c *Cursor) bool {
+		w := v.Visit(c.Token())
+		if w == nil {
+			return false
+		}
+		stack = append(stack, v)
+		v = w
+		return true
+	}, func(c *Cursor) bool {
+		v.Visit(nil)
+		v, stack = stack[len(stack)-1], stack[:len(stack)-1]
+		return true
+	})
+}
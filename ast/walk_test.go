@@ -0,0 +1,225 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package ast_test
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"remixdb.io/ast"
+)
+
+// Counts every token within the value using reflection. Embedded structs are part of the token
+// which embeds them, so they are not counted.
+func countTokens(v reflect.Value, counts map[string]int) {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if !v.IsNil() {
+			countTokens(v.Elem(), counts)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			countTokens(v.Index(i), counts)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			countTokens(iter.Value(), counts)
+		}
+	case reflect.Struct:
+		counts[v.Type().String()]++
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			if f.Anonymous {
+				// Count the fields of the embedded struct without the struct itself.
+				embedded := map[string]int{}
+				countTokens(v.Field(i), embedded)
+				embedded[f.Type.String()]--
+				for k, n := range embedded {
+					if counts[k] += n; counts[k] == 0 {
+						delete(counts, k)
+					}
+				}
+				continue
+			}
+			countTokens(v.Field(i), counts)
+		}
+	}
+}
+
+func TestInspect_visitsEveryToken(t *testing.T) {
+	seen := map[string]bool{}
+	err := filepath.WalkDir("testdata/tests", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".rql") {
+			return err
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		tokens, _ := ast.ParseAll(string(b))
+
+		want := map[string]int{}
+		countTokens(reflect.ValueOf(tokens), want)
+		got := map[string]int{}
+		ast.Inspect(tokens, func(token any) bool {
+			got[fmt.Sprintf("%T", token)]++
+			seen[fmt.Sprintf("%T", token)] = true
+			return true
+		})
+		assert.Equal(t, want, got, path)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Make sure the corpus has the tokens with the most nesting in it.
+	for _, name := range []string{"ast.ElseToken", "ast.CatchToken", "ast.SwitchCaseToken", "ast.MappingPartialToken"} {
+		assert.True(t, seen[name], name)
+	}
+}
+
+const walkContract = `contract Test(x: int) -> int {
+    if x > 1 {
+        // Hello
+        return x + 1
+    }
+    return 2
+}
+`
+
+func TestInspect_order(t *testing.T) {
+	tokens, perr := ast.Parse(walkContract)
+	require.Nil(t, perr)
+
+	var order []string
+	ast.Inspect(tokens, func(token any) bool {
+		order = append(order, strings.TrimPrefix(fmt.Sprintf("%T", token), "ast."))
+		// Skip the inside of the return statements.
+		_, ok := token.(ast.ReturnToken)
+		return !ok
+	})
+	assert.Equal(t, []string{
+		"ContractToken", "ContractArgumentToken", "IfToken", "GreaterThanToken", "ReferenceToken",
+		"NumberLiteralToken", "CommentToken", "ReturnToken", "ReturnToken",
+	}, order)
+}
+
+// Defines a visitor which records when it enters and leaves tokens.
+type recordingVisitor struct {
+	events *[]string
+}
+
+func (v recordingVisitor) Visit(token any) ast.Visitor {
+	if token == nil {
+		*v.events = append(*v.events, "end")
+		return nil
+	}
+	*v.events = append(*v.events, strings.TrimPrefix(fmt.Sprintf("%T", token), "ast."))
+	return v
+}
+
+func TestWalk(t *testing.T) {
+	tokens, perr := ast.Parse("contract Test() -> int {\n    return 1 + 2\n}\n")
+	require.Nil(t, perr)
+
+	var events []string
+	ast.Walk(recordingVisitor{events: &events}, tokens)
+	assert.Equal(t, []string{
+		"ContractToken",
+		"ReturnToken",
+		"AddToken",
+		"NumberLiteralToken", "end",
+		"NumberLiteralToken", "end",
+		"end",
+		"end",
+		"end",
+	}, events)
+}
+
+func TestApply_replace(t *testing.T) {
+	tokens, perr := ast.Parse(walkContract)
+	require.Nil(t, perr)
+
+	// Replace every number with one more than it and track where each one was.
+	var fields []string
+	result := ast.Apply(tokens, nil, func(c *ast.Cursor) bool {
+		if n, ok := c.Token().(ast.NumberLiteralToken); ok {
+			fields = append(fields, fmt.Sprintf("%T.%s", c.Parent(), c.Field()))
+			n.Value++
+			c.Replace(n)
+		}
+		return true
+	})
+	assert.Equal(t, []string{"ast.GreaterThanToken.Right", "ast.AddToken.Right", "ast.ReturnToken.Token"}, fields)
+	assert.Equal(t, "contract Test(x: int) -> int {\n    if x > 2 {\n        // Hello\n        return x + 2\n    }\n    return 3\n}\n",
+		ast.Format(result.([]any)))
+
+	// The tokens passed in are not changed.
+	assert.Equal(t, walkContract, ast.Format(tokens))
+
+	// Nothing is copied if nothing is replaced.
+	same := ast.Apply(tokens, func(*ast.Cursor) bool { return true }, nil)
+	assert.Equal(t, reflect.ValueOf(tokens).Pointer(), reflect.ValueOf(same).Pointer())
+}
+
+func TestApply_delete(t *testing.T) {
+	tokens, perr := ast.Parse(walkContract)
+	require.Nil(t, perr)
+
+	result := ast.Apply(tokens, func(c *ast.Cursor) bool {
+		if _, ok := c.Token().(ast.CommentToken); ok {
+			assert.Equal(t, "Statements", c.Field())
+			assert.Equal(t, 0, c.Index())
+			c.Delete()
+		}
+		return true
+	}, nil)
+	assert.Equal(t, "contract Test(x: int) -> int {\n    if x > 1 {\n        return x + 1\n    }\n    return 2\n}\n",
+		ast.Format(result.([]any)))
+
+	// Tokens which are not within a slice or map cannot be deleted.
+	assert.Panics(t, func() {
+		ast.Apply(tokens, func(c *ast.Cursor) bool {
+			if _, ok := c.Token().(ast.GreaterThanToken); ok {
+				c.Delete()
+			}
+			return true
+		}, nil)
+	})
+
+	// Replacements must fit within the field.
+	assert.Panics(t, func() {
+		ast.Apply(tokens, func(c *ast.Cursor) bool {
+			if _, ok := c.Token().(ast.ContractArgumentToken); ok {
+				c.Replace(ast.NullLiteralToken{})
+			}
+			return true
+		}, nil)
+	})
+}
+
+func TestApply_stop(t *testing.T) {
+	tokens, perr := ast.Parse(walkContract)
+	require.Nil(t, perr)
+
+	count := 0
+	ast.Apply(tokens, func(*ast.Cursor) bool {
+		count++
+		return true
+	}, func(c *ast.Cursor) bool {
+		_, ok := c.Token().(ast.ContractArgumentToken)
+		return !ok
+	})
+	assert.Equal(t, 2, count)
+}
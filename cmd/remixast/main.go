@@ -23,10 +23,12 @@ func main() {
 		panic(err)
 	}
 	file := string(b)
-	parsed, perr := ast.Parse(file)
-	if perr != nil {
+	parsed, errs := ast.ParseAll(file)
+	if errs != nil {
 		_, _ = os.Stderr.WriteString("Error parsing file:\n")
-		spew.Fdump(os.Stderr, perr)
+		for _, perr := range errs {
+			_, _ = os.Stderr.WriteString(perr.Render(file) + "\n\n")
+		}
 		os.Exit(1)
 	}
 	spew.Dump(parsed)
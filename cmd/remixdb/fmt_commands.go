@@ -0,0 +1,32 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package main
+
+import (
+	"github.com/urfave/cli/v2"
+	"remixdb.io/cmd/remixdb/format"
+)
+
+var fmtCommand = &cli.Command{
+	Name:      "fmt",
+	Usage:     "Formats RQL files. Directories are searched for .rql files and stdin is used if no paths are given. The formatted output is written to stdout unless --write or --check is used.",
+	ArgsUsage: "[paths...]",
+	Action:    format.Format,
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:    "write",
+			Aliases: []string{"w"},
+			Usage:   "Writes the formatted output back to the files instead of stdout.",
+		},
+		&cli.BoolFlag{
+			Name:    "check",
+			Aliases: []string{"c"},
+			Usage:   "Lists the files which are not formatted and errors if there are any. Nothing is written.",
+		},
+	},
+}
+
+func init() {
+	app.Commands = append(app.Commands, fmtCommand)
+}
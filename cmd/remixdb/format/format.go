@@ -0,0 +1,135 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package format
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/urfave/cli/v2"
+	"remixdb.io/ast"
+)
+
+// Gets all of the .rql files from the paths. Directories are searched recursively.
+func findFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && filepath.Ext(path) == ".rql" {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// Formats the input. If it fails to parse, every error is written to stderr with the name.
+func formatInput(ctx *cli.Context, name, input string) (string, bool) {
+	tokens, errs := ast.ParseAll(input)
+	if errs != nil {
+		for _, perr := range errs {
+			_, _ = fmt.Fprintf(ctx.App.ErrWriter, "%s: %s\n\n", name, perr.Render(input))
+		}
+		return "", false
+	}
+	return ast.Format(tokens), true
+}
+
+// Format is used to format RQL files.
+func Format(ctx *cli.Context) error {
+	write, check := ctx.Bool("write"), ctx.Bool("check")
+	if write && check {
+		return errors.New("--write and --check cannot be used together")
+	}
+
+	// Handle formatting stdin.
+	if ctx.NArg() == 0 {
+		if write {
+			return errors.New("--write cannot be used with stdin")
+		}
+		b, err := io.ReadAll(ctx.App.Reader)
+		if err != nil {
+			return err
+		}
+		formatted, ok := formatInput(ctx, "<stdin>", string(b))
+		if !ok {
+			return errors.New("failed to parse stdin")
+		}
+		if check {
+			if formatted != string(b) {
+				return errors.New("stdin is not formatted")
+			}
+			return nil
+		}
+		_, err = io.WriteString(ctx.App.Writer, formatted)
+		return err
+	}
+
+	// Get the files.
+	files, err := findFiles(ctx.Args().Slice())
+	if err != nil {
+		return err
+	}
+
+	// Format each file.
+	failed, unformatted := 0, 0
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		formatted, ok := formatInput(ctx, file, string(b))
+		if !ok {
+			failed++
+			continue
+		}
+
+		switch {
+		case check:
+			if formatted != string(b) {
+				unformatted++
+				_, _ = fmt.Fprintln(ctx.App.Writer, file)
+			}
+		case write:
+			if formatted != string(b) {
+				if err := os.WriteFile(file, []byte(formatted), 0o644); err != nil {
+					return err
+				}
+			}
+		default:
+			if _, err := io.WriteString(ctx.App.Writer, formatted); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Return any errors.
+	if failed != 0 {
+		return errors.New(strconv.Itoa(failed) + " file(s) failed to parse")
+	}
+	if unformatted != 0 {
+		return errors.New(strconv.Itoa(unformatted) + " file(s) are not formatted")
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package langserver
+
+import (
+	"github.com/urfave/cli/v2"
+	"remixdb.io/internal/lsp"
+)
+
+// Serve is used to run the language server over the stdin and stdout of the app until the
+// editor exits.
+func Serve(ctx *cli.Context) error {
+	return lsp.NewServer(ctx.App.Reader, ctx.App.Writer).Run()
+}
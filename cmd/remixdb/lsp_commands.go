@@ -0,0 +1,19 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package main
+
+import (
+	"github.com/urfave/cli/v2"
+	"remixdb.io/cmd/remixdb/langserver"
+)
+
+var lspCommand = &cli.Command{
+	Name:   "lsp",
+	Usage:  "Starts a language server for RQL files which talks to the editor over stdin and stdout.",
+	Action: langserver.Serve,
+}
+
+func init() {
+	app.Commands = append(app.Commands, lspCommand)
+}
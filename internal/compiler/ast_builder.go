@@ -86,7 +86,9 @@ func (b *astBuilder) addFunc(
 
 func (b *astBuilder) string() (string, error) {
 	var buf strings.Builder
-	if err := printer.Fprint(&buf, token.NewFileSet(), &b.f); err != nil {
+	// Use the same settings as gofmt so that alignment is done with spaces rather than tabs.
+	cfg := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+	if err := cfg.Fprint(&buf, token.NewFileSet(), &b.f); err != nil {
 		return "", err
 	}
 	return buf.String(), nil
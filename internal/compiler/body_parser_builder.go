@@ -6,9 +6,11 @@ package compiler
 import (
 	goAst "go/ast"
 	"go/token"
+	"strconv"
 	"strings"
 
 	"remixdb.io/ast"
+	"remixdb.io/internal/rqltypes"
 )
 
 // Creates the body parser.
@@ -255,3 +257,200 @@ func (f *functionBody) createBodyParser(contract *ast.ContractToken) {
 		}
 	}
 }
+
+// Creates a body parser for any type which is not specially handled. This uses the RemixDB
+// decoder on the RPC structure to turn the body into the Go representation of the type.
+func (b *statementBuilder) createGenericBodyParser(t rqltypes.Type) {
+	goType := b.goType(t)
+
+	// Declare the body variable.
+	b.f.body = append(b.f.body, &goAst.DeclStmt{
+		Decl: &goAst.GenDecl{
+			Tok: token.VAR,
+			Specs: []goAst.Spec{
+				&goAst.ValueSpec{
+					Names: []*goAst.Ident{
+						goAst.NewIdent("body"),
+					},
+					Type: goAst.NewIdent(goType),
+				},
+			},
+		},
+	})
+
+	// Add the parser to the interface.
+	b.addToInterface("ParseRemixDBBody", &goAst.FuncType{
+		Params: &goAst.FieldList{
+			List: []*goAst.Field{
+				{
+					Names: []*goAst.Ident{
+						goAst.NewIdent("type_"),
+					},
+					Type: goAst.NewIdent("string"),
+				},
+			},
+		},
+		Results: &goAst.FieldList{
+			List: []*goAst.Field{
+				{
+					Type: goAst.NewIdent("any"),
+				},
+				{
+					Type: goAst.NewIdent("error"),
+				},
+			},
+		},
+	})
+
+	// Parse the body inside a block and respond with a exception if it is invalid.
+	b.f.body = append(b.f.body, &goAst.BlockStmt{
+		List: []goAst.Stmt{
+			&goAst.AssignStmt{
+				Lhs: []goAst.Expr{
+					goAst.NewIdent("v"),
+					goAst.NewIdent("err"),
+				},
+				Tok: token.DEFINE,
+				Rhs: []goAst.Expr{
+					&goAst.CallExpr{
+						Fun: &goAst.SelectorExpr{
+							X:   goAst.NewIdent("r"),
+							Sel: goAst.NewIdent("ParseRemixDBBody"),
+						},
+						Args: []goAst.Expr{
+							&goAst.BasicLit{
+								Kind:  token.STRING,
+								Value: strconv.Quote(t.String()),
+							},
+						},
+					},
+				},
+			},
+			&goAst.IfStmt{
+				Cond: &goAst.BinaryExpr{
+					X:  goAst.NewIdent("err"),
+					Op: token.NEQ,
+					Y:  goAst.NewIdent("nil"),
+				},
+				Body: &goAst.BlockStmt{
+					List: []goAst.Stmt{
+						&goAst.ExprStmt{
+							X: &goAst.CallExpr{
+								Fun: &goAst.SelectorExpr{
+									X:   goAst.NewIdent("r"),
+									Sel: goAst.NewIdent("RespondWithRemixDBException"),
+								},
+								Args: []goAst.Expr{
+									&goAst.BasicLit{
+										Kind:  token.INT,
+										Value: "400",
+									},
+									&goAst.BasicLit{
+										Kind:  token.STRING,
+										Value: `"invalid_body"`,
+									},
+									&goAst.CallExpr{
+										Fun: goAst.NewIdent("err.Error"),
+									},
+								},
+							},
+						},
+						&goAst.ReturnStmt{
+							Results: []goAst.Expr{
+								goAst.NewIdent("nil"),
+							},
+						},
+					},
+				},
+			},
+			&goAst.AssignStmt{
+				Lhs: []goAst.Expr{
+					goAst.NewIdent("body"),
+				},
+				Tok: token.ASSIGN,
+				Rhs: []goAst.Expr{
+					&goAst.TypeAssertExpr{
+						X:    goAst.NewIdent("v"),
+						Type: goAst.NewIdent(goType),
+					},
+				},
+			},
+		},
+	})
+
+	// If the body is a enum, make sure it is one of the values.
+	if t.IsEnum() {
+		b.f.body = append(b.f.body, enumBodyCheck(t))
+	}
+}
+
+// Creates a check that the body is one of the values of the enum. Responds with a exception if
+// it is not.
+func enumBodyCheck(t rqltypes.Type) goAst.Stmt {
+	// Build the values which are allowed.
+	values := make([]goAst.Expr, len(t.Enum))
+	for i, v := range t.Enum {
+		values[i] = &goAst.BasicLit{
+			Kind:  token.STRING,
+			Value: strconv.Quote(v),
+		}
+	}
+
+	// Build the switch statement.
+	var tag goAst.Expr = goAst.NewIdent("body")
+	if t.Optional {
+		tag = &goAst.StarExpr{X: tag}
+	}
+	var stmt goAst.Stmt = &goAst.SwitchStmt{
+		Tag: tag,
+		Body: &goAst.BlockStmt{
+			List: []goAst.Stmt{
+				&goAst.CaseClause{List: values},
+				&goAst.CaseClause{
+					Body: []goAst.Stmt{
+						&goAst.ExprStmt{
+							X: &goAst.CallExpr{
+								Fun: &goAst.SelectorExpr{
+									X:   goAst.NewIdent("r"),
+									Sel: goAst.NewIdent("RespondWithRemixDBException"),
+								},
+								Args: []goAst.Expr{
+									&goAst.BasicLit{
+										Kind:  token.INT,
+										Value: "400",
+									},
+									&goAst.BasicLit{
+										Kind:  token.STRING,
+										Value: `"invalid_body"`,
+									},
+									&goAst.BasicLit{
+										Kind:  token.STRING,
+										Value: strconv.Quote("The body is not a value of the enum " + t.Name + "."),
+									},
+								},
+							},
+						},
+						&goAst.ReturnStmt{
+							Results: []goAst.Expr{
+								goAst.NewIdent("nil"),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// Null is allowed if the enum is optional.
+	if t.Optional {
+		stmt = &goAst.IfStmt{
+			Cond: &goAst.BinaryExpr{
+				X:  goAst.NewIdent("body"),
+				Op: token.NEQ,
+				Y:  goAst.NewIdent("nil"),
+			},
+			Body: &goAst.BlockStmt{List: []goAst.Stmt{stmt}},
+		}
+	}
+	return stmt
+}
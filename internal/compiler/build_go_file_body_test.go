@@ -4,6 +4,14 @@
 package compiler
 
 import (
+	goAst "go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	// This import is required to ensure the moq library is accessible by the go generate command.
@@ -13,6 +21,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"remixdb.io/ast"
 	"remixdb.io/internal/compiler/mocksession"
+	"remixdb.io/internal/engine"
 )
 
 //go:generate go run generate_mock_session_implementation.go
@@ -81,3 +90,125 @@ func Test_contract2go(t *testing.T) {
 		})
 	}
 }
+
+// Defines the folder containing the contract fixtures for the parser.
+var contractFixtures = filepath.Join("..", "..", "ast", "testdata", "tests", "contracts")
+
+// Defines the folder containing the contracts which are expected to compile.
+var compilerFixtures = filepath.Join("testdata", "contracts")
+
+// Type checks the generated Go code to make sure it would actually build.
+func assertGoCompiles(t *testing.T, code string) {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "contract.go", code, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err = conf.Check("main", fset, []*goAst.File{f}, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// Compiles every contract within each RemixDB file in the folder and compares the result
+// to a golden file. Errors are written to the golden file so we can make sure invalid
+// contracts are rejected.
+func runContractFixtures(t *testing.T, folder string) {
+	err := filepath.Walk(folder, func(fp string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(fp) != ".rql" {
+			return err
+		}
+
+		// Get the test name from the relative path.
+		rel, err := filepath.Rel(folder, fp)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSuffix(filepath.ToSlash(rel), ".rql")
+
+		t.Run(name, func(t *testing.T) {
+			// Read and parse the file.
+			b, err := os.ReadFile(fp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tokens, perr := ast.Parse(strings.ReplaceAll(string(b), "<<R>>", "\r"))
+			if perr != nil {
+				t.Fatal(perr.Message)
+			}
+
+			// Create a mock session with the structs, exceptions and enums within the file.
+			structs := map[string]*ast.StructToken{}
+			exceptions := map[string]*ast.ExceptionToken{}
+			enums := map[string]*ast.EnumToken{}
+			for _, token := range tokens {
+				switch x := token.(type) {
+				case ast.StructToken:
+					structs[x.Name] = &x
+				case ast.ExceptionToken:
+					exceptions[x.Name] = &x
+				case ast.EnumToken:
+					enums[x.Name] = &x
+				}
+			}
+			mock := &mocksession.SessionMock{
+				GetStructByKeyFunc: func(key string) ([]*ast.StructToken, error) {
+					s, ok := structs[key]
+					if !ok {
+						return nil, engine.ErrNotExists
+					}
+					return []*ast.StructToken{s}, nil
+				},
+				GetExceptionByKeyFunc: func(key string) (*ast.ExceptionToken, error) {
+					e, ok := exceptions[key]
+					if !ok {
+						return nil, engine.ErrNotExists
+					}
+					return e, nil
+				},
+				GetEnumByKeyFunc: func(key string) (*ast.EnumToken, error) {
+					e, ok := enums[key]
+					if !ok {
+						return nil, engine.ErrNotExists
+					}
+					return e, nil
+				},
+			}
+
+			// Compile each contract.
+			goCode := ""
+			for _, token := range tokens {
+				contract, ok := token.(ast.ContractToken)
+				if !ok {
+					continue
+				}
+				code, err := contract2go(&contract, mock)
+				if err != nil {
+					code = "// error: " + err.Error() + "\n"
+				} else {
+					assertGoCompiles(t, code)
+				}
+				goCode += code
+			}
+
+			// Check the output with a golden file.
+			if golden.Update() {
+				golden.Set(t, []byte(goCode))
+			}
+			assert.Equal(t, string(golden.Get(t)), goCode)
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_contract2go_fixtures(t *testing.T) {
+	runContractFixtures(t, contractFixtures)
+}
+
+func Test_contract2go_compiler_fixtures(t *testing.T) {
+	runContractFixtures(t, compilerFixtures)
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"reflect"
 	"runtime"
+	"strconv"
 	"sync"
 
 	"github.com/fatih/semgroup"
@@ -15,9 +16,24 @@ import (
 	"remixdb.io/internal/goplugin"
 )
 
+// CompilerError is used to define an error within the contract that was found during
+// compilation.
+type CompilerError struct {
+	// Message is the error message.
+	Message string
+
+	// Position is the position of the token that caused the error.
+	Position int
+}
+
+// Error is used to return the error message.
+func (e CompilerError) Error() string {
+	return e.Message + " (position " + strconv.Itoa(e.Position) + ")"
+}
+
 // Compiler is used to compile a contract into a Go plugin or cache it. Note the job of
-// the compiler is not to validate the contract. You should invoke the planner before doing
-// any compilation from a user input.
+// the compiler is not to validate the contract. You should run schema.Check on the tokens before
+// doing any compilation from a user input.
 type Compiler struct {
 	compilationCache   map[string]map[string]reflect.Value
 	compilationCacheMu sync.RWMutex
@@ -0,0 +1,535 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package compiler
+
+import (
+	goAst "go/ast"
+	"go/token"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"remixdb.io/ast"
+	"remixdb.io/internal/engine"
+	"remixdb.io/internal/rqltypes"
+)
+
+// Gets the position of a token. Returns 0 if the token has no position.
+func tokenPosition(t any) int {
+	v := reflect.ValueOf(t)
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return 0
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return 0
+	}
+	pos := v.FieldByName("Position")
+	if !pos.IsValid() || pos.Kind() != reflect.Int {
+		return 0
+	}
+	return int(pos.Int())
+}
+
+// Creates a compiler error for the token specified.
+func errorAt(t any, message string) error {
+	return CompilerError{
+		Message:  message,
+		Position: tokenPosition(t),
+	}
+}
+
+// Gets the Go type used to represent the type specified.
+func (b *statementBuilder) goType(t rqltypes.Type) string {
+	var s string
+	if t.Elem != nil {
+		s = "[]" + b.goType(*t.Elem)
+	} else {
+		switch t.Name {
+		case rqltypes.String:
+			s = "string"
+		case rqltypes.Int:
+			s = "int"
+		case rqltypes.Uint:
+			s = "uint"
+		case rqltypes.Float:
+			s = "float64"
+		case rqltypes.Bigint:
+			b.f.addImport("math/big")
+			s = "*big.Int"
+		case rqltypes.Timestamp:
+			b.f.addImport("time")
+			s = "time.Time"
+		case rqltypes.Bool:
+			s = "bool"
+		case rqltypes.Bytes:
+			s = "[]byte"
+		default:
+			s = "map[string]any"
+			if t.IsEnum() {
+				s = "string"
+			}
+		}
+	}
+	if t.Optional && !t.Nilable() {
+		s = "*" + s
+	}
+	return s
+}
+
+// Gets the fields of a struct or exception by its name with any enums resolved. The position is
+// used for any errors.
+func (b *statementBuilder) structFields(name string, pos int) (map[string]rqltypes.Type, error) {
+	history, err := b.s.GetStructByKey(name)
+	if err == nil {
+		return rqltypes.FieldsFromSession(b.s, history[len(history)-1])
+	}
+	if err != engine.ErrNotExists {
+		return nil, err
+	}
+	exception, err := b.s.GetExceptionByKey(name)
+	if err != nil {
+		if err == engine.ErrNotExists {
+			return nil, CompilerError{Message: "unknown type " + name, Position: pos}
+		}
+		return nil, err
+	}
+	fields := rqltypes.FieldsFromException(exception)
+	for k, t := range fields {
+		if fields[k], err = rqltypes.ResolveEnums(b.s, t); err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
+
+// Validates that a type exists and resolves it if it is a enum (or a array of a enum). The
+// position is used for any errors.
+func (b *statementBuilder) resolveType(type_ rqltypes.Type, pos int) (rqltypes.Type, error) {
+	if type_.Elem != nil {
+		elem, err := b.resolveType(*type_.Elem, pos)
+		if err != nil {
+			return type_, err
+		}
+		type_.Elem = &elem
+		return type_, nil
+	}
+	type_, err := rqltypes.ResolveEnums(b.s, type_)
+	if err != nil || type_.IsEnum() {
+		return type_, err
+	}
+	return type_, b.validateType(type_, pos)
+}
+
+// Makes sure that a string literal is a value of the enum if it is being used as one.
+func checkEnumLiteral(t any, type_ rqltypes.Type) error {
+	literal, ok := t.(ast.StringLiteralToken)
+	if !ok || !type_.IsEnum() || type_.HasEnumValue(literal.Value) {
+		return nil
+	}
+	return errorAt(t, strconv.Quote(literal.Value)+" is not a value of the enum "+type_.Name)
+}
+
+// Validates that a type exists. The position is used for any errors.
+func (b *statementBuilder) validateType(type_ rqltypes.Type, pos int) error {
+	if type_.Elem != nil {
+		return b.validateType(*type_.Elem, pos)
+	}
+	if type_.IsBuiltin() {
+		return nil
+	}
+	_, err := b.structFields(type_.Name, pos)
+	return err
+}
+
+// Wraps a expression which is not optional so that it can be used where a optional type
+// is expected.
+func (b *statementBuilder) toOptional(expr goAst.Expr, t rqltypes.Type) goAst.Expr {
+	if t.Optional || t.Nilable() || (t.Name == rqltypes.Null && t.Elem == nil) {
+		return expr
+	}
+
+	// Create a function literal which returns a pointer to the value.
+	return &goAst.CallExpr{
+		Fun: &goAst.FuncLit{
+			Type: &goAst.FuncType{
+				Params: &goAst.FieldList{},
+				Results: &goAst.FieldList{
+					List: []*goAst.Field{
+						{Type: goAst.NewIdent("*" + b.goType(t))},
+					},
+				},
+			},
+			Body: &goAst.BlockStmt{
+				List: []goAst.Stmt{
+					&goAst.AssignStmt{
+						Lhs: []goAst.Expr{goAst.NewIdent("v")},
+						Tok: token.DEFINE,
+						Rhs: []goAst.Expr{expr},
+					},
+					&goAst.ReturnStmt{
+						Results: []goAst.Expr{
+							&goAst.UnaryExpr{Op: token.AND, X: goAst.NewIdent("v")},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// Converts an expression of the type from into the type to. The caller must check that
+// the types are assignable first.
+func (b *statementBuilder) convert(expr goAst.Expr, from, to rqltypes.Type) goAst.Expr {
+	if to.Optional && !from.Optional {
+		return b.toOptional(expr, from)
+	}
+	return expr
+}
+
+// Builds an expression into Go and returns the type of it.
+func (b *statementBuilder) buildExpression(sc *scope, t any) (goAst.Expr, rqltypes.Type, error) {
+	switch x := t.(type) {
+	case ast.StringLiteralToken:
+		return &goAst.BasicLit{
+			Kind:  token.STRING,
+			Value: strconv.Quote(x.Value),
+		}, rqltypes.Type{Name: rqltypes.String}, nil
+	case ast.NumberLiteralToken:
+		return intLiteral(big.NewInt(int64(x.Value))), rqltypes.Type{Name: rqltypes.Int}, nil
+	case ast.FloatLiteralToken:
+		// Make sure there is always a dot so Go infers a float.
+		s := strconv.FormatFloat(math.Abs(x.Value), 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		var expr goAst.Expr = &goAst.BasicLit{
+			Kind:  token.FLOAT,
+			Value: s,
+		}
+		if x.Value < 0 {
+			expr = &goAst.UnaryExpr{Op: token.SUB, X: expr}
+		}
+		return expr, rqltypes.Type{Name: rqltypes.Float}, nil
+	case ast.BigIntLiteralToken:
+		return b.buildBigIntLiteral(x)
+	case ast.BooleanLiteralToken:
+		return goAst.NewIdent(strconv.FormatBool(x.Value)), rqltypes.Type{Name: rqltypes.Bool}, nil
+	case ast.NullLiteralToken:
+		return goAst.NewIdent("nil"), rqltypes.Type{Name: rqltypes.Null}, nil
+	case ast.ArrayLiteralToken:
+		return b.buildArrayLiteral(sc, x)
+	case ast.ObjectLiteralToken:
+		return nil, rqltypes.Type{}, errorAt(x, "object literals can only be used as method arguments")
+	case ast.ReferenceToken:
+		return b.buildReference(sc, x)
+	case ast.NotToken:
+		expr, type_, err := b.buildExpression(sc, x.Token)
+		if err != nil {
+			return nil, type_, err
+		}
+		if !type_.Equal(rqltypes.Type{Name: rqltypes.Bool}) {
+			return nil, type_, errorAt(x, "cannot use ! on a value of type "+type_.String())
+		}
+		return &goAst.UnaryExpr{
+			Op: token.NOT,
+			X:  parenthesize(expr),
+		}, type_, nil
+	case ast.MethodCallToken:
+		return b.buildMethodCall(sc, x)
+	case ast.AddToken:
+		return b.buildArithmetic(sc, x, token.ADD, x.Left, x.Right)
+	case ast.SubtractToken:
+		return b.buildArithmetic(sc, x, token.SUB, x.Left, x.Right)
+	case ast.MultiplyToken:
+		return b.buildArithmetic(sc, x, token.MUL, x.Left, x.Right)
+	case ast.DivideToken:
+		return b.buildArithmetic(sc, x, token.QUO, x.Left, x.Right)
+	case ast.ModuloToken:
+		return b.buildArithmetic(sc, x, token.REM, x.Left, x.Right)
+	case ast.ExponentToken:
+		return b.buildArithmetic(sc, x, token.XOR, x.Left, x.Right)
+	case ast.AndToken:
+		return b.buildLogical(sc, x, token.LAND, x.Left, x.Right)
+	case ast.OrToken:
+		return b.buildLogical(sc, x, token.LOR, x.Left, x.Right)
+	case ast.EqualToken:
+		return b.buildComparison(sc, x, token.EQL, x.Left, x.Right)
+	case ast.NotEqualToken:
+		return b.buildComparison(sc, x, token.NEQ, x.Left, x.Right)
+	case ast.LessThanToken:
+		return b.buildComparison(sc, x, token.LSS, x.Left, x.Right)
+	case ast.GreaterThanToken:
+		return b.buildComparison(sc, x, token.GTR, x.Left, x.Right)
+	case ast.LessThanOrEqualToken:
+		return b.buildComparison(sc, x, token.LEQ, x.Left, x.Right)
+	case ast.GreaterThanOrEqualToken:
+		return b.buildComparison(sc, x, token.GEQ, x.Left, x.Right)
+	}
+	return nil, rqltypes.Type{}, errorAt(t, "unsupported expression")
+}
+
+// Wraps the expression in brackets if it is not a simple expression.
+func parenthesize(expr goAst.Expr) goAst.Expr {
+	switch expr.(type) {
+	case *goAst.Ident, *goAst.BasicLit, *goAst.CallExpr, *goAst.ParenExpr,
+		*goAst.SelectorExpr, *goAst.IndexExpr, *goAst.TypeAssertExpr:
+		return expr
+	}
+	return &goAst.ParenExpr{X: expr}
+}
+
+// Builds a big integer literal.
+func (b *statementBuilder) buildBigIntLiteral(x ast.BigIntLiteralToken) (goAst.Expr, rqltypes.Type, error) {
+	type_ := rqltypes.Type{Name: rqltypes.Bigint}
+	if _, ok := new(big.Int).SetString(x.Value, 10); !ok {
+		return nil, type_, errorAt(x, "invalid bigint literal")
+	}
+	b.f.addImport("math/big")
+
+	// Create a function literal which parses the string since the value does not fit
+	// into a int64.
+	return &goAst.CallExpr{
+		Fun: &goAst.FuncLit{
+			Type: &goAst.FuncType{
+				Params: &goAst.FieldList{},
+				Results: &goAst.FieldList{
+					List: []*goAst.Field{
+						{Type: goAst.NewIdent("*big.Int")},
+					},
+				},
+			},
+			Body: &goAst.BlockStmt{
+				List: []goAst.Stmt{
+					&goAst.AssignStmt{
+						Lhs: []goAst.Expr{goAst.NewIdent("v"), goAst.NewIdent("_")},
+						Tok: token.DEFINE,
+						Rhs: []goAst.Expr{
+							&goAst.CallExpr{
+								Fun: goAst.NewIdent("new(big.Int).SetString"),
+								Args: []goAst.Expr{
+									&goAst.BasicLit{Kind: token.STRING, Value: strconv.Quote(x.Value)},
+									&goAst.BasicLit{Kind: token.INT, Value: "10"},
+								},
+							},
+						},
+					},
+					&goAst.ReturnStmt{
+						Results: []goAst.Expr{goAst.NewIdent("v")},
+					},
+				},
+			},
+		},
+	}, type_, nil
+}
+
+// Builds an array literal. All of the values must be of the same type.
+func (b *statementBuilder) buildArrayLiteral(sc *scope, x ast.ArrayLiteralToken) (goAst.Expr, rqltypes.Type, error) {
+	var elemType *rqltypes.Type
+	elems := []goAst.Expr{}
+	for _, v := range x.Values {
+		// Skip any comments.
+		if _, ok := v.(ast.CommentToken); ok {
+			continue
+		}
+
+		// Build the value and make sure it is the same type as the others.
+		expr, type_, err := b.buildExpression(sc, v)
+		if err != nil {
+			return nil, type_, err
+		}
+		if elemType == nil {
+			elemType = &type_
+		} else if !type_.Equal(*elemType) {
+			return nil, type_, errorAt(v, "array values must all be of the type "+elemType.String()+
+				", got "+type_.String())
+		}
+		elems = append(elems, expr)
+	}
+
+	// We cannot infer the type of an empty array or one of just nulls.
+	if elemType == nil {
+		return nil, rqltypes.Type{}, errorAt(x, "cannot infer the type of an empty array")
+	}
+	if elemType.Name == rqltypes.Null && elemType.Elem == nil {
+		return nil, rqltypes.Type{}, errorAt(x, "cannot infer the type of an array of nulls")
+	}
+
+	// Return the composite literal.
+	type_ := rqltypes.Type{Elem: elemType}
+	return &goAst.CompositeLit{
+		Type: goAst.NewIdent(b.goType(type_)),
+		Elts: elems,
+	}, type_, nil
+}
+
+// Builds a reference to a variable, optionally followed by dots to access struct fields.
+func (b *statementBuilder) buildReference(sc *scope, x ast.ReferenceToken) (goAst.Expr, rqltypes.Type, error) {
+	// Find the variable.
+	parts := strings.Split(x.Name, ".")
+	v := sc.lookup(parts[0])
+	if v == nil {
+		// Handle queries such as Tree.count.
+		if expr, type_, err := b.buildQuery(sc, x); expr != nil || err != nil {
+			return expr, type_, err
+		}
+		return nil, rqltypes.Type{}, errorAt(x, "undefined variable "+parts[0])
+	}
+	v.used = true
+	var expr goAst.Expr = goAst.NewIdent(v.goName)
+	type_ := v.type_
+
+	// Handle any field access.
+	for _, field := range parts[1:] {
+		if !type_.IsStruct() {
+			return nil, type_, errorAt(x, "cannot access field "+field+" on type "+type_.String())
+		}
+		if type_.Optional {
+			return nil, type_, errorAt(x, "cannot access field "+field+" on optional type "+type_.String())
+		}
+		fields, err := b.structFields(type_.Name, x.Position)
+		if err != nil {
+			return nil, type_, err
+		}
+		fieldType, ok := fields[field]
+		if !ok {
+			return nil, type_, errorAt(x, "struct "+type_.Name+" has no field "+field)
+		}
+		expr = &goAst.TypeAssertExpr{
+			X: &goAst.IndexExpr{
+				X:     expr,
+				Index: &goAst.BasicLit{Kind: token.STRING, Value: strconv.Quote(field)},
+			},
+			Type: goAst.NewIdent(b.goType(fieldType)),
+		}
+		type_ = fieldType
+	}
+	return expr, type_, nil
+}
+
+// Builds a method call.
+func (b *statementBuilder) buildMethodCall(sc *scope, x ast.MethodCallToken) (goAst.Expr, rqltypes.Type, error) {
+	// Handle queries on structs.
+	if expr, type_, err := b.buildQuery(sc, x); expr != nil || err != nil {
+		return expr, type_, err
+	}
+	return nil, rqltypes.Type{}, errorAt(x, "unknown method "+x.Name)
+}
+
+// Builds an object literal into a map for the struct fields specified. If fields is nil,
+// any keys are allowed.
+func (b *statementBuilder) buildObjectLiteral(
+	sc *scope, x ast.ObjectLiteralToken, fields map[string]rqltypes.Type,
+) (goAst.Expr, error) {
+	// Sort the keys so the output is stable.
+	keys := make([]string, 0, len(x.Values))
+	for k := range x.Values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	// Build each value.
+	elems := []goAst.Expr{}
+	for _, k := range keys {
+		expr, type_, err := b.buildExpression(sc, x.Values[k])
+		if err != nil {
+			return nil, err
+		}
+		if fields != nil {
+			fieldType, ok := fields[k]
+			if !ok {
+				return nil, errorAt(x.Values[k], "unknown field "+k)
+			}
+			if !type_.AssignableTo(fieldType) {
+				return nil, errorAt(x.Values[k], "cannot use a value of type "+type_.String()+
+					" for the field "+k+" of type "+fieldType.String())
+			}
+			if err := checkEnumLiteral(x.Values[k], fieldType); err != nil {
+				return nil, err
+			}
+			expr = b.convert(expr, type_, fieldType)
+		}
+		elems = append(elems, &goAst.KeyValueExpr{
+			Key:   &goAst.BasicLit{Kind: token.STRING, Value: strconv.Quote(k)},
+			Value: expr,
+		})
+	}
+
+	// Return the map.
+	return &goAst.CompositeLit{
+		Type: goAst.NewIdent("map[string]any"),
+		Elts: elems,
+	}, nil
+}
+
+// Builds an object literal into the Go representation of the struct. Every field of the struct is
+// present, so optional fields which are not set are null.
+func (b *statementBuilder) buildStructLiteral(
+	sc *scope, x ast.ObjectLiteralToken, structName string,
+) (goAst.Expr, error) {
+	fields, err := b.structFields(structName, x.Position)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range x.Values {
+		if _, ok := fields[k]; !ok {
+			return nil, errorAt(v, "struct "+structName+" has no field "+k)
+		}
+	}
+
+	// Sort the fields so the output is stable.
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Build each field.
+	elems := []goAst.Expr{}
+	for _, name := range names {
+		fieldType := fields[name]
+		var expr goAst.Expr
+		if v, ok := x.Values[name]; ok {
+			var type_ rqltypes.Type
+			if expr, type_, err = b.buildExpression(sc, v); err != nil {
+				return nil, err
+			}
+			if !type_.AssignableTo(fieldType) {
+				return nil, errorAt(v, "cannot use a value of type "+type_.String()+
+					" for the field "+name+" of type "+fieldType.String())
+			}
+			if err := checkEnumLiteral(v, fieldType); err != nil {
+				return nil, err
+			}
+			if type_.Name == rqltypes.Null && type_.Elem == nil {
+				expr = nil
+			} else {
+				expr = b.convert(expr, type_, fieldType)
+			}
+		} else if !fieldType.Optional {
+			return nil, errorAt(x, "the field "+name+" of "+structName+" must be set")
+		}
+
+		// Null values are typed so that they can be read back from the struct.
+		if expr == nil {
+			expr = &goAst.CallExpr{
+				Fun:  &goAst.ParenExpr{X: goAst.NewIdent(b.goType(fieldType))},
+				Args: []goAst.Expr{goAst.NewIdent("nil")},
+			}
+		}
+		elems = append(elems, &goAst.KeyValueExpr{
+			Key:   &goAst.BasicLit{Kind: token.STRING, Value: strconv.Quote(name)},
+			Value: expr,
+		})
+	}
+	return &goAst.CompositeLit{
+		Type: goAst.NewIdent("map[string]any"),
+		Elts: elems,
+	}, nil
+}
@@ -10,6 +10,7 @@ import (
 
 	"remixdb.io/ast"
 	"remixdb.io/internal/engine"
+	"remixdb.io/internal/rqltypes"
 )
 
 // Handles adding to a interface.
@@ -80,6 +81,16 @@ type functionBody struct {
 	body    []goAst.Stmt
 }
 
+// Adds an import if it is not already present.
+func (f *functionBody) addImport(name string) {
+	for _, v := range f.imports {
+		if v == name {
+			return
+		}
+	}
+	f.imports = append(f.imports, name)
+}
+
 // Checks if the output is Cursor<T>. This is a special case where we return a cursor.
 var cursorBuiltin = regexp.MustCompile(`^Cursor<(.+)>$`)
 
@@ -90,15 +101,45 @@ func buildFunctionBody(
 	// Check if this is a cursor.
 	matches := cursorBuiltin.FindStringSubmatch(contract.ReturnType)
 	isCursor := false
-	//outputType := contract.ReturnType
+	outputType := contract.ReturnType
 	if matches != nil {
 		isCursor = true
-		//outputType = matches[1]
+		outputType = matches[1]
 	}
 
 	// Defines all already used things in the interface.
 	used := map[string]struct{}{}
 
+	// Create the statement builder and validate the output type.
+	sb := &statementBuilder{
+		f: &funcBody,
+		s: s,
+		addToInterface: func(name string, fn *goAst.FuncType) {
+			addToInterface(used, iface, name, fn)
+		},
+		isCursor:       isCursor,
+		writtenStructs: writtenStructs(contract.Statements),
+		throws:         map[string]bool{},
+	}
+	if outputType != rqltypes.Void {
+		var returnType rqltypes.Type
+		if returnType, err = sb.resolveType(rqltypes.Parse(outputType), contract.ReturnTypeIndex); err != nil {
+			return
+		}
+		sb.returnType = &returnType
+	} else if isCursor {
+		err = errorAt(contract, "a cursor cannot be of type void")
+		return
+	}
+
+	// Make sure the exceptions the contract throws exist.
+	for _, throw := range contract.Throws {
+		if err = sb.validateType(rqltypes.Type{Name: throw.Name}, throw.Position); err != nil {
+			return
+		}
+		sb.throws[throw.Name] = true
+	}
+
 	// Add Close to the interface.
 	addToInterface(used, iface, "Close", noParamsJustError())
 
@@ -122,46 +163,69 @@ func buildFunctionBody(
 	iam.addValidator("contract:execute")
 	defer iam.compile()
 
+	// Create the root scope for the contract.
+	root := newScope(nil)
+	var argument *scopeVariable
+
 	if contract.Argument != nil {
-		// Capture the body into a variable.
-		addToInterface(used, iface, "Body", &goAst.FuncType{
-			Params: &goAst.FieldList{},
-			Results: &goAst.FieldList{
-				List: []*goAst.Field{
-					{
-						Type: goAst.NewIdent("[]byte"),
+		// Validate the argument type and add it to the scope.
+		var argumentType rqltypes.Type
+		argumentType, err = sb.resolveType(rqltypes.Parse(contract.Argument.Type), contract.Argument.TypeIndex)
+		if err != nil {
+			return
+		}
+		argument = root.declare(contract.Argument.Name, "body", argumentType, -1)
+
+		// Create the body parser. Booleans are parsed inline from the raw body since they
+		// are a single byte.
+		if argumentType.NonOptional().Equal(rqltypes.Type{Name: rqltypes.Bool}) {
+			// Capture the body into a variable.
+			addToInterface(used, iface, "Body", &goAst.FuncType{
+				Params: &goAst.FieldList{},
+				Results: &goAst.FieldList{
+					List: []*goAst.Field{
+						{
+							Type: goAst.NewIdent("[]byte"),
+						},
 					},
 				},
-			},
-		})
-		funcBody.body = append(funcBody.body, &goAst.AssignStmt{
-			Lhs: []goAst.Expr{
-				goAst.NewIdent("rawBody"),
-			},
-			Tok: token.DEFINE,
-			Rhs: []goAst.Expr{
-				&goAst.CallExpr{
-					Fun: goAst.NewIdent("r.Body"),
+			})
+			funcBody.body = append(funcBody.body, &goAst.AssignStmt{
+				Lhs: []goAst.Expr{
+					goAst.NewIdent("rawBody"),
 				},
-			},
-		})
+				Tok: token.DEFINE,
+				Rhs: []goAst.Expr{
+					&goAst.CallExpr{
+						Fun: goAst.NewIdent("r.Body"),
+					},
+				},
+			})
 
-		// Create the body parser.
-		funcBody.createBodyParser(contract)
+			funcBody.createBodyParser(contract)
+		} else {
+			sb.createGenericBodyParser(argumentType)
+		}
 	}
 
-	// At the end, we want to do a commit since getting to the end means we have succeeded.
-	addToInterface(used, iface, "Commit", noParamsJustError())
-	funcBody.body = append(funcBody.body, &goAst.ReturnStmt{
-		Results: []goAst.Expr{
-			&goAst.CallExpr{
-				Fun: &goAst.SelectorExpr{
-					X:   goAst.NewIdent("r"),
-					Sel: goAst.NewIdent("Commit"),
-				},
-			},
-		},
-	})
+	// Build the statements within the contract.
+	stmts, err := sb.buildContract(root, contract.Statements)
+	if err != nil {
+		return
+	}
+	if argument != nil && !argument.used {
+		funcBody.body = append(funcBody.body, discardVariable(argument.goName))
+	}
+	if sb.divides {
+		funcBody.body = append(funcBody.body, sb.divisionByZeroHandler()...)
+	}
+	funcBody.body = append(funcBody.body, stmts...)
+
+	// At the end, we want to do a commit since getting to the end means we have succeeded. If
+	// the contract already ended in a return, this would be unreachable.
+	if len(stmts) == 0 || !isReturn(stmts[len(stmts)-1]) {
+		funcBody.body = append(funcBody.body, sb.commitAndReturn())
+	}
 
 	// Just return the values created.
 	return
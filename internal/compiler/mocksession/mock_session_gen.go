@@ -46,15 +46,51 @@ var _ engine.Session = &SessionMock{}
 //			DeleteContractByKeyFunc: func(key string) error {
 //				panic("mock out the DeleteContractByKey method")
 //			},
+//			DeleteEnumByKeyFunc: func(key string) error {
+//				panic("mock out the DeleteEnumByKey method")
+//			},
+//			DeleteExceptionByKeyFunc: func(key string) error {
+//				panic("mock out the DeleteExceptionByKey method")
+//			},
 //			DeleteStructByKeyFunc: func(key string) error {
 //				panic("mock out the DeleteStructByKey method")
 //			},
+//			DeleteStructObjectFunc: func(structName string, key []byte) error {
+//				panic("mock out the DeleteStructObject method")
+//			},
+//			EnumsFunc: func() ([]*ast.EnumToken, error) {
+//				panic("mock out the Enums method")
+//			},
+//			ExceptionsFunc: func() ([]*ast.ExceptionToken, error) {
+//				panic("mock out the Exceptions method")
+//			},
 //			GetContractByKeyFunc: func(key string) (*ast.ContractToken, error) {
 //				panic("mock out the GetContractByKey method")
 //			},
+//			GetEnumByKeyFunc: func(key string) (*ast.EnumToken, error) {
+//				panic("mock out the GetEnumByKey method")
+//			},
+//			GetExceptionByKeyFunc: func(key string) (*ast.ExceptionToken, error) {
+//				panic("mock out the GetExceptionByKey method")
+//			},
 //			GetStructByKeyFunc: func(key string) ([]*ast.StructToken, error) {
 //				panic("mock out the GetStructByKey method")
 //			},
+//			GetStructObjectFunc: func(structName string, key []byte) ([]byte, error) {
+//				panic("mock out the GetStructObject method")
+//			},
+//			InsertStructObjectFunc: func(structName string, key []byte, value []byte) ([]byte, error) {
+//				panic("mock out the InsertStructObject method")
+//			},
+//			IterateStructIndexFunc: func(structName string, field string, options engine.StructIteratorOptions) (engine.StructObjectIterator, error) {
+//				panic("mock out the IterateStructIndex method")
+//			},
+//			IterateStructObjectsFunc: func(structName string, options engine.StructIteratorOptions) (engine.StructObjectIterator, error) {
+//				panic("mock out the IterateStructObjects method")
+//			},
+//			LookupStructIndexFunc: func(structName string, field string, value []byte) ([][]byte, error) {
+//				panic("mock out the LookupStructIndex method")
+//			},
 //			ReleaseStructObjectReadLockFunc: func(structName string, keys ...[]byte) error {
 //				panic("mock out the ReleaseStructObjectReadLock method")
 //			},
@@ -67,18 +103,39 @@ var _ engine.Session = &SessionMock{}
 //			ReleaseStructWriteLockFunc: func(structNames ...string) error {
 //				panic("mock out the ReleaseStructWriteLock method")
 //			},
+//			RenameStructFunc: func(oldName string, newName string) error {
+//				panic("mock out the RenameStruct method")
+//			},
 //			RollbackFunc: func() error {
 //				panic("mock out the Rollback method")
 //			},
+//			StructIndexStatusFunc: func(structName string, field string) (engine.IndexStatus, error) {
+//				panic("mock out the StructIndexStatus method")
+//			},
+//			StructMigrationProgressFunc: func(structName string) (engine.MigrationProgress, error) {
+//				panic("mock out the StructMigrationProgress method")
+//			},
 //			StructTombstonesFunc: func() (map[string]string, []*ast.StructToken, error) {
 //				panic("mock out the StructTombstones method")
 //			},
 //			StructsFunc: func() ([]*ast.StructToken, error) {
 //				panic("mock out the Structs method")
 //			},
+//			UpdateStructObjectFunc: func(structName string, key []byte, value []byte) error {
+//				panic("mock out the UpdateStructObject method")
+//			},
 //			WriteContractFunc: func(contract *ast.ContractToken) error {
 //				panic("mock out the WriteContract method")
 //			},
+//			WriteEnumFunc: func(enum *ast.EnumToken) error {
+//				panic("mock out the WriteEnum method")
+//			},
+//			WriteExceptionFunc: func(exception *ast.ExceptionToken) error {
+//				panic("mock out the WriteException method")
+//			},
+//			WriteStructFunc: func(structToken *ast.StructToken, force bool) error {
+//				panic("mock out the WriteStruct method")
+//			},
 //		}
 //
 //		// use mockedSession in code that requires engine.Session
@@ -113,15 +170,51 @@ type SessionMock struct {
 	// DeleteContractByKeyFunc mocks the DeleteContractByKey method.
 	DeleteContractByKeyFunc func(key string) error
 
+	// DeleteEnumByKeyFunc mocks the DeleteEnumByKey method.
+	DeleteEnumByKeyFunc func(key string) error
+
+	// DeleteExceptionByKeyFunc mocks the DeleteExceptionByKey method.
+	DeleteExceptionByKeyFunc func(key string) error
+
 	// DeleteStructByKeyFunc mocks the DeleteStructByKey method.
 	DeleteStructByKeyFunc func(key string) error
 
+	// DeleteStructObjectFunc mocks the DeleteStructObject method.
+	DeleteStructObjectFunc func(structName string, key []byte) error
+
+	// EnumsFunc mocks the Enums method.
+	EnumsFunc func() ([]*ast.EnumToken, error)
+
+	// ExceptionsFunc mocks the Exceptions method.
+	ExceptionsFunc func() ([]*ast.ExceptionToken, error)
+
 	// GetContractByKeyFunc mocks the GetContractByKey method.
 	GetContractByKeyFunc func(key string) (*ast.ContractToken, error)
 
+	// GetEnumByKeyFunc mocks the GetEnumByKey method.
+	GetEnumByKeyFunc func(key string) (*ast.EnumToken, error)
+
+	// GetExceptionByKeyFunc mocks the GetExceptionByKey method.
+	GetExceptionByKeyFunc func(key string) (*ast.ExceptionToken, error)
+
 	// GetStructByKeyFunc mocks the GetStructByKey method.
 	GetStructByKeyFunc func(key string) ([]*ast.StructToken, error)
 
+	// GetStructObjectFunc mocks the GetStructObject method.
+	GetStructObjectFunc func(structName string, key []byte) ([]byte, error)
+
+	// InsertStructObjectFunc mocks the InsertStructObject method.
+	InsertStructObjectFunc func(structName string, key []byte, value []byte) ([]byte, error)
+
+	// IterateStructIndexFunc mocks the IterateStructIndex method.
+	IterateStructIndexFunc func(structName string, field string, options engine.StructIteratorOptions) (engine.StructObjectIterator, error)
+
+	// IterateStructObjectsFunc mocks the IterateStructObjects method.
+	IterateStructObjectsFunc func(structName string, options engine.StructIteratorOptions) (engine.StructObjectIterator, error)
+
+	// LookupStructIndexFunc mocks the LookupStructIndex method.
+	LookupStructIndexFunc func(structName string, field string, value []byte) ([][]byte, error)
+
 	// ReleaseStructObjectReadLockFunc mocks the ReleaseStructObjectReadLock method.
 	ReleaseStructObjectReadLockFunc func(structName string, keys ...[]byte) error
 
@@ -134,18 +227,39 @@ type SessionMock struct {
 	// ReleaseStructWriteLockFunc mocks the ReleaseStructWriteLock method.
 	ReleaseStructWriteLockFunc func(structNames ...string) error
 
+	// RenameStructFunc mocks the RenameStruct method.
+	RenameStructFunc func(oldName string, newName string) error
+
 	// RollbackFunc mocks the Rollback method.
 	RollbackFunc func() error
 
+	// StructIndexStatusFunc mocks the StructIndexStatus method.
+	StructIndexStatusFunc func(structName string, field string) (engine.IndexStatus, error)
+
+	// StructMigrationProgressFunc mocks the StructMigrationProgress method.
+	StructMigrationProgressFunc func(structName string) (engine.MigrationProgress, error)
+
 	// StructTombstonesFunc mocks the StructTombstones method.
 	StructTombstonesFunc func() (map[string]string, []*ast.StructToken, error)
 
 	// StructsFunc mocks the Structs method.
 	StructsFunc func() ([]*ast.StructToken, error)
 
+	// UpdateStructObjectFunc mocks the UpdateStructObject method.
+	UpdateStructObjectFunc func(structName string, key []byte, value []byte) error
+
 	// WriteContractFunc mocks the WriteContract method.
 	WriteContractFunc func(contract *ast.ContractToken) error
 
+	// WriteEnumFunc mocks the WriteEnum method.
+	WriteEnumFunc func(enum *ast.EnumToken) error
+
+	// WriteExceptionFunc mocks the WriteException method.
+	WriteExceptionFunc func(exception *ast.ExceptionToken) error
+
+	// WriteStructFunc mocks the WriteStruct method.
+	WriteStructFunc func(structToken *ast.StructToken, force bool) error
+
 	// calls tracks calls to the methods.
 	calls struct {
 		// AcquireStructObjectReadLock holds details about calls to the AcquireStructObjectReadLock method.
@@ -189,21 +303,95 @@ type SessionMock struct {
 			// Key is the key argument value.
 			Key string
 		}
+		// DeleteEnumByKey holds details about calls to the DeleteEnumByKey method.
+		DeleteEnumByKey []struct {
+			// Key is the key argument value.
+			Key string
+		}
+		// DeleteExceptionByKey holds details about calls to the DeleteExceptionByKey method.
+		DeleteExceptionByKey []struct {
+			// Key is the key argument value.
+			Key string
+		}
 		// DeleteStructByKey holds details about calls to the DeleteStructByKey method.
 		DeleteStructByKey []struct {
 			// Key is the key argument value.
 			Key string
 		}
+		// DeleteStructObject holds details about calls to the DeleteStructObject method.
+		DeleteStructObject []struct {
+			// StructName is the structName argument value.
+			StructName string
+			// Key is the key argument value.
+			Key []byte
+		}
+		// Enums holds details about calls to the Enums method.
+		Enums []struct {
+		}
+		// Exceptions holds details about calls to the Exceptions method.
+		Exceptions []struct {
+		}
 		// GetContractByKey holds details about calls to the GetContractByKey method.
 		GetContractByKey []struct {
 			// Key is the key argument value.
 			Key string
 		}
+		// GetEnumByKey holds details about calls to the GetEnumByKey method.
+		GetEnumByKey []struct {
+			// Key is the key argument value.
+			Key string
+		}
+		// GetExceptionByKey holds details about calls to the GetExceptionByKey method.
+		GetExceptionByKey []struct {
+			// Key is the key argument value.
+			Key string
+		}
 		// GetStructByKey holds details about calls to the GetStructByKey method.
 		GetStructByKey []struct {
 			// Key is the key argument value.
 			Key string
 		}
+		// GetStructObject holds details about calls to the GetStructObject method.
+		GetStructObject []struct {
+			// StructName is the structName argument value.
+			StructName string
+			// Key is the key argument value.
+			Key []byte
+		}
+		// InsertStructObject holds details about calls to the InsertStructObject method.
+		InsertStructObject []struct {
+			// StructName is the structName argument value.
+			StructName string
+			// Key is the key argument value.
+			Key []byte
+			// Value is the value argument value.
+			Value []byte
+		}
+		// IterateStructIndex holds details about calls to the IterateStructIndex method.
+		IterateStructIndex []struct {
+			// StructName is the structName argument value.
+			StructName string
+			// Field is the field argument value.
+			Field string
+			// Options is the options argument value.
+			Options engine.StructIteratorOptions
+		}
+		// IterateStructObjects holds details about calls to the IterateStructObjects method.
+		IterateStructObjects []struct {
+			// StructName is the structName argument value.
+			StructName string
+			// Options is the options argument value.
+			Options engine.StructIteratorOptions
+		}
+		// LookupStructIndex holds details about calls to the LookupStructIndex method.
+		LookupStructIndex []struct {
+			// StructName is the structName argument value.
+			StructName string
+			// Field is the field argument value.
+			Field string
+			// Value is the value argument value.
+			Value []byte
+		}
 		// ReleaseStructObjectReadLock holds details about calls to the ReleaseStructObjectReadLock method.
 		ReleaseStructObjectReadLock []struct {
 			// StructName is the structName argument value.
@@ -228,20 +416,65 @@ type SessionMock struct {
 			// StructNames is the structNames argument value.
 			StructNames []string
 		}
+		// RenameStruct holds details about calls to the RenameStruct method.
+		RenameStruct []struct {
+			// OldName is the oldName argument value.
+			OldName string
+			// NewName is the newName argument value.
+			NewName string
+		}
 		// Rollback holds details about calls to the Rollback method.
 		Rollback []struct {
 		}
+		// StructIndexStatus holds details about calls to the StructIndexStatus method.
+		StructIndexStatus []struct {
+			// StructName is the structName argument value.
+			StructName string
+			// Field is the field argument value.
+			Field string
+		}
+		// StructMigrationProgress holds details about calls to the StructMigrationProgress method.
+		StructMigrationProgress []struct {
+			// StructName is the structName argument value.
+			StructName string
+		}
 		// StructTombstones holds details about calls to the StructTombstones method.
 		StructTombstones []struct {
 		}
 		// Structs holds details about calls to the Structs method.
 		Structs []struct {
 		}
+		// UpdateStructObject holds details about calls to the UpdateStructObject method.
+		UpdateStructObject []struct {
+			// StructName is the structName argument value.
+			StructName string
+			// Key is the key argument value.
+			Key []byte
+			// Value is the value argument value.
+			Value []byte
+		}
 		// WriteContract holds details about calls to the WriteContract method.
 		WriteContract []struct {
 			// Contract is the contract argument value.
 			Contract *ast.ContractToken
 		}
+		// WriteEnum holds details about calls to the WriteEnum method.
+		WriteEnum []struct {
+			// Enum is the enum argument value.
+			Enum *ast.EnumToken
+		}
+		// WriteException holds details about calls to the WriteException method.
+		WriteException []struct {
+			// Exception is the exception argument value.
+			Exception *ast.ExceptionToken
+		}
+		// WriteStruct holds details about calls to the WriteStruct method.
+		WriteStruct []struct {
+			// StructToken is the structToken argument value.
+			StructToken *ast.StructToken
+			// Force is the force argument value.
+			Force bool
+		}
 	}
 	lockAcquireStructObjectReadLock  sync.RWMutex
 	lockAcquireStructObjectWriteLock sync.RWMutex
@@ -252,17 +485,36 @@ type SessionMock struct {
 	lockContractTombstones           sync.RWMutex
 	lockContracts                    sync.RWMutex
 	lockDeleteContractByKey          sync.RWMutex
+	lockDeleteEnumByKey              sync.RWMutex
+	lockDeleteExceptionByKey         sync.RWMutex
 	lockDeleteStructByKey            sync.RWMutex
+	lockDeleteStructObject           sync.RWMutex
+	lockEnums                        sync.RWMutex
+	lockExceptions                   sync.RWMutex
 	lockGetContractByKey             sync.RWMutex
+	lockGetEnumByKey                 sync.RWMutex
+	lockGetExceptionByKey            sync.RWMutex
 	lockGetStructByKey               sync.RWMutex
+	lockGetStructObject              sync.RWMutex
+	lockInsertStructObject           sync.RWMutex
+	lockIterateStructIndex           sync.RWMutex
+	lockIterateStructObjects         sync.RWMutex
+	lockLookupStructIndex            sync.RWMutex
 	lockReleaseStructObjectReadLock  sync.RWMutex
 	lockReleaseStructObjectWriteLock sync.RWMutex
 	lockReleaseStructReadLock        sync.RWMutex
 	lockReleaseStructWriteLock       sync.RWMutex
+	lockRenameStruct                 sync.RWMutex
 	lockRollback                     sync.RWMutex
+	lockStructIndexStatus            sync.RWMutex
+	lockStructMigrationProgress      sync.RWMutex
 	lockStructTombstones             sync.RWMutex
 	lockStructs                      sync.RWMutex
+	lockUpdateStructObject           sync.RWMutex
 	lockWriteContract                sync.RWMutex
+	lockWriteEnum                    sync.RWMutex
+	lockWriteException               sync.RWMutex
+	lockWriteStruct                  sync.RWMutex
 }
 
 // AcquireStructObjectReadLock calls AcquireStructObjectReadLockFunc.
@@ -541,6 +793,70 @@ func (mock *SessionMock) DeleteContractByKeyCalls() []struct {
 	return calls
 }
 
+// DeleteEnumByKey calls DeleteEnumByKeyFunc.
+func (mock *SessionMock) DeleteEnumByKey(key string) error {
+	if mock.DeleteEnumByKeyFunc == nil {
+		panic("SessionMock.DeleteEnumByKeyFunc: method is nil but Session.DeleteEnumByKey was just called")
+	}
+	callInfo := struct {
+		Key string
+	}{
+		Key: key,
+	}
+	mock.lockDeleteEnumByKey.Lock()
+	mock.calls.DeleteEnumByKey = append(mock.calls.DeleteEnumByKey, callInfo)
+	mock.lockDeleteEnumByKey.Unlock()
+	return mock.DeleteEnumByKeyFunc(key)
+}
+
+// DeleteEnumByKeyCalls gets all the calls that were made to DeleteEnumByKey.
+// Check the length with:
+//
+//	len(mockedSession.DeleteEnumByKeyCalls())
+func (mock *SessionMock) DeleteEnumByKeyCalls() []struct {
+	Key string
+} {
+	var calls []struct {
+		Key string
+	}
+	mock.lockDeleteEnumByKey.RLock()
+	calls = mock.calls.DeleteEnumByKey
+	mock.lockDeleteEnumByKey.RUnlock()
+	return calls
+}
+
+// DeleteExceptionByKey calls DeleteExceptionByKeyFunc.
+func (mock *SessionMock) DeleteExceptionByKey(key string) error {
+	if mock.DeleteExceptionByKeyFunc == nil {
+		panic("SessionMock.DeleteExceptionByKeyFunc: method is nil but Session.DeleteExceptionByKey was just called")
+	}
+	callInfo := struct {
+		Key string
+	}{
+		Key: key,
+	}
+	mock.lockDeleteExceptionByKey.Lock()
+	mock.calls.DeleteExceptionByKey = append(mock.calls.DeleteExceptionByKey, callInfo)
+	mock.lockDeleteExceptionByKey.Unlock()
+	return mock.DeleteExceptionByKeyFunc(key)
+}
+
+// DeleteExceptionByKeyCalls gets all the calls that were made to DeleteExceptionByKey.
+// Check the length with:
+//
+//	len(mockedSession.DeleteExceptionByKeyCalls())
+func (mock *SessionMock) DeleteExceptionByKeyCalls() []struct {
+	Key string
+} {
+	var calls []struct {
+		Key string
+	}
+	mock.lockDeleteExceptionByKey.RLock()
+	calls = mock.calls.DeleteExceptionByKey
+	mock.lockDeleteExceptionByKey.RUnlock()
+	return calls
+}
+
 // DeleteStructByKey calls DeleteStructByKeyFunc.
 func (mock *SessionMock) DeleteStructByKey(key string) error {
 	if mock.DeleteStructByKeyFunc == nil {
@@ -573,6 +889,96 @@ func (mock *SessionMock) DeleteStructByKeyCalls() []struct {
 	return calls
 }
 
+// DeleteStructObject calls DeleteStructObjectFunc.
+func (mock *SessionMock) DeleteStructObject(structName string, key []byte) error {
+	if mock.DeleteStructObjectFunc == nil {
+		panic("SessionMock.DeleteStructObjectFunc: method is nil but Session.DeleteStructObject was just called")
+	}
+	callInfo := struct {
+		StructName string
+		Key        []byte
+	}{
+		StructName: structName,
+		Key:        key,
+	}
+	mock.lockDeleteStructObject.Lock()
+	mock.calls.DeleteStructObject = append(mock.calls.DeleteStructObject, callInfo)
+	mock.lockDeleteStructObject.Unlock()
+	return mock.DeleteStructObjectFunc(structName, key)
+}
+
+// DeleteStructObjectCalls gets all the calls that were made to DeleteStructObject.
+// Check the length with:
+//
+//	len(mockedSession.DeleteStructObjectCalls())
+func (mock *SessionMock) DeleteStructObjectCalls() []struct {
+	StructName string
+	Key        []byte
+} {
+	var calls []struct {
+		StructName string
+		Key        []byte
+	}
+	mock.lockDeleteStructObject.RLock()
+	calls = mock.calls.DeleteStructObject
+	mock.lockDeleteStructObject.RUnlock()
+	return calls
+}
+
+// Enums calls EnumsFunc.
+func (mock *SessionMock) Enums() ([]*ast.EnumToken, error) {
+	if mock.EnumsFunc == nil {
+		panic("SessionMock.EnumsFunc: method is nil but Session.Enums was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockEnums.Lock()
+	mock.calls.Enums = append(mock.calls.Enums, callInfo)
+	mock.lockEnums.Unlock()
+	return mock.EnumsFunc()
+}
+
+// EnumsCalls gets all the calls that were made to Enums.
+// Check the length with:
+//
+//	len(mockedSession.EnumsCalls())
+func (mock *SessionMock) EnumsCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockEnums.RLock()
+	calls = mock.calls.Enums
+	mock.lockEnums.RUnlock()
+	return calls
+}
+
+// Exceptions calls ExceptionsFunc.
+func (mock *SessionMock) Exceptions() ([]*ast.ExceptionToken, error) {
+	if mock.ExceptionsFunc == nil {
+		panic("SessionMock.ExceptionsFunc: method is nil but Session.Exceptions was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockExceptions.Lock()
+	mock.calls.Exceptions = append(mock.calls.Exceptions, callInfo)
+	mock.lockExceptions.Unlock()
+	return mock.ExceptionsFunc()
+}
+
+// ExceptionsCalls gets all the calls that were made to Exceptions.
+// Check the length with:
+//
+//	len(mockedSession.ExceptionsCalls())
+func (mock *SessionMock) ExceptionsCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockExceptions.RLock()
+	calls = mock.calls.Exceptions
+	mock.lockExceptions.RUnlock()
+	return calls
+}
+
 // GetContractByKey calls GetContractByKeyFunc.
 func (mock *SessionMock) GetContractByKey(key string) (*ast.ContractToken, error) {
 	if mock.GetContractByKeyFunc == nil {
@@ -605,6 +1011,70 @@ func (mock *SessionMock) GetContractByKeyCalls() []struct {
 	return calls
 }
 
+// GetEnumByKey calls GetEnumByKeyFunc.
+func (mock *SessionMock) GetEnumByKey(key string) (*ast.EnumToken, error) {
+	if mock.GetEnumByKeyFunc == nil {
+		panic("SessionMock.GetEnumByKeyFunc: method is nil but Session.GetEnumByKey was just called")
+	}
+	callInfo := struct {
+		Key string
+	}{
+		Key: key,
+	}
+	mock.lockGetEnumByKey.Lock()
+	mock.calls.GetEnumByKey = append(mock.calls.GetEnumByKey, callInfo)
+	mock.lockGetEnumByKey.Unlock()
+	return mock.GetEnumByKeyFunc(key)
+}
+
+// GetEnumByKeyCalls gets all the calls that were made to GetEnumByKey.
+// Check the length with:
+//
+//	len(mockedSession.GetEnumByKeyCalls())
+func (mock *SessionMock) GetEnumByKeyCalls() []struct {
+	Key string
+} {
+	var calls []struct {
+		Key string
+	}
+	mock.lockGetEnumByKey.RLock()
+	calls = mock.calls.GetEnumByKey
+	mock.lockGetEnumByKey.RUnlock()
+	return calls
+}
+
+// GetExceptionByKey calls GetExceptionByKeyFunc.
+func (mock *SessionMock) GetExceptionByKey(key string) (*ast.ExceptionToken, error) {
+	if mock.GetExceptionByKeyFunc == nil {
+		panic("SessionMock.GetExceptionByKeyFunc: method is nil but Session.GetExceptionByKey was just called")
+	}
+	callInfo := struct {
+		Key string
+	}{
+		Key: key,
+	}
+	mock.lockGetExceptionByKey.Lock()
+	mock.calls.GetExceptionByKey = append(mock.calls.GetExceptionByKey, callInfo)
+	mock.lockGetExceptionByKey.Unlock()
+	return mock.GetExceptionByKeyFunc(key)
+}
+
+// GetExceptionByKeyCalls gets all the calls that were made to GetExceptionByKey.
+// Check the length with:
+//
+//	len(mockedSession.GetExceptionByKeyCalls())
+func (mock *SessionMock) GetExceptionByKeyCalls() []struct {
+	Key string
+} {
+	var calls []struct {
+		Key string
+	}
+	mock.lockGetExceptionByKey.RLock()
+	calls = mock.calls.GetExceptionByKey
+	mock.lockGetExceptionByKey.RUnlock()
+	return calls
+}
+
 // GetStructByKey calls GetStructByKeyFunc.
 func (mock *SessionMock) GetStructByKey(key string) ([]*ast.StructToken, error) {
 	if mock.GetStructByKeyFunc == nil {
@@ -637,6 +1107,198 @@ func (mock *SessionMock) GetStructByKeyCalls() []struct {
 	return calls
 }
 
+// GetStructObject calls GetStructObjectFunc.
+func (mock *SessionMock) GetStructObject(structName string, key []byte) ([]byte, error) {
+	if mock.GetStructObjectFunc == nil {
+		panic("SessionMock.GetStructObjectFunc: method is nil but Session.GetStructObject was just called")
+	}
+	callInfo := struct {
+		StructName string
+		Key        []byte
+	}{
+		StructName: structName,
+		Key:        key,
+	}
+	mock.lockGetStructObject.Lock()
+	mock.calls.GetStructObject = append(mock.calls.GetStructObject, callInfo)
+	mock.lockGetStructObject.Unlock()
+	return mock.GetStructObjectFunc(structName, key)
+}
+
+// GetStructObjectCalls gets all the calls that were made to GetStructObject.
+// Check the length with:
+//
+//	len(mockedSession.GetStructObjectCalls())
+func (mock *SessionMock) GetStructObjectCalls() []struct {
+	StructName string
+	Key        []byte
+} {
+	var calls []struct {
+		StructName string
+		Key        []byte
+	}
+	mock.lockGetStructObject.RLock()
+	calls = mock.calls.GetStructObject
+	mock.lockGetStructObject.RUnlock()
+	return calls
+}
+
+// InsertStructObject calls InsertStructObjectFunc.
+func (mock *SessionMock) InsertStructObject(structName string, key []byte, value []byte) ([]byte, error) {
+	if mock.InsertStructObjectFunc == nil {
+		panic("SessionMock.InsertStructObjectFunc: method is nil but Session.InsertStructObject was just called")
+	}
+	callInfo := struct {
+		StructName string
+		Key        []byte
+		Value      []byte
+	}{
+		StructName: structName,
+		Key:        key,
+		Value:      value,
+	}
+	mock.lockInsertStructObject.Lock()
+	mock.calls.InsertStructObject = append(mock.calls.InsertStructObject, callInfo)
+	mock.lockInsertStructObject.Unlock()
+	return mock.InsertStructObjectFunc(structName, key, value)
+}
+
+// InsertStructObjectCalls gets all the calls that were made to InsertStructObject.
+// Check the length with:
+//
+//	len(mockedSession.InsertStructObjectCalls())
+func (mock *SessionMock) InsertStructObjectCalls() []struct {
+	StructName string
+	Key        []byte
+	Value      []byte
+} {
+	var calls []struct {
+		StructName string
+		Key        []byte
+		Value      []byte
+	}
+	mock.lockInsertStructObject.RLock()
+	calls = mock.calls.InsertStructObject
+	mock.lockInsertStructObject.RUnlock()
+	return calls
+}
+
+// IterateStructIndex calls IterateStructIndexFunc.
+func (mock *SessionMock) IterateStructIndex(structName string, field string, options engine.StructIteratorOptions) (engine.StructObjectIterator, error) {
+	if mock.IterateStructIndexFunc == nil {
+		panic("SessionMock.IterateStructIndexFunc: method is nil but Session.IterateStructIndex was just called")
+	}
+	callInfo := struct {
+		StructName string
+		Field      string
+		Options    engine.StructIteratorOptions
+	}{
+		StructName: structName,
+		Field:      field,
+		Options:    options,
+	}
+	mock.lockIterateStructIndex.Lock()
+	mock.calls.IterateStructIndex = append(mock.calls.IterateStructIndex, callInfo)
+	mock.lockIterateStructIndex.Unlock()
+	return mock.IterateStructIndexFunc(structName, field, options)
+}
+
+// IterateStructIndexCalls gets all the calls that were made to IterateStructIndex.
+// Check the length with:
+//
+//	len(mockedSession.IterateStructIndexCalls())
+func (mock *SessionMock) IterateStructIndexCalls() []struct {
+	StructName string
+	Field      string
+	Options    engine.StructIteratorOptions
+} {
+	var calls []struct {
+		StructName string
+		Field      string
+		Options    engine.StructIteratorOptions
+	}
+	mock.lockIterateStructIndex.RLock()
+	calls = mock.calls.IterateStructIndex
+	mock.lockIterateStructIndex.RUnlock()
+	return calls
+}
+
+// IterateStructObjects calls IterateStructObjectsFunc.
+func (mock *SessionMock) IterateStructObjects(structName string, options engine.StructIteratorOptions) (engine.StructObjectIterator, error) {
+	if mock.IterateStructObjectsFunc == nil {
+		panic("SessionMock.IterateStructObjectsFunc: method is nil but Session.IterateStructObjects was just called")
+	}
+	callInfo := struct {
+		StructName string
+		Options    engine.StructIteratorOptions
+	}{
+		StructName: structName,
+		Options:    options,
+	}
+	mock.lockIterateStructObjects.Lock()
+	mock.calls.IterateStructObjects = append(mock.calls.IterateStructObjects, callInfo)
+	mock.lockIterateStructObjects.Unlock()
+	return mock.IterateStructObjectsFunc(structName, options)
+}
+
+// IterateStructObjectsCalls gets all the calls that were made to IterateStructObjects.
+// Check the length with:
+//
+//	len(mockedSession.IterateStructObjectsCalls())
+func (mock *SessionMock) IterateStructObjectsCalls() []struct {
+	StructName string
+	Options    engine.StructIteratorOptions
+} {
+	var calls []struct {
+		StructName string
+		Options    engine.StructIteratorOptions
+	}
+	mock.lockIterateStructObjects.RLock()
+	calls = mock.calls.IterateStructObjects
+	mock.lockIterateStructObjects.RUnlock()
+	return calls
+}
+
+// LookupStructIndex calls LookupStructIndexFunc.
+func (mock *SessionMock) LookupStructIndex(structName string, field string, value []byte) ([][]byte, error) {
+	if mock.LookupStructIndexFunc == nil {
+		panic("SessionMock.LookupStructIndexFunc: method is nil but Session.LookupStructIndex was just called")
+	}
+	callInfo := struct {
+		StructName string
+		Field      string
+		Value      []byte
+	}{
+		StructName: structName,
+		Field:      field,
+		Value:      value,
+	}
+	mock.lockLookupStructIndex.Lock()
+	mock.calls.LookupStructIndex = append(mock.calls.LookupStructIndex, callInfo)
+	mock.lockLookupStructIndex.Unlock()
+	return mock.LookupStructIndexFunc(structName, field, value)
+}
+
+// LookupStructIndexCalls gets all the calls that were made to LookupStructIndex.
+// Check the length with:
+//
+//	len(mockedSession.LookupStructIndexCalls())
+func (mock *SessionMock) LookupStructIndexCalls() []struct {
+	StructName string
+	Field      string
+	Value      []byte
+} {
+	var calls []struct {
+		StructName string
+		Field      string
+		Value      []byte
+	}
+	mock.lockLookupStructIndex.RLock()
+	calls = mock.calls.LookupStructIndex
+	mock.lockLookupStructIndex.RUnlock()
+	return calls
+}
+
 // ReleaseStructObjectReadLock calls ReleaseStructObjectReadLockFunc.
 func (mock *SessionMock) ReleaseStructObjectReadLock(structName string, keys ...[]byte) error {
 	if mock.ReleaseStructObjectReadLockFunc == nil {
@@ -773,6 +1435,42 @@ func (mock *SessionMock) ReleaseStructWriteLockCalls() []struct {
 	return calls
 }
 
+// RenameStruct calls RenameStructFunc.
+func (mock *SessionMock) RenameStruct(oldName string, newName string) error {
+	if mock.RenameStructFunc == nil {
+		panic("SessionMock.RenameStructFunc: method is nil but Session.RenameStruct was just called")
+	}
+	callInfo := struct {
+		OldName string
+		NewName string
+	}{
+		OldName: oldName,
+		NewName: newName,
+	}
+	mock.lockRenameStruct.Lock()
+	mock.calls.RenameStruct = append(mock.calls.RenameStruct, callInfo)
+	mock.lockRenameStruct.Unlock()
+	return mock.RenameStructFunc(oldName, newName)
+}
+
+// RenameStructCalls gets all the calls that were made to RenameStruct.
+// Check the length with:
+//
+//	len(mockedSession.RenameStructCalls())
+func (mock *SessionMock) RenameStructCalls() []struct {
+	OldName string
+	NewName string
+} {
+	var calls []struct {
+		OldName string
+		NewName string
+	}
+	mock.lockRenameStruct.RLock()
+	calls = mock.calls.RenameStruct
+	mock.lockRenameStruct.RUnlock()
+	return calls
+}
+
 // Rollback calls RollbackFunc.
 func (mock *SessionMock) Rollback() error {
 	if mock.RollbackFunc == nil {
@@ -800,6 +1498,74 @@ func (mock *SessionMock) RollbackCalls() []struct {
 	return calls
 }
 
+// StructIndexStatus calls StructIndexStatusFunc.
+func (mock *SessionMock) StructIndexStatus(structName string, field string) (engine.IndexStatus, error) {
+	if mock.StructIndexStatusFunc == nil {
+		panic("SessionMock.StructIndexStatusFunc: method is nil but Session.StructIndexStatus was just called")
+	}
+	callInfo := struct {
+		StructName string
+		Field      string
+	}{
+		StructName: structName,
+		Field:      field,
+	}
+	mock.lockStructIndexStatus.Lock()
+	mock.calls.StructIndexStatus = append(mock.calls.StructIndexStatus, callInfo)
+	mock.lockStructIndexStatus.Unlock()
+	return mock.StructIndexStatusFunc(structName, field)
+}
+
+// StructIndexStatusCalls gets all the calls that were made to StructIndexStatus.
+// Check the length with:
+//
+//	len(mockedSession.StructIndexStatusCalls())
+func (mock *SessionMock) StructIndexStatusCalls() []struct {
+	StructName string
+	Field      string
+} {
+	var calls []struct {
+		StructName string
+		Field      string
+	}
+	mock.lockStructIndexStatus.RLock()
+	calls = mock.calls.StructIndexStatus
+	mock.lockStructIndexStatus.RUnlock()
+	return calls
+}
+
+// StructMigrationProgress calls StructMigrationProgressFunc.
+func (mock *SessionMock) StructMigrationProgress(structName string) (engine.MigrationProgress, error) {
+	if mock.StructMigrationProgressFunc == nil {
+		panic("SessionMock.StructMigrationProgressFunc: method is nil but Session.StructMigrationProgress was just called")
+	}
+	callInfo := struct {
+		StructName string
+	}{
+		StructName: structName,
+	}
+	mock.lockStructMigrationProgress.Lock()
+	mock.calls.StructMigrationProgress = append(mock.calls.StructMigrationProgress, callInfo)
+	mock.lockStructMigrationProgress.Unlock()
+	return mock.StructMigrationProgressFunc(structName)
+}
+
+// StructMigrationProgressCalls gets all the calls that were made to StructMigrationProgress.
+// Check the length with:
+//
+//	len(mockedSession.StructMigrationProgressCalls())
+func (mock *SessionMock) StructMigrationProgressCalls() []struct {
+	StructName string
+} {
+	var calls []struct {
+		StructName string
+	}
+	mock.lockStructMigrationProgress.RLock()
+	calls = mock.calls.StructMigrationProgress
+	mock.lockStructMigrationProgress.RUnlock()
+	return calls
+}
+
 // StructTombstones calls StructTombstonesFunc.
 func (mock *SessionMock) StructTombstones() (map[string]string, []*ast.StructToken, error) {
 	if mock.StructTombstonesFunc == nil {
@@ -854,6 +1620,46 @@ func (mock *SessionMock) StructsCalls() []struct {
 	return calls
 }
 
+// UpdateStructObject calls UpdateStructObjectFunc.
+func (mock *SessionMock) UpdateStructObject(structName string, key []byte, value []byte) error {
+	if mock.UpdateStructObjectFunc == nil {
+		panic("SessionMock.UpdateStructObjectFunc: method is nil but Session.UpdateStructObject was just called")
+	}
+	callInfo := struct {
+		StructName string
+		Key        []byte
+		Value      []byte
+	}{
+		StructName: structName,
+		Key:        key,
+		Value:      value,
+	}
+	mock.lockUpdateStructObject.Lock()
+	mock.calls.UpdateStructObject = append(mock.calls.UpdateStructObject, callInfo)
+	mock.lockUpdateStructObject.Unlock()
+	return mock.UpdateStructObjectFunc(structName, key, value)
+}
+
+// UpdateStructObjectCalls gets all the calls that were made to UpdateStructObject.
+// Check the length with:
+//
+//	len(mockedSession.UpdateStructObjectCalls())
+func (mock *SessionMock) UpdateStructObjectCalls() []struct {
+	StructName string
+	Key        []byte
+	Value      []byte
+} {
+	var calls []struct {
+		StructName string
+		Key        []byte
+		Value      []byte
+	}
+	mock.lockUpdateStructObject.RLock()
+	calls = mock.calls.UpdateStructObject
+	mock.lockUpdateStructObject.RUnlock()
+	return calls
+}
+
 // WriteContract calls WriteContractFunc.
 func (mock *SessionMock) WriteContract(contract *ast.ContractToken) error {
 	if mock.WriteContractFunc == nil {
@@ -885,3 +1691,103 @@ func (mock *SessionMock) WriteContractCalls() []struct {
 	mock.lockWriteContract.RUnlock()
 	return calls
 }
+
+// WriteEnum calls WriteEnumFunc.
+func (mock *SessionMock) WriteEnum(enum *ast.EnumToken) error {
+	if mock.WriteEnumFunc == nil {
+		panic("SessionMock.WriteEnumFunc: method is nil but Session.WriteEnum was just called")
+	}
+	callInfo := struct {
+		Enum *ast.EnumToken
+	}{
+		Enum: enum,
+	}
+	mock.lockWriteEnum.Lock()
+	mock.calls.WriteEnum = append(mock.calls.WriteEnum, callInfo)
+	mock.lockWriteEnum.Unlock()
+	return mock.WriteEnumFunc(enum)
+}
+
+// WriteEnumCalls gets all the calls that were made to WriteEnum.
+// Check the length with:
+//
+//	len(mockedSession.WriteEnumCalls())
+func (mock *SessionMock) WriteEnumCalls() []struct {
+	Enum *ast.EnumToken
+} {
+	var calls []struct {
+		Enum *ast.EnumToken
+	}
+	mock.lockWriteEnum.RLock()
+	calls = mock.calls.WriteEnum
+	mock.lockWriteEnum.RUnlock()
+	return calls
+}
+
+// WriteException calls WriteExceptionFunc.
+func (mock *SessionMock) WriteException(exception *ast.ExceptionToken) error {
+	if mock.WriteExceptionFunc == nil {
+		panic("SessionMock.WriteExceptionFunc: method is nil but Session.WriteException was just called")
+	}
+	callInfo := struct {
+		Exception *ast.ExceptionToken
+	}{
+		Exception: exception,
+	}
+	mock.lockWriteException.Lock()
+	mock.calls.WriteException = append(mock.calls.WriteException, callInfo)
+	mock.lockWriteException.Unlock()
+	return mock.WriteExceptionFunc(exception)
+}
+
+// WriteExceptionCalls gets all the calls that were made to WriteException.
+// Check the length with:
+//
+//	len(mockedSession.WriteExceptionCalls())
+func (mock *SessionMock) WriteExceptionCalls() []struct {
+	Exception *ast.ExceptionToken
+} {
+	var calls []struct {
+		Exception *ast.ExceptionToken
+	}
+	mock.lockWriteException.RLock()
+	calls = mock.calls.WriteException
+	mock.lockWriteException.RUnlock()
+	return calls
+}
+
+// WriteStruct calls WriteStructFunc.
+func (mock *SessionMock) WriteStruct(structToken *ast.StructToken, force bool) error {
+	if mock.WriteStructFunc == nil {
+		panic("SessionMock.WriteStructFunc: method is nil but Session.WriteStruct was just called")
+	}
+	callInfo := struct {
+		StructToken *ast.StructToken
+		Force       bool
+	}{
+		StructToken: structToken,
+		Force:       force,
+	}
+	mock.lockWriteStruct.Lock()
+	mock.calls.WriteStruct = append(mock.calls.WriteStruct, callInfo)
+	mock.lockWriteStruct.Unlock()
+	return mock.WriteStructFunc(structToken, force)
+}
+
+// WriteStructCalls gets all the calls that were made to WriteStruct.
+// Check the length with:
+//
+//	len(mockedSession.WriteStructCalls())
+func (mock *SessionMock) WriteStructCalls() []struct {
+	StructToken *ast.StructToken
+	Force       bool
+} {
+	var calls []struct {
+		StructToken *ast.StructToken
+		Force       bool
+	}
+	mock.lockWriteStruct.RLock()
+	calls = mock.calls.WriteStruct
+	mock.lockWriteStruct.RUnlock()
+	return calls
+}
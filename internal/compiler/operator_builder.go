@@ -0,0 +1,747 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package compiler
+
+import (
+	goAst "go/ast"
+	"go/token"
+	"math/big"
+	"strconv"
+
+	"remixdb.io/internal/rqltypes"
+)
+
+// Defines the names of the math/big methods used for each arithmetic operator. Exponents
+// are represented by token.XOR since that is the RemixDB operator.
+var bigIntMethods = map[token.Token]string{
+	token.ADD: "Add",
+	token.SUB: "Sub",
+	token.MUL: "Mul",
+	token.QUO: "Quo",
+	token.REM: "Rem",
+	token.XOR: "Exp",
+}
+
+// Creates a binary expression, adding brackets to either side if they would bind looser
+// than the operator.
+func binaryExpr(x goAst.Expr, op token.Token, y goAst.Expr) goAst.Expr {
+	if b, ok := x.(*goAst.BinaryExpr); ok && b.Op.Precedence() < op.Precedence() {
+		x = &goAst.ParenExpr{X: x}
+	}
+	if b, ok := y.(*goAst.BinaryExpr); ok && b.Op.Precedence() <= op.Precedence() {
+		y = &goAst.ParenExpr{X: y}
+	}
+	return &goAst.BinaryExpr{X: x, Op: op, Y: y}
+}
+
+// Creates a call to the method on the expression specified.
+func methodCall(x goAst.Expr, method string, args ...goAst.Expr) goAst.Expr {
+	return &goAst.CallExpr{
+		Fun: &goAst.SelectorExpr{
+			X:   parenthesize(x),
+			Sel: goAst.NewIdent(method),
+		},
+		Args: args,
+	}
+}
+
+// Creates a integer literal. Negative values are turned into a unary expression since Go
+// literals cannot contain a sign.
+func intLiteral(v *big.Int) goAst.Expr {
+	if v.Sign() < 0 {
+		return &goAst.UnaryExpr{
+			Op: token.SUB,
+			X:  &goAst.BasicLit{Kind: token.INT, Value: new(big.Int).Neg(v).String()},
+		}
+	}
+	return &goAst.BasicLit{Kind: token.INT, Value: v.String()}
+}
+
+// Gets the value of an integer constant. Returns false if the expression is not one.
+func intConstant(expr goAst.Expr) (*big.Int, bool) {
+	negate := false
+	if u, ok := expr.(*goAst.UnaryExpr); ok && u.Op == token.SUB {
+		negate = true
+		expr = u.X
+	}
+	lit, ok := expr.(*goAst.BasicLit)
+	if !ok || lit.Kind != token.INT {
+		return nil, false
+	}
+	v, ok := new(big.Int).SetString(lit.Value, 10)
+	if !ok {
+		return nil, false
+	}
+	if negate {
+		v.Neg(v)
+	}
+	return v, true
+}
+
+// Checks if the expression is a numeric constant which is zero.
+func isZeroConstant(expr goAst.Expr) bool {
+	if u, ok := expr.(*goAst.UnaryExpr); ok && u.Op == token.SUB {
+		expr = u.X
+	}
+	lit, ok := expr.(*goAst.BasicLit)
+	if !ok || (lit.Kind != token.INT && lit.Kind != token.FLOAT) {
+		return false
+	}
+	f, err := strconv.ParseFloat(lit.Value, 64)
+	return err == nil && f == 0
+}
+
+// Checks if the type is the builtin specified and is not optional.
+func isType(t rqltypes.Type, name string) bool {
+	return t.Elem == nil && !t.Optional && t.Name == name
+}
+
+// Checks if the type is null.
+func isNull(t rqltypes.Type) bool {
+	return t.Elem == nil && t.Name == rqltypes.Null
+}
+
+// Creates the error for when an operator cannot be used on the types specified.
+func invalidOperation(x any, op token.Token, lt, rt rqltypes.Type) error {
+	return errorAt(x, "cannot use the operator "+op.String()+" on the types "+lt.String()+" and "+rt.String())
+}
+
+// Converts an integer constant so that it can be used with the other side of a operation.
+// Values which are not integer constants are returned unchanged.
+func (b *statementBuilder) coerceConstant(
+	t any, expr goAst.Expr, type_, other rqltypes.Type,
+) (goAst.Expr, rqltypes.Type, error) {
+	v, ok := intConstant(expr)
+	if !ok || !isType(type_, rqltypes.Int) || other.Elem != nil {
+		return expr, type_, nil
+	}
+	to := other.NonOptional()
+	switch to.Name {
+	case rqltypes.Uint:
+		if v.Sign() < 0 {
+			return nil, type_, errorAt(t, "cannot use the negative value "+v.String()+" as a uint")
+		}
+		return expr, to, nil
+	case rqltypes.Float:
+		return expr, to, nil
+	case rqltypes.Bigint:
+		b.f.addImport("math/big")
+		return &goAst.CallExpr{
+			Fun:  goAst.NewIdent("big.NewInt"),
+			Args: []goAst.Expr{expr},
+		}, to, nil
+	}
+	return expr, type_, nil
+}
+
+// Builds both sides of an operation. Integer constants are converted to the type of the
+// other side if possible.
+func (b *statementBuilder) buildOperands(
+	sc *scope, left, right any,
+) (l, r goAst.Expr, lt, rt rqltypes.Type, err error) {
+	// Build both sides.
+	if l, lt, err = b.buildExpression(sc, left); err != nil {
+		return
+	}
+	if r, rt, err = b.buildExpression(sc, right); err != nil {
+		return
+	}
+
+	// Convert any constants.
+	if l, lt, err = b.coerceConstant(left, l, lt, rt); err != nil {
+		return
+	}
+	r, rt, err = b.coerceConstant(right, r, rt, lt)
+	return
+}
+
+// Builds a arithmetic operation. Exponents are specified with token.XOR.
+func (b *statementBuilder) buildArithmetic(
+	sc *scope, x any, op token.Token, left, right any,
+) (goAst.Expr, rqltypes.Type, error) {
+	// Build the operands.
+	l, r, lt, rt, err := b.buildOperands(sc, left, right)
+	if err != nil {
+		return nil, rqltypes.Type{}, err
+	}
+
+	// Arithmetic is not supported on optionals or arrays.
+	if lt.Optional || rt.Optional || lt.Elem != nil || rt.Elem != nil {
+		return nil, lt, invalidOperation(x, op, lt, rt)
+	}
+
+	// Handle timestamps since they can be mixed with integers.
+	if lt.Name == rqltypes.Timestamp || rt.Name == rqltypes.Timestamp {
+		return b.buildTimestampArithmetic(x, op, l, r, lt, rt)
+	}
+
+	// Everything else must be the same type.
+	if !lt.Equal(rt) {
+		return nil, lt, invalidOperation(x, op, lt, rt)
+	}
+
+	// Division by a constant zero would fail the Go build.
+	if v, ok := bigIntConstant(r); (op == token.QUO || op == token.REM) && (isZeroConstant(r) || ok && v.Sign() == 0) {
+		return nil, lt, errorAt(x, "division by zero")
+	}
+
+	switch lt.Name {
+	case rqltypes.String:
+		// Strings only support concatenation.
+		if op != token.ADD {
+			return nil, lt, invalidOperation(x, op, lt, rt)
+		}
+		return binaryExpr(l, op, r), lt, nil
+	case rqltypes.Int, rqltypes.Uint:
+		// Handle exponents.
+		if op == token.XOR {
+			return b.integerPower(l, r, lt), lt, nil
+		}
+
+		// Fold integer constants so that Go does not reject constants which overflow.
+		if lv, ok := intConstant(l); ok {
+			if rv, ok := intConstant(r); ok {
+				return foldIntConstants(x, op, lt, lv, rv)
+			}
+		}
+		if op == token.QUO || op == token.REM {
+			r = b.checkDivisor(r, lt)
+		}
+		return binaryExpr(l, op, r), lt, nil
+	case rqltypes.Float:
+		// Go does not support modulo or exponents on floats, so use the math package.
+		switch op {
+		case token.REM:
+			b.f.addImport("math")
+			return &goAst.CallExpr{Fun: goAst.NewIdent("math.Mod"), Args: []goAst.Expr{l, r}}, lt, nil
+		case token.XOR:
+			b.f.addImport("math")
+			return &goAst.CallExpr{Fun: goAst.NewIdent("math.Pow"), Args: []goAst.Expr{l, r}}, lt, nil
+		}
+		return binaryExpr(l, op, r), lt, nil
+	case rqltypes.Bigint:
+		// Create a new big integer with the result.
+		args := []goAst.Expr{l, r}
+		if op == token.XOR {
+			args = append(args, goAst.NewIdent("nil"))
+		}
+		if op == token.QUO || op == token.REM {
+			args[1] = b.checkDivisor(r, lt)
+		}
+		newInt := &goAst.CallExpr{Fun: goAst.NewIdent("new"), Args: []goAst.Expr{goAst.NewIdent("big.Int")}}
+		return methodCall(newInt, bigIntMethods[op], args...), lt, nil
+	}
+	return nil, lt, invalidOperation(x, op, lt, rt)
+}
+
+// Folds two integer constants into one, returning an error if the result overflows the
+// type specified.
+func foldIntConstants(x any, op token.Token, type_ rqltypes.Type, l, r *big.Int) (goAst.Expr, rqltypes.Type, error) {
+	v := new(big.Int)
+	switch op {
+	case token.ADD:
+		v.Add(l, r)
+	case token.SUB:
+		v.Sub(l, r)
+	case token.MUL:
+		v.Mul(l, r)
+	case token.QUO:
+		v.Quo(l, r)
+	case token.REM:
+		v.Rem(l, r)
+	}
+	fits := v.IsInt64()
+	if type_.Name == rqltypes.Uint {
+		fits = v.Sign() >= 0 && v.IsUint64()
+	}
+	if !fits {
+		return nil, type_, errorAt(x, "constant "+v.String()+" overflows "+type_.Name)
+	}
+	return intLiteral(v), type_, nil
+}
+
+// Gets the integer constant within a big.NewInt call. Returns false if the expression is not one.
+func bigIntConstant(expr goAst.Expr) (*big.Int, bool) {
+	call, ok := expr.(*goAst.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return nil, false
+	}
+	if fn, ok := call.Fun.(*goAst.Ident); !ok || fn.Name != "big.NewInt" {
+		return nil, false
+	}
+	return intConstant(call.Args[0])
+}
+
+// Wraps the divisor of a integer division or modulo in a function literal which checks that it
+// is not zero when the division happens, since Go panics on a division by zero. The panic is
+// turned into a exception by divisionByZeroHandler. Constant divisors are returned unchanged
+// since they were already checked when building.
+func (b *statementBuilder) checkDivisor(r goAst.Expr, t rqltypes.Type) goAst.Expr {
+	var isZero goAst.Expr
+	if t.Name == rqltypes.Bigint {
+		if _, ok := bigIntConstant(r); ok {
+			return r
+		}
+		isZero = binaryExpr(methodCall(goAst.NewIdent("d"), "Sign"), token.EQL, goAst.NewIdent("0"))
+	} else {
+		if _, ok := intConstant(r); ok {
+			return r
+		}
+		isZero = binaryExpr(goAst.NewIdent("d"), token.EQL, goAst.NewIdent("0"))
+	}
+	b.divides = true
+	goType := goAst.NewIdent(b.goType(t))
+	return &goAst.CallExpr{
+		Fun: &goAst.FuncLit{
+			Type: &goAst.FuncType{
+				Params: &goAst.FieldList{
+					List: []*goAst.Field{{Names: []*goAst.Ident{goAst.NewIdent("d")}, Type: goType}},
+				},
+				Results: &goAst.FieldList{List: []*goAst.Field{{Type: goType}}},
+			},
+			Body: &goAst.BlockStmt{
+				List: []goAst.Stmt{
+					&goAst.IfStmt{
+						Cond: isZero,
+						Body: &goAst.BlockStmt{
+							List: []goAst.Stmt{
+								&goAst.ExprStmt{
+									X: &goAst.CallExpr{
+										Fun:  goAst.NewIdent("panic"),
+										Args: []goAst.Expr{&goAst.CompositeLit{Type: goAst.NewIdent("divisionByZero")}},
+									},
+								},
+							},
+						},
+					},
+					&goAst.ReturnStmt{Results: []goAst.Expr{goAst.NewIdent("d")}},
+				},
+			},
+		},
+		Args: []goAst.Expr{r},
+	}
+}
+
+// Creates the statements which declare the panic used by checkDivisor and recover from it by
+// responding with a exception. The transaction is not committed, so nothing the contract did
+// is kept.
+func (b *statementBuilder) divisionByZeroHandler() []goAst.Stmt {
+	b.addToInterface("RespondWithRemixDBException", remixDbExceptionSig())
+	recovered := goAst.NewIdent("p")
+	return []goAst.Stmt{
+		&goAst.DeclStmt{
+			Decl: &goAst.GenDecl{
+				Tok: token.TYPE,
+				Specs: []goAst.Spec{
+					&goAst.TypeSpec{Name: goAst.NewIdent("divisionByZero"), Type: goAst.NewIdent("struct{}")},
+				},
+			},
+		},
+		&goAst.DeferStmt{
+			Call: &goAst.CallExpr{
+				Fun: &goAst.FuncLit{
+					Type: &goAst.FuncType{Params: &goAst.FieldList{}},
+					Body: &goAst.BlockStmt{
+						List: []goAst.Stmt{
+							&goAst.IfStmt{
+								Init: &goAst.AssignStmt{
+									Lhs: []goAst.Expr{recovered},
+									Tok: token.DEFINE,
+									Rhs: []goAst.Expr{&goAst.CallExpr{Fun: goAst.NewIdent("recover")}},
+								},
+								Cond: binaryExpr(recovered, token.NEQ, goAst.NewIdent("nil")),
+								Body: &goAst.BlockStmt{
+									List: []goAst.Stmt{
+										// Panics which are not a division by zero are passed on.
+										&goAst.IfStmt{
+											Init: &goAst.AssignStmt{
+												Lhs: []goAst.Expr{goAst.NewIdent("_"), goAst.NewIdent("ok")},
+												Tok: token.DEFINE,
+												Rhs: []goAst.Expr{&goAst.TypeAssertExpr{X: recovered, Type: goAst.NewIdent("divisionByZero")}},
+											},
+											Cond: &goAst.UnaryExpr{Op: token.NOT, X: goAst.NewIdent("ok")},
+											Body: &goAst.BlockStmt{
+												List: []goAst.Stmt{
+													&goAst.ExprStmt{
+														X: &goAst.CallExpr{Fun: goAst.NewIdent("panic"), Args: []goAst.Expr{recovered}},
+													},
+												},
+											},
+										},
+										&goAst.ExprStmt{
+											X: &goAst.CallExpr{
+												Fun: goAst.NewIdent("r.RespondWithRemixDBException"),
+												Args: []goAst.Expr{
+													goAst.NewIdent("400"),
+													&goAst.BasicLit{Kind: token.STRING, Value: strconv.Quote("division_by_zero")},
+													&goAst.BasicLit{Kind: token.STRING, Value: strconv.Quote("The contract tried to divide by zero.")},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// Builds a integer exponent. Go has no operator for this, so a function literal is used
+// which does exponentiation by squaring. Negative exponents truncate like division would.
+func (b *statementBuilder) integerPower(l, r goAst.Expr, t rqltypes.Type) goAst.Expr {
+	goType := b.goType(t)
+	stmts := []goAst.Stmt{}
+	if t.Name == rqltypes.Int {
+		// 1 / base^exp is only non-zero when the base is 1 or -1.
+		stmts = append(stmts, &goAst.IfStmt{
+			Cond: binaryExpr(goAst.NewIdent("exp"), token.LSS, goAst.NewIdent("0")),
+			Body: &goAst.BlockStmt{
+				List: []goAst.Stmt{
+					&goAst.IfStmt{
+						Cond: binaryExpr(
+							binaryExpr(goAst.NewIdent("base"), token.EQL, goAst.NewIdent("1")),
+							token.LOR,
+							binaryExpr(goAst.NewIdent("base"), token.EQL, intLiteral(big.NewInt(-1))),
+						),
+						Body: &goAst.BlockStmt{
+							List: []goAst.Stmt{
+								&goAst.AssignStmt{
+									Lhs: []goAst.Expr{goAst.NewIdent("exp")},
+									Tok: token.ASSIGN,
+									Rhs: []goAst.Expr{&goAst.UnaryExpr{Op: token.SUB, X: goAst.NewIdent("exp")}},
+								},
+							},
+						},
+						Else: &goAst.ReturnStmt{Results: []goAst.Expr{goAst.NewIdent("0")}},
+					},
+				},
+			},
+		})
+	}
+	stmts = append(stmts,
+		&goAst.AssignStmt{
+			Lhs: []goAst.Expr{goAst.NewIdent("result")},
+			Tok: token.DEFINE,
+			Rhs: []goAst.Expr{&goAst.CallExpr{Fun: goAst.NewIdent(goType), Args: []goAst.Expr{goAst.NewIdent("1")}}},
+		},
+		&goAst.ForStmt{
+			Cond: binaryExpr(goAst.NewIdent("exp"), token.GTR, goAst.NewIdent("0")),
+			Body: &goAst.BlockStmt{
+				List: []goAst.Stmt{
+					&goAst.IfStmt{
+						Cond: binaryExpr(
+							binaryExpr(goAst.NewIdent("exp"), token.AND, goAst.NewIdent("1")),
+							token.EQL, goAst.NewIdent("1"),
+						),
+						Body: &goAst.BlockStmt{
+							List: []goAst.Stmt{
+								&goAst.AssignStmt{
+									Lhs: []goAst.Expr{goAst.NewIdent("result")},
+									Tok: token.MUL_ASSIGN,
+									Rhs: []goAst.Expr{goAst.NewIdent("base")},
+								},
+							},
+						},
+					},
+					&goAst.AssignStmt{
+						Lhs: []goAst.Expr{goAst.NewIdent("base")},
+						Tok: token.MUL_ASSIGN,
+						Rhs: []goAst.Expr{goAst.NewIdent("base")},
+					},
+					&goAst.AssignStmt{
+						Lhs: []goAst.Expr{goAst.NewIdent("exp")},
+						Tok: token.SHR_ASSIGN,
+						Rhs: []goAst.Expr{goAst.NewIdent("1")},
+					},
+				},
+			},
+		},
+		&goAst.ReturnStmt{Results: []goAst.Expr{goAst.NewIdent("result")}},
+	)
+
+	return &goAst.CallExpr{
+		Fun: &goAst.FuncLit{
+			Type: &goAst.FuncType{
+				Params: &goAst.FieldList{
+					List: []*goAst.Field{
+						{
+							Names: []*goAst.Ident{goAst.NewIdent("base"), goAst.NewIdent("exp")},
+							Type:  goAst.NewIdent(goType),
+						},
+					},
+				},
+				Results: &goAst.FieldList{
+					List: []*goAst.Field{{Type: goAst.NewIdent(goType)}},
+				},
+			},
+			Body: &goAst.BlockStmt{List: stmts},
+		},
+		Args: []goAst.Expr{l, r},
+	}
+}
+
+// Creates a time.Duration of milliseconds from the integer expression.
+func millisecondsDuration(expr goAst.Expr, negate bool) goAst.Expr {
+	var d goAst.Expr = &goAst.CallExpr{Fun: goAst.NewIdent("time.Duration"), Args: []goAst.Expr{expr}}
+	if negate {
+		d = &goAst.UnaryExpr{Op: token.SUB, X: d}
+	}
+	return binaryExpr(d, token.MUL, goAst.NewIdent("time.Millisecond"))
+}
+
+// Builds arithmetic on timestamps. Integers are treated as milliseconds.
+func (b *statementBuilder) buildTimestampArithmetic(
+	x any, op token.Token, l, r goAst.Expr, lt, rt rqltypes.Type,
+) (goAst.Expr, rqltypes.Type, error) {
+	b.f.addImport("time")
+	timestamp := rqltypes.Type{Name: rqltypes.Timestamp}
+	lTimestamp, rTimestamp := isType(lt, rqltypes.Timestamp), isType(rt, rqltypes.Timestamp)
+	switch {
+	case op == token.ADD && lTimestamp && isType(rt, rqltypes.Int):
+		return methodCall(l, "Add", millisecondsDuration(r, false)), timestamp, nil
+	case op == token.ADD && isType(lt, rqltypes.Int) && rTimestamp:
+		return methodCall(r, "Add", millisecondsDuration(l, false)), timestamp, nil
+	case op == token.SUB && lTimestamp && isType(rt, rqltypes.Int):
+		return methodCall(l, "Add", millisecondsDuration(r, true)), timestamp, nil
+	case op == token.SUB && lTimestamp && rTimestamp:
+		return &goAst.CallExpr{
+			Fun:  goAst.NewIdent("int"),
+			Args: []goAst.Expr{methodCall(methodCall(l, "Sub", r), "Milliseconds")},
+		}, rqltypes.Type{Name: rqltypes.Int}, nil
+	}
+	return nil, lt, invalidOperation(x, op, lt, rt)
+}
+
+// Builds a logical operation on two booleans. If the right side needs statements to run before
+// it, such as a query, they only run if the left side does not decide the result.
+func (b *statementBuilder) buildLogical(
+	sc *scope, x any, op token.Token, left, right any,
+) (goAst.Expr, rqltypes.Type, error) {
+	// Build both sides. Anything hoisted by the right side is kept apart from the rest.
+	l, lt, err := b.buildExpression(sc, left)
+	if err != nil {
+		return nil, rqltypes.Type{}, err
+	}
+	outer := b.takeHoisted()
+	r, rt, err := b.buildExpression(sc, right)
+	rightHoisted := b.takeHoisted()
+	b.hoisted = outer
+	if err != nil {
+		return nil, rqltypes.Type{}, err
+	}
+
+	// Convert any constants and check the types.
+	if l, lt, err = b.coerceConstant(left, l, lt, rt); err != nil {
+		return nil, rqltypes.Type{}, err
+	}
+	if r, rt, err = b.coerceConstant(right, r, rt, lt); err != nil {
+		return nil, rqltypes.Type{}, err
+	}
+	if !isType(lt, rqltypes.Bool) || !isType(rt, rqltypes.Bool) {
+		return nil, lt, invalidOperation(x, op, lt, rt)
+	}
+	if len(rightHoisted) == 0 {
+		return binaryExpr(l, op, r), lt, nil
+	}
+
+	// Hoist the operation as a if statement which only runs the right side when it is needed.
+	varName := "logical" + b.nextID()
+	var cond goAst.Expr = goAst.NewIdent(varName)
+	if op == token.LOR {
+		cond = &goAst.UnaryExpr{Op: token.NOT, X: cond}
+	}
+	b.hoisted = append(b.hoisted,
+		&goAst.AssignStmt{
+			Lhs: []goAst.Expr{goAst.NewIdent(varName)},
+			Tok: token.DEFINE,
+			Rhs: []goAst.Expr{l},
+		},
+		&goAst.IfStmt{
+			Cond: cond,
+			Body: &goAst.BlockStmt{
+				List: append(rightHoisted, &goAst.AssignStmt{
+					Lhs: []goAst.Expr{goAst.NewIdent(varName)},
+					Tok: token.ASSIGN,
+					Rhs: []goAst.Expr{r},
+				}),
+			},
+		},
+	)
+	return goAst.NewIdent(varName), lt, nil
+}
+
+// Builds an equality check for two non-optional values of the type specified. Returns
+// false if the type cannot be compared.
+func (b *statementBuilder) equalityExpr(l, r goAst.Expr, t rqltypes.Type) (goAst.Expr, bool) {
+	if t.Elem != nil {
+		return nil, false
+	}
+	switch t.Name {
+	case rqltypes.String, rqltypes.Int, rqltypes.Uint, rqltypes.Float, rqltypes.Bool:
+		return binaryExpr(l, token.EQL, r), true
+	case rqltypes.Timestamp:
+		return methodCall(l, "Equal", r), true
+	case rqltypes.Bigint:
+		return binaryExpr(methodCall(l, "Cmp", r), token.EQL, goAst.NewIdent("0")), true
+	case rqltypes.Bytes:
+		b.f.addImport("bytes")
+		return &goAst.CallExpr{Fun: goAst.NewIdent("bytes.Equal"), Args: []goAst.Expr{l, r}}, true
+	}
+	return nil, false
+}
+
+// Builds an equality check where one or both sides are optional. This is done within a
+// function literal so that each side is only evaluated once.
+func (b *statementBuilder) optionalEqualityExpr(
+	l, r goAst.Expr, lt, rt rqltypes.Type,
+) (goAst.Expr, bool) {
+	// Gets the value of a parameter, dereferencing it if needed.
+	value := func(name string, t rqltypes.Type) goAst.Expr {
+		if t.Optional && !t.Nilable() {
+			return &goAst.StarExpr{X: goAst.NewIdent(name)}
+		}
+		return goAst.NewIdent(name)
+	}
+	eq, ok := b.equalityExpr(value("a", lt), value("b", rt), lt.NonOptional())
+	if !ok {
+		return nil, false
+	}
+
+	// Build the nil checks.
+	nilCheck := func(name string, op token.Token) goAst.Expr {
+		return binaryExpr(goAst.NewIdent(name), op, goAst.NewIdent("nil"))
+	}
+	var result goAst.Expr
+	switch {
+	case lt.Optional && rt.Optional:
+		result = binaryExpr(
+			binaryExpr(nilCheck("a", token.EQL), token.LAND, nilCheck("b", token.EQL)),
+			token.LOR,
+			binaryExpr(
+				binaryExpr(nilCheck("a", token.NEQ), token.LAND, nilCheck("b", token.NEQ)),
+				token.LAND, eq,
+			),
+		)
+	case lt.Optional:
+		result = binaryExpr(nilCheck("a", token.NEQ), token.LAND, eq)
+	default:
+		result = binaryExpr(nilCheck("b", token.NEQ), token.LAND, eq)
+	}
+
+	// Return the function literal call.
+	return &goAst.CallExpr{
+		Fun: &goAst.FuncLit{
+			Type: &goAst.FuncType{
+				Params: &goAst.FieldList{
+					List: []*goAst.Field{
+						{Names: []*goAst.Ident{goAst.NewIdent("a")}, Type: goAst.NewIdent(b.goType(lt))},
+						{Names: []*goAst.Ident{goAst.NewIdent("b")}, Type: goAst.NewIdent(b.goType(rt))},
+					},
+				},
+				Results: &goAst.FieldList{
+					List: []*goAst.Field{{Type: goAst.NewIdent("bool")}},
+				},
+			},
+			Body: &goAst.BlockStmt{
+				List: []goAst.Stmt{&goAst.ReturnStmt{Results: []goAst.Expr{result}}},
+			},
+		},
+		Args: []goAst.Expr{l, r},
+	}, true
+}
+
+// Builds a comparison between two values.
+func (b *statementBuilder) buildComparison(
+	sc *scope, x any, op token.Token, left, right any,
+) (goAst.Expr, rqltypes.Type, error) {
+	// Build the operands.
+	l, r, lt, rt, err := b.buildOperands(sc, left, right)
+	if err != nil {
+		return nil, rqltypes.Type{}, err
+	}
+	boolType := rqltypes.Type{Name: rqltypes.Bool}
+
+	// Enums are compared as strings.
+	if lt.IsEnum() && rt.Elem == nil && rt.Name == rqltypes.String {
+		if err := checkEnumLiteral(right, lt); err != nil {
+			return nil, boolType, err
+		}
+		lt = rqltypes.Type{Name: rqltypes.String, Optional: lt.Optional}
+	} else if rt.IsEnum() && lt.Elem == nil && lt.Name == rqltypes.String {
+		if err := checkEnumLiteral(left, rt); err != nil {
+			return nil, boolType, err
+		}
+		rt = rqltypes.Type{Name: rqltypes.String, Optional: rt.Optional}
+	} else if lt.IsEnum() && rt.IsEnum() && lt.Name == rt.Name {
+		lt = rqltypes.Type{Name: rqltypes.String, Optional: lt.Optional}
+		rt = rqltypes.Type{Name: rqltypes.String, Optional: rt.Optional}
+	}
+
+	if op == token.EQL || op == token.NEQ {
+		// Handle comparisons with null.
+		if isNull(lt) || isNull(rt) {
+			other := lt
+			if isNull(lt) {
+				other = rt
+			}
+			if isNull(other) || !other.Optional {
+				return nil, boolType, invalidOperation(x, op, lt, rt)
+			}
+			return binaryExpr(l, op, r), boolType, nil
+		}
+
+		// Make sure the types match, ignoring if they are optional.
+		if !lt.NonOptional().Equal(rt.NonOptional()) {
+			return nil, boolType, invalidOperation(x, op, lt, rt)
+		}
+
+		// Build the equality check.
+		var eq goAst.Expr
+		ok := false
+		if lt.Optional || rt.Optional {
+			eq, ok = b.optionalEqualityExpr(l, r, lt, rt)
+		} else {
+			eq, ok = b.equalityExpr(l, r, lt)
+		}
+		if !ok {
+			return nil, boolType, errorAt(x, "cannot compare values of the type "+lt.NonOptional().String())
+		}
+
+		// Invert the result for not equal.
+		if op == token.NEQ {
+			if bin, isBin := eq.(*goAst.BinaryExpr); isBin && bin.Op == token.EQL {
+				bin.Op = token.NEQ
+			} else {
+				eq = &goAst.UnaryExpr{Op: token.NOT, X: parenthesize(eq)}
+			}
+		}
+		return eq, boolType, nil
+	}
+
+	// Ordering is only supported on non-optional values of the same type.
+	if lt.Optional || rt.Optional || lt.Elem != nil || !lt.Equal(rt) {
+		return nil, boolType, invalidOperation(x, op, lt, rt)
+	}
+	switch lt.Name {
+	case rqltypes.String, rqltypes.Int, rqltypes.Uint, rqltypes.Float:
+		return binaryExpr(l, op, r), boolType, nil
+	case rqltypes.Timestamp:
+		switch op {
+		case token.LSS:
+			return methodCall(l, "Before", r), boolType, nil
+		case token.GTR:
+			return methodCall(l, "After", r), boolType, nil
+		case token.LEQ:
+			return &goAst.UnaryExpr{Op: token.NOT, X: methodCall(l, "After", r)}, boolType, nil
+		default:
+			return &goAst.UnaryExpr{Op: token.NOT, X: methodCall(l, "Before", r)}, boolType, nil
+		}
+	case rqltypes.Bigint:
+		return binaryExpr(methodCall(l, "Cmp", r), op, goAst.NewIdent("0")), boolType, nil
+	}
+	return nil, boolType, invalidOperation(x, op, lt, rt)
+}
@@ -0,0 +1,581 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package compiler
+
+import (
+	"encoding/json"
+	goAst "go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"remixdb.io/ast"
+	"remixdb.io/internal/engine"
+	"remixdb.io/internal/query"
+	"remixdb.io/internal/rqltypes"
+)
+
+// Defines a single part of a chain such as Tree.where({ ... }).first. Each dot is a new link.
+type chainLink struct {
+	// token is the token the link came from. This is used for errors.
+	token any
+
+	// name is the name of the link.
+	name string
+
+	// call is true if the link is a method call.
+	call bool
+
+	// args are the arguments of the method call without any comments.
+	args []any
+}
+
+// Flattens a method call or reference and any chained calls into links.
+func flattenChain(t any) []chainLink {
+	links := []chainLink{}
+	for t != nil {
+		var (
+			name string
+			call bool
+			args []any
+			next any
+		)
+		switch x := t.(type) {
+		case ast.MethodCallToken:
+			name, call, next = x.Name, true, x.ChainedCall
+			args = []any{}
+			for _, arg := range x.Arguments {
+				if _, ok := arg.(ast.CommentToken); !ok {
+					args = append(args, arg)
+				}
+			}
+		case ast.ReferenceToken:
+			name = x.Name
+		default:
+			return links
+		}
+
+		// Only the last part of the name is called.
+		parts := strings.Split(name, ".")
+		for i, part := range parts {
+			link := chainLink{token: t, name: part}
+			if i == len(parts)-1 {
+				link.call, link.args = call, args
+			}
+			links = append(links, link)
+		}
+		t = next
+	}
+	return links
+}
+
+// Checks if the links are a query on a struct. A variable with the same name as the struct takes
+// priority. Returns nil if this is not a query.
+func (b *statementBuilder) queryStruct(sc *scope, links []chainLink) (*ast.StructToken, error) {
+	if len(links) < 2 || links[0].call || (sc != nil && sc.lookup(links[0].name) != nil) {
+		return nil, nil
+	}
+	history, err := b.s.GetStructByKey(links[0].name)
+	if err != nil {
+		if err == engine.ErrNotExists {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return history[len(history)-1], nil
+}
+
+// Defines the methods which can come before the action of a query.
+var queryModifiers = map[string]bool{
+	"where":   true,
+	"orderBy": true,
+	"limit":   true,
+	"offset":  true,
+	"after":   true,
+	"groupBy": true,
+}
+
+// Builds orderBy('field') or orderBy('field', 'desc') into the query. The field must be a string
+// literal so that it can be checked when compiling.
+func (b *statementBuilder) buildOrderBy(link chainLink, fields map[string]rqltypes.Type, q *query.Query) error {
+	// Get the arguments as strings.
+	if !link.call || len(link.args) == 0 || len(link.args) > 2 {
+		return errorAt(link.token, "orderBy expects a field name and optionally a direction")
+	}
+	args := make([]string, len(link.args))
+	for i, arg := range link.args {
+		lit, ok := arg.(ast.StringLiteralToken)
+		if !ok {
+			return errorAt(arg, "the arguments of orderBy must be string literals")
+		}
+		args[i] = lit.Value
+	}
+
+	// Make sure the field can be ordered.
+	fieldType, ok := fields[args[0]]
+	if !ok {
+		return errorAt(link.args[0], "unknown field "+args[0])
+	}
+	if !rqltypes.Orderable(fieldType) {
+		return errorAt(link.args[0], "cannot order by the field "+args[0]+" of type "+fieldType.String())
+	}
+	q.OrderBy = args[0]
+
+	// Handle the direction.
+	if len(args) == 2 {
+		switch args[1] {
+		case "asc":
+		case "desc":
+			q.Descending = true
+		default:
+			return errorAt(link.args[1], "the direction of orderBy must be 'asc' or 'desc'")
+		}
+	}
+	return nil
+}
+
+// Gets the field name passed to a method as a string literal. Returns a blank string if the
+// argument is not a string literal.
+func fieldArgument(arg any) string {
+	lit, ok := arg.(ast.StringLiteralToken)
+	if !ok {
+		return ""
+	}
+	return lit.Value
+}
+
+// Builds groupBy('field', Result) into the query. Result is the struct each group is returned as.
+func (b *statementBuilder) buildGroupBy(
+	link chainLink, fields map[string]rqltypes.Type, q *query.Query,
+) (*ast.StructToken, error) {
+	// Get the field.
+	if !link.call || len(link.args) != 2 {
+		return nil, errorAt(link.token, "groupBy expects a field name and the struct to return each group as")
+	}
+	field := fieldArgument(link.args[0])
+	if field == "" {
+		return nil, errorAt(link.args[0], "the field of groupBy must be a string literal")
+	}
+	fieldType, ok := fields[field]
+	if !ok {
+		return nil, errorAt(link.args[0], "unknown field "+field)
+	}
+	if !rqltypes.Orderable(fieldType) {
+		return nil, errorAt(link.args[0], "cannot group by the field "+field+" of type "+fieldType.String())
+	}
+	q.GroupBy = field
+
+	// Get the struct the groups are returned as.
+	ref, ok := link.args[1].(ast.ReferenceToken)
+	if !ok {
+		return nil, errorAt(link.args[1], "the second argument of groupBy must be the name of a struct")
+	}
+	history, err := b.s.GetStructByKey(ref.Name)
+	if err != nil {
+		if err == engine.ErrNotExists {
+			return nil, errorAt(ref, "unknown struct "+ref.Name)
+		}
+		return nil, err
+	}
+	return history[len(history)-1], nil
+}
+
+// Builds a aggregate such as sum('field') and gets the type it returns for each group.
+func (b *statementBuilder) buildAggregate(
+	link chainLink, fields map[string]rqltypes.Type, q *query.Query,
+) (rqltypes.Type, error) {
+	if q.Action == query.ActionCount {
+		if len(link.args) != 0 {
+			return rqltypes.Type{}, errorAt(link.token, link.name+" does not take any arguments")
+		}
+		return query.AggregateType(q.Action, rqltypes.Type{}), nil
+	}
+
+	// Get the field.
+	if !link.call || len(link.args) != 1 {
+		return rqltypes.Type{}, errorAt(link.token, link.name+" expects a field name")
+	}
+	field := fieldArgument(link.args[0])
+	if field == "" {
+		return rqltypes.Type{}, errorAt(link.args[0], "the field of "+link.name+" must be a string literal")
+	}
+	fieldType, ok := fields[field]
+	if !ok {
+		return rqltypes.Type{}, errorAt(link.args[0], "unknown field "+field)
+	}
+	q.Field = field
+
+	// Make sure the field can be aggregated.
+	if q.Action == query.ActionSum || q.Action == query.ActionAvg {
+		if !query.Numeric(fieldType) {
+			return rqltypes.Type{}, errorAt(link.args[0], "cannot "+link.name+" the field "+field+
+				" of type "+fieldType.String())
+		}
+	} else if !rqltypes.Orderable(fieldType) {
+		return rqltypes.Type{}, errorAt(link.args[0], "cannot get the "+link.name+" of the field "+field+
+			" of type "+fieldType.String())
+	}
+	return query.AggregateType(q.Action, fieldType), nil
+}
+
+// Checks that each group can be returned as the struct. The struct must have the field the
+// objects are grouped by and a field named after the aggregate, and nothing else.
+func checkGroupStruct(
+	t any, groupStruct *ast.StructToken, groupBy string, groupType rqltypes.Type, action query.Action, type_ rqltypes.Type,
+) error {
+	groupFields := rqltypes.FieldsFromStruct(groupStruct)
+	expected := []struct {
+		name  string
+		type_ rqltypes.Type
+	}{{groupBy, groupType}, {string(action), type_}}
+	for _, field := range expected {
+		if got, ok := groupFields[field.name]; !ok || !got.Equal(field.type_) {
+			return errorAt(t, "struct "+groupStruct.Name+" must have the field "+field.name+" of type "+field.type_.String())
+		}
+	}
+	if len(groupFields) != len(expected) {
+		return errorAt(t, "struct "+groupStruct.Name+" must only have the fields "+groupBy+" and "+string(action))
+	}
+	return nil
+}
+
+// Builds the parameter for where, limit, offset or after.
+func (b *statementBuilder) buildQueryModifier(
+	sc *scope, link chainLink, fields map[string]rqltypes.Type,
+) (goAst.Expr, error) {
+	var (
+		expr  goAst.Expr
+		param string
+	)
+	if link.name == "where" {
+		obj, err := objectArgument(link)
+		if err != nil {
+			return nil, err
+		}
+		if expr, err = b.buildObjectLiteral(sc, obj, fields); err != nil {
+			return nil, err
+		}
+		param = query.ParamWhere
+	} else {
+		// The rest take a single value.
+		if !link.call || len(link.args) != 1 {
+			return nil, errorAt(link.token, link.name+" expects a single argument")
+		}
+		var (
+			type_ rqltypes.Type
+			err   error
+		)
+		if expr, type_, err = b.buildExpression(sc, link.args[0]); err != nil {
+			return nil, err
+		}
+		switch link.name {
+		case "limit", "offset":
+			if !type_.Equal(rqltypes.Type{Name: rqltypes.Int}) {
+				return nil, errorAt(link.args[0], link.name+" must be an int, got "+type_.String())
+			}
+			param = query.ParamLimit
+			if link.name == "offset" {
+				param = query.ParamOffset
+			}
+		default:
+			cursorType := rqltypes.Type{Name: rqltypes.String, Optional: true}
+			if !type_.AssignableTo(cursorType) {
+				return nil, errorAt(link.args[0], "after must be a string?, got "+type_.String())
+			}
+			expr = b.convert(expr, type_, cursorType)
+			param = query.ParamAfter
+		}
+	}
+	return &goAst.KeyValueExpr{
+		Key:   &goAst.BasicLit{Kind: token.STRING, Value: strconv.Quote(param)},
+		Value: expr,
+	}, nil
+}
+
+// Gets the single object literal argument of a query method.
+func objectArgument(link chainLink) (ast.ObjectLiteralToken, error) {
+	if link.call && len(link.args) == 1 {
+		if obj, ok := link.args[0].(ast.ObjectLiteralToken); ok {
+			return obj, nil
+		}
+	}
+	return ast.ObjectLiteralToken{}, errorAt(link.token, link.name+" expects a single object literal argument")
+}
+
+// Gets the names of the structs which are written to by queries within the tokens. This is used
+// to acquire write locks from the start so they do not need to be upgraded. Since the scope is not
+// known, a variable with the same name as a struct may cause it to be included.
+func writtenStructs(tokens []any) map[string]bool {
+	written := map[string]bool{}
+	ast.Inspect(tokens, func(token any) bool {
+		switch token.(type) {
+		case ast.MethodCallToken, ast.ReferenceToken:
+			links := flattenChain(token)
+			for _, link := range links[1:] {
+				if link.name == string(query.ActionDelete) || link.name == string(query.ActionUpdate) {
+					written[links[0].name] = true
+				}
+			}
+		}
+		return true
+	})
+	return written
+}
+
+// Parses a query on a struct such as Tree.where({ treeId = treeId }).first. Returns the query,
+// the parameters it is ran with and the type of the result. The query is nil if the token is not
+// a query.
+func (b *statementBuilder) parseQuery(sc *scope, t any) (*query.Query, []goAst.Expr, rqltypes.Type, error) {
+	// Check if this is a query on a struct.
+	links := flattenChain(t)
+	structToken, err := b.queryStruct(sc, links)
+	if err != nil || structToken == nil {
+		return nil, nil, rqltypes.Type{}, err
+	}
+	for _, decorator := range structToken.Decorators {
+		if decorator.Method == "notable" {
+			return nil, nil, rqltypes.Type{}, errorAt(t, "struct "+structToken.Name+" is not a table so it cannot be queried")
+		}
+	}
+	fields, err := rqltypes.FieldsFromSession(b.s, structToken)
+	if err != nil {
+		return nil, nil, rqltypes.Type{}, err
+	}
+	receiver := links[0].name
+	links = links[1:]
+
+	// Handle the methods which come before the action.
+	q := query.Query{Struct: structToken.Name, Write: b.writtenStructs[receiver]}
+	params := []goAst.Expr{}
+	used := map[string]struct{}{}
+	var groupStruct *ast.StructToken
+	for ; len(links) != 0 && queryModifiers[links[0].name]; links = links[1:] {
+		link := links[0]
+		if _, ok := used[link.name]; ok {
+			return nil, nil, rqltypes.Type{}, errorAt(link.token, link.name+" can only be used once in a query")
+		}
+		used[link.name] = struct{}{}
+
+		switch link.name {
+		case "orderBy":
+			if err := b.buildOrderBy(link, fields, &q); err != nil {
+				return nil, nil, rqltypes.Type{}, err
+			}
+			continue
+		case "groupBy":
+			if groupStruct, err = b.buildGroupBy(link, fields, &q); err != nil {
+				return nil, nil, rqltypes.Type{}, err
+			}
+			continue
+		}
+		param, err := b.buildQueryModifier(sc, link, fields)
+		if err != nil {
+			return nil, nil, rqltypes.Type{}, err
+		}
+		params = append(params, param)
+	}
+	if len(links) == 0 {
+		return nil, nil, rqltypes.Type{}, errorAt(t, "expected first, all, count, sum, avg, min, max, nextCursor, delete or update at the end of the query")
+	}
+
+	// Handle the action.
+	link := links[0]
+	action := query.Action(link.name)
+	q.Action = action
+	var type_ rqltypes.Type
+	switch action {
+	case query.ActionCount, query.ActionSum, query.ActionAvg, query.ActionMin, query.ActionMax:
+		if type_, err = b.buildAggregate(link, fields, &q); err != nil {
+			return nil, nil, rqltypes.Type{}, err
+		}
+		if groupStruct != nil {
+			err := checkGroupStruct(link.token, groupStruct, q.GroupBy, fields[q.GroupBy], action, type_)
+			if err != nil {
+				return nil, nil, rqltypes.Type{}, err
+			}
+			type_ = rqltypes.Type{Elem: &rqltypes.Type{Name: groupStruct.Name}}
+		}
+	case query.ActionFirst, query.ActionAll, query.ActionNextCursor, query.ActionDelete:
+		if len(link.args) != 0 {
+			return nil, nil, rqltypes.Type{}, errorAt(link.token, link.name+" does not take any arguments")
+		}
+		switch action {
+		case query.ActionFirst:
+			type_ = rqltypes.Type{Name: structToken.Name, Optional: true}
+		case query.ActionAll:
+			type_ = rqltypes.Type{Elem: &rqltypes.Type{Name: structToken.Name}}
+		case query.ActionNextCursor:
+			type_ = rqltypes.Type{Name: rqltypes.String, Optional: true}
+		default:
+			type_ = rqltypes.Type{Name: rqltypes.Int}
+		}
+	case query.ActionUpdate:
+		obj, err := objectArgument(link)
+		if err != nil {
+			return nil, nil, rqltypes.Type{}, err
+		}
+		if pk := query.PrimaryKey(structToken); pk != "" {
+			if _, ok := obj.Values[pk]; ok {
+				return nil, nil, rqltypes.Type{}, errorAt(obj, "the primary key "+pk+" cannot be updated")
+			}
+		}
+		set, err := b.buildObjectLiteral(sc, obj, fields)
+		if err != nil {
+			return nil, nil, rqltypes.Type{}, err
+		}
+		params = append(params, &goAst.KeyValueExpr{
+			Key:   &goAst.BasicLit{Kind: token.STRING, Value: strconv.Quote(query.ParamSet)},
+			Value: set,
+		})
+		type_ = rqltypes.Type{Name: rqltypes.Int}
+	default:
+		return nil, nil, rqltypes.Type{}, errorAt(link.token, "unknown query method "+link.name+" on struct "+structToken.Name)
+	}
+	if b.isCursor && (action == query.ActionDelete || action == query.ActionUpdate) {
+		return nil, nil, rqltypes.Type{}, errorAt(link.token, "cannot "+link.name+
+			" within a contract which returns a cursor since it is never committed")
+	}
+	if groupStruct != nil && !action.Aggregate() {
+		return nil, nil, rqltypes.Type{}, errorAt(link.token, "groupBy can only be used with count, sum, avg, min or max")
+	}
+
+	// Make sure nothing is chained after the action.
+	if len(links) > 1 {
+		next := links[1]
+		if action == query.ActionFirst && !next.call {
+			return nil, nil, rqltypes.Type{}, errorAt(next.token, "cannot access field "+next.name+
+				" on optional type "+type_.String())
+		}
+		return nil, nil, rqltypes.Type{}, errorAt(next.token, "unexpected "+next.name+" after "+link.name)
+	}
+
+	return &q, params, type_, nil
+}
+
+// Adds the statements which run the query before the statement containing it. method is the
+// method on the RPC which runs it and result is the Go type it returns. Returns the name of the
+// variable holding the result.
+func (b *statementBuilder) hoistQuery(method, result string, q *query.Query, params []goAst.Expr) (string, error) {
+	// Encode the query.
+	encoded, err := json.Marshal(q)
+	if err != nil {
+		return "", err
+	}
+
+	// Run the query before the statement.
+	b.addToInterface(method, &goAst.FuncType{
+		Params: &goAst.FieldList{
+			List: []*goAst.Field{
+				{Names: []*goAst.Ident{goAst.NewIdent("query")}, Type: goAst.NewIdent("string")},
+				{Names: []*goAst.Ident{goAst.NewIdent("params")}, Type: goAst.NewIdent("map[string]any")},
+			},
+		},
+		Results: &goAst.FieldList{
+			List: []*goAst.Field{{Type: goAst.NewIdent(result)}, {Type: goAst.NewIdent("error")}},
+		},
+	})
+	prefix := "query"
+	if method != "RunQuery" {
+		prefix = "cursor"
+	}
+	resultVar := prefix + b.nextID()
+	b.hoisted = append(b.hoisted,
+		&goAst.AssignStmt{
+			Lhs: []goAst.Expr{goAst.NewIdent(resultVar), goAst.NewIdent("err")},
+			Tok: token.DEFINE,
+			Rhs: []goAst.Expr{
+				&goAst.CallExpr{
+					Fun: &goAst.SelectorExpr{
+						X:   goAst.NewIdent("r"),
+						Sel: goAst.NewIdent(method),
+					},
+					Args: []goAst.Expr{
+						&goAst.BasicLit{Kind: token.STRING, Value: strconv.Quote(string(encoded))},
+						&goAst.CompositeLit{Type: goAst.NewIdent("map[string]any"), Elts: params},
+					},
+				},
+			},
+		},
+		&goAst.IfStmt{
+			Cond: &goAst.BinaryExpr{
+				X:  goAst.NewIdent("err"),
+				Op: token.NEQ,
+				Y:  goAst.NewIdent("nil"),
+			},
+			Body: &goAst.BlockStmt{
+				List: []goAst.Stmt{
+					&goAst.ReturnStmt{Results: []goAst.Expr{goAst.NewIdent("err")}},
+				},
+			},
+		},
+	)
+	return resultVar, nil
+}
+
+// Builds a query on a struct such as Tree.where({ treeId = treeId }).first. The query is ran
+// before the statement containing it, and the result is stored in a variable. Returns a nil
+// expression if the token is not a query.
+func (b *statementBuilder) buildQuery(sc *scope, t any) (goAst.Expr, rqltypes.Type, error) {
+	q, params, type_, err := b.parseQuery(sc, t)
+	if err != nil || q == nil {
+		return nil, rqltypes.Type{}, err
+	}
+	resultVar, err := b.hoistQuery("RunQuery", "any", q, params)
+	if err != nil {
+		return nil, rqltypes.Type{}, err
+	}
+
+	// Return the result as the Go type.
+	return &goAst.TypeAssertExpr{
+		X:    goAst.NewIdent(resultVar),
+		Type: goAst.NewIdent(b.goType(type_)),
+	}, type_, nil
+}
+
+// Builds returning a query from a contract which returns a cursor. The objects are streamed to
+// the client as they are read rather than being read up front.
+func (b *statementBuilder) buildCursorReturn(sc *scope, x ast.ReturnToken) ([]goAst.Stmt, error) {
+	// Get the query.
+	cursorType := "Cursor<" + b.returnType.String() + ">"
+	if x.Token == nil {
+		return nil, errorAt(x, "expected a query to be returned from a contract which returns "+cursorType)
+	}
+	q, params, type_, err := b.parseQuery(sc, x.Token)
+	if err != nil {
+		return nil, err
+	}
+	if q == nil || q.Action != query.ActionAll {
+		return nil, errorAt(x.Token, "a contract which returns "+cursorType+" must return a query ending in all")
+	}
+	if !type_.Equal(rqltypes.Type{Elem: b.returnType}) {
+		return nil, errorAt(x.Token, "cannot return a query of type "+type_.String()+
+			" from a contract which returns "+cursorType)
+	}
+
+	// Open the cursor and respond with it. The session is closed by the cursor once the client
+	// is done with it.
+	cursorVar, err := b.hoistQuery("QueryCursor", "func() ([]byte, error)", q, params)
+	if err != nil {
+		return nil, err
+	}
+	b.addToInterface("RespondWithCursor", &goAst.FuncType{
+		Params: &goAst.FieldList{
+			List: []*goAst.Field{
+				{Names: []*goAst.Ident{goAst.NewIdent("hn")}, Type: goAst.NewIdent("func() ([]byte, error)")},
+			},
+		},
+	})
+	return []goAst.Stmt{
+		&goAst.ExprStmt{
+			X: &goAst.CallExpr{
+				Fun: &goAst.SelectorExpr{
+					X:   goAst.NewIdent("r"),
+					Sel: goAst.NewIdent("RespondWithCursor"),
+				},
+				Args: []goAst.Expr{goAst.NewIdent(cursorVar)},
+			},
+		},
+		&goAst.ReturnStmt{Results: []goAst.Expr{goAst.NewIdent("nil")}},
+	}, nil
+}
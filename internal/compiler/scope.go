@@ -0,0 +1,96 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package compiler
+
+import (
+	goAst "go/ast"
+	"go/token"
+	"sort"
+
+	"remixdb.io/internal/rqltypes"
+)
+
+// Defines a variable which is in scope within the contract.
+type scopeVariable struct {
+	// goName is the name of the variable within the generated Go code.
+	goName string
+
+	// type_ is the type of the variable.
+	type_ rqltypes.Type
+
+	// used is set to true when the variable is read.
+	used bool
+
+	// declIndex is the index of the statement that declared the variable within the
+	// block. Negative values mean the declaration is handled by the caller.
+	declIndex int
+}
+
+// Defines the variables which were declared within a block.
+type scope struct {
+	parent    *scope
+	variables map[string]*scopeVariable
+}
+
+// Creates a new child scope.
+func newScope(parent *scope) *scope {
+	return &scope{
+		parent:    parent,
+		variables: map[string]*scopeVariable{},
+	}
+}
+
+// Looks up a variable in this scope or any parent scope. Returns nil if it does not exist.
+func (s *scope) lookup(name string) *scopeVariable {
+	for ; s != nil; s = s.parent {
+		if v, ok := s.variables[name]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// Declares a variable within this scope.
+func (s *scope) declare(name, goName string, t rqltypes.Type, declIndex int) *scopeVariable {
+	v := &scopeVariable{
+		goName:    goName,
+		type_:     t,
+		declIndex: declIndex,
+	}
+	s.variables[name] = v
+	return v
+}
+
+// Creates a statement that discards the variable so that Go does not complain that it
+// is unused.
+func discardVariable(goName string) goAst.Stmt {
+	return &goAst.AssignStmt{
+		Lhs: []goAst.Expr{goAst.NewIdent("_")},
+		Tok: token.ASSIGN,
+		Rhs: []goAst.Expr{goAst.NewIdent(goName)},
+	}
+}
+
+// Finishes the scope by inserting a discard after the declaration of any variable which
+// was never read.
+func (s *scope) finish(stmts []goAst.Stmt) []goAst.Stmt {
+	// Get all the unused variables in reverse declaration order so inserting does not
+	// shift the indexes of the ones we have yet to handle.
+	unused := []*scopeVariable{}
+	for _, v := range s.variables {
+		if !v.used && v.declIndex >= 0 {
+			unused = append(unused, v)
+		}
+	}
+	sort.Slice(unused, func(i, j int) bool {
+		return unused[i].declIndex > unused[j].declIndex
+	})
+
+	// Insert the discards.
+	for _, v := range unused {
+		i := v.declIndex + 1
+		stmts = append(stmts[:i], append([]goAst.Stmt{discardVariable(v.goName)}, stmts[i:]...)...)
+	}
+	return stmts
+}
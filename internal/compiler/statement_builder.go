@@ -0,0 +1,920 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package compiler
+
+import (
+	goAst "go/ast"
+	"go/token"
+	"sort"
+	"strconv"
+
+	"remixdb.io/ast"
+	"remixdb.io/internal/engine"
+	"remixdb.io/internal/rqltypes"
+)
+
+// Defines a try block which thrown exceptions will jump to.
+type catchTarget struct {
+	// label is the label of the catch handler.
+	label string
+
+	// nameVar is the variable holding the name of the exception.
+	nameVar string
+
+	// bodyVar is the variable holding the body of the exception.
+	bodyVar string
+
+	// statusVar is the variable holding the HTTP status of the exception.
+	statusVar string
+
+	// thrown is a map of the names of the exceptions thrown to this target to the token which
+	// threw them first.
+	thrown map[string]any
+}
+
+// Defines the state used when lowering the statements of a contract into Go.
+type statementBuilder struct {
+	// f is the function body which is being built.
+	f *functionBody
+
+	// s is the session used to look up the schema.
+	s engine.Session
+
+	// addToInterface is used to add a method to the interface of r.
+	addToInterface func(name string, fn *goAst.FuncType)
+
+	// returnType is the type the contract returns. This is nil if the contract returns void. If
+	// the contract returns a cursor, this is the type of each item.
+	returnType *rqltypes.Type
+
+	// isCursor is true if the contract returns a cursor.
+	isCursor bool
+
+	// idCount is used to generate unique labels and variable names.
+	idCount int
+
+	// catchTargets is the stack of try blocks the current statement is within.
+	catchTargets []*catchTarget
+
+	// throws is the names of the exceptions the contract declares that it throws. Any exception
+	// which is not caught within the contract must be within this.
+	throws map[string]bool
+
+	// writtenStructs is the names of the structs which are written to by queries within the
+	// contract.
+	writtenStructs map[string]bool
+
+	// hoisted is the statements which need to run before the current statement, such as
+	// queries.
+	hoisted []goAst.Stmt
+
+	// divides is true if the contract divides by a integer which is not constant. In this case,
+	// the statements from divisionByZeroHandler must run before the contract.
+	divides bool
+}
+
+// Gets the next unique ID.
+func (b *statementBuilder) nextID() string {
+	b.idCount++
+	return strconv.Itoa(b.idCount)
+}
+
+// Checks if the token is an expression rather than a statement.
+func isExpressionToken(t any) bool {
+	switch t.(type) {
+	case ast.CommentToken, ast.AssignmentToken, ast.IfToken, ast.UnlessToken, ast.ForToken,
+		ast.WhileToken, ast.SwitchToken, ast.TryToken, ast.ReturnToken, ast.ThrowLiteralToken,
+		ast.InlineIfToken, ast.InlineUnlessToken:
+		return false
+	}
+	return true
+}
+
+// Unwraps an inline if or unless. Returns ok as false if the token is not one.
+func unwrapInline(t any) (inner, cond any, negate, ok bool) {
+	switch x := t.(type) {
+	case ast.InlineIfToken:
+		return x.Token, x.Condition, false, true
+	case ast.InlineUnlessToken:
+		return x.Token, x.Condition, true, true
+	}
+	return nil, nil, false, false
+}
+
+// Builds the top level statements of a contract.
+func (b *statementBuilder) buildContract(sc *scope, tokens []any) ([]goAst.Stmt, error) {
+	if b.returnType != nil {
+		// The last expression in a contract which returns a value is returned implicitly.
+		for i := len(tokens) - 1; i >= 0; i-- {
+			if _, ok := tokens[i].(ast.CommentToken); ok {
+				continue
+			}
+			if isExpressionToken(tokens[i]) {
+				tokens = append([]any{}, tokens...)
+				tokens[i] = ast.ReturnToken{
+					Token:    tokens[i],
+					Position: tokenPosition(tokens[i]),
+				}
+			}
+			break
+		}
+	}
+	return b.buildBlock(sc, nil, tokens)
+}
+
+// Builds a block of statements within the scope specified, appending them to stmts. The
+// scope is finished once the block is built.
+func (b *statementBuilder) buildBlock(sc *scope, stmts []goAst.Stmt, tokens []any) ([]goAst.Stmt, error) {
+	for _, t := range tokens {
+		var err error
+		stmts, err = b.buildStatement(sc, stmts, t)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sc.finish(stmts), nil
+}
+
+// Builds a single statement and appends it to stmts. Any statements which were hoisted
+// whilst building it are inserted before it.
+func (b *statementBuilder) buildStatement(sc *scope, stmts []goAst.Stmt, t any) ([]goAst.Stmt, error) {
+	// Build the statement with nothing hoisted.
+	outer := b.hoisted
+	b.hoisted = nil
+	defer func() { b.hoisted = outer }()
+	start := len(stmts)
+	stmts, err := b.buildSingleStatement(sc, stmts, t)
+	if err != nil || len(b.hoisted) == 0 {
+		return stmts, err
+	}
+
+	// Insert the hoisted statements and move the declarations after them.
+	for _, v := range sc.variables {
+		if v.declIndex >= start {
+			v.declIndex += len(b.hoisted)
+		}
+	}
+	return append(stmts[:start], append(b.hoisted, stmts[start:]...)...), nil
+}
+
+// Takes the statements which were hoisted since the last call so that they can be placed
+// somewhere other than before the current statement.
+func (b *statementBuilder) takeHoisted() []goAst.Stmt {
+	hoisted := b.hoisted
+	b.hoisted = nil
+	return hoisted
+}
+
+// Creates the statements at the start of a loop body which break out of the loop if the
+// condition is false. This is used when statements need to run before the condition.
+func loopConditionCheck(hoisted []goAst.Stmt, cond goAst.Expr) []goAst.Stmt {
+	return append(hoisted, &goAst.IfStmt{
+		Cond: &goAst.UnaryExpr{Op: token.NOT, X: parenthesize(cond)},
+		Body: &goAst.BlockStmt{List: []goAst.Stmt{&goAst.BranchStmt{Tok: token.BREAK}}},
+	})
+}
+
+// Builds a single statement and appends it to stmts without handling hoisting.
+func (b *statementBuilder) buildSingleStatement(sc *scope, stmts []goAst.Stmt, t any) ([]goAst.Stmt, error) {
+	switch x := t.(type) {
+	case ast.CommentToken:
+		// Comments do nothing.
+		return stmts, nil
+	case ast.AssignmentToken:
+		stmt, err := b.buildAssignment(sc, x, len(stmts))
+		if err != nil {
+			return nil, err
+		}
+		return append(stmts, stmt), nil
+	case ast.IfToken:
+		stmt, err := b.buildIf(sc, x.Condition, false, x.Statements, x.Else)
+		if err != nil {
+			return nil, err
+		}
+		return append(stmts, stmt), nil
+	case ast.UnlessToken:
+		stmt, err := b.buildIf(sc, x.Condition, true, x.Statements, x.Else)
+		if err != nil {
+			return nil, err
+		}
+		return append(stmts, stmt), nil
+	case ast.ForToken:
+		stmt, err := b.buildFor(sc, x)
+		if err != nil {
+			return nil, err
+		}
+		return append(stmts, stmt), nil
+	case ast.WhileToken:
+		// If the condition needs statements to run before it, they are ran at the start of
+		// each iteration.
+		cond, err := b.buildCondition(sc, x.Condition)
+		if err != nil {
+			return nil, err
+		}
+		var pre []goAst.Stmt
+		if hoisted := b.takeHoisted(); len(hoisted) != 0 {
+			pre = loopConditionCheck(hoisted, cond)
+			cond = nil
+		}
+		body, err := b.buildBlock(newScope(sc), pre, x.Statements)
+		if err != nil {
+			return nil, err
+		}
+		return append(stmts, &goAst.ForStmt{
+			Cond: cond,
+			Body: &goAst.BlockStmt{List: body},
+		}), nil
+	case ast.SwitchToken:
+		stmt, err := b.buildSwitch(sc, x)
+		if err != nil {
+			return nil, err
+		}
+		return append(stmts, stmt), nil
+	case ast.TryToken:
+		stmt, err := b.buildTry(sc, x)
+		if err != nil {
+			return nil, err
+		}
+		return append(stmts, stmt), nil
+	case ast.InlineIfToken, ast.InlineUnlessToken:
+		inner, cond, negate, _ := unwrapInline(x)
+		stmt, err := b.buildInline(sc, inner, cond, negate)
+		if err != nil {
+			return nil, err
+		}
+		return append(stmts, stmt), nil
+	case ast.ReturnToken:
+		// Handle return x if y.
+		if inner, cond, negate, ok := unwrapInline(x.Token); ok {
+			stmt, err := b.buildInline(sc, ast.ReturnToken{Token: inner, Position: x.Position}, cond, negate)
+			if err != nil {
+				return nil, err
+			}
+			return append(stmts, stmt), nil
+		}
+		ret, err := b.buildReturn(sc, x)
+		if err != nil {
+			return nil, err
+		}
+		return append(stmts, ret...), nil
+	case ast.ThrowLiteralToken:
+		// Handle throw x if y.
+		if inner, cond, negate, ok := unwrapInline(x.Token); ok {
+			stmt, err := b.buildInline(sc, ast.ThrowLiteralToken{Token: inner, Position: x.Position}, cond, negate)
+			if err != nil {
+				return nil, err
+			}
+			return append(stmts, stmt), nil
+		}
+		throw, err := b.buildThrow(sc, x)
+		if err != nil {
+			return nil, err
+		}
+		return append(stmts, throw...), nil
+	}
+
+	// Anything else is an expression that we should evaluate.
+	expr, type_, err := b.buildExpression(sc, t)
+	if err != nil {
+		return nil, err
+	}
+	if type_.Name == rqltypes.Null && type_.Elem == nil {
+		// Null does nothing.
+		return stmts, nil
+	}
+	if _, ok := expr.(*goAst.CallExpr); ok {
+		return append(stmts, &goAst.ExprStmt{X: expr}), nil
+	}
+	return append(stmts, &goAst.AssignStmt{
+		Lhs: []goAst.Expr{goAst.NewIdent("_")},
+		Tok: token.ASSIGN,
+		Rhs: []goAst.Expr{expr},
+	}), nil
+}
+
+// Builds an assignment. If the variable does not exist within the scope, it is declared.
+func (b *statementBuilder) buildAssignment(sc *scope, x ast.AssignmentToken, declIndex int) (*goAst.AssignStmt, error) {
+	// Build the value.
+	expr, type_, err := b.buildExpression(sc, x.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if the variable already exists.
+	if v := sc.lookup(x.Name); v != nil {
+		if !type_.AssignableTo(v.type_) {
+			return nil, errorAt(x, "cannot assign a value of type "+type_.String()+
+				" to the variable "+x.Name+" of type "+v.type_.String())
+		}
+		if err := checkEnumLiteral(x.Value, v.type_); err != nil {
+			return nil, err
+		}
+		return &goAst.AssignStmt{
+			Lhs: []goAst.Expr{goAst.NewIdent(v.goName)},
+			Tok: token.ASSIGN,
+			Rhs: []goAst.Expr{b.convert(expr, type_, v.type_)},
+		}, nil
+	}
+
+	// Declare the variable.
+	if type_.Name == rqltypes.Null && type_.Elem == nil {
+		return nil, errorAt(x, "cannot infer the type of the variable "+x.Name+" from null")
+	}
+	v := sc.declare(x.Name, "v_"+x.Name, type_, declIndex)
+	return &goAst.AssignStmt{
+		Lhs: []goAst.Expr{goAst.NewIdent(v.goName)},
+		Tok: token.DEFINE,
+		Rhs: []goAst.Expr{expr},
+	}, nil
+}
+
+// Builds a condition which must be a boolean.
+func (b *statementBuilder) buildCondition(sc *scope, t any) (goAst.Expr, error) {
+	expr, type_, err := b.buildExpression(sc, t)
+	if err != nil {
+		return nil, err
+	}
+	if !type_.Equal(rqltypes.Type{Name: rqltypes.Bool}) {
+		return nil, errorAt(t, "condition must be a bool, got "+type_.String())
+	}
+	return expr, nil
+}
+
+// Builds an if statement. If negate is true, the condition is inverted (for unless).
+func (b *statementBuilder) buildIf(
+	sc *scope, cond any, negate bool, tokens []any, else_ *ast.ElseToken,
+) (*goAst.IfStmt, error) {
+	// Build the condition.
+	condExpr, err := b.buildCondition(sc, cond)
+	if err != nil {
+		return nil, err
+	}
+	if negate {
+		condExpr = &goAst.UnaryExpr{Op: token.NOT, X: parenthesize(condExpr)}
+	}
+
+	// Build the body.
+	body, err := b.buildBlock(newScope(sc), nil, tokens)
+	if err != nil {
+		return nil, err
+	}
+	ifStmt := &goAst.IfStmt{
+		Cond: condExpr,
+		Body: &goAst.BlockStmt{List: body},
+	}
+
+	// Handle any else statements.
+	if else_ != nil {
+		if else_.Condition == nil {
+			elseBody, err := b.buildBlock(newScope(sc), nil, else_.Statements)
+			if err != nil {
+				return nil, err
+			}
+			ifStmt.Else = &goAst.BlockStmt{List: elseBody}
+		} else {
+			// Statements hoisted by the else if condition should only run if we get to it.
+			outer := b.takeHoisted()
+			elseIf, err := b.buildIf(sc, else_.Condition, false, else_.Statements, else_.Next)
+			hoisted := b.takeHoisted()
+			b.hoisted = outer
+			if err != nil {
+				return nil, err
+			}
+			if len(hoisted) == 0 {
+				ifStmt.Else = elseIf
+			} else {
+				ifStmt.Else = &goAst.BlockStmt{List: append(hoisted, elseIf)}
+			}
+		}
+	}
+	return ifStmt, nil
+}
+
+// Builds a inline if or unless statement.
+func (b *statementBuilder) buildInline(sc *scope, inner, cond any, negate bool) (goAst.Stmt, error) {
+	// Build the condition.
+	condExpr, err := b.buildCondition(sc, cond)
+	if err != nil {
+		return nil, err
+	}
+	if negate {
+		condExpr = &goAst.UnaryExpr{Op: token.NOT, X: parenthesize(condExpr)}
+	}
+
+	// Build the statement inside.
+	body, err := b.buildBlock(newScope(sc), nil, []any{inner})
+	if err != nil {
+		return nil, err
+	}
+	return &goAst.IfStmt{
+		Cond: condExpr,
+		Body: &goAst.BlockStmt{List: body},
+	}, nil
+}
+
+// Builds a for loop.
+func (b *statementBuilder) buildFor(sc *scope, x ast.ForToken) (goAst.Stmt, error) {
+	// Create the scope for the loop variables.
+	forScope := newScope(sc)
+	forStmt := &goAst.ForStmt{}
+
+	// Handle the assignment.
+	if x.Assignment != nil {
+		a, ok := x.Assignment.(ast.AssignmentToken)
+		if !ok {
+			return nil, errorAt(x.Assignment, "expected an assignment at the start of the for loop")
+		}
+		init, err := b.buildAssignment(forScope, a, -1)
+		if err != nil {
+			return nil, err
+		}
+		forStmt.Init = init
+	}
+
+	// Handle the condition. If it needs statements to run before it, they are ran at the start
+	// of each iteration.
+	hoisted := b.takeHoisted()
+	var pre []goAst.Stmt
+	if x.Condition != nil {
+		cond, err := b.buildCondition(forScope, x.Condition)
+		if err != nil {
+			return nil, err
+		}
+		if condHoisted := b.takeHoisted(); len(condHoisted) != 0 {
+			pre = loopConditionCheck(condHoisted, cond)
+		} else {
+			forStmt.Cond = cond
+		}
+	}
+
+	// Handle the increment.
+	if x.Increment != nil {
+		a, ok := x.Increment.(ast.AssignmentToken)
+		if !ok {
+			return nil, errorAt(x.Increment, "expected an assignment at the end of the for loop")
+		}
+		post, err := b.buildAssignment(forScope, a, -1)
+		if err != nil {
+			return nil, err
+		}
+		if post.Tok == token.DEFINE {
+			return nil, errorAt(a, "cannot declare a variable at the end of the for loop")
+		}
+		if len(b.hoisted) != 0 {
+			return nil, errorAt(a, "queries cannot be used at the end of the for loop")
+		}
+		forStmt.Post = post
+	}
+	b.hoisted = hoisted
+
+	// Build the body.
+	body, err := b.buildBlock(newScope(forScope), pre, x.Statements)
+	if err != nil {
+		return nil, err
+	}
+
+	// Discard any loop variables that were not used at the start of the body.
+	for _, v := range forScope.variables {
+		if !v.used {
+			body = append([]goAst.Stmt{discardVariable(v.goName)}, body...)
+		}
+	}
+	forStmt.Body = &goAst.BlockStmt{List: body}
+	return forStmt, nil
+}
+
+// Builds a switch statement.
+func (b *statementBuilder) buildSwitch(sc *scope, x ast.SwitchToken) (goAst.Stmt, error) {
+	// Build the condition and make sure it is comparable.
+	cond, condType, err := b.buildExpression(sc, x.Condition)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case condType.Optional, !condType.IsBuiltin(),
+		condType.Name == rqltypes.Bytes, condType.Name == rqltypes.Bigint:
+		return nil, errorAt(x.Condition, "cannot switch on a value of type "+condType.String())
+	}
+
+	// Build each case.
+	clauses := []goAst.Stmt{}
+	for _, c := range x.Cases {
+		clause := &goAst.CaseClause{}
+		if c.Name != nil {
+			caseExpr, caseType, err := b.buildExpression(sc, c.Name)
+			if err != nil {
+				return nil, err
+			}
+			if !caseType.Equal(condType) {
+				return nil, errorAt(c.Name, "case of type "+caseType.String()+
+					" does not match the switch type "+condType.String())
+			}
+			clause.List = []goAst.Expr{caseExpr}
+		}
+		clause.Body, err = b.buildBlock(newScope(sc), nil, c.Statements)
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, clause)
+	}
+
+	// Return the switch statement.
+	return &goAst.SwitchStmt{
+		Tag:  cond,
+		Body: &goAst.BlockStmt{List: clauses},
+	}, nil
+}
+
+// Checks if the catch catches everything.
+func isCatchAll(c *ast.CatchToken) bool {
+	return c.Exception == "" || c.Exception == "Exception"
+}
+
+// Builds a try/catch block. Exceptions thrown within the try block are stored in
+// variables and then jump to a switch statement which handles them by name.
+func (b *statementBuilder) buildTry(sc *scope, x ast.TryToken) (goAst.Stmt, error) {
+	// Build the try block with the target on the stack.
+	id := b.nextID()
+	target := &catchTarget{
+		label:     "catch" + id,
+		nameVar:   "exceptionName" + id,
+		bodyVar:   "exceptionBody" + id,
+		statusVar: "exceptionStatus" + id,
+		thrown:    map[string]any{},
+	}
+	b.catchTargets = append(b.catchTargets, target)
+	tryBody, err := b.buildBlock(newScope(sc), nil, x.Statements)
+	b.catchTargets = b.catchTargets[:len(b.catchTargets)-1]
+	if err != nil {
+		return nil, err
+	}
+
+	// Build the catch blocks.
+	clauses := []goAst.Stmt{}
+	caught := map[string]struct{}{}
+	hasCatchAll := false
+	for c := x.Catch; c != nil; c = c.Next {
+		// Make sure this is not a duplicate.
+		exceptionType := rqltypes.Type{Name: c.Exception}
+		if isCatchAll(c) {
+			if hasCatchAll {
+				return nil, errorAt(c, "only one catch block can catch all exceptions")
+			}
+			hasCatchAll = true
+			exceptionType.Name = "Exception"
+		} else {
+			if _, ok := caught[c.Exception]; ok {
+				return nil, errorAt(c, "the exception "+c.Exception+" is already caught")
+			}
+			if err := b.validateType(exceptionType, c.Position); err != nil {
+				return nil, err
+			}
+			caught[c.Exception] = struct{}{}
+		}
+
+		// Declare the variable if one is specified.
+		catchScope := newScope(sc)
+		var pre []goAst.Stmt
+		if c.Variable != "" {
+			v := catchScope.declare(c.Variable, "v_"+c.Variable, exceptionType, 0)
+			pre = append(pre, &goAst.AssignStmt{
+				Lhs: []goAst.Expr{goAst.NewIdent(v.goName)},
+				Tok: token.DEFINE,
+				Rhs: []goAst.Expr{goAst.NewIdent(target.bodyVar)},
+			})
+		}
+
+		// A catch all block never rethrows, so make sure Go does not consider the status and body unused.
+		if isCatchAll(c) {
+			pre = append(pre, &goAst.AssignStmt{
+				Lhs: []goAst.Expr{goAst.NewIdent("_"), goAst.NewIdent("_")},
+				Tok: token.ASSIGN,
+				Rhs: []goAst.Expr{goAst.NewIdent(target.statusVar), goAst.NewIdent(target.bodyVar)},
+			})
+		}
+
+		// Build the body.
+		body, err := b.buildBlock(catchScope, pre, c.Statements)
+		if err != nil {
+			return nil, err
+		}
+		clause := &goAst.CaseClause{Body: body}
+		if !isCatchAll(c) {
+			clause.List = []goAst.Expr{
+				&goAst.BasicLit{Kind: token.STRING, Value: strconv.Quote(c.Exception)},
+			}
+		}
+		clauses = append(clauses, clause)
+	}
+
+	// If nothing was thrown within the try block, just return the block.
+	if len(target.thrown) == 0 {
+		return &goAst.BlockStmt{List: tryBody}, nil
+	}
+
+	// If nothing catches everything, rethrow anything that was not caught.
+	if !hasCatchAll {
+		names := make([]string, 0, len(target.thrown))
+		for name := range target.thrown {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if _, ok := caught[name]; ok {
+				continue
+			}
+			if err := b.markThrown(name, target.thrown[name]); err != nil {
+				return nil, err
+			}
+		}
+		clauses = append(clauses, &goAst.CaseClause{
+			Body: b.throwStmts(
+				goAst.NewIdent(target.nameVar), goAst.NewIdent(target.statusVar), goAst.NewIdent(target.bodyVar),
+			),
+		})
+	}
+
+	// Return the block.
+	endLabel := "tryEnd" + id
+	return &goAst.BlockStmt{
+		List: []goAst.Stmt{
+			&goAst.DeclStmt{
+				Decl: &goAst.GenDecl{
+					Tok: token.VAR,
+					Specs: []goAst.Spec{
+						&goAst.ValueSpec{
+							Names: []*goAst.Ident{goAst.NewIdent(target.nameVar)},
+							Type:  goAst.NewIdent("string"),
+						},
+						&goAst.ValueSpec{
+							Names: []*goAst.Ident{goAst.NewIdent(target.bodyVar)},
+							Type:  goAst.NewIdent("map[string]any"),
+						},
+						&goAst.ValueSpec{
+							Names: []*goAst.Ident{goAst.NewIdent(target.statusVar)},
+							Type:  goAst.NewIdent("int"),
+						},
+					},
+				},
+			},
+			&goAst.BlockStmt{List: tryBody},
+			&goAst.BranchStmt{Tok: token.GOTO, Label: goAst.NewIdent(endLabel)},
+			&goAst.LabeledStmt{
+				Label: goAst.NewIdent(target.label),
+				Stmt: &goAst.SwitchStmt{
+					Tag:  goAst.NewIdent(target.nameVar),
+					Body: &goAst.BlockStmt{List: clauses},
+				},
+			},
+			&goAst.LabeledStmt{
+				Label: goAst.NewIdent(endLabel),
+				Stmt:  &goAst.EmptyStmt{},
+			},
+		},
+	}, nil
+}
+
+// Records that the exception is thrown from the current statement. If we are within a try block,
+// it is recorded against the block. Otherwise, the exception leaves the contract, so it must be
+// declared in the throws of the contract.
+func (b *statementBuilder) markThrown(name string, t any) error {
+	if len(b.catchTargets) != 0 {
+		target := b.catchTargets[len(b.catchTargets)-1]
+		if _, ok := target.thrown[name]; !ok {
+			target.thrown[name] = t
+		}
+		return nil
+	}
+	if !b.throws[name] {
+		return errorAt(t, "the exception "+name+" must be declared in the throws of the contract or caught")
+	}
+	return nil
+}
+
+// Creates the statements to throw an exception. If we are within a try block, this jumps
+// to the catch handler. Otherwise, it responds with the exception.
+func (b *statementBuilder) throwStmts(name, status, body goAst.Expr) []goAst.Stmt {
+	// Handle if we are inside a try block.
+	if len(b.catchTargets) != 0 {
+		target := b.catchTargets[len(b.catchTargets)-1]
+		return []goAst.Stmt{
+			&goAst.AssignStmt{
+				Lhs: []goAst.Expr{
+					goAst.NewIdent(target.nameVar), goAst.NewIdent(target.statusVar), goAst.NewIdent(target.bodyVar),
+				},
+				Tok: token.ASSIGN,
+				Rhs: []goAst.Expr{name, status, body},
+			},
+			&goAst.BranchStmt{Tok: token.GOTO, Label: goAst.NewIdent(target.label)},
+		}
+	}
+
+	// Respond with the exception and return nil. Since we do not commit, the session is
+	// rolled back when closed.
+	b.addToInterface("RespondWithCustomException", &goAst.FuncType{
+		Params: &goAst.FieldList{
+			List: []*goAst.Field{
+				{Names: []*goAst.Ident{goAst.NewIdent("code")}, Type: goAst.NewIdent("int")},
+				{Names: []*goAst.Ident{goAst.NewIdent("exceptionName")}, Type: goAst.NewIdent("string")},
+				{Names: []*goAst.Ident{goAst.NewIdent("body")}, Type: goAst.NewIdent("any")},
+			},
+		},
+	})
+	return []goAst.Stmt{
+		&goAst.ExprStmt{
+			X: &goAst.CallExpr{
+				Fun: &goAst.SelectorExpr{
+					X:   goAst.NewIdent("r"),
+					Sel: goAst.NewIdent("RespondWithCustomException"),
+				},
+				Args: []goAst.Expr{status, name, body},
+			},
+		},
+		&goAst.ReturnStmt{
+			Results: []goAst.Expr{goAst.NewIdent("nil")},
+		},
+	}
+}
+
+// Gets the HTTP status a exception is sent to the client with. This is set with @status on the
+// exception or struct and defaults to 400.
+func exceptionStatus(name string, decorators []ast.DecoratorToken) (int, error) {
+	for _, decorator := range decorators {
+		if decorator.Method != "status" {
+			continue
+		}
+		status, err := rqltypes.DecoratorValue(rqltypes.Type{Name: rqltypes.Int}, decorator)
+		if err != nil || status.(int) < 400 || status.(int) > 599 {
+			return 0, errorAt(decorator, "@status on the exception "+name+
+				" must be a HTTP status between 400 and 599")
+		}
+		return status.(int), nil
+	}
+	return 400, nil
+}
+
+// Builds a throw statement. The exception is a exception or struct, and the argument is the object
+// literal which is its body.
+func (b *statementBuilder) buildThrow(sc *scope, x ast.ThrowLiteralToken) ([]goAst.Stmt, error) {
+	// Make sure this is a exception call.
+	call, ok := x.Token.(ast.MethodCallToken)
+	if !ok || call.ChainedCall != nil {
+		return nil, errorAt(x, "expected an exception to be thrown in the format Name({ ... })")
+	}
+
+	// Get the decorators of the exception. Structs can be thrown as well as exceptions.
+	var decorators []ast.DecoratorToken
+	exception, err := b.s.GetExceptionByKey(call.Name)
+	if err == nil {
+		decorators = exception.Decorators
+	} else if err == engine.ErrNotExists {
+		history, err := b.s.GetStructByKey(call.Name)
+		if err != nil {
+			if err == engine.ErrNotExists {
+				return nil, errorAt(call, "unknown exception "+call.Name+
+					" since there is no exception or struct with the name")
+			}
+			return nil, err
+		}
+		decorators = history[len(history)-1].Decorators
+	} else {
+		return nil, err
+	}
+	status, err := exceptionStatus(call.Name, decorators)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the arguments without any comments.
+	args := []any{}
+	for _, arg := range call.Arguments {
+		if _, ok := arg.(ast.CommentToken); !ok {
+			args = append(args, arg)
+		}
+	}
+
+	// Build the body.
+	obj := ast.ObjectLiteralToken{Values: map[string]any{}, Position: call.Position}
+	switch len(args) {
+	case 0:
+	case 1:
+		obj, ok = args[0].(ast.ObjectLiteralToken)
+		if !ok {
+			return nil, errorAt(args[0], "expected an object literal as the body of the exception")
+		}
+	default:
+		return nil, errorAt(call, "exceptions can only have one argument")
+	}
+	body, err := b.buildStructLiteral(sc, obj, call.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return the throw.
+	if err := b.markThrown(call.Name, call); err != nil {
+		return nil, err
+	}
+	return b.throwStmts(
+		&goAst.BasicLit{Kind: token.STRING, Value: strconv.Quote(call.Name)},
+		&goAst.BasicLit{Kind: token.INT, Value: strconv.Itoa(status)},
+		body,
+	), nil
+}
+
+// Checks if the statement is a return statement.
+func isReturn(stmt goAst.Stmt) bool {
+	_, ok := stmt.(*goAst.ReturnStmt)
+	return ok
+}
+
+// Creates a statement which returns r.Commit().
+func (b *statementBuilder) commitAndReturn() goAst.Stmt {
+	b.addToInterface("Commit", noParamsJustError())
+	return &goAst.ReturnStmt{
+		Results: []goAst.Expr{
+			&goAst.CallExpr{
+				Fun: &goAst.SelectorExpr{
+					X:   goAst.NewIdent("r"),
+					Sel: goAst.NewIdent("Commit"),
+				},
+			},
+		},
+	}
+}
+
+// Builds a return statement.
+func (b *statementBuilder) buildReturn(sc *scope, x ast.ReturnToken) ([]goAst.Stmt, error) {
+	// Handle cursors.
+	if b.isCursor {
+		return b.buildCursorReturn(sc, x)
+	}
+
+	// Handle void contracts.
+	if b.returnType == nil {
+		if x.Token != nil {
+			return nil, errorAt(x, "cannot return a value from a contract which returns void")
+		}
+		return []goAst.Stmt{b.commitAndReturn()}, nil
+	}
+
+	// Build the value.
+	if x.Token == nil {
+		return nil, errorAt(x, "expected a value of type "+b.returnType.String()+" to be returned")
+	}
+	expr, type_, err := b.buildExpression(sc, x.Token)
+	if err != nil {
+		return nil, err
+	}
+	if !type_.AssignableTo(*b.returnType) {
+		return nil, errorAt(x.Token, "cannot return a value of type "+type_.String()+
+			" from a contract which returns "+b.returnType.String())
+	}
+	if err := checkEnumLiteral(x.Token, *b.returnType); err != nil {
+		return nil, err
+	}
+
+	// Respond with the value and then commit.
+	b.addToInterface("RespondWithRemixDBValue", &goAst.FuncType{
+		Params: &goAst.FieldList{
+			List: []*goAst.Field{
+				{Names: []*goAst.Ident{goAst.NewIdent("type_")}, Type: goAst.NewIdent("string")},
+				{Names: []*goAst.Ident{goAst.NewIdent("value")}, Type: goAst.NewIdent("any")},
+			},
+		},
+		Results: &goAst.FieldList{
+			List: []*goAst.Field{{Type: goAst.NewIdent("error")}},
+		},
+	})
+	return []goAst.Stmt{
+		&goAst.IfStmt{
+			Init: &goAst.AssignStmt{
+				Lhs: []goAst.Expr{goAst.NewIdent("err")},
+				Tok: token.DEFINE,
+				Rhs: []goAst.Expr{
+					&goAst.CallExpr{
+						Fun: &goAst.SelectorExpr{
+							X:   goAst.NewIdent("r"),
+							Sel: goAst.NewIdent("RespondWithRemixDBValue"),
+						},
+						Args: []goAst.Expr{
+							&goAst.BasicLit{Kind: token.STRING, Value: strconv.Quote(b.returnType.String())},
+							expr,
+						},
+					},
+				},
+			},
+			Cond: &goAst.BinaryExpr{
+				X:  goAst.NewIdent("err"),
+				Op: token.NEQ,
+				Y:  goAst.NewIdent("nil"),
+			},
+			Body: &goAst.BlockStmt{
+				List: []goAst.Stmt{
+					&goAst.ReturnStmt{Results: []goAst.Expr{goAst.NewIdent("err")}},
+				},
+			},
+		},
+		b.commitAndReturn(),
+	}, nil
+}
@@ -5,6 +5,8 @@ package engine
 
 import (
 	"errors"
+	"strconv"
+	"strings"
 
 	"remixdb.io/ast"
 )
@@ -15,6 +17,72 @@ var ErrNotExists = errors.New("key does not exist")
 // ErrNotTable is used to define the error when the struct is not a table.
 var ErrNotTable = errors.New("struct is not a table")
 
+// ErrAlreadyExists is used to define the error when the key already exists.
+var ErrAlreadyExists = errors.New("key already exists")
+
+// ErrNotIndexed is used to define the error when a field does not have a index that is ready to use.
+var ErrNotIndexed = errors.New("field is not indexed")
+
+// UniqueConstraintError is used to define the error when a write would cause two objects within a
+// struct to have the same value for a field marked with @unique.
+type UniqueConstraintError struct {
+	// Struct is the name of the struct.
+	Struct string
+
+	// Field is the name of the field.
+	Field string
+}
+
+// Error is used to return the error message.
+func (e UniqueConstraintError) Error() string {
+	return "the value of " + e.Struct + "." + e.Field + " must be unique"
+}
+
+// EnumValueError is used to define the error when a value which is not within a enum is used where
+// the enum is expected.
+type EnumValueError struct {
+	// Enum is the name of the enum.
+	Enum string
+
+	// Value is the value which is not within the enum.
+	Value string
+}
+
+// Error is used to return the error message.
+func (e EnumValueError) Error() string {
+	return strconv.Quote(e.Value) + " is not a value of the enum " + e.Enum
+}
+
+// LossyMigrationError is used to define the error when writing a struct would lose data within
+// the objects that are already stored.
+type LossyMigrationError struct {
+	// Struct is the name of the struct.
+	Struct string
+
+	// Changes are human readable descriptions of the changes which would lose data.
+	Changes []string
+}
+
+// Error is used to return the error message.
+func (e LossyMigrationError) Error() string {
+	return "changing " + e.Struct + " would lose data (" + strings.Join(e.Changes, ", ") +
+		"), the write must be forced to allow this"
+}
+
+// MigrationProgress is used to define the progress of migrating the objects within a struct to
+// the latest version of the struct.
+type MigrationProgress struct {
+	// Pending is true if there are objects which have not been migrated yet. Objects which are
+	// read before they are migrated are migrated when they are read.
+	Pending bool
+
+	// Migrated is the number of objects which have been migrated so far.
+	Migrated uint64
+
+	// Total is the number of objects which need migrating. This is 0 until it is known.
+	Total uint64
+}
+
 // StructSessionMethods is used to define the methods for the struct session.
 type StructSessionMethods interface {
 	// GetStructByKey is used to get the struct for a specified key. If the key does not
@@ -28,6 +96,23 @@ type StructSessionMethods interface {
 	// ErrNotExists is returned.
 	DeleteStructByKey(key string) error
 
+	// WriteStruct is used to write a struct. If a struct with the same name already exists, the
+	// struct is appended to its history and the objects within it are migrated to the new version
+	// in the background. If the change would lose data (such as removing a field or narrowing a
+	// type), a LossyMigrationError is returned unless force is true. If a exception or enum has the
+	// same name, ErrAlreadyExists is returned.
+	WriteStruct(structToken *ast.StructToken, force bool) error
+
+	// StructMigrationProgress is used to get the progress of migrating the objects within a struct
+	// to the latest version. If the struct does not exist, the error ErrNotExists is returned.
+	StructMigrationProgress(structName string) (progress MigrationProgress, err error)
+
+	// RenameStruct is used to rename a struct. The renamed struct is appended to the history of the
+	// struct and the old name will point to the new name. If the old name does not exist, the error
+	// ErrNotExists is returned. If the new name is already in use, the error ErrAlreadyExists is
+	// returned.
+	RenameStruct(oldName, newName string) error
+
 	// Structs is used to return all of the latest structs in the database partition. Note the
 	// positions on the AST tokens are not set.
 	Structs() (structs []*ast.StructToken, err error)
@@ -74,6 +159,101 @@ type StructSessionMethods interface {
 	ReleaseStructObjectReadLock(structName string, keys ...[]byte) error
 }
 
+// StructObjectSessionMethods is used to define the methods for reading and writing the objects
+// stored within a struct. Objects are keyed by the same keys as the struct object locks, and the
+// values are the RemixDB encoding of the object. The relevant struct object lock should be held
+// before using any of these. If the struct is marked with @notable, the error ErrNotTable is
+// returned. If a write would cause a field marked with @unique to have the same value as another
+// object, a UniqueConstraintError is returned. If a field which uses a enum is written with a value
+// which is not within the enum, a EnumValueError is returned.
+type StructObjectSessionMethods interface {
+	// InsertStructObject is used to insert a object into a struct. Fields marked with @autoincrement
+	// which are missing or zero are set to the next value of the structs counter, and fields marked
+	// with @default which are missing are set to the default. The object which was written is
+	// returned. If the key already exists, the error ErrAlreadyExists is returned.
+	InsertStructObject(structName string, key, value []byte) (object []byte, err error)
+
+	// GetStructObject is used to get a object from a struct. If the key does not exist, the error
+	// ErrNotExists is returned.
+	GetStructObject(structName string, key []byte) (value []byte, err error)
+
+	// UpdateStructObject is used to replace a object within a struct. If the key does not exist, the
+	// error ErrNotExists is returned.
+	UpdateStructObject(structName string, key, value []byte) error
+
+	// DeleteStructObject is used to delete a object from a struct. If the key does not exist, the
+	// error ErrNotExists is returned.
+	DeleteStructObject(structName string, key []byte) error
+
+	// LookupStructIndex is used to get the keys of the objects within a struct where the field has
+	// the value specified. The value is the RemixDB encoding of the field value. The field must be
+	// marked with @index, otherwise the error ErrNotIndexed is returned. ErrNotIndexed is also
+	// returned whilst the index is being built in the background or if building it failed, in which
+	// case the caller should fall back to scanning the objects.
+	LookupStructIndex(structName, field string, value []byte) (keys [][]byte, err error)
+
+	// IterateStructObjects is used to iterate over the objects within a struct in key order. The
+	// struct should be read locked whilst iterating, and the objects should not be written to until
+	// the iteration is done.
+	IterateStructObjects(structName string, options StructIteratorOptions) (StructObjectIterator, error)
+
+	// IterateStructIndex is used to iterate over the objects within a struct in order of the value
+	// of the field, and then in key order for objects with the same value. Null values come first.
+	// The field must be marked with @index and its type must be orderable, otherwise the error
+	// ErrNotIndexed is returned. Like LookupStructIndex, ErrNotIndexed is also returned whilst the
+	// index is being built. The same locking rules as IterateStructObjects apply.
+	IterateStructIndex(structName, field string, options StructIteratorOptions) (StructObjectIterator, error)
+
+	// StructIndexStatus is used to get the status of the index on a field of a struct. The field
+	// must be marked with @index or @unique, otherwise the error ErrNotIndexed is returned.
+	StructIndexStatus(structName, field string) (status IndexStatus, err error)
+}
+
+// IndexStatus is used to define the status of a index which is built in the background.
+type IndexStatus struct {
+	// Building is true whilst the index is being built.
+	Building bool
+
+	// Error is the reason building the index failed. If this is set, the index is not used until
+	// it is built again. This happens the next time the struct is written.
+	Error string
+}
+
+// StructIteratorOptions is used to define how the objects within a struct are iterated.
+type StructIteratorOptions struct {
+	// Value is used by IterateStructIndex to only return objects where the field has this value.
+	// This is the RemixDB encoding of the value. If nil, all objects are returned.
+	Value []byte
+
+	// After is used to resume iteration after the object with this key. If nil, iteration starts
+	// from the beginning.
+	After []byte
+
+	// AfterValue is the RemixDB encoding of the field value of the object specified by After. This
+	// is only used by IterateStructIndex.
+	AfterValue []byte
+
+	// Reverse is used to iterate in the opposite order.
+	Reverse bool
+}
+
+// StructObjectIterator is used to iterate over the objects within a struct. Call Next to move to
+// the first object.
+type StructObjectIterator interface {
+	// Next is used to move to the next object. Returns false when there are no more objects or an
+	// error occurred. Check Err after this returns false.
+	Next() bool
+
+	// Key is used to get the key of the current object.
+	Key() []byte
+
+	// Value is used to get the RemixDB encoding of the current object.
+	Value() ([]byte, error)
+
+	// Err is used to get the error which stopped the iteration, if any.
+	Err() error
+}
+
 // ContractSessionMethods is used to define the methods for the contract session.
 type ContractSessionMethods interface {
 	// GetContractByKey is used to get the contract for a specified key. If the key does not
@@ -96,6 +276,42 @@ type ContractSessionMethods interface {
 	ContractTombstones() (contracts []*ast.ContractToken, err error)
 }
 
+// ExceptionSessionMethods is used to define the methods for the exception session.
+type ExceptionSessionMethods interface {
+	// GetExceptionByKey is used to get the exception for a specified key. If the key does not
+	// exist, the error ErrNotExists is returned.
+	GetExceptionByKey(key string) (exception *ast.ExceptionToken, err error)
+
+	// DeleteExceptionByKey is used to delete the exception for a specified key. If the key does not
+	// exist, the error ErrNotExists is returned.
+	DeleteExceptionByKey(key string) error
+
+	// WriteException is used to write a exception. If the exception already exists, it will be
+	// overwritten. If a struct or enum has the same name, ErrAlreadyExists is returned.
+	WriteException(exception *ast.ExceptionToken) error
+
+	// Exceptions is used to get all of the exceptions.
+	Exceptions() (exceptions []*ast.ExceptionToken, err error)
+}
+
+// EnumSessionMethods is used to define the methods for the enum session.
+type EnumSessionMethods interface {
+	// GetEnumByKey is used to get the enum for a specified key. If the key does not exist, the error
+	// ErrNotExists is returned.
+	GetEnumByKey(key string) (enum *ast.EnumToken, err error)
+
+	// DeleteEnumByKey is used to delete the enum for a specified key. If the key does not exist, the
+	// error ErrNotExists is returned.
+	DeleteEnumByKey(key string) error
+
+	// WriteEnum is used to write a enum. If the enum already exists, it will be overwritten. If a
+	// struct or exception has the same name, ErrAlreadyExists is returned.
+	WriteEnum(enum *ast.EnumToken) error
+
+	// Enums is used to get all of the enums.
+	Enums() (enums []*ast.EnumToken, err error)
+}
+
 // Session is used to define a session. You must call Close on the session.
 type Session interface {
 	// Close is used to close the session. If this is a write session, it will be rolled back if
@@ -110,7 +326,10 @@ type Session interface {
 	Commit() error
 
 	StructSessionMethods
+	StructObjectSessionMethods
 	ContractSessionMethods
+	ExceptionSessionMethods
+	EnumSessionMethods
 }
 
 // ErrPartitionDoesNotExist is used to define the error when the partition does not exist.
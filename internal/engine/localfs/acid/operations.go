@@ -143,11 +143,7 @@ func (t *Transaction) Rename(path, newPath string) {
 		}
 
 		// Attempt a copy to the transaction folder.
-		s := filepath.SplitList(p2)
-		if s[len(s)-1] == "" {
-			s = s[:len(s)-1]
-		}
-		if err := os.MkdirAll(filepath.Join(s[:len(s)-1]...), 0755); err != nil {
+		if err := os.MkdirAll(filepath.Dir(p2), 0755); err != nil {
 			panic(err)
 		}
 		_ = cp.Copy(filepath.Join(t.dataPath, path), p2)
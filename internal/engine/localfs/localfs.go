@@ -32,13 +32,14 @@ func (e *Engine) CreateSession(partition string) (engine.Session, error) {
 
 	// Return the session.
 	return &session.Session{
-		Logger:        e.logger,
-		Transaction:   acid.New(e.path),
-		PartitionName: partition,
-		Cache:         &e.s,
-		DataFolder:    e.path,
-		RelativePath:  e.getPartitionPath(partition, true),
-		Unlocker:      unlock,
+		Logger:         e.logger,
+		Transaction:    acid.New(e.path),
+		PartitionName:  partition,
+		Cache:          &e.s,
+		DataFolder:     e.path,
+		RelativePath:   e.getPartitionPath(partition, true),
+		Unlocker:       unlock,
+		BackgroundJobs: func() { go e.runBackgroundJobs(partition) },
 	}, nil
 }
 
@@ -59,9 +60,49 @@ func (e *Engine) CreateSchemaWriteSession(partition string) (engine.Session, err
 		RelativePath:    e.getPartitionPath(partition, true),
 		SchemaWriteLock: true,
 		Unlocker:        unlock,
+		BackgroundJobs:  func() { go e.runBackgroundJobs(partition) },
 	}, nil
 }
 
+// Migrates the objects within structs which were changed and builds any indexes which were added
+// to existing structs in the partition. This is ran in the background after the struct is written.
+func (e *Engine) runBackgroundJobs(partition string) {
+	// Migrate the objects first so that the indexes are built from the migrated values. If this
+	// fails, the migration is rolled back and stays pending so the objects are still migrated as
+	// they are read. The indexes are built either way.
+	if s, ok := e.createBackgroundSession(partition); ok {
+		if err := s.RunPendingMigrations(); err != nil {
+			e.logger.Errorw("failed to migrate objects", "partition", partition, "error", err)
+		} else if err := s.Commit(); err != nil {
+			e.logger.Errorw("failed to commit migrated objects", "partition", partition, "error", err)
+		}
+		_ = s.Close()
+	}
+
+	// Build the indexes. Indexes which fail to build are marked as failed within the session, so
+	// it is committed even if there was a error.
+	if s, ok := e.createBackgroundSession(partition); ok {
+		if err := s.BuildPendingIndexes(); err != nil {
+			e.logger.Errorw("failed to build indexes", "partition", partition, "error", err)
+		}
+		if err := s.Commit(); err != nil {
+			e.logger.Errorw("failed to commit built indexes", "partition", partition, "error", err)
+		}
+		_ = s.Close()
+	}
+}
+
+// Creates a session to run background jobs in. Returns false if the session could not be created,
+// in which case the error is logged.
+func (e *Engine) createBackgroundSession(partition string) (*session.Session, bool) {
+	sess, err := e.CreateSession(partition)
+	if err != nil {
+		e.logger.Errorw("failed to create session for background jobs", "partition", partition, "error", err)
+		return nil, false
+	}
+	return sess.(*session.Session), true
+}
+
 var _ engine.Engine = (*Engine)(nil)
 
 // New is used to create a new engine. If path is empty, the environment variable REMIXDB_DATA_PATH is used or
@@ -72,6 +72,9 @@ func (c *Cache) GetPage(page uint64) ([]byte, error) {
 func (c *Cache) SetPage(page uint64, data []byte) error {
 	// Update the page in the cache.
 	c.mu.Lock()
+	if c.m == nil {
+		c.m = map[uint64]*timer{}
+	}
 	p := c.m[page]
 	if p != nil {
 		p.timer.Stop()
@@ -147,6 +147,11 @@ func (t TreeIO) GetValue(key []byte) ([]byte, error) {
 			// Chop off the prefix.
 			key = key[len(b.key):]
 
+			// If there are no children, the key does not exist.
+			if b.childrenPage == 0 && b.childrenIndex == 0 {
+				return nil, ErrNotFound
+			}
+
 			// Go to the children.
 			if b.childrenPage != pageNum {
 				// Load the page.
@@ -164,8 +169,10 @@ func (t TreeIO) GetValue(key []byte) ([]byte, error) {
 			childrenLen := binary.LittleEndian.Uint32(page[b.childrenIndex:])
 			offset = b.childrenIndex + 4
 
-			// Push the current offset onto the stack.
+			// Push the current offset onto the stack. No siblings can share a prefix with the
+			// key, so we do not need to check the rest of them.
 			offsetStack = &stack[branches]{prev: offsetStack, data: branches{offset: offset, count: childrenLen}}
+			break
 		}
 	}
 
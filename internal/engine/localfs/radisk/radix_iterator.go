@@ -0,0 +1,267 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package radisk
+
+import "bytes"
+
+// IteratorOptions is used to define the options for iterating over the tree. All of the options
+// are optional and can be combined.
+type IteratorOptions struct {
+	// Prefix is used to only return keys which start with this prefix.
+	Prefix []byte
+
+	// Start is used to only return keys which are greater than or equal to this key.
+	Start []byte
+
+	// End is used to only return keys which are less than this key. If nil, there is no end.
+	End []byte
+
+	// After is used to resume iteration. Only keys which come after this key in the order of
+	// iteration are returned. If nil, iteration starts from the beginning.
+	After []byte
+
+	// Reverse is used to iterate from the largest key to the smallest key.
+	Reverse bool
+}
+
+// Defines a branch on the iterators stack.
+type iteratorFrame struct {
+	// key is the full key of the branch.
+	key []byte
+
+	// branch is the branch itself.
+	branch memBranch
+
+	// children are the children of the branch. Only set if loaded is true.
+	children []memBranch
+	loaded   bool
+
+	// index is the index of the next child to visit.
+	index int
+
+	// emitted is true if the value of the branch has already been considered.
+	emitted bool
+}
+
+// Iterator is used to iterate over the tree in key order. Pages are only loaded as they are
+// needed, so only the path to the current key is held in memory. Note that the tree should
+// not be written to whilst iterating.
+type Iterator struct {
+	t     TreeIO
+	opts  IteratorOptions
+	stack []*iteratorFrame
+	done  bool
+	err   error
+
+	current memBranch
+	key     []byte
+}
+
+// Iterate is used to create an iterator over the tree with the options specified. The keys are
+// sorted by bytes.Compare. Call Next to move to the first key.
+func (t TreeIO) Iterate(opts IteratorOptions) *Iterator {
+	return &Iterator{t: t, opts: opts}
+}
+
+// Joins two keys into a new slice so that the result does not share memory with either.
+func joinKey(a, b []byte) []byte {
+	key := make([]byte, len(a)+len(b))
+	copy(key, a)
+	copy(key[len(a):], b)
+	return key
+}
+
+// Returns true if the key is allowed by the options.
+func (it *Iterator) inRange(key []byte) bool {
+	o := it.opts
+	if !bytes.HasPrefix(key, o.Prefix) {
+		return false
+	}
+	if o.Start != nil && bytes.Compare(key, o.Start) < 0 {
+		return false
+	}
+	if o.End != nil && bytes.Compare(key, o.End) >= 0 {
+		return false
+	}
+	if o.After != nil {
+		c := bytes.Compare(key, o.After)
+		if (!o.Reverse && c <= 0) || (o.Reverse && c >= 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns true if every key which starts with the key specified is less than the bound. This
+// is the case when the key is smaller than the bound and is not a prefix of it.
+func allBelow(key, bound []byte) bool {
+	return bytes.Compare(key, bound) < 0 && !bytes.HasPrefix(bound, key)
+}
+
+// Checks if a branch with the full key specified can contain any keys in range. If it cannot
+// and no branch after it in the order of iteration can either, done is set to true.
+func (it *Iterator) mayContain(key []byte) (ok, done bool) {
+	o := it.opts
+
+	// Check the prefix. Either the key is within the prefix or the prefix goes further down.
+	if !bytes.HasPrefix(key, o.Prefix) && !bytes.HasPrefix(o.Prefix, key) {
+		return false, false
+	}
+
+	// Handle the lower bound. Everything within the branch is smaller than it.
+	for _, lower := range [][]byte{o.Start, it.lowerAfter()} {
+		if lower != nil && allBelow(key, lower) {
+			return false, o.Reverse
+		}
+	}
+
+	// Handle the upper bound. Everything within the branch is larger than or equal to it.
+	for _, upper := range [][]byte{o.End, it.upperAfter()} {
+		if upper != nil && bytes.Compare(key, upper) >= 0 {
+			return false, !o.Reverse
+		}
+	}
+	return true, false
+}
+
+// Returns After if it is the lower bound.
+func (it *Iterator) lowerAfter() []byte {
+	if it.opts.Reverse {
+		return nil
+	}
+	return it.opts.After
+}
+
+// Returns After if it is the upper bound.
+func (it *Iterator) upperAfter() []byte {
+	if it.opts.Reverse {
+		return it.opts.After
+	}
+	return nil
+}
+
+// Loads the children of the frame if they are not loaded.
+func (it *Iterator) loadChildren(f *iteratorFrame) error {
+	if f.loaded {
+		return nil
+	}
+	children, err := it.t.readChildren(f.branch)
+	if err != nil {
+		return err
+	}
+	f.children = children
+	f.loaded = true
+	if it.opts.Reverse {
+		f.index = len(children) - 1
+	}
+	return nil
+}
+
+// Pushes the child onto the stack if it may contain keys in range.
+func (it *Iterator) pushChild(parent *iteratorFrame, child memBranch) {
+	key := joinKey(parent.key, child.key)
+	ok, done := it.mayContain(key)
+	if done {
+		it.done = true
+		return
+	}
+	if ok {
+		it.stack = append(it.stack, &iteratorFrame{key: key, branch: child})
+	}
+}
+
+// Sets the current key and value.
+func (it *Iterator) setCurrent(f *iteratorFrame) {
+	it.current = f.branch
+	it.key = f.key
+}
+
+// Next is used to move to the next key. Returns false when there are no more keys or an
+// error occurred. Check Err after this returns false.
+func (it *Iterator) Next() bool {
+	if it.done {
+		return false
+	}
+
+	// Load the root if this is the first call.
+	if it.stack == nil {
+		root, err := it.t.readRoot()
+		if err != nil {
+			it.err = err
+			it.done = true
+			return false
+		}
+		it.stack = []*iteratorFrame{{key: []byte{}, branch: root}}
+	}
+
+	for len(it.stack) != 0 && !it.done {
+		f := it.stack[len(it.stack)-1]
+
+		// Going forwards, the value of a branch comes before its children.
+		if !it.opts.Reverse && !f.emitted {
+			f.emitted = true
+			if f.branch.hasValue && it.inRange(f.key) {
+				it.setCurrent(f)
+				return true
+			}
+		}
+
+		// Load the children.
+		if err := it.loadChildren(f); err != nil {
+			it.err = err
+			it.done = true
+			return false
+		}
+
+		// Go to the next child.
+		if it.opts.Reverse {
+			if f.index >= 0 {
+				child := f.children[f.index]
+				f.index--
+				it.pushChild(f, child)
+				continue
+			}
+		} else if f.index < len(f.children) {
+			child := f.children[f.index]
+			f.index++
+			it.pushChild(f, child)
+			continue
+		}
+
+		// Pop the branch off the stack. Going in reverse, the value of a branch comes after its
+		// children.
+		it.stack = it.stack[:len(it.stack)-1]
+		if it.opts.Reverse && f.branch.hasValue && it.inRange(f.key) {
+			it.setCurrent(f)
+			return true
+		}
+	}
+
+	// There are no more keys.
+	it.done = true
+	return false
+}
+
+// Key is used to get the current key. The key can be passed to IteratorOptions.After to resume
+// iteration from this point.
+func (it *Iterator) Key() []byte {
+	return it.key
+}
+
+// Value is used to get the value of the current key. If the value is on its own page, it is
+// only loaded when this is called.
+func (it *Iterator) Value() ([]byte, error) {
+	if it.current.overflowPage != 0 {
+		return it.t.FS.GetPage(uint64(it.current.overflowPage))
+	}
+	if it.current.value == nil {
+		return []byte{}, nil
+	}
+	return it.current.value, nil
+}
+
+// Err is used to get the error which stopped the iteration, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
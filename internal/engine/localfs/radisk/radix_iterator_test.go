@@ -0,0 +1,130 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package radisk
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var iteratorKeys = []string{
+	"", "a", "aa", "aab", "ab", "abc", "abd", "b", "ba", "bab", "c", "ca", "cab", "cb",
+}
+
+func newIteratorTree(t *testing.T) TreeIO {
+	t.Helper()
+	io := TreeIO{FS: mockFS{pages: map[uint64][]byte{}}}
+	for _, k := range iteratorKeys {
+		v := "v:" + k
+		if k == "ab" {
+			// Make sure values on their own page are loaded.
+			v = strings.Repeat("x", maxInlineValueSize+1)
+		}
+		assert.NoError(t, io.SetValue([]byte(k), []byte(v)))
+	}
+	return io
+}
+
+// Collects the keys from the iterator, checking the values along the way.
+func collectKeys(t *testing.T, it *Iterator, limit int) []string {
+	t.Helper()
+	keys := []string{}
+	for (limit == 0 || len(keys) < limit) && it.Next() {
+		k := string(it.Key())
+		v, err := it.Value()
+		assert.NoError(t, err)
+		if k == "ab" {
+			assert.Equal(t, strings.Repeat("x", maxInlineValueSize+1), string(v))
+		} else {
+			assert.Equal(t, "v:"+k, string(v))
+		}
+		keys = append(keys, k)
+	}
+	assert.NoError(t, it.Err())
+	return keys
+}
+
+// Filters the keys in the same way the iterator should.
+func expectedKeys(opts IteratorOptions) []string {
+	keys := []string{}
+	for _, k := range iteratorKeys {
+		b := []byte(k)
+		if !bytes.HasPrefix(b, opts.Prefix) ||
+			(opts.Start != nil && bytes.Compare(b, opts.Start) < 0) ||
+			(opts.End != nil && bytes.Compare(b, opts.End) >= 0) {
+			continue
+		}
+		if opts.After != nil {
+			c := bytes.Compare(b, opts.After)
+			if (!opts.Reverse && c <= 0) || (opts.Reverse && c >= 0) {
+				continue
+			}
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if opts.Reverse {
+		for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
+			keys[i], keys[j] = keys[j], keys[i]
+		}
+	}
+	return keys
+}
+
+func TestTreeIO_Iterate(t *testing.T) {
+	tests := []struct {
+		name string
+		opts IteratorOptions
+	}{
+		{name: "all"},
+		{name: "prefix", opts: IteratorOptions{Prefix: []byte("a")}},
+		{name: "prefix between branches", opts: IteratorOptions{Prefix: []byte("ca")}},
+		{name: "prefix with no keys", opts: IteratorOptions{Prefix: []byte("d")}},
+		{name: "range", opts: IteratorOptions{Start: []byte("aab"), End: []byte("bab")}},
+		{name: "range not on keys", opts: IteratorOptions{Start: []byte("aaa"), End: []byte("bb")}},
+		{name: "after", opts: IteratorOptions{After: []byte("abc")}},
+		{name: "after not on key", opts: IteratorOptions{After: []byte("abcc")}},
+		{name: "prefix and range", opts: IteratorOptions{Prefix: []byte("a"), Start: []byte("aa"), End: []byte("abd")}},
+	}
+	for _, tt := range tests {
+		for _, reverse := range []bool{false, true} {
+			name := tt.name
+			if reverse {
+				name += " reversed"
+			}
+			t.Run(name, func(t *testing.T) {
+				io := newIteratorTree(t)
+				opts := tt.opts
+				opts.Reverse = reverse
+				assert.Equal(t, expectedKeys(opts), collectKeys(t, io.Iterate(opts), 0))
+			})
+		}
+	}
+}
+
+func TestTreeIO_Iterate_empty(t *testing.T) {
+	io := TreeIO{FS: mockFS{pages: map[uint64][]byte{}}}
+	assert.Equal(t, []string{}, collectKeys(t, io.Iterate(IteratorOptions{}), 0))
+}
+
+func TestTreeIO_Iterate_resume(t *testing.T) {
+	for _, reverse := range []bool{false, true} {
+		io := newIteratorTree(t)
+		opts := IteratorOptions{Reverse: reverse}
+		keys := []string{}
+		for {
+			page := collectKeys(t, io.Iterate(opts), 3)
+			if len(page) == 0 {
+				break
+			}
+			keys = append(keys, page...)
+			opts.After = []byte(page[len(page)-1])
+		}
+		assert.Equal(t, expectedKeys(IteratorOptions{Reverse: reverse}), keys)
+	}
+}
@@ -4,6 +4,10 @@
 package radisk
 
 import (
+	"encoding/binary"
+	"math/rand"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -222,3 +226,217 @@ func BenchmarkTreeIO_GetValue(b *testing.B) {
 		_, _ = io.GetValue([]byte("abc:d"))
 	}
 }
+
+type mockFS struct {
+	pages map[uint64][]byte
+}
+
+func (m mockFS) GetPage(page uint64) ([]byte, error) {
+	if data, ok := m.pages[page]; ok {
+		return data, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (m mockFS) SetPage(page uint64, data []byte) error {
+	m.pages[page] = data
+	return nil
+}
+
+func TestTreeIO_SetValue(t *testing.T) {
+	tests := []struct {
+		name string
+
+		values map[string]string
+	}{
+		{
+			name:   "blank key",
+			values: map[string]string{"": "test"},
+		},
+		{
+			name:   "empty value",
+			values: map[string]string{"a": ""},
+		},
+		{
+			name:   "large value",
+			values: map[string]string{"a": strings.Repeat("a", maxInlineValueSize+1)},
+		},
+		{
+			name: "split keys",
+			values: map[string]string{
+				"abc:d": "1",
+				"abc:e": "2",
+				"abc":   "3",
+				"ab":    "4",
+				"b":     "5",
+				"":      "6",
+				"abd":   "7",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			io := TreeIO{FS: mockFS{pages: map[uint64][]byte{}}}
+			for k, v := range tt.values {
+				assert.NoError(t, io.SetValue([]byte(k), []byte(v)))
+			}
+			for k, v := range tt.values {
+				got, err := io.GetValue([]byte(k))
+				assert.NoError(t, err)
+				assert.Equal(t, v, string(got))
+			}
+			_, err := io.GetValue([]byte("does not exist"))
+			assert.Equal(t, ErrNotFound, err)
+		})
+	}
+}
+
+func TestTreeIO_DeleteValue(t *testing.T) {
+	io := TreeIO{FS: mockFS{pages: map[uint64][]byte{}}}
+	for _, k := range []string{"abc", "abd", "ab", "b"} {
+		assert.NoError(t, io.SetValue([]byte(k), []byte(k)))
+	}
+
+	assert.NoError(t, io.DeleteValue([]byte("ab")))
+	assert.NoError(t, io.DeleteValue([]byte("abc")))
+	assert.Equal(t, ErrNotFound, io.DeleteValue([]byte("abc")))
+	assert.Equal(t, ErrNotFound, io.DeleteValue([]byte("a")))
+
+	for k, expected := range map[string]error{"abc": ErrNotFound, "ab": ErrNotFound, "abd": nil, "b": nil} {
+		got, err := io.GetValue([]byte(k))
+		assert.Equal(t, expected, err)
+		if expected == nil {
+			assert.Equal(t, k, string(got))
+		}
+	}
+}
+
+// Walks the tree and checks that it is compact. Returns the pages that are in use.
+func checkTree(t *testing.T, io TreeIO) map[uint32]bool {
+	t.Helper()
+	used := map[uint32]bool{}
+	root, err := io.readRoot()
+	if !assert.NoError(t, err) {
+		return used
+	}
+	var walk func(b memBranch, isRoot bool)
+	walk = func(b memBranch, isRoot bool) {
+		if b.overflowPage != 0 {
+			used[b.overflowPage] = true
+		}
+		children, err := io.readChildren(b)
+		if !assert.NoError(t, err) {
+			return
+		}
+		if b.childrenPage != 0 {
+			used[b.childrenPage] = true
+		}
+		if !isRoot && !b.hasValue {
+			// A branch without a value should have been merged or removed.
+			assert.GreaterOrEqual(t, len(children), 2)
+		}
+		for i, child := range children {
+			if i != 0 {
+				assert.Less(t, children[i-1].key[0], child.key[0])
+			}
+			walk(child, false)
+		}
+	}
+	walk(root, true)
+	return used
+}
+
+func TestTreeIO_MatchesMap(t *testing.T) {
+	filesystems := map[string]func(pages map[uint64][]byte) Filesystem{
+		"filesystem": func(pages map[uint64][]byte) Filesystem {
+			return mockFS{pages: pages}
+		},
+		"cache": func(pages map[uint64][]byte) Filesystem {
+			return &Cache{FS: mockFS{pages: pages}}
+		},
+	}
+	for name, fsFn := range filesystems {
+		for seed := int64(0); seed < 20; seed++ {
+			t.Run(name+"/"+strconv.FormatInt(seed, 10), func(t *testing.T) {
+				pages := map[uint64][]byte{}
+				io := TreeIO{FS: fsFn(pages)}
+				r := rand.New(rand.NewSource(seed))
+				expected := map[string]string{}
+
+				randomKey := func() string {
+					// Use a small alphabet so that keys share prefixes.
+					b := make([]byte, r.Intn(6))
+					for i := range b {
+						b[i] = "abc"[r.Intn(3)]
+					}
+					return string(b)
+				}
+				randomValue := func() string {
+					switch r.Intn(10) {
+					case 0:
+						return ""
+					case 1:
+						return strings.Repeat("x", maxInlineValueSize+r.Intn(10))
+					default:
+						return strconv.Itoa(r.Int())
+					}
+				}
+
+				for i := 0; i < 500; i++ {
+					key := randomKey()
+					if r.Intn(3) == 0 {
+						// Delete the key.
+						err := io.DeleteValue([]byte(key))
+						if _, ok := expected[key]; ok {
+							assert.NoError(t, err)
+							delete(expected, key)
+						} else {
+							assert.Equal(t, ErrNotFound, err)
+						}
+					} else {
+						// Set the key.
+						value := randomValue()
+						assert.NoError(t, io.SetValue([]byte(key), []byte(value)))
+						expected[key] = value
+					}
+				}
+
+				// Check every key matches the map.
+				for k, v := range expected {
+					got, err := io.GetValue([]byte(k))
+					assert.NoError(t, err)
+					assert.Equal(t, v, string(got))
+				}
+				for i := 0; i < 50; i++ {
+					key := randomKey()
+					if _, ok := expected[key]; !ok {
+						_, err := io.GetValue([]byte(key))
+						assert.Equal(t, ErrNotFound, err)
+					}
+				}
+
+				// Check no pages were leaked.
+				used := checkTree(t, io)
+				freeList, err := io.readFreeList()
+				assert.NoError(t, err)
+				for i := 0; i < len(freeList); i += 4 {
+					page := binary.LittleEndian.Uint32(freeList[i:])
+					assert.False(t, used[page], "page %d is both free and in use", page)
+					used[page] = true
+				}
+				next := uint32(1)
+				if b, ok := pages[allocatorPage]; ok {
+					next = binary.LittleEndian.Uint32(b)
+				}
+				assert.Equal(t, int(next-1), len(used))
+
+				// Delete everything and make sure the tree is empty.
+				for k := range expected {
+					assert.NoError(t, io.DeleteValue([]byte(k)))
+				}
+				used = checkTree(t, io)
+				assert.Empty(t, used)
+			})
+		}
+	}
+}
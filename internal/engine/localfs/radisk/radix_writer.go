@@ -0,0 +1,464 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package radisk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+)
+
+// The writer keeps each list of children on its own page at index 0. This means that when a
+// list changes, the page can just be rewritten without needing to worry about anything that
+// is after it. The root branch is the only thing on page 0.
+
+// allocatorPage is the page used to store the next page number to allocate.
+const allocatorPage = math.MaxUint64
+
+// freeListPage is the page used to store the pages which were freed and can be allocated again.
+// It is a list of uint32 little endian page numbers.
+const freeListPage = math.MaxUint64 - 1
+
+// maxInlineValueSize is the largest value that will be stored on the same page as the branch.
+// Anything larger is stored on its own page.
+const maxInlineValueSize = 1024
+
+// ErrTreeFull is used to define the error when there are no more pages to allocate.
+var ErrTreeFull = errors.New("no more pages can be allocated")
+
+// Defines a branch that has been loaded into memory so that its page can be rewritten.
+type memBranch struct {
+	// key is the part of the key that this branch represents.
+	key []byte
+
+	// hasValue is true if the branch has a value.
+	hasValue bool
+
+	// value is the inline value of the branch. Only used if overflowPage is 0.
+	value []byte
+
+	// overflowPage is the page the value is stored on if it is not inline.
+	overflowPage uint32
+
+	// childrenPage is the page the children are stored on. If 0, there are no children.
+	childrenPage uint32
+}
+
+// Reads the branches at the offset specified into memory.
+func readBranches(page []byte, offset, count uint32) ([]memBranch, error) {
+	branches := make([]memBranch, count)
+	for i := uint32(0); i < count; i++ {
+		// Read the branch.
+		if int(offset) > len(page) {
+			return nil, ErrInvalidData
+		}
+		b, n, err := readBranch(page[offset:])
+		if err != nil {
+			return nil, err
+		}
+		offset += uint32(n)
+		m := memBranch{key: b.key, childrenPage: b.childrenPage}
+
+		// Read the value if there is one.
+		if b.valuePtr != 0 {
+			m.hasValue = true
+			if len(page) < int(b.valuePtr+4) {
+				return nil, ErrInvalidData
+			}
+			valueLen := binary.LittleEndian.Uint32(page[b.valuePtr:])
+			if valueLen == 0 {
+				if len(page) < int(b.valuePtr+8) {
+					return nil, ErrInvalidData
+				}
+				m.overflowPage = binary.LittleEndian.Uint32(page[b.valuePtr+4:])
+			} else {
+				if len(page) < int(b.valuePtr+4+valueLen) {
+					return nil, ErrInvalidData
+				}
+				m.value = page[b.valuePtr+4 : b.valuePtr+4+valueLen]
+			}
+		}
+		branches[i] = m
+	}
+	return branches, nil
+}
+
+// Encodes the branches. If withCount is true, the count is written before the branches.
+func encodeBranches(branches []memBranch, withCount bool) []byte {
+	// Figure out the size of the branches and the values.
+	size := 0
+	if withCount {
+		size = 4
+	}
+	for _, b := range branches {
+		size += 16 + len(b.key)
+	}
+	valuePos := size
+	for _, b := range branches {
+		if b.hasValue {
+			if b.overflowPage == 0 {
+				size += 4 + len(b.value)
+			} else {
+				size += 8
+			}
+		}
+	}
+
+	// Write the count.
+	buf := make([]byte, size)
+	pos := 0
+	if withCount {
+		binary.LittleEndian.PutUint32(buf, uint32(len(branches)))
+		pos = 4
+	}
+
+	// Write each branch and its value.
+	for _, b := range branches {
+		if b.hasValue {
+			binary.LittleEndian.PutUint32(buf[pos:], uint32(valuePos))
+			if b.overflowPage == 0 {
+				binary.LittleEndian.PutUint32(buf[valuePos:], uint32(len(b.value)))
+				copy(buf[valuePos+4:], b.value)
+				valuePos += 4 + len(b.value)
+			} else {
+				binary.LittleEndian.PutUint32(buf[valuePos+4:], b.overflowPage)
+				valuePos += 8
+			}
+		}
+		binary.LittleEndian.PutUint32(buf[pos+4:], uint32(len(b.key)))
+		copy(buf[pos+8:], b.key)
+		pos += 8 + len(b.key)
+		binary.LittleEndian.PutUint32(buf[pos:], b.childrenPage)
+		pos += 8
+	}
+	return buf
+}
+
+// Reads the free list. If there is no free list, a empty slice is returned.
+func (t TreeIO) readFreeList() ([]byte, error) {
+	b, err := t.FS.GetPage(freeListPage)
+	if err != nil {
+		if err == ErrNotFound {
+			return []byte{}, nil
+		}
+		return nil, err
+	}
+	if len(b)%4 != 0 {
+		return nil, ErrInvalidData
+	}
+	return b, nil
+}
+
+// Allocates a new page. Pages in the free list are used before new pages are allocated.
+func (t TreeIO) allocatePage() (uint32, error) {
+	// Try to pop a page off the free list. Note we copy the list since the filesystem may
+	// be caching the slice.
+	freeList, err := t.readFreeList()
+	if err != nil {
+		return 0, err
+	}
+	if len(freeList) != 0 {
+		last := len(freeList) - 4
+		page := binary.LittleEndian.Uint32(freeList[last:])
+		if err := t.FS.SetPage(freeListPage, append([]byte{}, freeList[:last]...)); err != nil {
+			return 0, err
+		}
+		return page, nil
+	}
+
+	// Get the next page.
+	next := uint32(1)
+	b, err := t.FS.GetPage(allocatorPage)
+	if err == nil {
+		if len(b) < 4 {
+			return 0, ErrInvalidData
+		}
+		next = binary.LittleEndian.Uint32(b)
+	} else if err != ErrNotFound {
+		return 0, err
+	}
+	if next == math.MaxUint32 {
+		return 0, ErrTreeFull
+	}
+
+	// Write the page after it.
+	b = make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, next+1)
+	if err := t.FS.SetPage(allocatorPage, b); err != nil {
+		return 0, err
+	}
+	return next, nil
+}
+
+// Adds the page to the free list so that it can be allocated again.
+func (t TreeIO) freePage(page uint32) error {
+	freeList, err := t.readFreeList()
+	if err != nil {
+		return err
+	}
+	b := make([]byte, len(freeList)+4)
+	copy(b, freeList)
+	binary.LittleEndian.PutUint32(b[len(freeList):], page)
+	return t.FS.SetPage(freeListPage, b)
+}
+
+// Sets the value on the branch. Values which cannot be stored inline are written to their
+// own page. Note that an empty value cannot be stored inline since a length of 0 means the
+// value is on another page.
+func (t TreeIO) setBranchValue(b *memBranch, value []byte) error {
+	b.hasValue = true
+	if len(value) != 0 && len(value) <= maxInlineValueSize {
+		// The value can go inline, so free the overflow page if there was one.
+		b.value = value
+		if b.overflowPage != 0 {
+			if err := t.freePage(b.overflowPage); err != nil {
+				return err
+			}
+			b.overflowPage = 0
+		}
+		return nil
+	}
+	if b.overflowPage == 0 {
+		page, err := t.allocatePage()
+		if err != nil {
+			return err
+		}
+		b.overflowPage = page
+	}
+	b.value = nil
+	return t.FS.SetPage(uint64(b.overflowPage), value)
+}
+
+// Reads the root branch. If the tree is empty, a blank branch is returned.
+func (t TreeIO) readRoot() (memBranch, error) {
+	page, err := t.FS.GetPage(0)
+	if err != nil {
+		if err == ErrNotFound {
+			return memBranch{}, nil
+		}
+		return memBranch{}, err
+	}
+	branches, err := readBranches(page, 0, 1)
+	if err != nil {
+		return memBranch{}, err
+	}
+	return branches[0], nil
+}
+
+// Reads the children of a branch.
+func (t TreeIO) readChildren(b memBranch) ([]memBranch, error) {
+	if b.childrenPage == 0 {
+		return []memBranch{}, nil
+	}
+	page, err := t.FS.GetPage(uint64(b.childrenPage))
+	if err != nil {
+		return nil, err
+	}
+	if len(page) < 4 {
+		return nil, ErrInvalidData
+	}
+	return readBranches(page, 4, binary.LittleEndian.Uint32(page))
+}
+
+// Writes the children of a branch, allocating a page if the branch does not have one yet. If
+// there are no children, the page is freed.
+func (t TreeIO) writeChildren(b *memBranch, children []memBranch) error {
+	if len(children) == 0 {
+		if b.childrenPage != 0 {
+			if err := t.freePage(b.childrenPage); err != nil {
+				return err
+			}
+			b.childrenPage = 0
+		}
+		return nil
+	}
+	if b.childrenPage == 0 {
+		page, err := t.allocatePage()
+		if err != nil {
+			return err
+		}
+		b.childrenPage = page
+	}
+	return t.FS.SetPage(uint64(b.childrenPage), encodeBranches(children, true))
+}
+
+// Finds the child which starts with the same byte as the key. Children are sorted by key, and
+// since no two children can start with the same byte, a binary search can be used. Returns the
+// index the child would be inserted at and if it was found.
+func findChild(children []memBranch, key []byte) (int, bool) {
+	i := sort.Search(len(children), func(i int) bool {
+		return children[i].key[0] >= key[0]
+	})
+	return i, i < len(children) && children[i].key[0] == key[0]
+}
+
+// Gets the length of the common prefix of two keys.
+func commonPrefix(a, b []byte) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// Sets the value for the key within the branch. The part of the key that the branch represents
+// should already be removed. The branch is updated in place and the caller is responsible for
+// writing the page it is on.
+func (t TreeIO) setInBranch(b *memBranch, key, value []byte) error {
+	// If the key is empty, the value belongs on this branch.
+	if len(key) == 0 {
+		return t.setBranchValue(b, value)
+	}
+
+	// Read the children.
+	children, err := t.readChildren(*b)
+	if err != nil {
+		return err
+	}
+
+	i, found := findChild(children, key)
+	if !found {
+		// Insert a new child.
+		child := memBranch{key: key}
+		if err := t.setBranchValue(&child, value); err != nil {
+			return err
+		}
+		children = append(children, memBranch{})
+		copy(children[i+1:], children[i:])
+		children[i] = child
+	} else {
+		child := children[i]
+		n := commonPrefix(child.key, key)
+		if n == len(child.key) {
+			// The child is a prefix of the key, so go down into it.
+			if err := t.setInBranch(&child, key[n:], value); err != nil {
+				return err
+			}
+		} else {
+			// Split the child at the common prefix.
+			lower := child
+			lower.key = child.key[n:]
+			split := memBranch{key: key[:n]}
+			splitChildren := []memBranch{lower}
+			if n == len(key) {
+				// The key ends at the split.
+				if err := t.setBranchValue(&split, value); err != nil {
+					return err
+				}
+			} else {
+				// Add the rest of the key as a sibling of the lower half.
+				leaf := memBranch{key: key[n:]}
+				if err := t.setBranchValue(&leaf, value); err != nil {
+					return err
+				}
+				if bytes.Compare(leaf.key, lower.key) < 0 {
+					splitChildren = []memBranch{leaf, lower}
+				} else {
+					splitChildren = append(splitChildren, leaf)
+				}
+			}
+			if err := t.writeChildren(&split, splitChildren); err != nil {
+				return err
+			}
+			child = split
+		}
+		children[i] = child
+	}
+
+	// Write the children.
+	return t.writeChildren(b, children)
+}
+
+// Writes the root branch to page 0.
+func (t TreeIO) writeRoot(root memBranch) error {
+	return t.FS.SetPage(0, encodeBranches([]memBranch{root}, false))
+}
+
+// SetValue is used to set the value for the key in the tree. If the key already exists, the
+// value is replaced.
+func (t TreeIO) SetValue(key, value []byte) error {
+	root, err := t.readRoot()
+	if err != nil {
+		return err
+	}
+	if err := t.setInBranch(&root, key, value); err != nil {
+		return err
+	}
+	return t.writeRoot(root)
+}
+
+// Deletes the key within the branch. The part of the key that the branch represents should
+// already be removed. Returns ErrNotFound if the key does not exist.
+func (t TreeIO) deleteInBranch(b *memBranch, key []byte) error {
+	// If the key is empty, the value is on this branch.
+	if len(key) == 0 {
+		if !b.hasValue {
+			return ErrNotFound
+		}
+		if b.overflowPage != 0 {
+			if err := t.freePage(b.overflowPage); err != nil {
+				return err
+			}
+		}
+		b.hasValue = false
+		b.value = nil
+		b.overflowPage = 0
+		return nil
+	}
+
+	// Find the child.
+	children, err := t.readChildren(*b)
+	if err != nil {
+		return err
+	}
+	i, found := findChild(children, key)
+	if !found || !bytes.HasPrefix(key, children[i].key) {
+		return ErrNotFound
+	}
+	child := children[i]
+	if err := t.deleteInBranch(&child, key[len(child.key):]); err != nil {
+		return err
+	}
+
+	// Remove the child if there is nothing left in it, or merge it with its only child if it
+	// has no value.
+	if !child.hasValue {
+		if child.childrenPage == 0 {
+			children = append(children[:i], children[i+1:]...)
+			return t.writeChildren(b, children)
+		}
+		grandchildren, err := t.readChildren(child)
+		if err != nil {
+			return err
+		}
+		if len(grandchildren) == 1 {
+			if err := t.freePage(child.childrenPage); err != nil {
+				return err
+			}
+			merged := grandchildren[0]
+			key := make([]byte, len(child.key)+len(merged.key))
+			copy(key, child.key)
+			copy(key[len(child.key):], merged.key)
+			merged.key = key
+			child = merged
+		}
+	}
+	children[i] = child
+	return t.writeChildren(b, children)
+}
+
+// DeleteValue is used to delete the value for the key in the tree. Returns ErrNotFound if the
+// key does not exist.
+func (t TreeIO) DeleteValue(key []byte) error {
+	root, err := t.readRoot()
+	if err != nil {
+		return err
+	}
+	if err := t.deleteInBranch(&root, key); err != nil {
+		return err
+	}
+	return t.writeRoot(root)
+}
@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	"remixdb.io/ast"
+	"remixdb.io/internal/engine"
 	"remixdb.io/internal/utils"
 )
 
@@ -17,16 +18,55 @@ type possibleRename struct {
 
 // Cache is the cache object that can be used to cache data across many sessions.
 type Cache struct {
-	contracts utils.TLRUCache[string, map[string]*ast.ContractToken]
-	structs   utils.TLRUCache[string, map[string]possibleRename]
+	contracts  utils.TLRUCache[string, map[string]*ast.ContractToken]
+	enums      utils.TLRUCache[string, map[string]*ast.EnumToken]
+	exceptions utils.TLRUCache[string, map[string]*ast.ExceptionToken]
+	structs    utils.TLRUCache[string, map[string]possibleRename]
 
 	partitionLocks   map[string]*utils.NamedLock
 	partitionLocksMu sync.RWMutex
+
+	migrationProgress   map[string]engine.MigrationProgress
+	migrationProgressMu sync.Mutex
+}
+
+// Sets the progress of the migration running for the struct.
+func (c *Cache) setMigrationProgress(partition, structName string, migrated, total uint64) {
+	c.migrationProgressMu.Lock()
+	if c.migrationProgress == nil {
+		c.migrationProgress = map[string]engine.MigrationProgress{}
+	}
+	c.migrationProgress[partition+"\x00"+structName] = engine.MigrationProgress{Migrated: migrated, Total: total}
+	c.migrationProgressMu.Unlock()
+}
+
+// Gets the progress of the migration running for the struct.
+func (c *Cache) getMigrationProgress(partition, structName string) engine.MigrationProgress {
+	c.migrationProgressMu.Lock()
+	defer c.migrationProgressMu.Unlock()
+	return c.migrationProgress[partition+"\x00"+structName]
+}
+
+// Moves the progress of the migration for the struct when the struct is renamed. The struct
+// with the old name no longer has a migration running, so its progress is dropped.
+func (c *Cache) renameMigrationProgress(partition, oldName, newName string) {
+	c.migrationProgressMu.Lock()
+	defer c.migrationProgressMu.Unlock()
+	oldKey := partition + "\x00" + oldName
+	newKey := partition + "\x00" + newName
+	if progress, ok := c.migrationProgress[oldKey]; ok {
+		c.migrationProgress[newKey] = progress
+		delete(c.migrationProgress, oldKey)
+	} else {
+		delete(c.migrationProgress, newKey)
+	}
 }
 
 // CleanPartition is used to clean the cache for a partition. Use with care! Make sure there's no sessions running for the partition.
 func (c *Cache) CleanPartition(partition string) {
 	c.contracts.Delete(partition)
+	c.enums.Delete(partition)
+	c.exceptions.Delete(partition)
 
 	c.partitionLocksMu.Lock()
 	if c.partitionLocks != nil {
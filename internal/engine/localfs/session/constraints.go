@@ -0,0 +1,348 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package session
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"remixdb.io/ast"
+	"remixdb.io/internal/engine"
+	"remixdb.io/internal/engine/localfs/radisk"
+	"remixdb.io/internal/rqltypes"
+)
+
+// Gets the field token with the name specified.
+func getField(structToken *ast.StructToken, name string) ast.FieldToken {
+	for _, f := range structToken.Fields {
+		if field, ok := f.(ast.FieldToken); ok && field.Name == name {
+			return field
+		}
+	}
+	return ast.FieldToken{}
+}
+
+// Gets the decorator on the field.
+func getDecorator(field ast.FieldToken, method string) ast.DecoratorToken {
+	for _, decorator := range field.Decorators {
+		if decorator.Method == method {
+			return decorator
+		}
+	}
+	return ast.DecoratorToken{}
+}
+
+// Validates the field decorators on a struct before it is written.
+func (s *Session) validateFieldDecorators(structToken *ast.StructToken) error {
+	for _, name := range fieldsWithDecorator(structToken, "autoincrement") {
+		t := rqltypes.Parse(getField(structToken, name).Type)
+		if t.Elem != nil || (t.Name != rqltypes.Int && t.Name != rqltypes.Uint) {
+			return errors.New(structToken.Name + "." + name + ": @autoincrement can only be used on int or uint fields")
+		}
+	}
+	for _, name := range fieldsWithDecorator(structToken, "default") {
+		field := getField(structToken, name)
+		t, err := rqltypes.ResolveEnums(s, rqltypes.Parse(field.Type))
+		if err != nil {
+			return err
+		}
+		_, err = rqltypes.DecoratorValue(t, getDecorator(field, "default"))
+		if err != nil {
+			return errors.New(structToken.Name + "." + name + ": @default: " + err.Error())
+		}
+	}
+	return nil
+}
+
+// Gets the path to the file used to store the next value for a @autoincrement field.
+func getCounterPath(relativePath, structName, field string) string {
+	return filepath.Join(
+		getStructTablePath(relativePath, structName),
+		"c_"+base64.URLEncoding.EncodeToString([]byte(field)))
+}
+
+// Reads the next value of the counter for the field. To stop two sessions from handing out the
+// same value, a lock on the counters for the struct is held until the session is closed.
+func (s *Session) readCounter(structName, field string) (uint64, error) {
+	// Acquire the lock if we do not already have it. The # is used since it cannot be in a struct name.
+	if s.openCounterUnlockers == nil {
+		s.openCounterUnlockers = map[string]func(){}
+	}
+	if _, ok := s.openCounterUnlockers[structName]; !ok {
+		l := s.getPartitionNamedLocks()
+		lockName := "#autoincrement " + structName
+		l.Lock(lockName)
+		s.openCounterUnlockers[structName] = func() {
+			l.Unlock(lockName)
+		}
+	}
+
+	// Read the current value.
+	next := uint64(1)
+	b, err := s.Transaction.ReadFile(getCounterPath(s.RelativePath, structName, field))
+	if err == nil {
+		if len(b) != 8 {
+			return 0, errors.New("counter for " + structName + "." + field + " is corrupt")
+		}
+		next = binary.LittleEndian.Uint64(b)
+	} else if !os.IsNotExist(err) {
+		return 0, err
+	}
+	return next, nil
+}
+
+// Journals the next value of the counter for the field.
+func (s *Session) writeCounter(structName, field string, next uint64) {
+	s.Transaction.MkdirAll(getStructTablePath(s.RelativePath, structName))
+	s.Transaction.WriteFile(
+		getCounterPath(s.RelativePath, structName, field),
+		binary.LittleEndian.AppendUint64(nil, next))
+}
+
+// Gets the next value of the counter for the field. The counter is written within the transaction
+// so that it is only moved on if the insert is committed.
+func (s *Session) nextCounterValue(structName, field string) (uint64, error) {
+	next, err := s.readCounter(structName, field)
+	if err != nil {
+		return 0, err
+	}
+	s.writeCounter(structName, field, next+1)
+	return next, nil
+}
+
+// Moves the counter for the field past a value which was set by the caller, so that the counter
+// does not hand out the same value later.
+func (s *Session) advanceCounter(structName, field string, used uint64) error {
+	next, err := s.readCounter(structName, field)
+	if err != nil {
+		return err
+	}
+	if used >= next {
+		s.writeCounter(structName, field, used+1)
+	}
+	return nil
+}
+
+// Applies the @autoincrement and @default decorators to a object which is being inserted.
+func (s *Session) applyInsertDecorators(structToken *ast.StructToken, object []byte) ([]byte, error) {
+	fields, err := rqltypes.StructFields(object)
+	if err != nil {
+		return nil, err
+	}
+	changed := false
+
+	// Set any @autoincrement fields which are missing or zero.
+	for _, name := range fieldsWithDecorator(structToken, "autoincrement") {
+		t := rqltypes.Parse(getField(structToken, name).Type)
+		var zero any = 0
+		if t.Name == rqltypes.Uint {
+			zero = uint(0)
+		}
+		zeroEncoded, err := rqltypes.Encode(t, zero, nil)
+		if err != nil {
+			return nil, err
+		}
+		if v, ok := fields[name]; ok && !bytes.Equal(v, zeroEncoded) {
+			// The value was set by the caller, so move the counter past it. Negative values are
+			// never handed out by the counter.
+			explicit, err := rqltypes.Decode(t, v, nil)
+			if err != nil {
+				return nil, err
+			}
+			switch x := explicit.(type) {
+			case int:
+				if x > 0 {
+					err = s.advanceCounter(structToken.Name, name, uint64(x))
+				}
+			case uint:
+				err = s.advanceCounter(structToken.Name, name, uint64(x))
+			}
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		next, err := s.nextCounterValue(structToken.Name, name)
+		if err != nil {
+			return nil, err
+		}
+		var value any = int(next)
+		if t.Name == rqltypes.Uint {
+			value = uint(next)
+		}
+		if fields[name], err = rqltypes.Encode(t, value, nil); err != nil {
+			return nil, err
+		}
+		changed = true
+	}
+
+	// Set any @default fields which are missing.
+	for _, name := range fieldsWithDecorator(structToken, "default") {
+		if _, ok := fields[name]; ok {
+			continue
+		}
+		field := getField(structToken, name)
+		t, err := rqltypes.ResolveEnums(s, rqltypes.Parse(field.Type))
+		if err != nil {
+			return nil, err
+		}
+		value, err := rqltypes.DecoratorValue(t, getDecorator(field, "default"))
+		if err != nil {
+			return nil, err
+		}
+		encoded, err := rqltypes.Encode(t, value, nil)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(encoded, []byte{0x00}) {
+			// Null fields are not included in the encoding.
+			fields[name] = encoded
+			changed = true
+		}
+	}
+
+	// Re-encode the object if anything changed.
+	if !changed {
+		return object, nil
+	}
+	return rqltypes.EncodeStructFields(structToken.Name, fields), nil
+}
+
+// Makes sure the values of any enum fields within the object are values of the enum. The error
+// is a engine.EnumValueError if a value is not within the enum.
+func (s *Session) checkEnums(structToken *ast.StructToken, object []byte) error {
+	types, err := rqltypes.FieldsFromSession(s, structToken)
+	if err != nil {
+		return err
+	}
+	values, err := rqltypes.StructFields(object)
+	if err != nil {
+		return err
+	}
+	for name, v := range values {
+		t, ok := types[name]
+		if !ok {
+			continue
+		}
+		if !t.IsEnum() && (t.Elem == nil || !t.Elem.IsEnum()) {
+			continue
+		}
+		if _, err := rqltypes.Decode(t, v, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Makes sure that no object other than the one with the key has the value in the index.
+func (s *Session) checkUniqueValue(structToken *ast.StructToken, field string, key, value []byte) error {
+	// Null values do not conflict with each other.
+	if bytes.Equal(value, []byte{0x00}) {
+		return nil
+	}
+
+	keys, err := s.indexKeys(structToken, field, value)
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if !bytes.Equal(k, key) {
+			return engine.UniqueConstraintError{Struct: structToken.Name, Field: field}
+		}
+	}
+	return nil
+}
+
+// Makes sure that no object other than the one with the key has the value by going through every
+// object within the table. This is used whilst the index cannot be trusted.
+func (s *Session) scanUniqueValue(structToken *ast.StructToken, field string, key, value []byte) error {
+	// Null values do not conflict with each other.
+	if bytes.Equal(value, []byte{0x00}) {
+		return nil
+	}
+
+	// Compare the values the same way as the index does, so both ways of checking agree on
+	// which values are equal.
+	types, err := rqltypes.FieldsFromSession(s, structToken)
+	if err != nil {
+		return err
+	}
+	want, err := indexPrefix(types[field], value)
+	if err != nil {
+		return err
+	}
+
+	// Get the table and any pending migration.
+	table, _, err := s.getStructTable(structToken.Name)
+	if err != nil {
+		return err
+	}
+	steps, err := s.pendingMigration(structToken.Name)
+	if err != nil {
+		return err
+	}
+
+	// Check the value of each object.
+	it := table.Iterate(radisk.IteratorOptions{})
+	for it.Next() {
+		k := it.Key()
+		if bytes.Equal(k, key) {
+			continue
+		}
+		object, err := it.Value()
+		if err != nil {
+			return err
+		}
+		if object, err = s.migrateObject(structToken.Name, steps, k, object); err != nil {
+			return err
+		}
+		values, err := indexValues([]string{field}, object)
+		if err != nil {
+			return err
+		}
+		got, err := indexPrefix(types[field], values[0])
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(got, want) {
+			return engine.UniqueConstraintError{Struct: structToken.Name, Field: field}
+		}
+	}
+	return it.Err()
+}
+
+// Makes sure the object does not conflict with any other objects on fields marked with @unique.
+// The caller must hold the table write lock so that another session cannot write the same value
+// between the check and the write. If the index for a field is still being built or failed to
+// build, the table is scanned instead.
+func (s *Session) checkUnique(structToken *ast.StructToken, key, object []byte) error {
+	fields := fieldsWithDecorator(structToken, "unique")
+	if len(fields) == 0 {
+		return nil
+	}
+	values, err := indexValues(fields, object)
+	if err != nil {
+		return err
+	}
+	for i, field := range fields {
+		status, err := s.indexStatus(structToken.Name, field)
+		if err != nil {
+			return err
+		}
+		if status.Building || status.Error != "" {
+			err = s.scanUniqueValue(structToken, field, key, values[i])
+		} else {
+			err = s.checkUniqueValue(structToken, field, key, values[i])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
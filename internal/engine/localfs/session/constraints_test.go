@@ -0,0 +1,228 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package session
+
+import (
+	"encoding/binary"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"remixdb.io/ast"
+	"remixdb.io/internal/engine"
+	"remixdb.io/internal/rqltypes"
+)
+
+// Decodes the object as the latest version of the struct.
+func decodeTestObject(t *testing.T, s *Session, structName string, object []byte) map[string]any {
+	t.Helper()
+	v, err := rqltypes.Decode(rqltypes.Type{Name: structName}, object, rqltypes.SessionResolver(s))
+	require.NoError(t, err)
+	return v.(map[string]any)
+}
+
+// Inserts the object and returns the object which was written.
+func insertTestObject(t *testing.T, s *Session, structName, key string, fields map[string]any) map[string]any {
+	t.Helper()
+	object, err := encodeTestObject(s, structName, fields)
+	require.NoError(t, err)
+	object, err = s.InsertStructObject(structName, []byte(key), object)
+	require.NoError(t, err)
+	return decodeTestObject(t, s, structName, object)
+}
+
+func TestSession_autoincrement(t *testing.T) {
+	dataFolder := t.TempDir()
+	cache := &Cache{}
+	writeTestStructs(t, dataFolder, cache, &ast.StructToken{
+		Name: "Counter",
+		Fields: []any{
+			ast.FieldToken{Name: "id", Type: "int", Decorators: []ast.DecoratorToken{{Method: "autoincrement"}}},
+			ast.FieldToken{Name: "seq", Type: "uint", Decorators: []ast.DecoratorToken{{Method: "autoincrement"}}},
+		},
+	})
+
+	// Missing and zero values take the next value of the counter, and other values are kept. The
+	// counter moves past values which are set, but not back to values which are below it.
+	s := newTestSession(t, dataFolder, cache)
+	assert.Equal(t, map[string]any{"id": 1, "seq": uint(1)}, insertTestObject(t, s, "Counter", "a", map[string]any{"id": 0, "seq": uint(0)}))
+	assert.Equal(t, map[string]any{"id": 10, "seq": uint(2)}, insertTestObject(t, s, "Counter", "b", map[string]any{"id": 10, "seq": uint(0)}))
+	assert.Equal(t, map[string]any{"id": 11, "seq": uint(3)}, insertTestObject(t, s, "Counter", "c", map[string]any{"id": 0, "seq": uint(0)}))
+	assert.Equal(t, map[string]any{"id": -5, "seq": uint(2)}, insertTestObject(t, s, "Counter", "e", map[string]any{"id": -5, "seq": uint(2)}))
+	assert.Equal(t, map[string]any{"id": 12, "seq": uint(4)}, insertTestObject(t, s, "Counter", "f", map[string]any{"id": 0, "seq": uint(0)}))
+	commitTestSession(t, s)
+
+	// Values handed out by a session which is rolled back are used again.
+	s = newTestSession(t, dataFolder, cache)
+	insertTestObject(t, s, "Counter", "d", map[string]any{"id": 0, "seq": uint(0)})
+	require.NoError(t, s.Close())
+	s = newTestSession(t, dataFolder, &Cache{})
+	assert.Equal(t, map[string]any{"id": 13, "seq": uint(5)}, insertTestObject(t, s, "Counter", "d", map[string]any{"id": 0, "seq": uint(0)}))
+}
+
+func TestSession_default(t *testing.T) {
+	dataFolder := t.TempDir()
+	cache := &Cache{}
+	writeTestStructs(t, dataFolder, cache, &ast.StructToken{
+		Name: "Tree",
+		Fields: []any{
+			ast.FieldToken{Name: "name", Type: "string", Decorators: []ast.DecoratorToken{
+				{Method: "default", Arguments: `"oak"`},
+			}},
+			ast.FieldToken{Name: "height", Type: "int?", Decorators: []ast.DecoratorToken{
+				{Method: "default", Arguments: "null"},
+			}},
+		},
+	})
+
+	// Missing fields are set to the default, and fields which are set are kept.
+	s := newTestSession(t, dataFolder, cache)
+	object, err := s.InsertStructObject("Tree", []byte("a"), rqltypes.EncodeStructFields("Tree", map[string][]byte{}))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"name": "oak", "height": (*int)(nil)}, decodeTestObject(t, s, "Tree", object))
+	assert.Equal(t, map[string]any{"name": "elm", "height": ptr(5)}, insertTestObject(t, s, "Tree", "b", map[string]any{"name": "elm", "height": 5}))
+
+	// Updates are written as they are.
+	object, err = encodeTestObject(s, "Tree", map[string]any{"name": "ash"})
+	require.NoError(t, err)
+	require.NoError(t, s.UpdateStructObject("Tree", []byte("b"), object))
+	got, err := s.GetStructObject("Tree", []byte("b"))
+	require.NoError(t, err)
+	assert.Equal(t, object, got)
+
+	// Defaults which are not valid for the type cannot be written.
+	err = s.WriteStruct(&ast.StructToken{
+		Name: "Bush",
+		Fields: []any{
+			ast.FieldToken{Name: "height", Type: "int", Decorators: []ast.DecoratorToken{
+				{Method: "default", Arguments: `"tall"`},
+			}},
+		},
+	}, false)
+	assert.Error(t, err)
+}
+
+func ptr[T any](v T) *T { return &v }
+
+// Creates a struct with a unique name field.
+func uniqueTreeStruct() *ast.StructToken {
+	return treeStructWithNameDecorators(ast.DecoratorToken{Method: "unique"})
+}
+
+func TestSession_unique(t *testing.T) {
+	dataFolder := t.TempDir()
+	cache := &Cache{}
+	writeTestStructs(t, dataFolder, cache, uniqueTreeStruct())
+	s := newTestSession(t, dataFolder, cache)
+	insertTestTrees(t, s, map[string]string{"tree 1": "oak", "tree 2": "elm"})
+
+	// Inserting a object with a name which is used fails.
+	conflict := engine.UniqueConstraintError{Struct: "Tree", Field: "name"}
+	object, err := encodeTestObject(s, "Tree", map[string]any{"id": 3, "name": "oak"})
+	require.NoError(t, err)
+	_, err = s.InsertStructObject("Tree", []byte("tree 3"), object)
+	assert.Equal(t, conflict, err)
+
+	// Updating a object to a name used by another object fails, but a object can keep its name.
+	assert.Equal(t, conflict, s.UpdateStructObject("Tree", []byte("tree 2"), object))
+	assert.NoError(t, s.UpdateStructObject("Tree", []byte("tree 1"), object))
+
+	// Once a object is deleted, the name can be used again.
+	require.NoError(t, s.DeleteStructObject("Tree", []byte("tree 1")))
+	assert.NoError(t, s.UpdateStructObject("Tree", []byte("tree 2"), object))
+}
+
+func TestSession_uniqueWhilstBuilding(t *testing.T) {
+	dataFolder := t.TempDir()
+	cache := &Cache{}
+	writeTestStructs(t, dataFolder, cache, treeStructWithNameDecorators())
+	s := newTestSession(t, dataFolder, cache)
+	insertTestTrees(t, s, map[string]string{"tree 1": "oak"})
+	commitTestSession(t, s)
+	writeTestStructs(t, dataFolder, cache, uniqueTreeStruct())
+
+	// The index is not built yet, so the table is scanned to find the conflict.
+	s = newTestSession(t, dataFolder, cache)
+	status, err := s.StructIndexStatus("Tree", "name")
+	require.NoError(t, err)
+	require.True(t, status.Building)
+	object, err := encodeTestObject(s, "Tree", map[string]any{"id": 2, "name": "oak"})
+	require.NoError(t, err)
+	_, err = s.InsertStructObject("Tree", []byte("tree 2"), object)
+	assert.Equal(t, engine.UniqueConstraintError{Struct: "Tree", Field: "name"}, err)
+	insertTestTrees(t, s, map[string]string{"tree 2": "elm"})
+	commitTestSession(t, s)
+
+	// The index builds since there are no conflicts.
+	s = newTestSession(t, dataFolder, cache)
+	require.NoError(t, s.BuildPendingIndexes())
+}
+
+func TestSession_uniqueWhilstBuildingNonCanonical(t *testing.T) {
+	dataFolder := t.TempDir()
+	cache := &Cache{}
+	writeTestStructs(t, dataFolder, cache, treeStruct())
+	s := newTestSession(t, dataFolder, cache)
+	object, err := encodeTestObject(s, "Tree", map[string]any{"id": 3, "name": "oak"})
+	require.NoError(t, err)
+	_, err = s.InsertStructObject("Tree", []byte("tree 1"), object)
+	require.NoError(t, err)
+	commitTestSession(t, s)
+	structToken := treeStruct()
+	field := structToken.Fields[0].(ast.FieldToken)
+	field.Decorators = []ast.DecoratorToken{{Method: "unique"}}
+	structToken.Fields[0] = field
+	writeTestStructs(t, dataFolder, cache, structToken)
+
+	// Encode the same id without the short form for small integers. The scan must treat it as
+	// the same value, as the index would.
+	s = newTestSession(t, dataFolder, cache)
+	status, err := s.StructIndexStatus("Tree", "id")
+	require.NoError(t, err)
+	require.True(t, status.Building)
+	object = rqltypes.EncodeStructFields("Tree", map[string][]byte{
+		"id":   binary.LittleEndian.AppendUint64([]byte{0x0a}, 3),
+		"name": encodeTestValue(t, "string", "elm"),
+	})
+	_, err = s.InsertStructObject("Tree", []byte("tree 2"), object)
+	assert.Equal(t, engine.UniqueConstraintError{Struct: "Tree", Field: "id"}, err)
+}
+
+func TestSession_uniqueConcurrentInserts(t *testing.T) {
+	dataFolder := t.TempDir()
+	cache := &Cache{}
+	writeTestStructs(t, dataFolder, cache, uniqueTreeStruct())
+
+	// Insert the same name from two sessions at once. The second session waits for the first to
+	// finish before checking the index, so only one insert succeeds.
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	for _, key := range []string{"tree 1", "tree 2"} {
+		s := newTestSession(t, dataFolder, cache)
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			defer s.Close()
+			object, err := encodeTestObject(s, "Tree", map[string]any{"id": 1, "name": "oak"})
+			if err == nil {
+				_, err = s.InsertStructObject("Tree", []byte(key), object)
+			}
+			if err == nil {
+				err = s.Commit()
+			}
+			errs <- err
+		}(key)
+	}
+	wg.Wait()
+	close(errs)
+	failed := 0
+	for err := range errs {
+		if err != nil {
+			assert.Equal(t, engine.UniqueConstraintError{Struct: "Tree", Field: "name"}, err)
+			failed++
+		}
+	}
+	assert.Equal(t, 1, failed)
+}
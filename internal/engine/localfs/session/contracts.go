@@ -33,8 +33,10 @@ func (s *Session) loadContracts() (map[string]*ast.ContractToken, error) {
 			return nil, err
 		}
 
-		// Cache the contracts file.
-		s.Cache.contracts.Set(s.PartitionName, contracts)
+		// Cache the contracts file unless the schema was changed within the session.
+		if !s.schemaChanged {
+			s.Cache.contracts.Set(s.PartitionName, contracts)
+		}
 	}
 	return contracts, nil
 }
@@ -108,7 +110,7 @@ func (s *Session) DeleteContractByKey(key string) error {
 	if err != nil {
 		return err
 	}
-	s.Transaction.WriteFile(filepath.Join(s.RelativePath, "contracts"), b)
+	s.writeSchemaFile("contracts", b)
 	s.writeContractTombstone(c)
 	return nil
 }
@@ -138,7 +140,7 @@ postCacheHandling:
 	if err != nil {
 		return err
 	}
-	s.Transaction.WriteFile(filepath.Join(s.RelativePath, "contracts"), b)
+	s.writeSchemaFile("contracts", b)
 
 	// Load the contract tombstones and remove the contract from it if it exists.
 	tombstones, err := s.ContractTombstones()
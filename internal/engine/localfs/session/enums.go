@@ -0,0 +1,133 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package session
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"remixdb.io/ast"
+	"remixdb.io/internal/engine"
+)
+
+func (s *Session) loadEnums() (map[string]*ast.EnumToken, error) {
+	enums, ok := s.Cache.enums.Get(s.PartitionName)
+	if !ok {
+		// Load the enums file from disk.
+		b, err := s.Transaction.ReadFile(filepath.Join(s.RelativePath, "enums"))
+		if err != nil {
+			if os.IsNotExist(err) {
+				// Return ErrNotExists.
+				return nil, engine.ErrNotExists
+			}
+
+			// This is another type of error, so return it.
+			return nil, err
+		}
+
+		// Unmarshal the enums file.
+		err = msgpack.Unmarshal(b, &enums)
+		if err != nil {
+			return nil, err
+		}
+
+		// Cache the enums file unless the schema was changed within the session.
+		if !s.schemaChanged {
+			s.Cache.enums.Set(s.PartitionName, enums)
+		}
+	}
+	return enums, nil
+}
+
+func (s *Session) GetEnumByKey(key string) (enum *ast.EnumToken, err error) {
+	enums, err := s.loadEnums()
+	if err != nil {
+		return nil, err
+	}
+
+	enum = enums[key]
+	if enum == nil {
+		err = engine.ErrNotExists
+	}
+	return
+}
+
+func (s *Session) DeleteEnumByKey(key string) error {
+	// Ensure the session has a write lock.
+	if err := s.ensureWriteLock(); err != nil {
+		return err
+	}
+
+	// Load the enums.
+	enums, err := s.loadEnums()
+	if err != nil {
+		return err
+	}
+
+	// Make sure the enum is present. If it is, remove it.
+	if _, ok := enums[key]; !ok {
+		return engine.ErrNotExists
+	}
+	s.Cache.enums.Delete(s.PartitionName)
+	delete(enums, key)
+
+	// Journal the action.
+	b, err := msgpack.Marshal(enums)
+	if err != nil {
+		return err
+	}
+	s.writeSchemaFile("enums", b)
+	return nil
+}
+
+func (s *Session) WriteEnum(enum *ast.EnumToken) error {
+	// Ensure the session has a write lock.
+	if err := s.ensureWriteLock(); err != nil {
+		return err
+	}
+
+	// Make sure the name is not used by another type.
+	if err := s.ensureTypeNameFree(enum.Name, "enum"); err != nil {
+		return err
+	}
+
+	// Load the enums and then drop them from the cache if they exist.
+	enums, err := s.loadEnums()
+	if err != nil {
+		if err != engine.ErrNotExists {
+			return err
+		}
+		enums = map[string]*ast.EnumToken{}
+	} else {
+		s.Cache.enums.Delete(s.PartitionName)
+	}
+
+	// Journal the enums edit.
+	enums[enum.Name] = enum
+	b, err := msgpack.Marshal(enums)
+	if err != nil {
+		return err
+	}
+	s.writeSchemaFile("enums", b)
+	return nil
+}
+
+func (s *Session) Enums() (enums []*ast.EnumToken, err error) {
+	enumsMap, err := s.loadEnums()
+	if err != nil {
+		if err == engine.ErrNotExists {
+			return []*ast.EnumToken{}, nil
+		}
+		return nil, err
+	}
+
+	enums = make([]*ast.EnumToken, 0, len(enumsMap))
+	for _, v := range enumsMap {
+		enums = append(enums, v)
+	}
+	return enums, nil
+}
+
+var _ engine.EnumSessionMethods = (*Session)(nil)
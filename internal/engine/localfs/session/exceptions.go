@@ -0,0 +1,133 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package session
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"remixdb.io/ast"
+	"remixdb.io/internal/engine"
+)
+
+func (s *Session) loadExceptions() (map[string]*ast.ExceptionToken, error) {
+	exceptions, ok := s.Cache.exceptions.Get(s.PartitionName)
+	if !ok {
+		// Load the exceptions file from disk.
+		b, err := s.Transaction.ReadFile(filepath.Join(s.RelativePath, "exceptions"))
+		if err != nil {
+			if os.IsNotExist(err) {
+				// Return ErrNotExists.
+				return nil, engine.ErrNotExists
+			}
+
+			// This is another type of error, so return it.
+			return nil, err
+		}
+
+		// Unmarshal the exceptions file.
+		err = msgpack.Unmarshal(b, &exceptions)
+		if err != nil {
+			return nil, err
+		}
+
+		// Cache the exceptions file unless the schema was changed within the session.
+		if !s.schemaChanged {
+			s.Cache.exceptions.Set(s.PartitionName, exceptions)
+		}
+	}
+	return exceptions, nil
+}
+
+func (s *Session) GetExceptionByKey(key string) (exception *ast.ExceptionToken, err error) {
+	exceptions, err := s.loadExceptions()
+	if err != nil {
+		return nil, err
+	}
+
+	exception = exceptions[key]
+	if exception == nil {
+		err = engine.ErrNotExists
+	}
+	return
+}
+
+func (s *Session) DeleteExceptionByKey(key string) error {
+	// Ensure the session has a write lock.
+	if err := s.ensureWriteLock(); err != nil {
+		return err
+	}
+
+	// Load the exceptions.
+	exceptions, err := s.loadExceptions()
+	if err != nil {
+		return err
+	}
+
+	// Make sure the exception is present. If it is, remove it.
+	if _, ok := exceptions[key]; !ok {
+		return engine.ErrNotExists
+	}
+	s.Cache.exceptions.Delete(s.PartitionName)
+	delete(exceptions, key)
+
+	// Journal the action.
+	b, err := msgpack.Marshal(exceptions)
+	if err != nil {
+		return err
+	}
+	s.writeSchemaFile("exceptions", b)
+	return nil
+}
+
+func (s *Session) WriteException(exception *ast.ExceptionToken) error {
+	// Ensure the session has a write lock.
+	if err := s.ensureWriteLock(); err != nil {
+		return err
+	}
+
+	// Make sure the name is not used by another type.
+	if err := s.ensureTypeNameFree(exception.Name, "exception"); err != nil {
+		return err
+	}
+
+	// Load the exceptions and then drop them from the cache if they exist.
+	exceptions, err := s.loadExceptions()
+	if err != nil {
+		if err != engine.ErrNotExists {
+			return err
+		}
+		exceptions = map[string]*ast.ExceptionToken{}
+	} else {
+		s.Cache.exceptions.Delete(s.PartitionName)
+	}
+
+	// Journal the exceptions edit.
+	exceptions[exception.Name] = exception
+	b, err := msgpack.Marshal(exceptions)
+	if err != nil {
+		return err
+	}
+	s.writeSchemaFile("exceptions", b)
+	return nil
+}
+
+func (s *Session) Exceptions() (exceptions []*ast.ExceptionToken, err error) {
+	exceptionsMap, err := s.loadExceptions()
+	if err != nil {
+		if err == engine.ErrNotExists {
+			return []*ast.ExceptionToken{}, nil
+		}
+		return nil, err
+	}
+
+	exceptions = make([]*ast.ExceptionToken, 0, len(exceptionsMap))
+	for _, v := range exceptionsMap {
+		exceptions = append(exceptions, v)
+	}
+	return exceptions, nil
+}
+
+var _ engine.ExceptionSessionMethods = (*Session)(nil)
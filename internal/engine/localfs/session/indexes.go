@@ -0,0 +1,482 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package session
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"remixdb.io/ast"
+	"remixdb.io/internal/engine"
+	"remixdb.io/internal/engine/localfs/radisk"
+	"remixdb.io/internal/rqltypes"
+)
+
+// Indexes are stored as a tree within the table folder of the struct so that they are moved and
+// deleted with it. The key of each item is the index prefix of the field value followed by the
+// key of the object, and the value is the key of the object. For fields which can be ordered, the
+// prefix is the sort key of the value so that the index can be iterated in order of the field.
+// Whilst a index is being built, a file named building is present within its folder. If building
+// the index failed, the building file is replaced with a file named failed containing the error.
+
+// Gets the names of the fields in the struct which are marked with the decorator.
+func fieldsWithDecorator(structToken *ast.StructToken, method string) []string {
+	fields := []string{}
+	for _, f := range structToken.Fields {
+		field, ok := f.(ast.FieldToken)
+		if !ok {
+			continue
+		}
+		for _, decorator := range field.Decorators {
+			if decorator.Method == method {
+				fields = append(fields, field.Name)
+				break
+			}
+		}
+	}
+	return fields
+}
+
+// Gets the names of the fields in the struct which have a index. Fields marked with @unique are
+// indexed so that conflicts can be found.
+func indexedFields(structToken *ast.StructToken) []string {
+	fields := fieldsWithDecorator(structToken, "index")
+	for _, field := range fieldsWithDecorator(structToken, "unique") {
+		found := false
+		for _, f := range fields {
+			if f == field {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// Gets the path to the folder used to store the index for the field.
+func getIndexPath(relativePath, structName, field string) string {
+	return filepath.Join(
+		getStructTablePath(relativePath, structName),
+		"i_"+base64.URLEncoding.EncodeToString([]byte(field)))
+}
+
+// Gets the tree used to store the index for the field.
+func (s *Session) getIndexTree(structName, field string) radisk.TreeIO {
+	return radisk.TreeIO{
+		FS: &transactionFS{
+			t:    s.Transaction,
+			path: getIndexPath(s.RelativePath, structName, field),
+		},
+	}
+}
+
+// Checks if the index is still being built.
+func (s *Session) indexBuilding(structName, field string) (bool, error) {
+	_, err := s.Transaction.ReadFile(filepath.Join(getIndexPath(s.RelativePath, structName, field), "building"))
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// Gets the reason building the index failed. Returns a blank string if it did not fail.
+func (s *Session) indexFailure(structName, field string) (string, error) {
+	b, err := s.Transaction.ReadFile(filepath.Join(getIndexPath(s.RelativePath, structName, field), "failed"))
+	if err == nil {
+		return string(b), nil
+	}
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return "", err
+}
+
+// Marks the index as failed. Anything written to the index whilst building it is dropped.
+func (s *Session) markIndexFailed(structName, field string, reason error) {
+	path := getIndexPath(s.RelativePath, structName, field)
+	s.Transaction.DeleteAll(path)
+	s.Transaction.MkdirAll(path)
+	s.Transaction.WriteFile(filepath.Join(path, "failed"), []byte(reason.Error()))
+}
+
+// Gets the prefix used for all objects in the index with the encoded value of the type specified.
+// The type is treated as optional since missing fields are indexed as null.
+func indexPrefix(t rqltypes.Type, value []byte) ([]byte, error) {
+	if rqltypes.Orderable(t) {
+		t.Optional = true
+		return rqltypes.SortKey(t, value)
+	}
+	b := binary.LittleEndian.AppendUint32(make([]byte, 0, 4+len(value)), uint32(len(value)))
+	return append(b, value...), nil
+}
+
+// Gets the encoded value of each field from the encoded object. Fields which are null are
+// encoded as null.
+func indexValues(fields []string, object []byte) ([][]byte, error) {
+	raw, err := rqltypes.StructFields(object)
+	if err != nil {
+		return nil, err
+	}
+	values := make([][]byte, len(fields))
+	for i, field := range fields {
+		v, ok := raw[field]
+		if !ok {
+			v = []byte{0x00}
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// Updates the indexes for the object. If oldObject is nil, the object did not exist before. If
+// newObject is nil, the object is being deleted.
+func (s *Session) updateIndexes(structToken *ast.StructToken, key, oldObject, newObject []byte) error {
+	// Get the indexed fields.
+	fields := indexedFields(structToken)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	// Get the old and new values of the fields.
+	var oldValues, newValues [][]byte
+	var err error
+	if oldObject != nil {
+		if oldValues, err = indexValues(fields, oldObject); err != nil {
+			return err
+		}
+	}
+	if newObject != nil {
+		if newValues, err = indexValues(fields, newObject); err != nil {
+			return err
+		}
+	}
+
+	types, err := rqltypes.FieldsFromSession(s, structToken)
+	if err != nil {
+		return err
+	}
+	for i, field := range fields {
+		// Skip the field if the value did not change.
+		if oldValues != nil && newValues != nil && string(oldValues[i]) == string(newValues[i]) {
+			continue
+		}
+
+		// Remove the old value and add the new one.
+		tree := s.getIndexTree(structToken.Name, field)
+		if oldValues != nil {
+			prefix, err := indexPrefix(types[field], oldValues[i])
+			if err != nil {
+				return err
+			}
+			err = tree.DeleteValue(append(prefix, key...))
+			if err != nil && err != radisk.ErrNotFound {
+				return err
+			}
+		}
+		if newValues != nil {
+			prefix, err := indexPrefix(types[field], newValues[i])
+			if err != nil {
+				return err
+			}
+			if err := tree.SetValue(append(prefix, key...), key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Gets the latest version of the struct and makes sure the field has a index which is ready.
+func (s *Session) readyIndex(structName, field string) (*ast.StructToken, error) {
+	// Get the latest version of the struct.
+	structHistory, err := s.GetStructByKey(structName)
+	if err != nil {
+		return nil, err
+	}
+	latest := structHistory[len(structHistory)-1]
+
+	// Make sure the field is indexed and the index is ready.
+	indexed := false
+	for _, f := range indexedFields(latest) {
+		if f == field {
+			indexed = true
+			break
+		}
+	}
+	if !indexed {
+		return nil, engine.ErrNotIndexed
+	}
+	status, err := s.indexStatus(latest.Name, field)
+	if err != nil {
+		return nil, err
+	}
+	if status.Building || status.Error != "" {
+		return nil, engine.ErrNotIndexed
+	}
+	return latest, nil
+}
+
+// Gets the status of the index on the field.
+func (s *Session) indexStatus(structName, field string) (status engine.IndexStatus, err error) {
+	if status.Building, err = s.indexBuilding(structName, field); err != nil {
+		return engine.IndexStatus{}, err
+	}
+	if status.Error, err = s.indexFailure(structName, field); err != nil {
+		return engine.IndexStatus{}, err
+	}
+	return status, nil
+}
+
+func (s *Session) StructIndexStatus(structName, field string) (status engine.IndexStatus, err error) {
+	// Get the latest version of the struct.
+	structHistory, err := s.GetStructByKey(structName)
+	if err != nil {
+		return engine.IndexStatus{}, err
+	}
+	latest := structHistory[len(structHistory)-1]
+
+	// Make sure the field is indexed and get the status.
+	for _, f := range indexedFields(latest) {
+		if f == field {
+			return s.indexStatus(latest.Name, field)
+		}
+	}
+	return engine.IndexStatus{}, engine.ErrNotIndexed
+}
+
+func (s *Session) LookupStructIndex(structName, field string, value []byte) (keys [][]byte, err error) {
+	// Make sure the index is ready.
+	structToken, err := s.readyIndex(structName, field)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the keys of all the objects with the value.
+	return s.indexKeys(structToken, field, value)
+}
+
+// Implements engine.StructObjectIterator on top of a index. The objects are read from the table
+// as the iterator moves.
+type structIndexIterator struct {
+	s           *Session
+	table       radisk.TreeIO
+	structToken *ast.StructToken
+	it          *radisk.Iterator
+}
+
+func (it *structIndexIterator) Next() bool { return it.it.Next() }
+
+func (it *structIndexIterator) Key() []byte {
+	// The value of each item in the index is the key of the object, so this can't error.
+	key, _ := it.it.Value()
+	return key
+}
+
+func (it *structIndexIterator) Value() ([]byte, error) {
+	key, err := it.it.Value()
+	if err != nil {
+		return nil, err
+	}
+	return it.s.readObject(it.table, it.structToken, key)
+}
+
+func (it *structIndexIterator) Err() error { return it.it.Err() }
+
+func (s *Session) IterateStructIndex(
+	structName, field string, options engine.StructIteratorOptions,
+) (engine.StructObjectIterator, error) {
+	// Make sure the index is ready and can be iterated in order.
+	structToken, err := s.readyIndex(structName, field)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := rqltypes.FieldsFromSession(s, structToken)
+	if err != nil {
+		return nil, err
+	}
+	fieldType := fields[field]
+	if !rqltypes.Orderable(fieldType) {
+		return nil, engine.ErrNotIndexed
+	}
+	table, _, err := s.getStructTable(structToken.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build the options for the tree.
+	treeOptions := radisk.IteratorOptions{Reverse: options.Reverse}
+	if options.Value != nil {
+		if treeOptions.Prefix, err = indexPrefix(fieldType, options.Value); err != nil {
+			return nil, err
+		}
+	}
+	if options.After != nil {
+		after, err := indexPrefix(fieldType, options.AfterValue)
+		if err != nil {
+			return nil, err
+		}
+		treeOptions.After = append(after, options.After...)
+	}
+	return &structIndexIterator{
+		s:           s,
+		table:       table,
+		structToken: structToken,
+		it:          s.getIndexTree(structToken.Name, field).Iterate(treeOptions),
+	}, nil
+}
+
+// Gets the keys of all the objects in the index with the encoded value.
+func (s *Session) indexKeys(structToken *ast.StructToken, field string, value []byte) ([][]byte, error) {
+	fields, err := rqltypes.FieldsFromSession(s, structToken)
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := indexPrefix(fields[field], value)
+	if err != nil {
+		return nil, err
+	}
+	keys := [][]byte{}
+	it := s.getIndexTree(structToken.Name, field).Iterate(radisk.IteratorOptions{Prefix: prefix})
+	for it.Next() {
+		key, err := it.Value()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, it.Err()
+}
+
+// Marks the indexes as needing to be built. The indexes are built in the background once the
+// session is committed. Indexes which are already marked are skipped, since deleting the folder
+// again within the same transaction would drop the marker when the journal is replayed.
+func (s *Session) markIndexesForBuild(structName string, fields []string) error {
+	for _, field := range fields {
+		building, err := s.indexBuilding(structName, field)
+		if err != nil {
+			return err
+		}
+		if building {
+			continue
+		}
+		path := getIndexPath(s.RelativePath, structName, field)
+		s.Transaction.DeleteAll(path)
+		s.Transaction.MkdirAll(path)
+		s.Transaction.WriteFile(filepath.Join(path, "building"), []byte{})
+	}
+	s.pendingJobs = true
+	return nil
+}
+
+// Builds a index by going through every object within the struct. Objects which have not been
+// migrated yet are migrated before they are indexed so that the index does not depend on the
+// migration finishing first.
+func (s *Session) buildIndex(structToken *ast.StructToken, field string) error {
+	// Get the table and any pending migration.
+	table, _, err := s.getStructTable(structToken.Name)
+	if err != nil {
+		return err
+	}
+	steps, err := s.pendingMigration(structToken.Name)
+	if err != nil {
+		return err
+	}
+
+	// Add each object to the index. If the field is unique, make sure no objects share the value.
+	unique := false
+	for _, f := range fieldsWithDecorator(structToken, "unique") {
+		if f == field {
+			unique = true
+			break
+		}
+	}
+	fields, err := rqltypes.FieldsFromSession(s, structToken)
+	if err != nil {
+		return err
+	}
+	fieldType := fields[field]
+	tree := s.getIndexTree(structToken.Name, field)
+	it := table.Iterate(radisk.IteratorOptions{})
+	for it.Next() {
+		key := it.Key()
+		object, err := it.Value()
+		if err != nil {
+			return err
+		}
+		if object, err = s.migrateObject(structToken.Name, steps, key, object); err != nil {
+			return err
+		}
+		values, err := indexValues([]string{field}, object)
+		if err != nil {
+			return err
+		}
+		if unique {
+			if err := s.checkUniqueValue(structToken, field, key, values[0]); err != nil {
+				return err
+			}
+		}
+		prefix, err := indexPrefix(fieldType, values[0])
+		if err != nil {
+			return err
+		}
+		if err := tree.SetValue(append(prefix, key...), key); err != nil {
+			return err
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	// Mark the index as built.
+	s.Transaction.Delete(filepath.Join(getIndexPath(s.RelativePath, structToken.Name, field), "building"))
+	return nil
+}
+
+// BuildPendingIndexes is used to build any indexes which were added to existing structs. A write
+// lock is acquired on each struct with indexes being built and its table. If a index cannot be
+// built, it is marked as failed and the other indexes are still built. The errors are returned
+// once every index has been tried. The session should be committed after, even if a error is
+// returned, so that the failures are saved.
+func (s *Session) BuildPendingIndexes() error {
+	// Get all of the structs.
+	structs, err := s.Structs()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, structToken := range structs {
+		for _, field := range indexedFields(structToken) {
+			// Check if the index needs building.
+			building, err := s.indexBuilding(structToken.Name, field)
+			if err != nil {
+				return err
+			}
+			if !building {
+				continue
+			}
+
+			// Lock the struct and its table and build the index.
+			if err := s.ensureStructWriteLock(structToken.Name); err != nil {
+				return err
+			}
+			s.ensureTableWriteLock(structToken.Name)
+			if err := s.buildIndex(structToken, field); err != nil {
+				s.markIndexFailed(structToken.Name, field, err)
+				errs = append(errs, fmt.Errorf("%s.%s: %w", structToken.Name, field, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,202 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package session
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"remixdb.io/ast"
+	"remixdb.io/internal/engine"
+	"remixdb.io/internal/query"
+)
+
+// Creates the tree struct with the decorator specified on the name field.
+func treeStructWithNameDecorators(decorators ...ast.DecoratorToken) *ast.StructToken {
+	structToken := treeStruct()
+	field := structToken.Fields[1].(ast.FieldToken)
+	field.Decorators = append([]ast.DecoratorToken{}, decorators...)
+	structToken.Fields[1] = field
+	return structToken
+}
+
+// Inserts a tree for each key with the name specified.
+func insertTestTrees(t *testing.T, s *Session, names map[string]string) {
+	t.Helper()
+	for key, name := range names {
+		object, err := encodeTestObject(s, "Tree", map[string]any{"id": 1, "name": name})
+		require.NoError(t, err)
+		_, err = s.InsertStructObject("Tree", []byte(key), object)
+		require.NoError(t, err)
+	}
+}
+
+// Gets the keys of the objects within the index iterator.
+func iteratorKeys(t *testing.T, it engine.StructObjectIterator) []string {
+	t.Helper()
+	keys := []string{}
+	for it.Next() {
+		keys = append(keys, string(it.Key()))
+	}
+	require.NoError(t, it.Err())
+	return keys
+}
+
+// Gets the keys of the objects where the field has the string value.
+func lookupTestIndex(t *testing.T, s *Session, field, value string) ([]string, error) {
+	t.Helper()
+	keys, err := s.LookupStructIndex("Tree", field, encodeTestValue(t, "string", value))
+	if err != nil {
+		return nil, err
+	}
+	strs := make([]string, len(keys))
+	for i, key := range keys {
+		strs[i] = string(key)
+	}
+	return strs, nil
+}
+
+// Wraps a session to count how many lookups the query path makes using a index that is ready.
+type lookupCountingSession struct {
+	*Session
+	lookups int
+}
+
+func (s *lookupCountingSession) LookupStructIndex(structName, field string, value []byte) ([][]byte, error) {
+	keys, err := s.Session.LookupStructIndex(structName, field, value)
+	if err == nil {
+		s.lookups++
+	}
+	return keys, err
+}
+
+// Runs a query for all the trees with the name. Returns how many were found and if the index
+// was used.
+func queryTreesByName(t *testing.T, s *Session, name string) (int, bool) {
+	t.Helper()
+	counting := &lookupCountingSession{Session: s}
+	v, err := query.Run(counting, &query.Locks{}, query.Query{Struct: "Tree", Action: query.ActionAll}, map[string]any{
+		query.ParamWhere: map[string]any{"name": name},
+	})
+	require.NoError(t, err)
+	return len(v.([]map[string]any)), counting.lookups != 0
+}
+
+func TestSession_indexMaintenance(t *testing.T) {
+	dataFolder := t.TempDir()
+	cache := &Cache{}
+	writeTestStructs(t, dataFolder, cache, treeStruct())
+
+	// Insert the objects. The index is updated within the transaction.
+	s := newTestSession(t, dataFolder, cache)
+	insertTestTrees(t, s, map[string]string{"tree 1": "oak", "tree 2": "oak", "tree 3": "elm"})
+	keys, err := lookupTestIndex(t, s, "name", "oak")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tree 1", "tree 2"}, keys)
+
+	// Update and delete objects. The old values are removed from the index.
+	object, err := encodeTestObject(s, "Tree", map[string]any{"id": 2, "name": "elm"})
+	require.NoError(t, err)
+	require.NoError(t, s.UpdateStructObject("Tree", []byte("tree 2"), object))
+	require.NoError(t, s.DeleteStructObject("Tree", []byte("tree 3")))
+	commitTestSession(t, s)
+
+	// Read the index from the disk.
+	s = newTestSession(t, dataFolder, &Cache{})
+	keys, err = lookupTestIndex(t, s, "name", "oak")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tree 1"}, keys)
+	keys, err = lookupTestIndex(t, s, "name", "elm")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tree 2"}, keys)
+	it, err := s.IterateStructIndex("Tree", "name", engine.StructIteratorOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tree 2", "tree 1"}, iteratorKeys(t, it))
+
+	// Fields without a index cannot be looked up.
+	_, err = lookupTestIndex(t, s, "id", "1")
+	assert.Equal(t, engine.ErrNotIndexed, err)
+	_, err = s.StructIndexStatus("Tree", "id")
+	assert.Equal(t, engine.ErrNotIndexed, err)
+}
+
+func TestSession_BuildPendingIndexes(t *testing.T) {
+	dataFolder := t.TempDir()
+	cache := &Cache{}
+	writeTestStructs(t, dataFolder, cache, treeStructWithNameDecorators())
+	s := newTestSession(t, dataFolder, cache)
+	insertTestTrees(t, s, map[string]string{"tree 1": "oak", "tree 2": "elm", "tree 3": "oak"})
+	commitTestSession(t, s)
+
+	// Add the index. It is not used until it is built.
+	writeTestStructs(t, dataFolder, cache, treeStruct())
+	s = newTestSession(t, dataFolder, cache)
+	status, err := s.StructIndexStatus("Tree", "name")
+	require.NoError(t, err)
+	assert.Equal(t, engine.IndexStatus{Building: true}, status)
+	_, err = lookupTestIndex(t, s, "name", "oak")
+	assert.Equal(t, engine.ErrNotIndexed, err)
+	count, usedIndex := queryTreesByName(t, s, "oak")
+	assert.Equal(t, 2, count)
+	assert.False(t, usedIndex)
+
+	// Build the index in the background session.
+	require.NoError(t, s.BuildPendingIndexes())
+	commitTestSession(t, s)
+
+	// The queries now use the index.
+	s = newTestSession(t, dataFolder, cache)
+	status, err = s.StructIndexStatus("Tree", "name")
+	require.NoError(t, err)
+	assert.Equal(t, engine.IndexStatus{}, status)
+	keys, err := lookupTestIndex(t, s, "name", "oak")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tree 1", "tree 3"}, keys)
+	count, usedIndex = queryTreesByName(t, s, "oak")
+	assert.Equal(t, 2, count)
+	assert.True(t, usedIndex)
+}
+
+func TestSession_BuildPendingIndexes_failure(t *testing.T) {
+	dataFolder := t.TempDir()
+	cache := &Cache{}
+	writeTestStructs(t, dataFolder, cache, treeStructWithNameDecorators())
+	s := newTestSession(t, dataFolder, cache)
+	insertTestTrees(t, s, map[string]string{"tree 1": "oak", "tree 2": "oak"})
+	commitTestSession(t, s)
+
+	// Make the name unique. The index cannot be built since two objects share a name.
+	unique := treeStructWithNameDecorators(ast.DecoratorToken{Method: "unique"})
+	writeTestStructs(t, dataFolder, cache, unique)
+	s = newTestSession(t, dataFolder, cache)
+	err := s.BuildPendingIndexes()
+	var uniqueErr engine.UniqueConstraintError
+	require.True(t, errors.As(err, &uniqueErr), "expected a unique constraint error, got %v", err)
+	commitTestSession(t, s)
+
+	// The failure is saved and the index is not used.
+	s = newTestSession(t, dataFolder, &Cache{})
+	status, err := s.StructIndexStatus("Tree", "name")
+	require.NoError(t, err)
+	assert.Equal(t, engine.IndexStatus{Error: "the value of Tree.name must be unique"}, status)
+	_, err = lookupTestIndex(t, s, "name", "oak")
+	assert.Equal(t, engine.ErrNotIndexed, err)
+
+	// Remove the duplicate and write the struct again. The index is built again.
+	require.NoError(t, s.DeleteStructObject("Tree", []byte("tree 2")))
+	require.NoError(t, s.WriteStruct(unique, false))
+	commitTestSession(t, s)
+	s = newTestSession(t, dataFolder, cache)
+	status, err = s.StructIndexStatus("Tree", "name")
+	require.NoError(t, err)
+	assert.Equal(t, engine.IndexStatus{Building: true}, status)
+	require.NoError(t, s.BuildPendingIndexes())
+	commitTestSession(t, s)
+	s = newTestSession(t, dataFolder, cache)
+	keys, err := lookupTestIndex(t, s, "name", "oak")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tree 1"}, keys)
+}
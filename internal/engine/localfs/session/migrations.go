@@ -0,0 +1,256 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package session
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"remixdb.io/ast"
+	"remixdb.io/internal/engine"
+	"remixdb.io/internal/engine/localfs/radisk"
+	"remixdb.io/internal/engine/migrations"
+)
+
+// Migrations which have not been applied to every object are stored within the table folder of
+// the struct. The file named migration holds the steps which are pending, and the tree within the
+// migrated folder maps the key of each object written since the migration started to the number of
+// steps which were already applied to it. Objects which are not in the tree have had no steps
+// applied. Objects are migrated when they are read, and every object is migrated in the background
+// once the session which wrote the struct is committed.
+
+// Defines how many objects are migrated before they are written to the table.
+const migrationBatchSize = 1000
+
+// Gets the path to the file used to store the pending migration steps for the struct.
+func getMigrationPath(relativePath, structName string) string {
+	return filepath.Join(getStructTablePath(relativePath, structName), "migration")
+}
+
+// Gets the tree used to store how many steps have been applied to objects written since the
+// migration started.
+func (s *Session) getMigratedTree(structName string) radisk.TreeIO {
+	return radisk.TreeIO{
+		FS: &transactionFS{
+			t:    s.Transaction,
+			path: filepath.Join(getStructTablePath(s.RelativePath, structName), "migrated"),
+		},
+	}
+}
+
+// Gets the migration steps which have not been applied to every object within the struct.
+func (s *Session) pendingMigration(structName string) ([]migrations.Step, error) {
+	b, err := s.Transaction.ReadFile(getMigrationPath(s.RelativePath, structName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var steps []migrations.Step
+	if err := msgpack.Unmarshal(b, &steps); err != nil {
+		return nil, err
+	}
+	return steps, nil
+}
+
+// Migrates a object which was read from the table to the latest version of the struct.
+func (s *Session) migrateObject(structName string, steps []migrations.Step, key, object []byte) ([]byte, error) {
+	if len(steps) == 0 {
+		return object, nil
+	}
+
+	// Get how many steps have already been applied.
+	applied := 0
+	b, err := s.getMigratedTree(structName).GetValue(key)
+	if err == nil {
+		applied = int(binary.LittleEndian.Uint32(b))
+	} else if err != radisk.ErrNotFound {
+		return nil, err
+	}
+	return migrations.Apply(steps[applied:], object)
+}
+
+// Records that the object with the key is at the latest version of the struct. If deleted is
+// true, the record is removed since the object is being deleted.
+func (s *Session) setMigrated(structName string, steps []migrations.Step, key []byte, deleted bool) error {
+	if len(steps) == 0 {
+		return nil
+	}
+	tree := s.getMigratedTree(structName)
+	if deleted {
+		err := tree.DeleteValue(key)
+		if err == radisk.ErrNotFound {
+			err = nil
+		}
+		return err
+	}
+	return tree.SetValue(key, binary.LittleEndian.AppendUint32(nil, uint32(len(steps))))
+}
+
+// Plans the migration from the old version of the struct to the new version and queues it. If the
+// struct has no objects, there is nothing to migrate.
+func (s *Session) queueMigration(oldStruct, newStruct *ast.StructToken, force bool) error {
+	// Check if there are any objects.
+	table := radisk.TreeIO{
+		FS: &transactionFS{
+			t:    s.Transaction,
+			path: getStructTablePath(s.RelativePath, newStruct.Name),
+		},
+	}
+	it := table.Iterate(radisk.IteratorOptions{})
+	if !it.Next() {
+		return it.Err()
+	}
+
+	// Plan the migration.
+	steps, err := migrations.Plan(oldStruct, newStruct, force)
+	if err != nil || len(steps) == 0 {
+		return err
+	}
+
+	// Add the steps to any which are already pending.
+	pending, err := s.pendingMigration(newStruct.Name)
+	if err != nil {
+		return err
+	}
+	b, err := msgpack.Marshal(append(pending, steps...))
+	if err != nil {
+		return err
+	}
+	s.Transaction.WriteFile(getMigrationPath(s.RelativePath, newStruct.Name), b)
+
+	// Rebuild any indexes on fields which are changed since the stored values will change.
+	changed := map[string]struct{}{}
+	for _, step := range steps {
+		changed[step.Field] = struct{}{}
+	}
+	rebuild := []string{}
+	for _, field := range indexedFields(newStruct) {
+		if _, ok := changed[field]; ok {
+			rebuild = append(rebuild, field)
+		}
+	}
+	s.pendingJobs = true
+	return s.markIndexesForBuild(newStruct.Name, rebuild)
+}
+
+// Applies the pending migration to every object within the struct.
+func (s *Session) migrateTable(structToken *ast.StructToken, steps []migrations.Step) error {
+	// Get the table. If the struct is no longer a table, there are no objects to migrate.
+	table, _, err := s.getStructTable(structToken.Name)
+	if err != nil && err != engine.ErrNotTable {
+		return err
+	}
+	if err == nil {
+		// Count the objects so the progress can be reported.
+		total := uint64(0)
+		it := table.Iterate(radisk.IteratorOptions{})
+		for it.Next() {
+			total++
+		}
+		if err := it.Err(); err != nil {
+			return err
+		}
+		s.Cache.setMigrationProgress(s.PartitionName, structToken.Name, 0, total)
+
+		// Migrate the objects in batches since the table cannot be written whilst iterating.
+		type migratedObject struct {
+			key, object []byte
+		}
+		var after []byte
+		migrated := uint64(0)
+		for {
+			batch := make([]migratedObject, 0, migrationBatchSize)
+			read := 0
+			it := table.Iterate(radisk.IteratorOptions{After: after})
+			for read < migrationBatchSize && it.Next() {
+				read++
+				after = it.Key()
+				object, err := it.Value()
+				if err != nil {
+					return err
+				}
+				newObject, err := s.migrateObject(structToken.Name, steps, after, object)
+				if err != nil {
+					return err
+				}
+				if !bytes.Equal(object, newObject) {
+					batch = append(batch, migratedObject{key: after, object: newObject})
+				}
+			}
+			if err := it.Err(); err != nil {
+				return err
+			}
+			if read == 0 {
+				break
+			}
+			for _, x := range batch {
+				if err := table.SetValue(x.key, x.object); err != nil {
+					return err
+				}
+			}
+			migrated += uint64(read)
+			s.Cache.setMigrationProgress(s.PartitionName, structToken.Name, migrated, total)
+		}
+	}
+
+	// Drop the migration.
+	s.Transaction.Delete(getMigrationPath(s.RelativePath, structToken.Name))
+	s.Transaction.DeleteAll(filepath.Join(getStructTablePath(s.RelativePath, structToken.Name), "migrated"))
+	return nil
+}
+
+// RunPendingMigrations is used to migrate every object within structs which have a pending
+// migration. A write lock is acquired on each struct being migrated and its table. The session
+// should be committed after.
+func (s *Session) RunPendingMigrations() error {
+	// Get all of the structs.
+	structs, err := s.Structs()
+	if err != nil {
+		return err
+	}
+
+	for _, structToken := range structs {
+		// Check if there is a pending migration.
+		steps, err := s.pendingMigration(structToken.Name)
+		if err != nil {
+			return err
+		}
+		if len(steps) == 0 {
+			continue
+		}
+
+		// Lock the struct and its table and migrate it.
+		if err := s.ensureStructWriteLock(structToken.Name); err != nil {
+			return err
+		}
+		s.ensureTableWriteLock(structToken.Name)
+		if err := s.migrateTable(structToken, steps); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Session) StructMigrationProgress(structName string) (progress engine.MigrationProgress, err error) {
+	// Get the latest version of the struct.
+	structHistory, err := s.GetStructByKey(structName)
+	if err != nil {
+		return engine.MigrationProgress{}, err
+	}
+	latest := structHistory[len(structHistory)-1]
+
+	// Check if there is a pending migration.
+	steps, err := s.pendingMigration(latest.Name)
+	if err != nil || len(steps) == 0 {
+		return engine.MigrationProgress{}, err
+	}
+	progress = s.Cache.getMigrationProgress(s.PartitionName, latest.Name)
+	progress.Pending = true
+	return progress, nil
+}
@@ -0,0 +1,280 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"remixdb.io/ast"
+	"remixdb.io/internal/engine"
+	"remixdb.io/internal/engine/localfs/acid"
+	"remixdb.io/internal/engine/localfs/radisk"
+	"remixdb.io/internal/engine/migrations"
+)
+
+// Implements radisk.Filesystem on top of the transaction so that all page writes are journaled.
+type transactionFS struct {
+	t       *acid.Transaction
+	path    string
+	dirMade bool
+}
+
+func (fs *transactionFS) GetPage(page uint64) ([]byte, error) {
+	b, err := fs.t.ReadFile(filepath.Join(fs.path, "p_"+strconv.FormatUint(page, 10)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, radisk.ErrNotFound
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+func (fs *transactionFS) SetPage(page uint64, data []byte) error {
+	// Make sure the folder is created before the page is moved into it on commit.
+	if !fs.dirMade {
+		fs.t.MkdirAll(fs.path)
+		fs.dirMade = true
+	}
+	fs.t.WriteFile(filepath.Join(fs.path, "p_"+strconv.FormatUint(page, 10)), data)
+	return nil
+}
+
+var _ radisk.Filesystem = (*transactionFS)(nil)
+
+// Gets the tree used to store the objects for the struct specified and the latest version of the struct.
+func (s *Session) getStructTable(structName string) (radisk.TreeIO, *ast.StructToken, error) {
+	// Get the latest version of the struct.
+	structHistory, err := s.GetStructByKey(structName)
+	if err != nil {
+		return radisk.TreeIO{}, nil, err
+	}
+	latest := structHistory[len(structHistory)-1]
+
+	// Make sure the struct is a table.
+	for _, decorator := range latest.Decorators {
+		if decorator.Method == "notable" {
+			return radisk.TreeIO{}, nil, engine.ErrNotTable
+		}
+	}
+
+	// Return the tree.
+	return radisk.TreeIO{
+		FS: &transactionFS{
+			t:    s.Transaction,
+			path: getStructTablePath(s.RelativePath, latest.Name),
+		},
+	}, latest, nil
+}
+
+// Acquires a write lock on the table of the struct if the session does not already have one. The
+// pages of the table and its indexes are shared by every object, so two sessions writing to the
+// same table would overwrite each others pages on commit. The lock is held until the session is
+// closed since the pages written are only visible to other sessions once it is committed. The #
+// is used since it cannot be in a struct name. Struct locks must be acquired before this lock.
+func (s *Session) ensureTableWriteLock(structName string) {
+	if s.openTableUnlockers == nil {
+		s.openTableUnlockers = map[string]func(){}
+	}
+	if _, ok := s.openTableUnlockers[structName]; ok {
+		return
+	}
+	l := s.getPartitionNamedLocks()
+	lockName := "#table " + structName
+	l.Lock(lockName)
+	s.openTableUnlockers[structName] = func() {
+		l.Unlock(lockName)
+	}
+}
+
+// Gets the table for the struct and acquires a write lock on it before anything is read from it.
+func (s *Session) getWritableStructTable(structName string) (radisk.TreeIO, *ast.StructToken, error) {
+	tree, structToken, err := s.getStructTable(structName)
+	if err != nil {
+		return radisk.TreeIO{}, nil, err
+	}
+	s.ensureTableWriteLock(structToken.Name)
+	return tree, structToken, nil
+}
+
+func (s *Session) InsertStructObject(structName string, key, value []byte) (object []byte, err error) {
+	// Get the table and lock it for writing.
+	tree, structToken, err := s.getWritableStructTable(structName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure the object does not already exist.
+	_, err = tree.GetValue(key)
+	if err == nil {
+		return nil, engine.ErrAlreadyExists
+	}
+	if err != radisk.ErrNotFound {
+		return nil, err
+	}
+
+	// Apply the field decorators and check the enum and unique fields.
+	object, err = s.applyInsertDecorators(structToken, value)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkEnums(structToken, object); err != nil {
+		return nil, err
+	}
+	if err := s.checkUnique(structToken, key, object); err != nil {
+		return nil, err
+	}
+
+	// Write the object and index it. If a migration is pending, the object is already migrated.
+	if err := tree.SetValue(key, object); err != nil {
+		return nil, err
+	}
+	if err := s.updateIndexes(structToken, key, nil, object); err != nil {
+		return nil, err
+	}
+	steps, err := s.pendingMigration(structToken.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.setMigrated(structToken.Name, steps, key, false); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+func (s *Session) GetStructObject(structName string, key []byte) (value []byte, err error) {
+	// Get the table.
+	tree, structToken, err := s.getStructTable(structName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the object and migrate it if it was written by a older version of the struct.
+	return s.readObject(tree, structToken, key)
+}
+
+// Reads the object from the table and migrates it to the latest version of the struct.
+func (s *Session) readObject(tree radisk.TreeIO, structToken *ast.StructToken, key []byte) ([]byte, error) {
+	object, err := tree.GetValue(key)
+	if err != nil {
+		if err == radisk.ErrNotFound {
+			return nil, engine.ErrNotExists
+		}
+		return nil, err
+	}
+	steps, err := s.pendingMigration(structToken.Name)
+	if err != nil {
+		return nil, err
+	}
+	return s.migrateObject(structToken.Name, steps, key, object)
+}
+
+func (s *Session) UpdateStructObject(structName string, key, value []byte) error {
+	// Get the table and lock it for writing.
+	tree, structToken, err := s.getWritableStructTable(structName)
+	if err != nil {
+		return err
+	}
+
+	// Make sure the object exists.
+	oldValue, err := s.readObject(tree, structToken, key)
+	if err != nil {
+		return err
+	}
+
+	// Check the enum and unique fields.
+	if err := s.checkEnums(structToken, value); err != nil {
+		return err
+	}
+	if err := s.checkUnique(structToken, key, value); err != nil {
+		return err
+	}
+
+	// Write the object and update the indexes. If a migration is pending, the object is now migrated.
+	if err := tree.SetValue(key, value); err != nil {
+		return err
+	}
+	if err := s.updateIndexes(structToken, key, oldValue, value); err != nil {
+		return err
+	}
+	steps, err := s.pendingMigration(structToken.Name)
+	if err != nil {
+		return err
+	}
+	return s.setMigrated(structToken.Name, steps, key, false)
+}
+
+func (s *Session) DeleteStructObject(structName string, key []byte) error {
+	// Get the table and lock it for writing.
+	tree, structToken, err := s.getWritableStructTable(structName)
+	if err != nil {
+		return err
+	}
+
+	// Get the object so it can be removed from the indexes.
+	oldValue, err := s.readObject(tree, structToken, key)
+	if err != nil {
+		return err
+	}
+
+	// Delete the object and remove it from the indexes and any pending migration.
+	if err := tree.DeleteValue(key); err != nil {
+		return err
+	}
+	if err := s.updateIndexes(structToken, key, oldValue, nil); err != nil {
+		return err
+	}
+	steps, err := s.pendingMigration(structToken.Name)
+	if err != nil {
+		return err
+	}
+	return s.setMigrated(structToken.Name, steps, key, true)
+}
+
+// Implements engine.StructObjectIterator on top of the table. Objects are migrated to the latest
+// version of the struct as they are read.
+type structObjectIterator struct {
+	s          *Session
+	structName string
+	steps      []migrations.Step
+	it         *radisk.Iterator
+}
+
+func (it *structObjectIterator) Next() bool { return it.it.Next() }
+
+func (it *structObjectIterator) Key() []byte { return it.it.Key() }
+
+func (it *structObjectIterator) Value() ([]byte, error) {
+	object, err := it.it.Value()
+	if err != nil {
+		return nil, err
+	}
+	return it.s.migrateObject(it.structName, it.steps, it.it.Key(), object)
+}
+
+func (it *structObjectIterator) Err() error { return it.it.Err() }
+
+func (s *Session) IterateStructObjects(structName string, options engine.StructIteratorOptions) (engine.StructObjectIterator, error) {
+	// Get the table.
+	tree, structToken, err := s.getStructTable(structName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get any pending migration and create the iterator.
+	steps, err := s.pendingMigration(structToken.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &structObjectIterator{
+		s:          s,
+		structName: structToken.Name,
+		steps:      steps,
+		it:         tree.Iterate(radisk.IteratorOptions{After: options.After, Reverse: options.Reverse}),
+	}, nil
+}
+
+var _ engine.StructObjectSessionMethods = (*Session)(nil)
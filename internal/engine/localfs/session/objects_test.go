@@ -0,0 +1,181 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package session
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"remixdb.io/ast"
+	"remixdb.io/internal/engine"
+	"remixdb.io/internal/rqltypes"
+)
+
+// Writes the structs in a session and commits them.
+func writeTestStructs(t *testing.T, dataFolder string, cache *Cache, structs ...*ast.StructToken) {
+	t.Helper()
+	s := newTestSession(t, dataFolder, cache)
+	for _, structToken := range structs {
+		require.NoError(t, s.WriteStruct(structToken, false))
+	}
+	commitTestSession(t, s)
+}
+
+// Encodes a value of the builtin type specified.
+func encodeTestValue(t *testing.T, type_ string, v any) []byte {
+	t.Helper()
+	b, err := rqltypes.Encode(rqltypes.Parse(type_), v, nil)
+	require.NoError(t, err)
+	return b
+}
+
+// Encodes a object of the struct using the latest version of it within the session.
+func encodeTestObject(s *Session, structName string, fields map[string]any) ([]byte, error) {
+	return rqltypes.Encode(rqltypes.Type{Name: structName}, fields, rqltypes.SessionResolver(s))
+}
+
+// Creates a struct with a indexed name field.
+func treeStruct() *ast.StructToken {
+	return &ast.StructToken{
+		Name: "Tree",
+		Fields: []any{
+			ast.FieldToken{Name: "id", Type: "int", Decorators: []ast.DecoratorToken{}},
+			ast.FieldToken{Name: "name", Type: "string", Decorators: []ast.DecoratorToken{{Method: "index"}}},
+		},
+	}
+}
+
+func TestSession_structObjects(t *testing.T) {
+	dataFolder := t.TempDir()
+	cache := &Cache{}
+	writeTestStructs(t, dataFolder, cache, treeStruct(), &ast.StructToken{
+		Name:       "Planter",
+		Decorators: []ast.DecoratorToken{{Method: "notable"}},
+		Fields:     []any{},
+	})
+	s := newTestSession(t, dataFolder, cache)
+	oak, err := encodeTestObject(s, "Tree", map[string]any{"id": 1, "name": "oak"})
+	require.NoError(t, err)
+	elm, err := encodeTestObject(s, "Tree", map[string]any{"id": 1, "name": "elm"})
+	require.NoError(t, err)
+
+	// Insert a object. Inserting the same key again fails.
+	_, err = s.InsertStructObject("Tree", []byte("tree 1"), oak)
+	require.NoError(t, err)
+	_, err = s.InsertStructObject("Tree", []byte("tree 1"), elm)
+	assert.Equal(t, engine.ErrAlreadyExists, err)
+	got, err := s.GetStructObject("Tree", []byte("tree 1"))
+	require.NoError(t, err)
+	assert.Equal(t, oak, got)
+
+	// Objects which do not exist cannot be read, updated or deleted.
+	_, err = s.GetStructObject("Tree", []byte("tree 2"))
+	assert.Equal(t, engine.ErrNotExists, err)
+	assert.Equal(t, engine.ErrNotExists, s.UpdateStructObject("Tree", []byte("tree 2"), elm))
+	assert.Equal(t, engine.ErrNotExists, s.DeleteStructObject("Tree", []byte("tree 2")))
+
+	// Update the object and insert another.
+	require.NoError(t, s.UpdateStructObject("Tree", []byte("tree 1"), elm))
+	_, err = s.InsertStructObject("Tree", []byte("tree 2"), oak)
+	require.NoError(t, err)
+	got, err = s.GetStructObject("Tree", []byte("tree 1"))
+	require.NoError(t, err)
+	assert.Equal(t, elm, got)
+
+	// Structs which are not tables and structs which do not exist have no objects.
+	_, err = s.InsertStructObject("Planter", []byte("planter 1"), oak)
+	assert.Equal(t, engine.ErrNotTable, err)
+	_, err = s.GetStructObject("Bush", []byte("bush 1"))
+	assert.Equal(t, engine.ErrNotExists, err)
+	commitTestSession(t, s)
+
+	// Delete a object and roll the session back. The object is only gone within the session.
+	s = newTestSession(t, dataFolder, cache)
+	require.NoError(t, s.DeleteStructObject("Tree", []byte("tree 2")))
+	_, err = s.GetStructObject("Tree", []byte("tree 2"))
+	assert.Equal(t, engine.ErrNotExists, err)
+	assert.Equal(t, engine.ErrNotExists, s.DeleteStructObject("Tree", []byte("tree 2")))
+	require.NoError(t, s.Close())
+
+	// The committed objects are read back from the disk.
+	s = newTestSession(t, dataFolder, &Cache{})
+	got, err = s.GetStructObject("Tree", []byte("tree 1"))
+	require.NoError(t, err)
+	assert.Equal(t, elm, got)
+	got, err = s.GetStructObject("Tree", []byte("tree 2"))
+	require.NoError(t, err)
+	assert.Equal(t, oak, got)
+}
+
+func TestSession_concurrentTableWrites(t *testing.T) {
+	dataFolder := t.TempDir()
+	cache := &Cache{}
+	writeTestStructs(t, dataFolder, cache, treeStruct())
+
+	// Insert, update and delete objects from two sessions at once. Each session rewrites the
+	// shared pages of the table and the index, so without the table lock the session which
+	// commits last would drop the writes of the other.
+	const perSession = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	for session := 0; session < 2; session++ {
+		s := newTestSession(t, dataFolder, cache)
+		wg.Add(1)
+		go func(session int) {
+			defer wg.Done()
+			defer s.Close()
+			run := func() error {
+				for i := 0; i < perSession; i++ {
+					id := session*perSession + i
+					key := []byte("tree " + strconv.Itoa(id))
+					object, err := encodeTestObject(s, "Tree", map[string]any{"id": id, "name": "oak"})
+					if err != nil {
+						return err
+					}
+					if _, err := s.InsertStructObject("Tree", key, object); err != nil {
+						return err
+					}
+				}
+				object, err := encodeTestObject(s, "Tree", map[string]any{"id": session * perSession, "name": "elm"})
+				if err != nil {
+					return err
+				}
+				if err := s.UpdateStructObject("Tree", []byte("tree "+strconv.Itoa(session*perSession)), object); err != nil {
+					return err
+				}
+				if err := s.DeleteStructObject("Tree", []byte("tree "+strconv.Itoa(session*perSession+1))); err != nil {
+					return err
+				}
+				return s.Commit()
+			}
+			errs <- run()
+		}(session)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	// Make sure every write from both sessions made it to the table and the index.
+	s := newTestSession(t, dataFolder, cache)
+	it, err := s.IterateStructObjects("Tree", engine.StructIteratorOptions{})
+	require.NoError(t, err)
+	count := 0
+	for it.Next() {
+		count++
+	}
+	require.NoError(t, it.Err())
+	assert.Equal(t, 2*perSession-2, count)
+
+	oak, err := s.LookupStructIndex("Tree", "name", encodeTestValue(t, "string", "oak"))
+	require.NoError(t, err)
+	assert.Len(t, oak, 2*perSession-4)
+	elm, err := s.LookupStructIndex("Tree", "name", encodeTestValue(t, "string", "elm"))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, [][]byte{[]byte("tree 0"), []byte("tree " + strconv.Itoa(perSession))}, elm)
+}
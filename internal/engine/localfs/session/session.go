@@ -4,6 +4,8 @@
 package session
 
 import (
+	"path/filepath"
+
 	"go.uber.org/zap"
 	"remixdb.io/internal/engine"
 	"remixdb.io/internal/engine/localfs/acid"
@@ -35,8 +37,25 @@ type Session struct {
 	// Unlocker is used to unlock the partition.
 	Unlocker func()
 
-	openObjectUnlockers map[string]map[string]func()
-	openStructUnlockers map[string]func()
+	// BackgroundJobs is called after a commit which changed existing structs. It should migrate the
+	// objects with RunPendingMigrations and then build the indexes with BuildPendingIndexes in the
+	// background.
+	BackgroundJobs func()
+
+	pendingJobs bool
+
+	// pendingRenames holds the old and new names of the structs renamed within the session. The
+	// migration progress in the cache is moved once the renames are committed.
+	pendingRenames [][2]string
+
+	// schemaChanged is true once the session has journaled a change to the schema. The schema
+	// is not cached after this since the cache is shared with other sessions.
+	schemaChanged bool
+
+	openObjectUnlockers  map[string]map[string]func()
+	openStructUnlockers  map[string]func()
+	openCounterUnlockers map[string]func()
+	openTableUnlockers   map[string]func()
 }
 
 func (s *Session) getObjectUnlockersMap(structName string) map[string]func() {
@@ -61,6 +80,14 @@ func (s *Session) getStructUnlockersMap() map[string]func() {
 	return s.openStructUnlockers
 }
 
+// Journals a file within the partition which holds part of the schema and marks the schema as
+// changed within the session. The cache must never hold anything which was not committed, so
+// nothing the session reads after this is cached.
+func (s *Session) writeSchemaFile(name string, b []byte) {
+	s.schemaChanged = true
+	s.Transaction.WriteFile(filepath.Join(s.RelativePath, name), b)
+}
+
 func (s *Session) ensureWriteLock() error {
 	if !s.SchemaWriteLock {
 		return engine.ErrReadOnlySession
@@ -73,7 +100,24 @@ func (s *Session) Rollback() error {
 }
 
 func (s *Session) Commit() error {
-	return s.Transaction.Commit(true)
+	if err := s.Transaction.Commit(true); err != nil {
+		return err
+	}
+
+	// Move the migration progress of any renamed structs now the renames are committed.
+	for _, rename := range s.pendingRenames {
+		s.Cache.renameMigrationProgress(s.PartitionName, rename[0], rename[1])
+	}
+	s.pendingRenames = nil
+
+	// Start any migrations and index builds which were queued in this commit.
+	if s.pendingJobs {
+		s.pendingJobs = false
+		if s.BackgroundJobs != nil {
+			s.BackgroundJobs()
+		}
+	}
+	return nil
 }
 
 func (s *Session) Close() error {
@@ -100,6 +144,20 @@ func (s *Session) Close() error {
 		}
 	}
 
+	// Handle any open counter unlockers within the session.
+	if s.openCounterUnlockers != nil {
+		for _, unlocker := range s.openCounterUnlockers {
+			unlocker()
+		}
+	}
+
+	// Handle any open table unlockers within the session.
+	if s.openTableUnlockers != nil {
+		for _, unlocker := range s.openTableUnlockers {
+			unlocker()
+		}
+	}
+
 	// Unlock the partition.
 	s.Unlocker()
 
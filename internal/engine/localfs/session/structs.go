@@ -55,8 +55,10 @@ func (s *Session) loadStructs() (map[string]possibleRename, error) {
 		return nil, err
 	}
 
-	// Cache the structs file.
-	s.Cache.structs.Set(s.PartitionName, structs)
+	// Cache the structs file unless the schema was changed within the session.
+	if !s.schemaChanged {
+		s.Cache.structs.Set(s.PartitionName, structs)
+	}
 
 	// Return the structs.
 	return structs, nil
@@ -185,21 +187,38 @@ func (s *Session) DeleteStructByKey(key string) error {
 		return engine.ErrNotExists
 	}
 
-	// Find all previous names for the struct.
-	structHistory := v.S
-	names := map[string]struct{}{}
-	for _, s := range structHistory {
-		names[s.Name] = struct{}{}
-	}
-
 	// Drop from the cache.
 	s.Cache.structs.Delete(s.PartitionName)
 
-	// Kill all names related to this struct.
-	for name := range names {
-		delete(structs, name)
+	// Kill the struct and all names which were renamed to it. Note that we do not go by the
+	// history since a old name may have been reused by another struct.
+	delete(structs, key)
+	for name, v := range structs {
+		if v.R != nil && *v.R == key {
+			delete(structs, name)
+		}
+	}
+
+	// Journal the structs file and tombstones file.
+	if err := s.writeStructs(structs); err != nil {
+		return err
 	}
+	s.writeStructTombstone(v.S)
 
+	// Journal deleting the struct folder.
+	s.Transaction.DeleteAll(getStructTablePath(s.RelativePath, key))
+
+	// Return no errors.
+	return nil
+}
+
+// Gets the path to the folder used to store the objects for the struct name specified.
+func getStructTablePath(relativePath, structName string) string {
+	return filepath.Join(relativePath, "tables", base64.URLEncoding.EncodeToString([]byte(structName)))
+}
+
+// Journals the structs file.
+func (s *Session) writeStructs(structs map[string]possibleRename) error {
 	// Marshal the structs contents.
 	b, err := msgpack.Marshal(structs)
 	if err != nil {
@@ -216,16 +235,184 @@ func (s *Session) DeleteStructByKey(key string) error {
 	if err := w.Close(); err != nil {
 		return err
 	}
-	b = buf.Bytes()
 
-	// Journal the structs file and tombstones file.
-	s.Transaction.WriteFile(filepath.Join(s.RelativePath, "structs"), b)
-	s.writeStructTombstone(structHistory)
+	// Journal the structs file.
+	s.writeSchemaFile("structs", buf.Bytes())
+	return nil
+}
 
-	// Journal deleting the struct folder.
-	s.Transaction.DeleteAll(filepath.Join(s.RelativePath, "tables", base64.URLEncoding.EncodeToString([]byte(key))))
+// Makes sure that the name is not used by a type other than the kind specified. Structs, exceptions,
+// and enums share the same names since they can all be used as types, so ErrAlreadyExists is
+// returned if another kind of type has the name.
+func (s *Session) ensureTypeNameFree(name, kind string) error {
+	lookups := map[string]func() error{
+		"struct": func() error {
+			_, err := s.GetStructByKey(name)
+			return err
+		},
+		"exception": func() error {
+			_, err := s.GetExceptionByKey(name)
+			return err
+		},
+		"enum": func() error {
+			_, err := s.GetEnumByKey(name)
+			return err
+		},
+	}
+	for k, lookup := range lookups {
+		if k == kind {
+			continue
+		}
+		if err := lookup(); err == nil {
+			return engine.ErrAlreadyExists
+		} else if err != engine.ErrNotExists {
+			return err
+		}
+	}
+	return nil
+}
 
-	// Return no errors.
+// Loads the structs for writing. The structs are dropped from the cache since they are about
+// to be changed.
+func (s *Session) loadStructsForWrite() (map[string]possibleRename, error) {
+	structs, err := s.loadStructs()
+	if err != nil {
+		if err == engine.ErrNotExists {
+			return map[string]possibleRename{}, nil
+		}
+		return nil, err
+	}
+	s.Cache.structs.Delete(s.PartitionName)
+	return structs, nil
+}
+
+func (s *Session) WriteStruct(structToken *ast.StructToken, force bool) error {
+	// Ensure the session has a write lock.
+	if err := s.ensureWriteLock(); err != nil {
+		return err
+	}
+
+	// Make sure the field decorators are valid.
+	if err := s.validateFieldDecorators(structToken); err != nil {
+		return err
+	}
+
+	// Make sure the name is not used by another type.
+	if err := s.ensureTypeNameFree(structToken.Name, "struct"); err != nil {
+		return err
+	}
+
+	// Load the structs for this partition.
+	structs, err := s.loadStructsForWrite()
+	if err != nil {
+		return err
+	}
+
+	// Append to the history if the struct exists. If the name is a old name of another struct,
+	// this is a new struct which takes over the name.
+	v := structs[structToken.Name]
+	if v.R != nil {
+		v = possibleRename{}
+	}
+
+	// Queue migrating the objects to the new version.
+	if len(v.S) != 0 {
+		if err := s.queueMigration(v.S[len(v.S)-1], structToken, force); err != nil {
+			return err
+		}
+	}
+
+	// Handle any changes to the indexes. New indexes on a existing struct need to be built, and
+	// indexes which were removed are deleted. Indexes which failed to build are built again.
+	if len(v.S) != 0 {
+		previous := map[string]struct{}{}
+		for _, field := range indexedFields(v.S[len(v.S)-1]) {
+			previous[field] = struct{}{}
+		}
+		added := []string{}
+		for _, field := range indexedFields(structToken) {
+			if _, ok := previous[field]; !ok {
+				added = append(added, field)
+				continue
+			}
+			delete(previous, field)
+			failure, err := s.indexFailure(structToken.Name, field)
+			if err != nil {
+				return err
+			}
+			if failure != "" {
+				added = append(added, field)
+			}
+		}
+		for field := range previous {
+			s.Transaction.DeleteAll(getIndexPath(s.RelativePath, structToken.Name, field))
+		}
+		if len(added) != 0 {
+			if err := s.markIndexesForBuild(structToken.Name, added); err != nil {
+				return err
+			}
+		}
+	}
+	history := make([]*ast.StructToken, len(v.S), len(v.S)+1)
+	copy(history, v.S)
+	structs[structToken.Name] = possibleRename{
+		S: append(history, structToken),
+	}
+
+	// Journal the structs file.
+	return s.writeStructs(structs)
+}
+
+func (s *Session) RenameStruct(oldName, newName string) error {
+	// Ensure the session has a write lock.
+	if err := s.ensureWriteLock(); err != nil {
+		return err
+	}
+
+	// Load the structs for this partition.
+	structs, err := s.loadStructsForWrite()
+	if err != nil {
+		return err
+	}
+
+	// Make sure the old name is a struct and the new name is free.
+	v, ok := structs[oldName]
+	if !ok || v.R != nil {
+		return engine.ErrNotExists
+	}
+	if n, ok := structs[newName]; ok && n.R == nil {
+		return engine.ErrAlreadyExists
+	}
+	if err := s.ensureTypeNameFree(newName, "struct"); err != nil {
+		return err
+	}
+
+	// Append the renamed struct to the history.
+	renamed := *v.S[len(v.S)-1]
+	renamed.Name = newName
+	history := make([]*ast.StructToken, len(v.S), len(v.S)+1)
+	copy(history, v.S)
+	structs[newName] = possibleRename{
+		S: append(history, &renamed),
+	}
+
+	// Point the old name and anything that pointed to it at the new name.
+	for name, x := range structs {
+		if x.R != nil && *x.R == oldName {
+			structs[name] = possibleRename{R: &newName}
+		}
+	}
+	structs[oldName] = possibleRename{R: &newName}
+
+	// Journal the structs file.
+	if err := s.writeStructs(structs); err != nil {
+		return err
+	}
+
+	// Journal moving the objects to the new table folder. Any pending migration moves with the
+	// table, so its progress moves too when the session is committed.
+	s.Transaction.Rename(getStructTablePath(s.RelativePath, oldName), getStructTablePath(s.RelativePath, newName))
+	s.pendingRenames = append(s.pendingRenames, [2]string{oldName, newName})
 	return nil
 }
 
@@ -250,7 +437,7 @@ postError:
 		structs[i] = v.S[len(v.S)-1]
 		i++
 	}
-	return structs, nil
+	return structs[:i], nil
 }
 
 func (s *Session) StructTombstones() (renames map[string]string, structs []*ast.StructToken, err error) {
@@ -455,6 +642,14 @@ func (s *Session) AcquireStructWriteLock(structNames ...string) error {
 	return nil
 }
 
+// Acquires a write lock on the struct if the session does not already have one.
+func (s *Session) ensureStructWriteLock(structName string) error {
+	if _, ok := s.getStructUnlockersMap()[structName]; ok {
+		return nil
+	}
+	return s.AcquireStructWriteLock(structName)
+}
+
 func (s *Session) ReleaseStructWriteLock(structNames ...string) error {
 	// Get the struct locker for this partition.
 	l := s.getPartitionNamedLocks()
@@ -0,0 +1,179 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"remixdb.io/ast"
+	"remixdb.io/internal/engine"
+	"remixdb.io/internal/engine/localfs/acid"
+)
+
+// Creates a schema write session for the partition within the data folder. Sessions with the same
+// cache share the cached schema and locks like sessions from the same engine do. The session is
+// closed when the test ends if it was not closed before.
+func newTestSession(t *testing.T, dataFolder string, cache *Cache) *Session {
+	t.Helper()
+	relativePath := filepath.Join("partitions", "test")
+	require.NoError(t, os.MkdirAll(filepath.Join(dataFolder, relativePath), 0755))
+	closed := false
+	s := &Session{
+		Logger:          zap.NewNop().Sugar(),
+		Transaction:     acid.New(dataFolder),
+		Cache:           cache,
+		PartitionName:   "test",
+		DataFolder:      dataFolder,
+		RelativePath:    relativePath,
+		SchemaWriteLock: true,
+		Unlocker:        func() { closed = true },
+	}
+	t.Cleanup(func() {
+		if !closed {
+			_ = s.Close()
+		}
+	})
+	return s
+}
+
+// Commits the session and closes it so that the locks it holds are released.
+func commitTestSession(t *testing.T, s *Session) {
+	t.Helper()
+	require.NoError(t, s.Commit())
+	require.NoError(t, s.Close())
+}
+
+func TestSession_structTokensRoundTrip(t *testing.T) {
+	dataFolder := t.TempDir()
+	structToken := &ast.StructToken{
+		Name:       "Tree",
+		Decorators: []ast.DecoratorToken{{Method: "index", Arguments: "name"}},
+		Fields: []any{
+			ast.CommentToken{Comment: " The ID of the tree.", Position: 14},
+			ast.FieldToken{
+				Name:       "id",
+				Type:       "int",
+				Position:   40,
+				Decorators: []ast.DecoratorToken{{Method: "primary", Position: 35}},
+			},
+			ast.ReferenceToken{Name: "planter", Position: 52, Decorators: []ast.DecoratorToken{}},
+		},
+	}
+
+	s := newTestSession(t, dataFolder, &Cache{})
+	require.NoError(t, s.WriteStruct(structToken, false))
+	commitTestSession(t, s)
+
+	// Read the struct back with a new cache so that it is decoded from disk. The tokens within the
+	// fields must keep their types rather than being decoded as maps.
+	history, err := newTestSession(t, dataFolder, &Cache{}).GetStructByKey("Tree")
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, structToken, history[0])
+}
+
+func TestSession_WriteStruct(t *testing.T) {
+	dataFolder := t.TempDir()
+	cache := &Cache{}
+	writeTestStructs(t, dataFolder, cache, treeStruct())
+
+	// Add a field to the struct. The new version is appended to the history.
+	second := treeStruct()
+	second.Fields = append(second.Fields, ast.FieldToken{Name: "height", Type: "int?", Decorators: []ast.DecoratorToken{}})
+	writeTestStructs(t, dataFolder, cache, second)
+
+	// Read the history back from the disk.
+	history, err := newTestSession(t, dataFolder, &Cache{}).GetStructByKey("Tree")
+	require.NoError(t, err)
+	assert.Equal(t, []*ast.StructToken{treeStruct(), second}, history)
+}
+
+func TestSession_RenameStruct(t *testing.T) {
+	dataFolder := t.TempDir()
+	cache := &Cache{}
+	writeTestStructs(t, dataFolder, cache, treeStruct(), &ast.StructToken{Name: "Forest", Fields: []any{}})
+	s := newTestSession(t, dataFolder, cache)
+	require.NoError(t, s.WriteEnum(&ast.EnumToken{Name: "Kind", Values: []any{ast.EnumValueToken{Name: "Oak"}}}))
+	object, err := encodeTestObject(s, "Tree", map[string]any{"id": 1, "name": "oak"})
+	require.NoError(t, err)
+	_, err = s.InsertStructObject("Tree", []byte("tree 1"), object)
+	require.NoError(t, err)
+	commitTestSession(t, s)
+
+	// Renaming to a name used by another struct or another type fails, as does renaming a struct
+	// which does not exist.
+	s = newTestSession(t, dataFolder, cache)
+	assert.Equal(t, engine.ErrAlreadyExists, s.RenameStruct("Tree", "Forest"))
+	assert.Equal(t, engine.ErrAlreadyExists, s.RenameStruct("Tree", "Kind"))
+	assert.Equal(t, engine.ErrNotExists, s.RenameStruct("Bush", "Shrub"))
+
+	// Rename the struct whilst a migration has reported progress and roll it back. The progress
+	// stays with the old name.
+	cache.setMigrationProgress("test", "Tree", 1, 2)
+	require.NoError(t, s.RenameStruct("Tree", "Plant"))
+	require.NoError(t, s.Close())
+	assert.Equal(t, engine.MigrationProgress{Migrated: 1, Total: 2}, cache.getMigrationProgress("test", "Tree"))
+	assert.Equal(t, engine.MigrationProgress{}, cache.getMigrationProgress("test", "Plant"))
+
+	// Rename the struct again and commit it. The progress moves with it.
+	s = newTestSession(t, dataFolder, cache)
+	require.NoError(t, s.RenameStruct("Tree", "Plant"))
+	assert.Equal(t, engine.MigrationProgress{Migrated: 1, Total: 2}, cache.getMigrationProgress("test", "Tree"))
+	commitTestSession(t, s)
+	assert.Equal(t, engine.MigrationProgress{}, cache.getMigrationProgress("test", "Tree"))
+	assert.Equal(t, engine.MigrationProgress{Migrated: 1, Total: 2}, cache.getMigrationProgress("test", "Plant"))
+
+	// Reload the structs from the disk. The old name points to the new one and the objects moved
+	// to the new table.
+	s = newTestSession(t, dataFolder, &Cache{})
+	history, err := s.GetStructByKey("Tree")
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "Tree", history[0].Name)
+	assert.Equal(t, "Plant", history[1].Name)
+	got, err := s.GetStructObject("Plant", []byte("tree 1"))
+	require.NoError(t, err)
+	assert.Equal(t, object, got)
+	got, err = s.GetStructObject("Tree", []byte("tree 1"))
+	require.NoError(t, err)
+	assert.Equal(t, object, got)
+}
+
+func TestSession_rollbackSchema(t *testing.T) {
+	dataFolder := t.TempDir()
+	cache := &Cache{}
+
+	// Change the schema and read it back within the session. Reading after a write would cache
+	// the changes if the session did not stop using the cache.
+	s := newTestSession(t, dataFolder, cache)
+	require.NoError(t, s.WriteStruct(treeStruct(), false))
+	require.NoError(t, s.WriteEnum(&ast.EnumToken{Name: "Kind", Values: []any{ast.EnumValueToken{Name: "Oak"}}}))
+	require.NoError(t, s.WriteException(&ast.ExceptionToken{Name: "Fallen", Fields: []any{}}))
+	require.NoError(t, s.WriteContract(&ast.ContractToken{Name: "Plant", ReturnType: "void"}))
+	_, err := s.GetStructByKey("Tree")
+	require.NoError(t, err)
+	_, err = s.GetEnumByKey("Kind")
+	require.NoError(t, err)
+	_, err = s.GetExceptionByKey("Fallen")
+	require.NoError(t, err)
+	_, err = s.GetContractByKey("Plant")
+	require.NoError(t, err)
+
+	// Roll the session back. A new session must not see any of the changes.
+	require.NoError(t, s.Close())
+	s = newTestSession(t, dataFolder, cache)
+	_, err = s.GetStructByKey("Tree")
+	assert.Equal(t, engine.ErrNotExists, err)
+	_, err = s.GetEnumByKey("Kind")
+	assert.Equal(t, engine.ErrNotExists, err)
+	_, err = s.GetExceptionByKey("Fallen")
+	assert.Equal(t, engine.ErrNotExists, err)
+	_, err = s.GetContractByKey("Plant")
+	assert.Equal(t, engine.ErrNotExists, err)
+}
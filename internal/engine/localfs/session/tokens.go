@@ -0,0 +1,93 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package session
+
+import (
+	"reflect"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"remixdb.io/ast"
+)
+
+// Tokens which are stored within an any (such as struct fields and contract statements) would be
+// decoded as maps by msgpack. To stop this, each of them is registered as a extension type so that
+// the type survives being written to disk. Tokens which are only ever stored in typed fields are
+// not registered so that existing data can still be decoded. Do not reorder this list since the
+// index is the extension ID written to disk.
+var anyTokens = []any{
+	ast.CommentToken{},
+	ast.FieldToken{},
+	ast.ReferenceToken{},
+	ast.ExtendsToken{},
+	ast.ReturnToken{},
+	ast.StringLiteralToken{},
+	ast.NumberLiteralToken{},
+	ast.FloatLiteralToken{},
+	ast.BigIntLiteralToken{},
+	ast.BooleanLiteralToken{},
+	ast.ArrayLiteralToken{},
+	ast.ObjectLiteralToken{},
+	ast.NullLiteralToken{},
+	ast.MethodCallToken{},
+	ast.AssignmentToken{},
+	ast.AddToken{},
+	ast.LessThanToken{},
+	ast.GreaterThanToken{},
+	ast.LessThanOrEqualToken{},
+	ast.GreaterThanOrEqualToken{},
+	ast.EqualToken{},
+	ast.NotEqualToken{},
+	ast.AndToken{},
+	ast.OrToken{},
+	ast.MultiplyToken{},
+	ast.SubtractToken{},
+	ast.DivideToken{},
+	ast.ModuloToken{},
+	ast.ExponentToken{},
+	ast.ThrowLiteralToken{},
+	ast.MappingPartialToken{},
+	ast.MappingToken{},
+	ast.ElseToken{},
+	ast.UnlessToken{},
+	ast.IfToken{},
+	ast.ForToken{},
+	ast.WhileToken{},
+	ast.InlineIfToken{},
+	ast.InlineUnlessToken{},
+	ast.CatchToken{},
+	ast.TryToken{},
+	ast.SwitchCaseToken{},
+	ast.SwitchToken{},
+	ast.NotToken{},
+	ast.EnumValueToken{},
+}
+
+// Registers the token as a msgpack extension. The token is converted to a unnamed struct with the
+// same fields when it is encoded, otherwise the extension encoder would call itself.
+func registerToken(extID int8, token any) {
+	typ := reflect.TypeOf(token)
+	fields := make([]reflect.StructField, typ.NumField())
+	for i := range fields {
+		fields[i] = typ.Field(i)
+	}
+	plain := reflect.StructOf(fields)
+
+	msgpack.RegisterExtEncoder(extID, token, func(_ *msgpack.Encoder, v reflect.Value) ([]byte, error) {
+		return msgpack.Marshal(v.Convert(plain).Interface())
+	})
+	msgpack.RegisterExtDecoder(extID, token, func(d *msgpack.Decoder, v reflect.Value, _ int) error {
+		p := reflect.New(plain)
+		if err := d.DecodeValue(p); err != nil {
+			return err
+		}
+		v.Set(p.Elem().Convert(typ))
+		return nil
+	})
+}
+
+func init() {
+	for i, token := range anyTokens {
+		registerToken(int8(i+1), token)
+	}
+}
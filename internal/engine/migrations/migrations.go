@@ -0,0 +1,333 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package migrations
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+
+	"remixdb.io/ast"
+	"remixdb.io/internal/engine"
+	"remixdb.io/internal/rqltypes"
+)
+
+// StepType is used to define what a migration step does to a object.
+type StepType string
+
+const (
+	// StepRemove removes the field.
+	StepRemove StepType = "remove"
+
+	// StepRename moves the value of the field named From to the field.
+	StepRename StepType = "rename"
+
+	// StepConvert converts the value of the field from the type From to the type To.
+	StepConvert StepType = "convert"
+
+	// StepFill sets the field to Value if it is null.
+	StepFill StepType = "fill"
+)
+
+// Step is used to define a single change to the objects within a struct. Steps are applied in
+// order and do not depend on the struct tokens, so they can be stored and applied later.
+type Step struct {
+	// Type is the type of the step.
+	Type StepType
+
+	// Field is the name of the field the step changes.
+	Field string
+
+	// From is the old name of the field for StepRename, or the old type for StepConvert.
+	From string `msgpack:",omitempty"`
+
+	// To is the new type for StepConvert.
+	To string `msgpack:",omitempty"`
+
+	// Value is the encoded value for StepFill.
+	Value []byte `msgpack:",omitempty"`
+}
+
+// Gets the fields of the struct in order.
+func structFields(structToken *ast.StructToken) []ast.FieldToken {
+	fields := []ast.FieldToken{}
+	for _, f := range structToken.Fields {
+		if field, ok := f.(ast.FieldToken); ok {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// Gets the decorator on the field and if it exists.
+func decorator(field ast.FieldToken, method string) (ast.DecoratorToken, bool) {
+	for _, d := range field.Decorators {
+		if d.Method == method {
+			return d, true
+		}
+	}
+	return ast.DecoratorToken{}, false
+}
+
+// Checks if values of the old type can be converted to the new type. Both types should not be
+// optional. If they can, convert is true if the encoded values change, and lossy is true if some
+// values cannot be stored exactly. Integers widen to bigints without losing data, but floats only
+// hold integers up to 2^53 exactly.
+func widens(from, to rqltypes.Type) (ok, convert, lossy bool) {
+	// Handle arrays element by element.
+	if from.Elem != nil || to.Elem != nil {
+		if from.Elem == nil || to.Elem == nil {
+			return false, false, false
+		}
+		if from.Elem.Optional && !to.Elem.Optional {
+			return false, false, false
+		}
+		return widens(from.Elem.NonOptional(), to.Elem.NonOptional())
+	}
+
+	// Handle scalars.
+	if from.Name == to.Name {
+		return true, false, false
+	}
+	if from.Name == rqltypes.Int || from.Name == rqltypes.Uint {
+		switch to.Name {
+		case rqltypes.Bigint:
+			return true, true, false
+		case rqltypes.Float:
+			return true, true, true
+		}
+	}
+	return false, false, false
+}
+
+// Gets the encoded @default value of the field. Returns nil if there is no default or it is null.
+func defaultValue(structName string, field ast.FieldToken, t rqltypes.Type) ([]byte, error) {
+	d, ok := decorator(field, "default")
+	if !ok {
+		return nil, nil
+	}
+	v, err := rqltypes.DecoratorValue(t, d)
+	if err != nil {
+		return nil, errors.New(structName + "." + field.Name + ": @default: " + err.Error())
+	}
+	b, err := rqltypes.Encode(t, v, nil)
+	if err != nil {
+		return nil, err
+	}
+	if b[0] == 0x00 {
+		return nil, nil
+	}
+	return b, nil
+}
+
+// Gets the step which fills the field with its default. Returns an error if the field is not
+// optional and there is no default to fill it with.
+func fillStep(structName string, field ast.FieldToken, t rqltypes.Type, reason string) (*Step, error) {
+	value, err := defaultValue(structName, field, t)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		if t.Optional {
+			return nil, nil
+		}
+		return nil, errors.New(structName + "." + field.Name + ": " + reason + " needs a @default")
+	}
+	return &Step{Type: StepFill, Field: field.Name, Value: value}, nil
+}
+
+// Plan is used to plan the steps to migrate the objects within a struct from the old version to
+// the new version. A field is renamed by adding @renamedFrom("oldName") to it. Adding fields,
+// making fields optional and widening int and uint to bigint do not lose data. Removing fields
+// and any other type change would lose data, so a engine.LossyMigrationError is returned unless
+// force is true. When forced, int and uint fields which changed to float are converted, losing
+// precision above 2^53, and other fields which changed type are reset to their default.
+func Plan(oldStruct, newStruct *ast.StructToken, force bool) ([]Step, error) {
+	// Map the old fields by name.
+	oldTypes := map[string]rqltypes.Type{}
+	for _, field := range structFields(oldStruct) {
+		oldTypes[field.Name] = rqltypes.Parse(field.Type)
+	}
+
+	// Find the field the data of each new field comes from. The @renamedFrom decorator is ignored
+	// if the field already exists, since it stays on the field after the rename.
+	sources := map[string]string{}
+	renamedAway := map[string]struct{}{}
+	var removes, renames, converts, fills []Step
+	for _, field := range structFields(newStruct) {
+		sources[field.Name] = field.Name
+		d, ok := decorator(field, "renamedFrom")
+		if !ok {
+			continue
+		}
+		v, err := rqltypes.DecoratorValue(rqltypes.Type{Name: rqltypes.String}, d)
+		if err != nil {
+			return nil, errors.New(newStruct.Name + "." + field.Name + ": @renamedFrom: " + err.Error())
+		}
+		if _, exists := oldTypes[field.Name]; !exists {
+			if _, ok := oldTypes[v.(string)]; ok {
+				sources[field.Name] = v.(string)
+				renamedAway[v.(string)] = struct{}{}
+				renames = append(renames, Step{Type: StepRename, Field: field.Name, From: v.(string)})
+			}
+		}
+	}
+
+	lossy := []string{}
+	consumed := map[string]struct{}{}
+	for _, field := range structFields(newStruct) {
+		to := rqltypes.Parse(field.Type)
+		source := sources[field.Name]
+		from, ok := oldTypes[source]
+		if _, gone := renamedAway[source]; gone && source == field.Name {
+			// The old field with this name was renamed, so this is a new field.
+			ok = false
+		}
+		if !ok {
+			// This is a new field.
+			step, err := fillStep(newStruct.Name, field, to, "adding a non-optional field")
+			if err != nil {
+				return nil, err
+			}
+			if step != nil {
+				fills = append(fills, *step)
+			}
+			continue
+		}
+		consumed[source] = struct{}{}
+		if from.Equal(to) {
+			continue
+		}
+
+		// Handle the type changing.
+		if widen, convert, lossyConvert := widens(from.NonOptional(), to.NonOptional()); widen {
+			if lossyConvert {
+				lossy = append(lossy, "changing the type of "+field.Name+" from "+from.String()+" to "+to.String())
+				if !force {
+					continue
+				}
+			}
+			if convert {
+				converts = append(converts, Step{Type: StepConvert, Field: field.Name, From: from.String(), To: to.String()})
+			}
+			if from.Optional && !to.Optional {
+				step, err := fillStep(newStruct.Name, field, to, "making a optional field non-optional")
+				if err != nil {
+					return nil, err
+				}
+				fills = append(fills, *step)
+			}
+			continue
+		}
+		lossy = append(lossy, "changing the type of "+field.Name+" from "+from.String()+" to "+to.String())
+		if !force {
+			continue
+		}
+		step, err := fillStep(newStruct.Name, field, to, "changing the type of a non-optional field")
+		if err != nil {
+			return nil, err
+		}
+		removes = append(removes, Step{Type: StepRemove, Field: source})
+		if step != nil {
+			fills = append(fills, *step)
+		}
+	}
+
+	// Remove any fields which are gone.
+	for _, field := range structFields(oldStruct) {
+		if _, ok := consumed[field.Name]; !ok {
+			lossy = append(lossy, "removing "+field.Name)
+			removes = append(removes, Step{Type: StepRemove, Field: field.Name})
+		}
+	}
+	if len(lossy) != 0 && !force {
+		return nil, engine.LossyMigrationError{Struct: newStruct.Name, Changes: lossy}
+	}
+
+	// Removes go first so that a rename can take the name of a removed field.
+	steps := append(removes, renames...)
+	steps = append(steps, converts...)
+	return append(steps, fills...), nil
+}
+
+// Converts a decoded value from one type to another. Arrays are returned as a []any.
+func convertValue(v any, from, to rqltypes.Type) any {
+	if v == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer && from.Name != rqltypes.Bigint {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+
+	if from.Elem != nil {
+		a := make([]any, rv.Len())
+		for i := range a {
+			a[i] = convertValue(rv.Index(i).Interface(), *from.Elem, *to.Elem)
+		}
+		return a
+	}
+	switch {
+	case from.Name == rqltypes.Int && to.Name == rqltypes.Bigint:
+		return big.NewInt(rv.Int())
+	case from.Name == rqltypes.Uint && to.Name == rqltypes.Bigint:
+		return new(big.Int).SetUint64(rv.Uint())
+	case from.Name == rqltypes.Int && to.Name == rqltypes.Float:
+		return float64(rv.Int())
+	case from.Name == rqltypes.Uint && to.Name == rqltypes.Float:
+		return float64(rv.Uint())
+	}
+	return rv.Interface()
+}
+
+// Apply is used to apply the steps to a encoded struct object. The object is returned as is if
+// nothing changed.
+func Apply(steps []Step, object []byte) ([]byte, error) {
+	if len(steps) == 0 {
+		return object, nil
+	}
+	name, err := rqltypes.StructName(object)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := rqltypes.StructFields(object)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, step := range steps {
+		switch step.Type {
+		case StepRemove:
+			delete(fields, step.Field)
+		case StepRename:
+			if v, ok := fields[step.From]; ok {
+				fields[step.Field] = v
+				delete(fields, step.From)
+			}
+		case StepConvert:
+			b, ok := fields[step.Field]
+			if !ok {
+				continue
+			}
+			from, to := rqltypes.Parse(step.From), rqltypes.Parse(step.To)
+			v, err := rqltypes.Decode(from, b, nil)
+			if err != nil {
+				return nil, err
+			}
+			if fields[step.Field], err = rqltypes.Encode(to, convertValue(v, from, to), nil); err != nil {
+				return nil, err
+			}
+		case StepFill:
+			if _, ok := fields[step.Field]; !ok {
+				fields[step.Field] = step.Value
+			}
+		default:
+			return nil, errors.New("unknown migration step " + string(step.Type))
+		}
+	}
+	return rqltypes.EncodeStructFields(name, fields), nil
+}
@@ -0,0 +1,265 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package migrations
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"remixdb.io/ast"
+	"remixdb.io/internal/engine"
+	"remixdb.io/internal/rqltypes"
+)
+
+func field(name, type_ string, decorators ...ast.DecoratorToken) ast.FieldToken {
+	return ast.FieldToken{Name: name, Type: type_, Decorators: decorators}
+}
+
+func decorated(method, arguments string) ast.DecoratorToken {
+	return ast.DecoratorToken{Method: method, Arguments: arguments}
+}
+
+func structToken(fields ...ast.FieldToken) *ast.StructToken {
+	s := &ast.StructToken{Name: "User"}
+	for _, f := range fields {
+		s.Fields = append(s.Fields, f)
+	}
+	return s
+}
+
+func encode(t *testing.T, type_ string, v any) []byte {
+	t.Helper()
+	b, err := rqltypes.Encode(rqltypes.Parse(type_), v, nil)
+	require.NoError(t, err)
+	return b
+}
+
+func TestPlan(t *testing.T) {
+	tests := []struct {
+		name string
+
+		old   *ast.StructToken
+		new   *ast.StructToken
+		force bool
+
+		expected    []Step
+		expectedErr string
+	}{
+		{
+			name:     "no changes",
+			old:      structToken(field("name", "string")),
+			new:      structToken(field("name", "string")),
+			expected: []Step{},
+		},
+		{
+			name:     "add optional field",
+			old:      structToken(field("name", "string")),
+			new:      structToken(field("name", "string"), field("age", "int?")),
+			expected: []Step{},
+		},
+		{
+			name: "add field with default",
+			old:  structToken(field("name", "string")),
+			new:  structToken(field("name", "string"), field("age", "int", decorated("default", "1"))),
+			expected: []Step{
+				{Type: StepFill, Field: "age", Value: []byte{0x11}},
+			},
+		},
+		{
+			name:        "add non-optional field without default",
+			old:         structToken(field("name", "string")),
+			new:         structToken(field("name", "string"), field("age", "int")),
+			force:       true,
+			expectedErr: "User.age: adding a non-optional field needs a @default",
+		},
+		{
+			name:     "make field optional",
+			old:      structToken(field("age", "int")),
+			new:      structToken(field("age", "int?")),
+			expected: []Step{},
+		},
+		{
+			name:        "make field non-optional without default",
+			old:         structToken(field("age", "int?")),
+			new:         structToken(field("age", "int")),
+			expectedErr: "User.age: making a optional field non-optional needs a @default",
+		},
+		{
+			name: "make field non-optional with default",
+			old:  structToken(field("age", "int?")),
+			new:  structToken(field("age", "int", decorated("default", "0"))),
+			expected: []Step{
+				{Type: StepFill, Field: "age", Value: []byte{0x10}},
+			},
+		},
+		{
+			name: "widen int to bigint",
+			old:  structToken(field("age", "int")),
+			new:  structToken(field("age", "bigint?")),
+			expected: []Step{
+				{Type: StepConvert, Field: "age", From: "int", To: "bigint?"},
+			},
+		},
+		{
+			name: "widen array elements",
+			old:  structToken(field("scores", "uint[]")),
+			new:  structToken(field("scores", "bigint[]")),
+			expected: []Step{
+				{Type: StepConvert, Field: "scores", From: "uint[]", To: "bigint[]"},
+			},
+		},
+		{
+			name:        "int to float",
+			old:         structToken(field("age", "int")),
+			new:         structToken(field("age", "float")),
+			expectedErr: "changing User would lose data (changing the type of age from int to float), the write must be forced to allow this",
+		},
+		{
+			name:        "uint array to float array",
+			old:         structToken(field("scores", "uint[]")),
+			new:         structToken(field("scores", "float[]")),
+			expectedErr: "changing User would lose data (changing the type of scores from uint[] to float[]), the write must be forced to allow this",
+		},
+		{
+			name:  "int to float forced",
+			old:   structToken(field("age", "int")),
+			new:   structToken(field("age", "float")),
+			force: true,
+			expected: []Step{
+				{Type: StepConvert, Field: "age", From: "int", To: "float"},
+			},
+		},
+		{
+			name: "rename field",
+			old:  structToken(field("name", "string")),
+			new:  structToken(field("username", "string", decorated("renamedFrom", `"name"`))),
+			expected: []Step{
+				{Type: StepRename, Field: "username", From: "name"},
+			},
+		},
+		{
+			name:     "rename already applied",
+			old:      structToken(field("username", "string", decorated("renamedFrom", `"name"`))),
+			new:      structToken(field("username", "string", decorated("renamedFrom", `"name"`))),
+			expected: []Step{},
+		},
+		{
+			name: "rename and reuse name",
+			old:  structToken(field("name", "string")),
+			new: structToken(
+				field("name", "string?"),
+				field("username", "string", decorated("renamedFrom", `"name"`)),
+			),
+			expected: []Step{
+				{Type: StepRename, Field: "username", From: "name"},
+			},
+		},
+		{
+			name:        "remove field",
+			old:         structToken(field("name", "string"), field("age", "int")),
+			new:         structToken(field("name", "string")),
+			expectedErr: "changing User would lose data (removing age), the write must be forced to allow this",
+		},
+		{
+			name:  "remove field forced",
+			old:   structToken(field("name", "string"), field("age", "int")),
+			new:   structToken(field("name", "string")),
+			force: true,
+			expected: []Step{
+				{Type: StepRemove, Field: "age"},
+			},
+		},
+		{
+			name:        "narrow type",
+			old:         structToken(field("age", "bigint")),
+			new:         structToken(field("age", "int")),
+			expectedErr: "changing User would lose data (changing the type of age from bigint to int), the write must be forced to allow this",
+		},
+		{
+			name:  "narrow type forced",
+			old:   structToken(field("age", "bigint")),
+			new:   structToken(field("age", "int?")),
+			force: true,
+			expected: []Step{
+				{Type: StepRemove, Field: "age"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps, err := Plan(tt.old, tt.new, tt.force)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			if len(tt.expected) == 0 {
+				assert.Empty(t, steps)
+			} else {
+				assert.Equal(t, tt.expected, steps)
+			}
+		})
+	}
+}
+
+func TestPlan_lossyError(t *testing.T) {
+	_, err := Plan(structToken(field("a", "string"), field("b", "int")), structToken(), false)
+	assert.Equal(t, engine.LossyMigrationError{
+		Struct:  "User",
+		Changes: []string{"removing a", "removing b"},
+	}, err)
+}
+
+func TestApply(t *testing.T) {
+	object := rqltypes.EncodeStructFields("User", map[string][]byte{
+		"name":   encode(t, "string", "astrid"),
+		"age":    encode(t, "int", 20),
+		"scores": encode(t, "int?[]", []*int{nil, new(int)}),
+		"old":    encode(t, "bool", true),
+	})
+	steps := []Step{
+		{Type: StepRemove, Field: "old"},
+		{Type: StepRename, Field: "username", From: "name"},
+		{Type: StepConvert, Field: "age", From: "int", To: "bigint"},
+		{Type: StepConvert, Field: "scores", From: "int?[]", To: "float?[]"},
+		{Type: StepFill, Field: "admin", Value: encode(t, "bool", false)},
+		{Type: StepFill, Field: "username", Value: encode(t, "string", "unused")},
+	}
+	migrated, err := Apply(steps, object)
+	require.NoError(t, err)
+
+	zero := float64(0)
+	assert.Equal(t, rqltypes.EncodeStructFields("User", map[string][]byte{
+		"username": encode(t, "string", "astrid"),
+		"age":      encode(t, "bigint", big.NewInt(20)),
+		"scores":   encode(t, "float?[]", []*float64{nil, &zero}),
+		"admin":    encode(t, "bool", false),
+	}), migrated)
+}
+
+func TestApply_noSteps(t *testing.T) {
+	object := rqltypes.EncodeStructFields("User", map[string][]byte{})
+	migrated, err := Apply(nil, object)
+	require.NoError(t, err)
+	assert.Equal(t, object, migrated)
+}
+
+func TestApply_intToFloatAboveTwoToThe53(t *testing.T) {
+	// Integers above 2^53 cannot be stored exactly as a float, which is why the conversion needs
+	// to be forced.
+	const above = 1<<53 + 1
+	object := rqltypes.EncodeStructFields("User", map[string][]byte{"age": encode(t, "int", above)})
+	steps, err := Plan(structToken(field("age", "int")), structToken(field("age", "float")), true)
+	require.NoError(t, err)
+	migrated, err := Apply(steps, object)
+	require.NoError(t, err)
+
+	fields, err := rqltypes.StructFields(migrated)
+	require.NoError(t, err)
+	v, err := rqltypes.Decode(rqltypes.Type{Name: rqltypes.Float}, fields["age"], nil)
+	require.NoError(t, err)
+	assert.Equal(t, float64(1<<53), v)
+}
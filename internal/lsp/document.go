@@ -0,0 +1,189 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package lsp
+
+import (
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"remixdb.io/ast"
+)
+
+// Defines a open document and the result of parsing it.
+type document struct {
+	uri    string
+	text   string
+	tokens []any
+	errs   []*ast.ParserError
+}
+
+// Creates a document and parses it. Declarations which fail to parse are skipped, so the tokens
+// are still useful while the document is being edited.
+func newDocument(uri, text string) *document {
+	tokens, errs := ast.ParseAll(text)
+	return &document{uri: uri, text: text, tokens: tokens, errs: errs}
+}
+
+// Gets the position of the byte offset within the document.
+func (d *document) position(offset int) Position {
+	offset = min(max(offset, 0), len(d.text))
+	lineStart := strings.LastIndexByte(d.text[:offset], '\n') + 1
+	character := 0
+	for _, c := range d.text[lineStart:offset] {
+		character += len(utf16.Encode([]rune{c}))
+	}
+	return Position{Line: strings.Count(d.text[:lineStart], "\n"), Character: character}
+}
+
+// Gets the range between the two byte offsets.
+func (d *document) rangeOf(start, end int) Range {
+	return Range{Start: d.position(start), End: d.position(end)}
+}
+
+// Gets the byte offset of the position within the document. Positions past the end of a line are
+// moved to the end of the line.
+func (d *document) offset(p Position) int {
+	offset := 0
+	for line := 0; line < p.Line; line++ {
+		i := strings.IndexByte(d.text[offset:], '\n')
+		if i == -1 {
+			return len(d.text)
+		}
+		offset += i + 1
+	}
+	for character := 0; character < p.Character && offset < len(d.text); {
+		c, size := utf8.DecodeRuneInString(d.text[offset:])
+		if c == '\n' {
+			break
+		}
+		character += len(utf16.Encode([]rune{c}))
+		offset += size
+	}
+	return offset
+}
+
+// Checks if the byte can be within a identifier.
+func isIdentifierByte(b byte) bool {
+	return b == '_' || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+}
+
+// Gets the identifier at the byte offset along with where it starts and ends. The word is blank
+// if there is no identifier there.
+func (d *document) wordAt(offset int) (word string, start, end int) {
+	start, end = offset, offset
+	for start > 0 && isIdentifierByte(d.text[start-1]) {
+		start--
+	}
+	for end < len(d.text) && isIdentifierByte(d.text[end]) {
+		end++
+	}
+	return d.text[start:end], start, end
+}
+
+// Gets the byte offset after the closing bracket of the declaration at the position. Strings and
+// comments are skipped. If the declaration is not closed, the end of the document is returned.
+func (d *document) declarationEnd(pos int) int {
+	depth := 0
+	opened := false
+	for i := pos; i < len(d.text); i++ {
+		switch d.text[i] {
+		case '/':
+			if i+1 < len(d.text) && d.text[i+1] == '/' {
+				next := strings.IndexByte(d.text[i:], '\n')
+				if next == -1 {
+					return len(d.text)
+				}
+				i += next
+			}
+		case '\'', '"':
+			quote := d.text[i]
+			for i++; i < len(d.text) && d.text[i] != quote; i++ {
+				if d.text[i] == '\\' {
+					i++
+				}
+			}
+		case '{':
+			depth++
+			opened = true
+		case '}':
+			depth--
+			if opened && depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(d.text)
+}
+
+// Gets the byte offset of the name of a declaration which starts at the position. The name is
+// the first time it is found as a whole word after the position.
+func (d *document) nameOffset(pos int, name string) int {
+	for i := pos; i < len(d.text); {
+		j := strings.Index(d.text[i:], name)
+		if j == -1 {
+			break
+		}
+		start, end := i+j, i+j+len(name)
+		if (start == 0 || !isIdentifierByte(d.text[start-1])) && (end == len(d.text) || !isIdentifierByte(d.text[end])) {
+			return start
+		}
+		i = end
+	}
+	return pos
+}
+
+// Gets the root token which contains the byte offset. Returns nil if the offset is not within one.
+func (d *document) tokenAt(offset int) any {
+	for _, t := range d.tokens {
+		pos := rootPosition(t)
+		if offset >= pos && offset < d.declarationEnd(pos) {
+			return t
+		}
+	}
+	return nil
+}
+
+// Gets the position of a root token.
+func rootPosition(t any) int {
+	switch x := t.(type) {
+	case ast.CommentToken:
+		return x.Position
+	case ast.StructToken:
+		return x.Position
+	case ast.ExceptionToken:
+		return x.Position
+	case ast.EnumToken:
+		return x.Position
+	case ast.ExtendsToken:
+		return x.Position
+	case ast.ContractToken:
+		return x.Position
+	case ast.MappingToken:
+		return x.Position
+	}
+	return 0
+}
+
+// Finds the struct, exception, or enum with the name. Extends are not returned since they are
+// not where the struct is declared.
+func (d *document) declaration(name string) any {
+	for _, t := range d.tokens {
+		switch x := t.(type) {
+		case ast.StructToken:
+			if x.Name == name {
+				return x
+			}
+		case ast.ExceptionToken:
+			if x.Name == name {
+				return x
+			}
+		case ast.EnumToken:
+			if x.Name == name {
+				return x
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,297 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package lsp
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"remixdb.io/ast"
+	"remixdb.io/internal/rqltypes"
+	"remixdb.io/internal/schema"
+)
+
+// Defines the decorators which are understood by the engine along with what they do.
+var decorators = []CompletionItem{
+	{Label: "primary", Kind: completionKindKeyword, Detail: "Makes the field the primary key of the struct."},
+	{Label: "unique", Kind: completionKindKeyword, Detail: "Makes sure no two items have the same value for the field."},
+	{Label: "index", Kind: completionKindKeyword, Detail: "Indexes the field so it can be queried quickly."},
+	{Label: "autoincrement", Kind: completionKindKeyword, Detail: "Sets the field to one more than the last item."},
+	{Label: "default", Kind: completionKindKeyword, Detail: "Sets the value of the field when one is not given."},
+	{Label: "notable", Kind: completionKindKeyword, Detail: "Stops the struct from having its own table."},
+	{Label: "status", Kind: completionKindKeyword, Detail: "Sets the HTTP status code of the exception."},
+}
+
+// Defines the keywords which start a declaration.
+var rootKeywords = []string{"struct", "contract", "mapping", "exception", "enum", "extends", "import"}
+
+// Defines the keywords which can be used within a contract.
+var contractKeywords = []string{
+	"if", "unless", "elif", "else", "for", "while", "switch", "case", "default",
+	"try", "catch", "return", "throw", "true", "false", "null",
+}
+
+var (
+	decoratorPrefix = regexp.MustCompile(`@\w*$`)
+	typePrefix      = regexp.MustCompile(`(:|->)\s*[\w<>\[\]]*$`)
+	memberPrefix    = regexp.MustCompile(`(\w+)\.\w*$`)
+)
+
+// Creates completion items for keywords.
+func keywordItems(keywords []string) []CompletionItem {
+	items := make([]CompletionItem, len(keywords))
+	for i, k := range keywords {
+		items[i] = CompletionItem{Label: k, Kind: completionKindKeyword}
+	}
+	return items
+}
+
+// Gets completion items for the structs, exceptions and enums within the document.
+func (d *document) typeItems() []CompletionItem {
+	var items []CompletionItem
+	for _, t := range d.tokens {
+		switch x := t.(type) {
+		case ast.StructToken:
+			items = append(items, CompletionItem{Label: x.Name, Kind: completionKindStruct, Detail: "struct"})
+		case ast.ExceptionToken:
+			items = append(items, CompletionItem{Label: x.Name, Kind: completionKindClass, Detail: "exception"})
+		case ast.EnumToken:
+			items = append(items, CompletionItem{Label: x.Name, Kind: completionKindEnum, Detail: "enum"})
+		}
+	}
+	return items
+}
+
+// Gets completion items for the builtin types.
+func builtinItems() []CompletionItem {
+	items := make([]CompletionItem, 0, len(rqltypes.Builtins))
+	for name := range rqltypes.Builtins {
+		items = append(items, CompletionItem{Label: name, Kind: completionKindTypeParam, Detail: "builtin"})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Label < items[j].Label })
+	return items
+}
+
+// Gets the fields of the struct with the name once any extends are resolved. Returns nil if the
+// struct is not defined.
+func (d *document) structFields(name string) []any {
+	resolved, _ := schema.ResolveExtends(d.tokens)
+	for _, t := range resolved {
+		if s, ok := t.(ast.StructToken); ok && s.Name == name {
+			return s.Fields
+		}
+	}
+	return nil
+}
+
+// Gets completion items for the members of a reference. The reference can be a struct or the
+// argument of the contract the offset is within.
+func (d *document) memberItems(offset int, name string) []CompletionItem {
+	if contract, ok := d.tokenAt(offset).(ast.ContractToken); ok && contract.Argument != nil &&
+		contract.Argument.Name == name {
+		name = contract.Argument.Type
+	}
+
+	var items []CompletionItem
+	for _, f := range d.structFields(name) {
+		switch x := f.(type) {
+		case ast.FieldToken:
+			items = append(items, CompletionItem{Label: x.Name, Kind: completionKindField, Detail: x.Type})
+		case ast.ReferenceToken:
+			items = append(items, CompletionItem{Label: x.Name, Kind: completionKindField, Detail: x.Name})
+		}
+	}
+	return items
+}
+
+// Gets the completion items at the byte offset. The text before the offset on the same line is
+// used to work out what is being typed.
+func (d *document) completion(offset int) []CompletionItem {
+	prefix := d.text[strings.LastIndexByte(d.text[:offset], '\n')+1 : offset]
+
+	// Handle decorators.
+	if decoratorPrefix.MatchString(prefix) {
+		return decorators
+	}
+
+	// Handle types after a field name or within a contract header.
+	token := d.tokenAt(offset)
+	if typePrefix.MatchString(prefix) {
+		if _, ok := token.(ast.MappingToken); !ok {
+			return append(builtinItems(), d.typeItems()...)
+		}
+	}
+
+	// Handle members of a struct or contract argument.
+	if m := memberPrefix.FindStringSubmatch(prefix); m != nil {
+		return d.memberItems(offset, m[1])
+	}
+
+	// Handle everything else based on what the offset is within.
+	switch x := token.(type) {
+	case nil, ast.CommentToken:
+		return keywordItems(rootKeywords)
+	case ast.ContractToken:
+		items := keywordItems(contractKeywords)
+		if x.Argument != nil {
+			items = append(items, CompletionItem{Label: x.Argument.Name, Kind: completionKindField, Detail: x.Argument.Type})
+		}
+		return append(items, d.typeItems()...)
+	default:
+		return d.typeItems()
+	}
+}
+
+// Gets the hover for the byte offset. Structs, exceptions and enums show their declaration, and
+// structs include the fields from any extends.
+func (d *document) hover(offset int) *Hover {
+	word, start, end := d.wordAt(offset)
+	t := d.declaration(word)
+	if t == nil {
+		return nil
+	}
+	if _, ok := t.(ast.StructToken); ok {
+		resolved, _ := schema.ResolveExtends(d.tokens)
+		for _, r := range resolved {
+			if s, ok := r.(ast.StructToken); ok && s.Name == word {
+				t = s
+				break
+			}
+		}
+	}
+	value := "```rql\n" + ast.Format([]any{t}) + "```"
+
+	// Put the doc comment under the declaration.
+	var comment string
+	switch x := t.(type) {
+	case ast.StructToken:
+		comment = x.Comment
+	case ast.ExceptionToken:
+		comment = x.Comment
+	case ast.EnumToken:
+		comment = x.Comment
+	}
+	if comment != "" {
+		value += "\n\n" + comment
+	}
+
+	r := d.rangeOf(start, end)
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: value},
+		Range:    &r,
+	}
+}
+
+// Gets the location of the declaration of the struct, exception or enum at the byte offset.
+func (d *document) definition(offset int) *Location {
+	word, _, _ := d.wordAt(offset)
+	var pos int
+	switch x := d.declaration(word).(type) {
+	case ast.StructToken:
+		pos = x.Position
+	case ast.ExceptionToken:
+		pos = x.Position
+	case ast.EnumToken:
+		pos = x.Position
+	default:
+		return nil
+	}
+	start := d.nameOffset(pos, word)
+	return &Location{URI: d.uri, Range: d.rangeOf(start, start+len(word))}
+}
+
+// Creates a symbol for a declaration starting at the position.
+func (d *document) symbol(name, detail string, kind, pos int) DocumentSymbol {
+	start := d.nameOffset(pos, name)
+	return DocumentSymbol{
+		Name:           name,
+		Detail:         detail,
+		Kind:           kind,
+		Range:          d.rangeOf(pos, d.declarationEnd(pos)),
+		SelectionRange: d.rangeOf(start, start+len(name)),
+	}
+}
+
+// Creates symbols for the fields of a struct or exception.
+func (d *document) fieldSymbols(fields []any) []DocumentSymbol {
+	var symbols []DocumentSymbol
+	for _, f := range fields {
+		var name, detail string
+		var pos int
+		switch x := f.(type) {
+		case ast.FieldToken:
+			name, detail, pos = x.Name, x.Type, x.Position
+		case ast.ReferenceToken:
+			name, detail, pos = x.Name, x.Name, x.Position
+		default:
+			continue
+		}
+		r := d.rangeOf(pos, pos+len(name))
+		symbols = append(symbols, DocumentSymbol{
+			Name: name, Detail: detail, Kind: symbolKindField, Range: r, SelectionRange: r,
+		})
+	}
+	return symbols
+}
+
+// Gets the symbols for the declarations within the document.
+func (d *document) symbols() []DocumentSymbol {
+	symbols := []DocumentSymbol{}
+	for _, t := range d.tokens {
+		switch x := t.(type) {
+		case ast.StructToken:
+			s := d.symbol(x.Name, "struct", symbolKindStruct, x.Position)
+			s.Children = d.fieldSymbols(x.Fields)
+			symbols = append(symbols, s)
+		case ast.ExtendsToken:
+			if st, ok := x.Token.(ast.StructToken); ok {
+				s := d.symbol(st.Name, "extends struct", symbolKindStruct, x.Position)
+				s.Children = d.fieldSymbols(st.Fields)
+				symbols = append(symbols, s)
+			}
+		case ast.ExceptionToken:
+			s := d.symbol(x.Name, "exception", symbolKindClass, x.Position)
+			s.Children = d.fieldSymbols(x.Fields)
+			symbols = append(symbols, s)
+		case ast.EnumToken:
+			s := d.symbol(x.Name, "enum", symbolKindEnum, x.Position)
+			for _, v := range x.Values {
+				if value, ok := v.(ast.EnumValueToken); ok {
+					r := d.rangeOf(value.Position, value.Position+len(value.Name))
+					s.Children = append(s.Children, DocumentSymbol{
+						Name: value.Name, Kind: symbolKindEnumMember, Range: r, SelectionRange: r,
+					})
+				}
+			}
+			symbols = append(symbols, s)
+		case ast.ContractToken:
+			detail := "-> " + x.ReturnType
+			if x.Argument != nil {
+				detail = "(" + x.Argument.Name + ": " + x.Argument.Type + ") " + detail
+			} else {
+				detail = "() " + detail
+			}
+			symbols = append(symbols, d.symbol(x.Name, detail, symbolKindFunction, x.Position))
+		case ast.MappingToken:
+			symbols = append(symbols, d.symbol(x.Name, "mapping", symbolKindObject, x.Position))
+		}
+	}
+	return symbols
+}
+
+// Gets the diagnostics for the parse errors within the document.
+func (d *document) diagnostics() []Diagnostic {
+	diagnostics := []Diagnostic{}
+	for _, err := range d.errs {
+		end := max(err.End, err.Position+1)
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    d.rangeOf(err.Position, end),
+			Severity: 1,
+			Source:   "remixdb",
+			Message:  err.Message,
+		})
+	}
+	return diagnostics
+}
@@ -0,0 +1,242 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrMissingContentLength is returned when a message does not have a Content-Length header.
+	ErrMissingContentLength = errors.New("message is missing the Content-Length header")
+
+	// ErrExitWithoutShutdown is returned when the client exits before shutting down the server.
+	ErrExitWithoutShutdown = errors.New("client exited without shutting down the server")
+)
+
+// Server is used to serve the language server protocol for RQL files over a stream. Documents
+// are parsed when they are opened or changed and diagnostics are published for any parse errors.
+type Server struct {
+	r         *bufio.Reader
+	w         io.Writer
+	documents map[string]*document
+	shutdown  bool
+}
+
+// NewServer is used to create a new server which reads messages from the reader and writes them
+// to the writer.
+func NewServer(r io.Reader, w io.Writer) *Server {
+	return &Server{r: bufio.NewReader(r), w: w, documents: map[string]*document{}}
+}
+
+// Reads a single message body from the stream.
+func (s *Server) read() ([]byte, error) {
+	length := -1
+	for {
+		line, err := s.r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if length == -1 {
+		return nil, ErrMissingContentLength
+	}
+	body := make([]byte, length)
+	_, err := io.ReadFull(s.r, body)
+	return body, err
+}
+
+// Writes a message to the stream.
+func (s *Server) write(v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err = io.WriteString(s.w, "Content-Length: "+strconv.Itoa(len(body))+"\r\n\r\n"); err != nil {
+		return err
+	}
+	_, err = s.w.Write(body)
+	return err
+}
+
+// Writes a error response to the stream.
+func (s *Server) writeError(id *json.RawMessage, code int, message string) error {
+	return s.write(response{JSONRPC: "2.0", ID: id, Error: &responseError{Code: code, Message: message}})
+}
+
+// Publishes the diagnostics for a document.
+func (s *Server) publish(d *document) error {
+	return s.write(notification{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params:  publishDiagnosticsParams{URI: d.uri, Diagnostics: d.diagnostics()},
+	})
+}
+
+// Gets the document and byte offset which the params point at. The document is nil if it is
+// not open.
+func (s *Server) at(params json.RawMessage) (*document, int, error) {
+	var p textDocumentPositionParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, 0, err
+	}
+	d := s.documents[p.TextDocument.URI]
+	if d == nil {
+		return nil, 0, nil
+	}
+	return d, d.offset(p.Position), nil
+}
+
+// Handles a notification from the client. Unknown notifications are ignored.
+func (s *Server) notify(method string, params json.RawMessage) error {
+	switch method {
+	case "textDocument/didOpen":
+		var p didOpenParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil
+		}
+		d := newDocument(p.TextDocument.URI, p.TextDocument.Text)
+		s.documents[d.uri] = d
+		return s.publish(d)
+	case "textDocument/didChange":
+		var p didChangeParams
+		if err := json.Unmarshal(params, &p); err != nil || len(p.ContentChanges) == 0 {
+			return nil
+		}
+		d := newDocument(p.TextDocument.URI, p.ContentChanges[len(p.ContentChanges)-1].Text)
+		s.documents[d.uri] = d
+		return s.publish(d)
+	case "textDocument/didClose":
+		var p textDocumentParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil
+		}
+		delete(s.documents, p.TextDocument.URI)
+
+		// Clear the diagnostics since the client no longer has the document open.
+		return s.write(notification{
+			JSONRPC: "2.0",
+			Method:  "textDocument/publishDiagnostics",
+			Params:  publishDiagnosticsParams{URI: p.TextDocument.URI, Diagnostics: []Diagnostic{}},
+		})
+	}
+	return nil
+}
+
+// Handles a request from the client and returns the result.
+func (s *Server) request(method string, params json.RawMessage) (any, *responseError) {
+	switch method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync": 1,
+				"completionProvider": map[string]any{
+					"triggerCharacters": []string{"@", ":", "."},
+				},
+				"hoverProvider":          true,
+				"definitionProvider":     true,
+				"documentSymbolProvider": true,
+			},
+			"serverInfo": map[string]any{"name": "remixdb"},
+		}, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/documentSymbol":
+		var p textDocumentParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &responseError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		d := s.documents[p.TextDocument.URI]
+		if d == nil {
+			return nil, nil
+		}
+		return d.symbols(), nil
+	case "textDocument/completion", "textDocument/hover", "textDocument/definition":
+		d, offset, err := s.at(params)
+		if err != nil {
+			return nil, &responseError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		if d == nil {
+			return nil, nil
+		}
+		switch method {
+		case "textDocument/completion":
+			items := d.completion(offset)
+			if items == nil {
+				items = []CompletionItem{}
+			}
+			return items, nil
+		case "textDocument/hover":
+			if h := d.hover(offset); h != nil {
+				return h, nil
+			}
+		default:
+			if l := d.definition(offset); l != nil {
+				return l, nil
+			}
+		}
+		return nil, nil
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: "method " + method + " is not supported"}
+}
+
+// Run is used to handle messages until the client sends exit or the stream ends. Returns nil
+// when the client exits after shutting down the server or the stream is closed.
+func (s *Server) Run() error {
+	for {
+		body, err := s.read()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		var msg message
+		if err = json.Unmarshal(body, &msg); err != nil {
+			if err = s.writeError(nil, codeParseError, err.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return ErrExitWithoutShutdown
+			}
+			return nil
+		}
+
+		if msg.ID == nil {
+			err = s.notify(msg.Method, msg.Params)
+		} else {
+			result, rerr := s.request(msg.Method, msg.Params)
+			if rerr != nil {
+				err = s.writeError(msg.ID, rerr.Code, rerr.Message)
+			} else {
+				err = s.write(response{JSONRPC: "2.0", ID: msg.ID, Result: result})
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
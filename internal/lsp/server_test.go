@@ -0,0 +1,291 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testURI = "file:///schema.rql"
+
+const testSchema = `struct Tree {
+    @primary
+    id: int
+    kind: Kind
+}
+
+extends struct Tree {
+    name: string
+}
+
+// Thrown when the tree is not found.
+exception TreeNotFound {
+    id: int
+}
+
+enum Kind {
+    Oak
+    Pine
+}
+
+contract GetTree(tree: Tree) -> Tree throws TreeNotFound {
+    return tree.
+}
+
+mapping Trees using Tree {
+    x -> y
+}
+`
+
+// Defines a message written by the server.
+type testMessage struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *responseError  `json:"error"`
+}
+
+// Runs the server over the messages and returns what it wrote.
+func runServer(t *testing.T, messages ...string) []testMessage {
+	t.Helper()
+	var in bytes.Buffer
+	for _, m := range messages {
+		in.WriteString("Content-Length: " + strconv.Itoa(len(m)) + "\r\n\r\n" + m)
+	}
+	var out bytes.Buffer
+	require.NoError(t, NewServer(&in, &out).Run())
+
+	var written []testMessage
+	r := NewServer(bufio.NewReader(&out), nil)
+	for {
+		body, err := r.read()
+		if err != nil {
+			break
+		}
+		var m testMessage
+		require.NoError(t, json.Unmarshal(body, &m))
+		written = append(written, m)
+	}
+	return written
+}
+
+// Creates a message which opens the document.
+func openMessage(text string) string {
+	b, _ := json.Marshal(text)
+	return `{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"` +
+		testURI + `","languageId":"rql","version":1,"text":` + string(b) + `}}}`
+}
+
+// Creates a request which points at the position of the first time the needle is found within
+// the test schema, plus the offset.
+func positionRequest(t *testing.T, id int, method, needle string, offset int) string {
+	t.Helper()
+	i := strings.Index(testSchema, needle)
+	require.NotEqual(t, -1, i, needle)
+	p := (&document{text: testSchema}).position(i + offset)
+	return `{"jsonrpc":"2.0","id":` + strconv.Itoa(id) + `,"method":"` + method + `","params":{"textDocument":{"uri":"` +
+		testURI + `"},"position":{"line":` + strconv.Itoa(p.Line) + `,"character":` + strconv.Itoa(p.Character) + `}}}`
+}
+
+// Gets the response with the ID.
+func responseWithID(t *testing.T, messages []testMessage, id int) testMessage {
+	t.Helper()
+	for _, m := range messages {
+		if m.ID != nil && *m.ID == id {
+			return m
+		}
+	}
+	t.Fatalf("no response with the ID %d", id)
+	return testMessage{}
+}
+
+// Gets the labels of the completion items.
+func labels(t *testing.T, m testMessage) []string {
+	t.Helper()
+	var items []CompletionItem
+	require.NoError(t, json.Unmarshal(m.Result, &items))
+	l := make([]string, len(items))
+	for i, item := range items {
+		l[i] = item.Label
+	}
+	return l
+}
+
+const shutdownMessage = `{"jsonrpc":"2.0","id":100,"method":"shutdown"}`
+
+const exitMessage = `{"jsonrpc":"2.0","method":"exit"}`
+
+func TestServer_lifecycle(t *testing.T) {
+	written := runServer(t,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"capabilities":{}}}`,
+		`{"jsonrpc":"2.0","method":"initialized","params":{}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"workspace/unknown"}`,
+		shutdownMessage, exitMessage,
+	)
+	require.Len(t, written, 3)
+
+	var result struct {
+		Capabilities map[string]any `json:"capabilities"`
+	}
+	require.NoError(t, json.Unmarshal(written[0].Result, &result))
+	assert.Equal(t, float64(1), result.Capabilities["textDocumentSync"])
+	assert.Equal(t, true, result.Capabilities["hoverProvider"])
+	assert.Equal(t, true, result.Capabilities["definitionProvider"])
+	assert.Equal(t, true, result.Capabilities["documentSymbolProvider"])
+
+	require.NotNil(t, written[1].Error)
+	assert.Equal(t, codeMethodNotFound, written[1].Error.Code)
+	assert.Equal(t, "null", string(written[2].Result))
+}
+
+func TestServer_exitWithoutShutdown(t *testing.T) {
+	in := strings.NewReader("Content-Length: " + strconv.Itoa(len(exitMessage)) + "\r\n\r\n" + exitMessage)
+	assert.Equal(t, ErrExitWithoutShutdown, NewServer(in, &bytes.Buffer{}).Run())
+}
+
+func TestServer_diagnostics(t *testing.T) {
+	text := "struct Tree {\n    id: int\n}\n\nstruct 🌳 {\n}\n"
+	written := runServer(t, openMessage(text), shutdownMessage, exitMessage)
+	require.Equal(t, "textDocument/publishDiagnostics", written[0].Method)
+
+	var params publishDiagnosticsParams
+	require.NoError(t, json.Unmarshal(written[0].Params, &params))
+	assert.Equal(t, testURI, params.URI)
+	require.Len(t, params.Diagnostics, 1)
+	d := params.Diagnostics[0]
+	assert.Equal(t, 1, d.Severity)
+	assert.Equal(t, "remixdb", d.Source)
+	assert.Equal(t, 4, d.Range.Start.Line)
+	assert.NotEmpty(t, d.Message)
+
+	// A document without errors clears the diagnostics.
+	written = runServer(t, openMessage(testSchema), shutdownMessage, exitMessage)
+	require.NoError(t, json.Unmarshal(written[0].Params, &params))
+	assert.Empty(t, params.Diagnostics)
+}
+
+func TestServer_completion(t *testing.T) {
+	written := runServer(t,
+		openMessage(testSchema),
+		positionRequest(t, 1, "textDocument/completion", "@primary", 1),
+		positionRequest(t, 2, "textDocument/completion", "kind: Kind", 6),
+		positionRequest(t, 3, "textDocument/completion", "tree.\n", 5),
+		positionRequest(t, 4, "textDocument/completion", "return", 0),
+		positionRequest(t, 5, "textDocument/completion", "\n\nextends", 1),
+		shutdownMessage, exitMessage,
+	)
+
+	assert.Contains(t, labels(t, responseWithID(t, written, 1)), "primary")
+	assert.Contains(t, labels(t, responseWithID(t, written, 1)), "default")
+
+	types := labels(t, responseWithID(t, written, 2))
+	assert.Contains(t, types, "string")
+	assert.Contains(t, types, "Tree")
+	assert.Contains(t, types, "Kind")
+	assert.Contains(t, types, "TreeNotFound")
+
+	// Fields from extends are included.
+	assert.Equal(t, []string{"id", "kind", "name"}, labels(t, responseWithID(t, written, 3)))
+
+	statements := labels(t, responseWithID(t, written, 4))
+	assert.Contains(t, statements, "if")
+	assert.Contains(t, statements, "tree")
+	assert.NotContains(t, statements, "struct")
+
+	assert.Equal(t, rootKeywords, labels(t, responseWithID(t, written, 5)))
+}
+
+func TestServer_hover(t *testing.T) {
+	written := runServer(t,
+		openMessage(testSchema),
+		positionRequest(t, 1, "textDocument/hover", "-> Tree", 4),
+		positionRequest(t, 2, "textDocument/hover", "return", 0),
+		positionRequest(t, 3, "textDocument/hover", "throws TreeNotFound", 10),
+		shutdownMessage, exitMessage,
+	)
+
+	var hover Hover
+	require.NoError(t, json.Unmarshal(responseWithID(t, written, 1).Result, &hover))
+	assert.Equal(t, "markdown", hover.Contents.Kind)
+	assert.Equal(t, "```rql\nstruct Tree {\n    @primary\n    id: int\n    kind: Kind\n    name: string\n}\n```",
+		hover.Contents.Value)
+
+	assert.Equal(t, "null", string(responseWithID(t, written, 2).Result))
+
+	// Doc comments are shown under the declaration.
+	require.NoError(t, json.Unmarshal(responseWithID(t, written, 3).Result, &hover))
+	assert.Equal(t, "```rql\nexception TreeNotFound {\n    id: int\n}\n```\n\nThrown when the tree is not found.",
+		hover.Contents.Value)
+}
+
+func TestServer_definition(t *testing.T) {
+	written := runServer(t,
+		openMessage(testSchema),
+		positionRequest(t, 1, "textDocument/definition", "tree: Tree", 7),
+		positionRequest(t, 2, "textDocument/definition", "kind: Kind", 7),
+		positionRequest(t, 3, "textDocument/definition", "throws TreeNotFound", 10),
+		shutdownMessage, exitMessage,
+	)
+
+	for id, want := range map[int]Range{
+		1: {Start: Position{Line: 0, Character: 7}, End: Position{Line: 0, Character: 11}},
+		2: {Start: Position{Line: 15, Character: 5}, End: Position{Line: 15, Character: 9}},
+		3: {Start: Position{Line: 11, Character: 10}, End: Position{Line: 11, Character: 22}},
+	} {
+		var location Location
+		require.NoError(t, json.Unmarshal(responseWithID(t, written, id).Result, &location))
+		assert.Equal(t, Location{URI: testURI, Range: want}, location)
+	}
+}
+
+func TestServer_documentSymbol(t *testing.T) {
+	written := runServer(t,
+		openMessage(testSchema),
+		`{"jsonrpc":"2.0","id":1,"method":"textDocument/documentSymbol","params":{"textDocument":{"uri":"`+testURI+`"}}}`,
+		shutdownMessage, exitMessage,
+	)
+
+	var symbols []DocumentSymbol
+	require.NoError(t, json.Unmarshal(responseWithID(t, written, 1).Result, &symbols))
+	var names []string
+	for _, s := range symbols {
+		names = append(names, s.Name)
+	}
+	assert.Equal(t, []string{"Tree", "Tree", "TreeNotFound", "Kind", "GetTree", "Trees"}, names)
+
+	tree := symbols[0]
+	assert.Equal(t, symbolKindStruct, tree.Kind)
+	assert.Equal(t, Range{Start: Position{Line: 0}, End: Position{Line: 4, Character: 1}}, tree.Range)
+	require.Len(t, tree.Children, 2)
+	assert.Equal(t, "kind", tree.Children[1].Name)
+	assert.Equal(t, "Kind", tree.Children[1].Detail)
+
+	assert.Equal(t, symbolKindFunction, symbols[4].Kind)
+	assert.Equal(t, "(tree: Tree) -> Tree", symbols[4].Detail)
+	assert.Equal(t, symbolKindObject, symbols[5].Kind)
+}
+
+func TestDocument_position(t *testing.T) {
+	// Characters outside of the basic multilingual plane are two UTF-16 characters.
+	d := &document{text: "a\n🌳b\nc"}
+	for offset, want := range map[int]Position{
+		0: {Line: 0, Character: 0},
+		2: {Line: 1, Character: 0},
+		6: {Line: 1, Character: 2},
+		7: {Line: 1, Character: 3},
+		8: {Line: 2, Character: 0},
+	} {
+		assert.Equal(t, want, d.position(offset))
+		assert.Equal(t, offset, d.offset(want))
+	}
+}
@@ -0,0 +1,276 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package query
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"sort"
+
+	"remixdb.io/internal/rqltypes"
+)
+
+// Aggregate is used to check if the action combines the matching objects into a single value.
+// These actions can be grouped with GroupBy.
+func (a Action) Aggregate() bool {
+	switch a {
+	case ActionCount, ActionSum, ActionAvg, ActionMin, ActionMax:
+		return true
+	}
+	return false
+}
+
+// Numeric is used to check if values of the type can be summed and averaged.
+func Numeric(t rqltypes.Type) bool {
+	if t.Elem != nil {
+		return false
+	}
+	switch t.Name {
+	case rqltypes.Int, rqltypes.Uint, rqltypes.Float, rqltypes.Bigint:
+		return true
+	}
+	return false
+}
+
+// AggregateType is used to get the type of the value an aggregate action returns for a group.
+// fieldType is the type of the field the action applies to, which is ignored for ActionCount.
+func AggregateType(action Action, fieldType rqltypes.Type) rqltypes.Type {
+	switch action {
+	case ActionSum:
+		return fieldType.NonOptional()
+	case ActionAvg:
+		return rqltypes.Type{Name: rqltypes.Float, Optional: true}
+	case ActionMin, ActionMax:
+		fieldType.Optional = true
+		return fieldType
+	default:
+		return rqltypes.Type{Name: rqltypes.Int}
+	}
+}
+
+// Defines the state of a aggregate for a single group.
+type group struct {
+	// value is the encoded value of the field the objects are grouped by.
+	value []byte
+
+	// count is the number of objects within the group. For everything other than ActionCount,
+	// objects where the field is null are not counted.
+	count int
+
+	// sum is the sum of the field. This is a int, uint, float64 or *big.Int depending on the type.
+	sum any
+
+	// sortKey and best are the sort key and encoded value of the min or max of the field.
+	sortKey, best []byte
+}
+
+// Defines a aggregate which is calculated as the objects are iterated so that they do not need
+// to be kept in memory.
+type aggregator struct {
+	q         Query
+	fieldType rqltypes.Type
+
+	// groupType is the type of the field the objects are grouped by.
+	groupType rqltypes.Type
+
+	// groups is a map of the sort key of the group value to the group. If the objects are not
+	// grouped, everything is in a group with a blank key.
+	groups map[string]*group
+}
+
+// Creates a aggregator for the query. The fields of the query must be checked before this is
+// called.
+func newAggregator(q Query, fields map[string]rqltypes.Type) *aggregator {
+	a := &aggregator{q: q, groups: map[string]*group{}}
+	if q.Field != "" {
+		a.fieldType = fields[q.Field].NonOptional()
+	}
+	if q.GroupBy != "" {
+		a.groupType = fields[q.GroupBy]
+	}
+	return a
+}
+
+// Adds a number to the sum.
+func addNumber(sum, v any) any {
+	switch x := v.(type) {
+	case int:
+		s, _ := sum.(int)
+		return s + x
+	case uint:
+		s, _ := sum.(uint)
+		return s + x
+	case float64:
+		s, _ := sum.(float64)
+		return s + x
+	case *big.Int:
+		s, ok := sum.(*big.Int)
+		if !ok {
+			s = new(big.Int)
+		}
+		return s.Add(s, x)
+	}
+	return sum
+}
+
+// Adds the object to the aggregate.
+func (a *aggregator) add(m match) error {
+	// Get the group.
+	var key, value []byte
+	if a.q.GroupBy != "" {
+		var err error
+		if value, err = fieldValue(m.object, a.q.GroupBy); err != nil {
+			return err
+		}
+		t := a.groupType
+		t.Optional = true
+		if key, err = rqltypes.SortKey(t, value); err != nil {
+			return err
+		}
+	}
+	g, ok := a.groups[string(key)]
+	if !ok {
+		g = &group{value: value}
+		a.groups[string(key)] = g
+	}
+	if a.q.Action == ActionCount {
+		g.count++
+		return nil
+	}
+
+	// Get the value of the field. Nulls are ignored.
+	b, err := fieldValue(m.object, a.q.Field)
+	if err != nil {
+		return err
+	}
+	if b[0] == 0x00 {
+		return nil
+	}
+	g.count++
+
+	// Update the aggregate.
+	switch a.q.Action {
+	case ActionSum, ActionAvg:
+		v, err := rqltypes.Decode(a.fieldType, b, nil)
+		if err != nil {
+			return err
+		}
+		g.sum = addNumber(g.sum, v)
+	case ActionMin, ActionMax:
+		sortKey, err := rqltypes.SortKey(a.fieldType, b)
+		if err != nil {
+			return err
+		}
+		c := bytes.Compare(sortKey, g.sortKey)
+		if g.best == nil || (a.q.Action == ActionMin && c < 0) || (a.q.Action == ActionMax && c > 0) {
+			g.sortKey, g.best = sortKey, b
+		}
+	}
+	return nil
+}
+
+// Gets the value of the aggregate for the group.
+func (a *aggregator) value(g *group) (any, error) {
+	switch a.q.Action {
+	case ActionSum:
+		if g.sum != nil {
+			return g.sum, nil
+		}
+		switch a.fieldType.Name {
+		case rqltypes.Uint:
+			return uint(0), nil
+		case rqltypes.Float:
+			return float64(0), nil
+		case rqltypes.Bigint:
+			return new(big.Int), nil
+		}
+		return 0, nil
+	case ActionAvg:
+		if g.count == 0 {
+			return (*float64)(nil), nil
+		}
+		var avg float64
+		switch x := g.sum.(type) {
+		case int:
+			avg = float64(x) / float64(g.count)
+		case uint:
+			avg = float64(x) / float64(g.count)
+		case float64:
+			avg = x / float64(g.count)
+		case *big.Int:
+			avg, _ = new(big.Float).Quo(new(big.Float).SetInt(x), big.NewFloat(float64(g.count))).Float64()
+		}
+		return &avg, nil
+	case ActionMin, ActionMax:
+		t := a.fieldType
+		t.Optional = true
+		if g.best == nil {
+			return rqltypes.Decode(t, []byte{0x00}, nil)
+		}
+		return rqltypes.Decode(t, g.best, nil)
+	default:
+		return g.count, nil
+	}
+}
+
+// Gets the result of the aggregate. If the objects are grouped, this is a object for each group
+// ordered by the group value, with the group value in the field the objects are grouped by and the
+// aggregate in a field named after the action.
+func (a *aggregator) result() (any, error) {
+	if a.q.GroupBy == "" {
+		g, ok := a.groups[""]
+		if !ok {
+			g = &group{}
+		}
+		return a.value(g)
+	}
+
+	// Sort the groups by their value.
+	keys := make([]string, 0, len(a.groups))
+	for key := range a.groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// Make a object for each group.
+	objects := make([]map[string]any, len(keys))
+	for i, key := range keys {
+		g := a.groups[key]
+		groupValue, err := rqltypes.Decode(a.groupType, g.value, nil)
+		if err != nil {
+			return nil, err
+		}
+		objects[i] = map[string]any{a.q.GroupBy: groupValue}
+		v, err := a.value(g)
+		if err != nil {
+			return nil, err
+		}
+		objects[i][string(a.q.Action)] = v
+	}
+	return objects, nil
+}
+
+// Checks that the fields used by the aggregate exist and have types which can be aggregated.
+func checkAggregate(q Query, structName string, fields map[string]rqltypes.Type) error {
+	if q.GroupBy != "" {
+		if !q.Action.Aggregate() {
+			return errors.New("only aggregates can be grouped")
+		}
+		if t, ok := fields[q.GroupBy]; !ok || !rqltypes.Orderable(t) {
+			return errors.New("cannot group " + structName + " by " + q.GroupBy)
+		}
+	}
+	switch q.Action {
+	case ActionSum, ActionAvg:
+		if t, ok := fields[q.Field]; !ok || !Numeric(t) {
+			return errors.New("cannot " + string(q.Action) + " the field " + q.Field + " of " + structName)
+		}
+	case ActionMin, ActionMax:
+		if t, ok := fields[q.Field]; !ok || !rqltypes.Orderable(t) {
+			return errors.New("cannot get the " + string(q.Action) + " of the field " + q.Field + " of " + structName)
+		}
+	}
+	return nil
+}
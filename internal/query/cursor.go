@@ -0,0 +1,66 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package query
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+)
+
+// ErrInvalidCursor is returned when a cursor token passed to after is not valid or was made by a
+// query with a different ordering.
+var ErrInvalidCursor = errors.New("the cursor token is invalid")
+
+// Defines the position of a object within the results of a query. This is what a cursor token
+// holds so that a listing can be resumed after the object.
+type position struct {
+	// value is the RemixDB encoding of the field the objects are ordered by. This is nil if the
+	// objects are ordered by key.
+	value []byte
+
+	// key is the key of the object.
+	key []byte
+}
+
+// Encodes the position into a opaque token for the client. The field the objects are ordered by
+// is included so that the token cannot be used with a different ordering.
+func encodeCursor(orderBy string, p position) string {
+	b := binary.AppendUvarint(nil, uint64(len(orderBy)))
+	b = append(b, orderBy...)
+	b = binary.AppendUvarint(b, uint64(len(p.value)))
+	b = append(b, p.value...)
+	b = append(b, p.key...)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Reads a length prefixed part of a cursor token.
+func readCursorPart(b []byte) (part, rest []byte, err error) {
+	l, n := binary.Uvarint(b)
+	if n <= 0 || uint64(len(b)-n) < l {
+		return nil, nil, ErrInvalidCursor
+	}
+	return b[n : n+int(l)], b[n+int(l):], nil
+}
+
+// Decodes a token made by encodeCursor. Returns ErrInvalidCursor if the token is not valid for
+// the ordering.
+func decodeCursor(orderBy, token string) (position, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return position{}, ErrInvalidCursor
+	}
+	field, b, err := readCursorPart(b)
+	if err != nil || string(field) != orderBy {
+		return position{}, ErrInvalidCursor
+	}
+	value, key, err := readCursorPart(b)
+	if err != nil || len(key) == 0 || (orderBy != "" && len(value) == 0) {
+		return position{}, ErrInvalidCursor
+	}
+	if orderBy == "" {
+		value = nil
+	}
+	return position{value: value, key: key}, nil
+}
@@ -0,0 +1,87 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package query
+
+import (
+	"errors"
+
+	"remixdb.io/internal/engine"
+)
+
+// ErrLockUpgrade is returned when a write lock is wanted on a struct or object which the session
+// has only read locked. Releasing the read lock to take the write lock would let another session
+// change the values which were read, so queries which write must set Query.Write instead.
+var ErrLockUpgrade = errors.New("cannot upgrade a read lock to a write lock")
+
+// Locks is used to keep track of the locks acquired by queries within a session so that each lock
+// is only acquired once. The locks are released when the session is closed. The zero value is
+// ready to use.
+type Locks struct {
+	// structs maps the name of each locked struct to if the lock is a write lock.
+	structs map[string]bool
+
+	// objects maps the name of each struct to the keys of the locked objects within it, and if
+	// each lock is a write lock.
+	objects map[string]map[string]bool
+}
+
+// Acquires a lock on the struct. Returns ErrLockUpgrade if the session only has a read lock and a
+// write lock is wanted.
+func (l *Locks) structLock(s engine.Session, structName string, write bool) error {
+	if l.structs == nil {
+		l.structs = map[string]bool{}
+	}
+	held, ok := l.structs[structName]
+	if ok {
+		if write && !held {
+			return ErrLockUpgrade
+		}
+		return nil
+	}
+
+	var err error
+	if write {
+		err = s.AcquireStructWriteLock(structName)
+	} else {
+		err = s.AcquireStructReadLock(structName)
+	}
+	if err != nil {
+		delete(l.structs, structName)
+		return err
+	}
+	l.structs[structName] = write
+	return nil
+}
+
+// Acquires a lock on the object. Returns ErrLockUpgrade in the same way as structLock.
+func (l *Locks) objectLock(s engine.Session, structName string, key []byte, write bool) error {
+	if l.objects == nil {
+		l.objects = map[string]map[string]bool{}
+	}
+	keys, ok := l.objects[structName]
+	if !ok {
+		keys = map[string]bool{}
+		l.objects[structName] = keys
+	}
+	held, ok := keys[string(key)]
+	if ok {
+		if write && !held {
+			return ErrLockUpgrade
+		}
+		return nil
+	}
+
+	var err error
+	if write {
+		err = s.AcquireStructObjectWriteLock(structName, key)
+	} else {
+		err = s.AcquireStructObjectReadLock(structName, key)
+	}
+	if err != nil {
+		delete(keys, string(key))
+		return err
+	}
+	keys[string(key)] = write
+	return nil
+}
@@ -0,0 +1,661 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package query
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"strconv"
+
+	"remixdb.io/ast"
+	"remixdb.io/internal/engine"
+	"remixdb.io/internal/rqltypes"
+)
+
+// Action is used to define what a query does with the objects which match it.
+type Action string
+
+const (
+	// ActionFirst returns the first matching object or null.
+	ActionFirst Action = "first"
+
+	// ActionAll returns all of the matching objects.
+	ActionAll Action = "all"
+
+	// ActionCount returns how many objects match.
+	ActionCount Action = "count"
+
+	// ActionDelete deletes the matching objects and returns how many were deleted.
+	ActionDelete Action = "delete"
+
+	// ActionUpdate sets fields on the matching objects and returns how many were updated.
+	ActionUpdate Action = "update"
+
+	// ActionNextCursor returns the cursor token which resumes the listing after the objects the
+	// query would return, or null if there are no objects after them.
+	ActionNextCursor Action = "nextCursor"
+
+	// ActionSum returns the sum of a numeric field. Null values are skipped.
+	ActionSum Action = "sum"
+
+	// ActionAvg returns the average of a numeric field as a float, or null if every value is null.
+	ActionAvg Action = "avg"
+
+	// ActionMin returns the smallest value of a field, or null if every value is null.
+	ActionMin Action = "min"
+
+	// ActionMax returns the largest value of a field, or null if every value is null.
+	ActionMax Action = "max"
+)
+
+// Query is used to define a query against the objects within a struct. Queries are built by the
+// compiler and embedded into the contract as JSON. The values used by the query are passed in
+// separately when it is ran since they are only known at runtime.
+type Query struct {
+	// Struct is the name of the struct.
+	Struct string `json:"struct"`
+
+	// Action is what is done with the objects which match.
+	Action Action `json:"action"`
+
+	// Write is true if the contract writes to the struct. In this case, write locks are acquired
+	// even when reading so that a lock never needs to be upgraded.
+	Write bool `json:"write,omitempty"`
+
+	// OrderBy is the name of the field the objects are ordered by. If blank, the objects are
+	// ordered by key.
+	OrderBy string `json:"orderBy,omitempty"`
+
+	// Descending is true if the objects are ordered from the largest value to the smallest.
+	Descending bool `json:"descending,omitempty"`
+
+	// Field is the name of the field ActionSum, ActionAvg, ActionMin and ActionMax apply to.
+	Field string `json:"field,omitempty"`
+
+	// GroupBy is the name of the field the objects are grouped by for aggregate actions. If set,
+	// the result is a object for each group. See Action.Aggregate.
+	GroupBy string `json:"groupBy,omitempty"`
+}
+
+// Params are the keys of the values passed to Run.
+const (
+	// ParamWhere is a map[string]any of fields the objects must be equal to.
+	ParamWhere = "where"
+
+	// ParamSet is a map[string]any of fields which are set by ActionUpdate.
+	ParamSet = "set"
+
+	// ParamLimit is a int of the maximum number of objects the action applies to.
+	ParamLimit = "limit"
+
+	// ParamOffset is a int of the number of matching objects which are skipped.
+	ParamOffset = "offset"
+
+	// ParamAfter is a string or *string of a cursor token. Only objects after the position in the
+	// token are matched. A null token is ignored.
+	ParamAfter = "after"
+)
+
+// PrimaryKey is used to get the name of the field which is the primary key of the struct. This is
+// the field marked with @primary, and the key of each object is the RemixDB encoding of its value.
+// Returns a blank string if the struct has no primary key.
+func PrimaryKey(structToken *ast.StructToken) string {
+	for _, f := range structToken.Fields {
+		field, ok := f.(ast.FieldToken)
+		if !ok {
+			continue
+		}
+		for _, decorator := range field.Decorators {
+			if decorator.Method == "primary" {
+				return field.Name
+			}
+		}
+	}
+	return ""
+}
+
+// Defines a object which matched the query.
+type match struct {
+	key, object []byte
+
+	// sortKey is the sort key of the field the objects are ordered by. This is only set when
+	// the objects are sorted in memory.
+	sortKey []byte
+}
+
+// Defines the options which decide which of the matching objects the action applies to.
+type page struct {
+	// where is the encoded values of the fields the objects must be equal to.
+	where map[string][]byte
+
+	// after is the position to resume from, or nil to start from the beginning.
+	after *position
+
+	// token is the cursor token which after was decoded from.
+	token *string
+
+	// afterSortKey is the sort key of the value in after, if the objects are ordered by a field.
+	afterSortKey []byte
+
+	// offset is the number of matching objects to skip.
+	offset int
+
+	// limit is the maximum number of objects to return. If negative, there is no limit.
+	limit int
+}
+
+// Encodes the values within a map of fields. Null values are encoded as null.
+func encodeFields(fields map[string]rqltypes.Type, values any, resolve rqltypes.StructResolver) (map[string][]byte, error) {
+	m, _ := values.(map[string]any)
+	encoded := make(map[string][]byte, len(m))
+	for k, v := range m {
+		t, ok := fields[k]
+		if !ok {
+			return nil, errors.New("unknown field " + k)
+		}
+		b, err := rqltypes.Encode(t, v, resolve)
+		if err != nil {
+			return nil, errors.New(k + ": " + err.Error())
+		}
+		encoded[k] = b
+	}
+	return encoded, nil
+}
+
+// Gets the encoded value of the field within the object. Null fields are not included in the
+// encoding, so they are returned as null.
+func fieldValue(object []byte, field string) ([]byte, error) {
+	fields, err := rqltypes.StructFields(object)
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := fields[field]; ok {
+		return v, nil
+	}
+	return []byte{0x00}, nil
+}
+
+// Checks if the object has all of the field values specified.
+func matches(where map[string][]byte, object []byte) (bool, error) {
+	fields, err := rqltypes.StructFields(object)
+	if err != nil {
+		return false, err
+	}
+	for k, v := range where {
+		got, ok := fields[k]
+		if !ok {
+			// Null fields are not included in the encoding.
+			got = []byte{0x00}
+		}
+		if !bytes.Equal(got, v) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Gets an int parameter. Returns def if the parameter is not set.
+func intParam(params map[string]any, name string, def int) (int, error) {
+	v, ok := params[name]
+	if !ok || v == nil {
+		return def, nil
+	}
+	i, ok := v.(int)
+	if !ok {
+		return 0, errors.New(name + " must be an int")
+	}
+	if i < 0 {
+		return 0, errors.New(name + " cannot be negative, got " + strconv.Itoa(i))
+	}
+	return i, nil
+}
+
+// Gets the page from the parameters.
+func getPage(
+	q Query, fields map[string]rqltypes.Type, params map[string]any, resolve rqltypes.StructResolver,
+) (*page, error) {
+	// Encode the conditions.
+	where, err := encodeFields(fields, params[ParamWhere], resolve)
+	if err != nil {
+		return nil, err
+	}
+	p := &page{where: where}
+
+	// Get the offset and limit.
+	if p.offset, err = intParam(params, ParamOffset, 0); err != nil {
+		return nil, err
+	}
+	if p.limit, err = intParam(params, ParamLimit, -1); err != nil {
+		return nil, err
+	}
+
+	// Decode the cursor token if there is one.
+	var token string
+	switch x := params[ParamAfter].(type) {
+	case string:
+		token = x
+	case *string:
+		if x == nil {
+			return p, nil
+		}
+		token = *x
+	default:
+		return p, nil
+	}
+	after, err := decodeCursor(q.OrderBy, token)
+	if err != nil {
+		return nil, err
+	}
+	if q.OrderBy != "" {
+		t := fields[q.OrderBy]
+		t.Optional = true
+		if p.afterSortKey, err = rqltypes.SortKey(t, after.value); err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+	p.after, p.token = &after, &token
+	return p, nil
+}
+
+// Decides which of the objects that match the conditions are within the page, skipping the offset
+// and stopping at the limit.
+type collector struct {
+	p       *page
+	skipped int
+
+	// count is the number of objects accepted.
+	count int
+}
+
+// Checks if the limit has been reached.
+func (c *collector) done() bool { return c.count == c.p.limit }
+
+// Checks if the object is within the page. The object must be passed in order.
+func (c *collector) accept(m match) (bool, error) {
+	if c.done() {
+		return false, nil
+	}
+	ok, err := matches(c.p.where, m.object)
+	if err != nil || !ok {
+		return false, err
+	}
+	if c.skipped < c.p.offset {
+		c.skipped++
+		return false, nil
+	}
+	c.count++
+	return true, nil
+}
+
+// Defines the objects within the page in order. If it is set, the objects are read from the
+// iterator as they are needed. If not, they come from matched.
+type results struct {
+	c       *collector
+	it      engine.StructObjectIterator
+	matched []match
+}
+
+// Gets the next object within the page. Returns false once there are no more.
+func (r *results) next() (match, bool, error) {
+	for !r.c.done() {
+		var m match
+		if r.it == nil {
+			if len(r.matched) == 0 {
+				return match{}, false, nil
+			}
+			m, r.matched = r.matched[0], r.matched[1:]
+		} else {
+			if !r.it.Next() {
+				return match{}, false, r.it.Err()
+			}
+			object, err := r.it.Value()
+			if err != nil {
+				return match{}, false, err
+			}
+			m = match{key: r.it.Key(), object: object}
+		}
+		ok, err := r.c.accept(m)
+		if err != nil {
+			return match{}, false, err
+		}
+		if ok {
+			return m, true, nil
+		}
+	}
+	return match{}, false, nil
+}
+
+// Calls the function with each of the remaining objects.
+func (r *results) each(fn func(m match) error) error {
+	for {
+		m, ok, err := r.next()
+		if err != nil || !ok {
+			return err
+		}
+		if err := fn(m); err != nil {
+			return err
+		}
+	}
+}
+
+// Gets all of the remaining objects.
+func (r *results) all() ([]match, error) {
+	matched := []match{}
+	err := r.each(func(m match) error {
+		matched = append(matched, m)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matched, nil
+}
+
+// Gets the objects with the keys which match the conditions. Objects which no longer exist are
+// skipped.
+func getMatching(s engine.Session, structName string, keys [][]byte, where map[string][]byte) ([]match, error) {
+	matched := []match{}
+	for _, key := range keys {
+		object, err := s.GetStructObject(structName, key)
+		if err != nil {
+			if err == engine.ErrNotExists {
+				continue
+			}
+			return nil, err
+		}
+		ok, err := matches(where, object)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, match{key: key, object: object})
+		}
+	}
+	return matched, nil
+}
+
+// Sorts the objects in memory and then gets the ones after the cursor.
+func sortMatches(q Query, fields map[string]rqltypes.Type, matched []match, p *page) (*results, error) {
+	// Get the sort key of each object.
+	if q.OrderBy != "" {
+		t := fields[q.OrderBy]
+		t.Optional = true
+		for i, m := range matched {
+			v, err := fieldValue(m.object, q.OrderBy)
+			if err != nil {
+				return nil, err
+			}
+			if matched[i].sortKey, err = rqltypes.SortKey(t, v); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Sort the objects by the sort key and then by key.
+	compare := func(aSortKey, aKey, bSortKey, bKey []byte) int {
+		c := bytes.Compare(aSortKey, bSortKey)
+		if c == 0 {
+			c = bytes.Compare(aKey, bKey)
+		}
+		if q.Descending {
+			c = -c
+		}
+		return c
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return compare(matched[i].sortKey, matched[i].key, matched[j].sortKey, matched[j].key) < 0
+	})
+
+	// Remove the objects up to the cursor.
+	if p.after != nil {
+		i := sort.Search(len(matched), func(i int) bool {
+			return compare(matched[i].sortKey, matched[i].key, p.afterSortKey, p.after.key) > 0
+		})
+		matched = matched[i:]
+	}
+	return &results{c: &collector{p: p}, matched: matched}, nil
+}
+
+// Finds the objects which match the query in order. If the primary key is within the conditions,
+// the object is fetched by its key. If the objects are ordered by a field with a index which is
+// ready, the index is iterated in order. If they are ordered by key, a index on one of the fields
+// is used if one is ready, and the objects are iterated in key order if not. Anything else is
+// sorted in memory.
+func find(
+	s engine.Session, locks *Locks, q Query, structToken *ast.StructToken, p *page,
+) (*results, error) {
+	fields, err := rqltypes.FieldsFromSession(s, structToken)
+	if err != nil {
+		return nil, err
+	}
+
+	// Handle looking up by the primary key.
+	if pk := PrimaryKey(structToken); pk != "" {
+		if key, ok := p.where[pk]; ok {
+			if err := locks.objectLock(s, q.Struct, key, q.Write); err != nil {
+				return nil, err
+			}
+			matched, err := getMatching(s, q.Struct, [][]byte{key}, p.where)
+			if err != nil {
+				return nil, err
+			}
+			return sortMatches(q, fields, matched, p)
+		}
+	}
+
+	// Lock the struct since we may read any object.
+	if err := locks.structLock(s, q.Struct, q.Write); err != nil {
+		return nil, err
+	}
+
+	// Handle iterating a index in order.
+	var after, afterValue []byte
+	if p.after != nil {
+		after, afterValue = p.after.key, p.after.value
+	}
+	if q.OrderBy != "" {
+		it, err := s.IterateStructIndex(q.Struct, q.OrderBy, engine.StructIteratorOptions{
+			Value:      p.where[q.OrderBy],
+			After:      after,
+			AfterValue: afterValue,
+			Reverse:    q.Descending,
+		})
+		if err == nil {
+			return &results{c: &collector{p: p}, it: it}, nil
+		}
+		if err != engine.ErrNotIndexed {
+			return nil, err
+		}
+	}
+
+	// Try each field in order to find a index which is ready.
+	fieldNames := make([]string, 0, len(p.where))
+	for field := range p.where {
+		fieldNames = append(fieldNames, field)
+	}
+	sort.Strings(fieldNames)
+	for _, field := range fieldNames {
+		keys, err := s.LookupStructIndex(q.Struct, field, p.where[field])
+		if err == engine.ErrNotIndexed {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		matched, err := getMatching(s, q.Struct, keys, p.where)
+		if err != nil {
+			return nil, err
+		}
+		return sortMatches(q, fields, matched, p)
+	}
+
+	// Fall back to iterating every object. If the objects are ordered by key, they are already in order.
+	if q.OrderBy == "" {
+		it, err := s.IterateStructObjects(q.Struct, engine.StructIteratorOptions{After: after, Reverse: q.Descending})
+		if err != nil {
+			return nil, err
+		}
+		return &results{c: &collector{p: p}, it: it}, nil
+	}
+	it, err := s.IterateStructObjects(q.Struct, engine.StructIteratorOptions{})
+	if err != nil {
+		return nil, err
+	}
+	matched, err := (&results{c: &collector{p: &page{where: p.where, limit: -1}}, it: it}).all()
+	if err != nil {
+		return nil, err
+	}
+	return sortMatches(q, fields, matched, p)
+}
+
+// Gets the latest version of the struct the query is on and the page from the parameters. The
+// fields used by the query are checked since the struct may have changed since it was compiled.
+func prepare(
+	s engine.Session, q Query, params map[string]any,
+) (*ast.StructToken, map[string]rqltypes.Type, *page, error) {
+	// Get the latest version of the struct.
+	structHistory, err := s.GetStructByKey(q.Struct)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	structToken := structHistory[len(structHistory)-1]
+	fields, err := rqltypes.FieldsFromSession(s, structToken)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Check the fields.
+	if q.OrderBy != "" {
+		if t, ok := fields[q.OrderBy]; !ok || !rqltypes.Orderable(t) {
+			return nil, nil, nil, errors.New("cannot order " + structToken.Name + " by " + q.OrderBy)
+		}
+	}
+	if err := checkAggregate(q, structToken.Name, fields); err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Get the page.
+	p, err := getPage(q, fields, params, rqltypes.SessionResolver(s))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return structToken, fields, p, nil
+}
+
+// Run is used to run the query within the session. The result is the Go representation of the
+// RemixDB type returned by the action, which is a struct for ActionFirst (or nil), a slice of
+// structs for ActionAll, a *string for ActionNextCursor, the AggregateType for aggregates (or a
+// slice of structs if grouped), and an int for everything else.
+func Run(s engine.Session, locks *Locks, q Query, params map[string]any) (any, error) {
+	structToken, fields, p, err := prepare(s, q, params)
+	if err != nil {
+		return nil, err
+	}
+	limit := p.limit
+	switch q.Action {
+	case ActionFirst:
+		if p.limit != 0 {
+			p.limit = 1
+		}
+	case ActionNextCursor:
+		// Get one more object to know if there are any after the page.
+		if limit < 0 {
+			return (*string)(nil), nil
+		}
+		p.limit++
+	}
+	res, err := find(s, locks, q, structToken, p)
+	if err != nil {
+		return nil, err
+	}
+
+	// Aggregates are calculated as the objects are found.
+	if q.Action.Aggregate() {
+		a := newAggregator(q, fields)
+		if err := res.each(a.add); err != nil {
+			return nil, err
+		}
+		return a.result()
+	}
+	matched, err := res.all()
+	if err != nil {
+		return nil, err
+	}
+	resolve := rqltypes.SessionResolver(s)
+
+	// Do the action.
+	structType := rqltypes.Type{Name: structToken.Name}
+	switch q.Action {
+	case ActionFirst:
+		if len(matched) == 0 {
+			return map[string]any(nil), nil
+		}
+		v, err := rqltypes.Decode(structType, matched[0].object, resolve)
+		if err != nil {
+			return nil, err
+		}
+		return v, nil
+	case ActionAll:
+		objects := make([]map[string]any, len(matched))
+		for i, m := range matched {
+			v, err := rqltypes.Decode(structType, m.object, resolve)
+			if err != nil {
+				return nil, err
+			}
+			objects[i] = v.(map[string]any)
+		}
+		return objects, nil
+	case ActionNextCursor:
+		if len(matched) <= limit {
+			return (*string)(nil), nil
+		}
+		if limit == 0 {
+			// Nothing is returned, so the listing resumes from the same place.
+			return p.token, nil
+		}
+		last := position{key: matched[limit-1].key}
+		if q.OrderBy != "" {
+			if last.value, err = fieldValue(matched[limit-1].object, q.OrderBy); err != nil {
+				return nil, err
+			}
+		}
+		token := encodeCursor(q.OrderBy, last)
+		return &token, nil
+	case ActionDelete:
+		for _, m := range matched {
+			if err := s.DeleteStructObject(q.Struct, m.key); err != nil {
+				return nil, err
+			}
+		}
+		return len(matched), nil
+	case ActionUpdate:
+		set, err := encodeFields(fields, params[ParamSet], resolve)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := set[PrimaryKey(structToken)]; ok {
+			return nil, errors.New("the primary key of a object cannot be updated")
+		}
+		for _, m := range matched {
+			objectFields, err := rqltypes.StructFields(m.object)
+			if err != nil {
+				return nil, err
+			}
+			for k, v := range set {
+				if bytes.Equal(v, []byte{0x00}) {
+					delete(objectFields, k)
+				} else {
+					objectFields[k] = v
+				}
+			}
+			object := rqltypes.EncodeStructFields(structToken.Name, objectFields)
+			if err := s.UpdateStructObject(q.Struct, m.key, object); err != nil {
+				return nil, err
+			}
+		}
+		return len(matched), nil
+	}
+	return nil, errors.New("unknown query action " + string(q.Action))
+}
@@ -0,0 +1,554 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package query
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"remixdb.io/ast"
+	"remixdb.io/internal/compiler/mocksession"
+	"remixdb.io/internal/engine"
+	"remixdb.io/internal/rqltypes"
+)
+
+var treeStruct = &ast.StructToken{
+	Name: "Tree",
+	Fields: []any{
+		ast.FieldToken{Name: "treeId", Type: "int", Decorators: []ast.DecoratorToken{{Method: "primary"}}},
+		ast.FieldToken{Name: "planterName", Type: "string", Decorators: []ast.DecoratorToken{{Method: "index"}}},
+		ast.FieldToken{Name: "planterAge", Type: "int?"},
+	},
+}
+
+// Implements engine.StructObjectIterator over a ordered slice of keys.
+type sliceIterator struct {
+	keys    []string
+	objects map[string][]byte
+	i       int
+}
+
+// Creates a iterator over the keys, applying the iterator options. sortKey is used to get the
+// position of each key in the order.
+func newSliceIterator(
+	objects map[string][]byte, keys []string, sortKey func(key string) string, options engine.StructIteratorOptions,
+) *sliceIterator {
+	sort.SliceStable(keys, func(i, j int) bool { return sortKey(keys[i]) < sortKey(keys[j]) })
+	if options.Reverse {
+		for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
+			keys[i], keys[j] = keys[j], keys[i]
+		}
+	}
+	if options.After != nil {
+		after := sortKey(string(options.After))
+		filtered := []string{}
+		for _, key := range keys {
+			if (!options.Reverse && sortKey(key) > after) || (options.Reverse && sortKey(key) < after) {
+				filtered = append(filtered, key)
+			}
+		}
+		keys = filtered
+	}
+	return &sliceIterator{keys: keys, objects: objects}
+}
+
+func (it *sliceIterator) Next() bool {
+	it.i++
+	return it.i <= len(it.keys)
+}
+
+func (it *sliceIterator) Key() []byte { return []byte(it.keys[it.i-1]) }
+
+func (it *sliceIterator) Value() ([]byte, error) { return it.objects[it.keys[it.i-1]], nil }
+
+func (it *sliceIterator) Err() error { return nil }
+
+// Defines a in memory table used by the tests.
+type testTable struct {
+	objects      map[string][]byte
+	indexReady   bool
+	scanned      bool
+	indexLookups int
+	locks        []string
+}
+
+func encodeTree(t *testing.T, id int, name string, age *int) []byte {
+	t.Helper()
+	b, err := rqltypes.Encode(rqltypes.Type{Name: "Tree"}, map[string]any{
+		"treeId": id, "planterName": name, "planterAge": age,
+	}, func(string) (map[string]rqltypes.Type, error) {
+		return rqltypes.FieldsFromStruct(treeStruct), nil
+	})
+	require.NoError(t, err)
+	return b
+}
+
+func encodeKey(t *testing.T, id int) string {
+	t.Helper()
+	b, err := rqltypes.Encode(rqltypes.Type{Name: rqltypes.Int}, id, nil)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func newTestTable(t *testing.T) (*testTable, *mocksession.SessionMock) {
+	age := 30
+	table := &testTable{
+		objects: map[string][]byte{
+			encodeKey(t, 1): encodeTree(t, 1, "astrid", &age),
+			encodeKey(t, 2): encodeTree(t, 2, "jake", nil),
+			encodeKey(t, 3): encodeTree(t, 3, "astrid", nil),
+		},
+	}
+	mock := &mocksession.SessionMock{
+		GetStructByKeyFunc: func(key string) ([]*ast.StructToken, error) {
+			if key != "Tree" {
+				return nil, engine.ErrNotExists
+			}
+			return []*ast.StructToken{treeStruct}, nil
+		},
+		GetEnumByKeyFunc: func(key string) (*ast.EnumToken, error) {
+			return nil, engine.ErrNotExists
+		},
+		GetStructObjectFunc: func(structName string, key []byte) ([]byte, error) {
+			object, ok := table.objects[string(key)]
+			if !ok {
+				return nil, engine.ErrNotExists
+			}
+			return object, nil
+		},
+		LookupStructIndexFunc: func(structName, field string, value []byte) ([][]byte, error) {
+			if field != "planterName" || !table.indexReady {
+				return nil, engine.ErrNotIndexed
+			}
+			table.indexLookups++
+			keys := [][]byte{}
+			for _, key := range sortedKeys(table.objects) {
+				fields, err := rqltypes.StructFields(table.objects[key])
+				require.NoError(t, err)
+				if string(fields[field]) == string(value) {
+					keys = append(keys, []byte(key))
+				}
+			}
+			return keys, nil
+		},
+		IterateStructObjectsFunc: func(structName string, options engine.StructIteratorOptions) (engine.StructObjectIterator, error) {
+			table.scanned = true
+			return newSliceIterator(table.objects, sortedKeys(table.objects), func(key string) string {
+				return key
+			}, options), nil
+		},
+		IterateStructIndexFunc: func(structName, field string, options engine.StructIteratorOptions) (engine.StructObjectIterator, error) {
+			if field != "planterName" || !table.indexReady {
+				return nil, engine.ErrNotIndexed
+			}
+			table.indexLookups++
+			keys := []string{}
+			for _, key := range sortedKeys(table.objects) {
+				fields, err := rqltypes.StructFields(table.objects[key])
+				require.NoError(t, err)
+				if options.Value == nil || string(fields[field]) == string(options.Value) {
+					keys = append(keys, key)
+				}
+			}
+			return newSliceIterator(table.objects, keys, func(key string) string {
+				value := options.AfterValue
+				if object, ok := table.objects[key]; ok {
+					fields, err := rqltypes.StructFields(object)
+					require.NoError(t, err)
+					value = fields[field]
+				}
+				sortKey, err := rqltypes.SortKey(rqltypes.Type{Name: rqltypes.String}, value)
+				require.NoError(t, err)
+				return string(sortKey) + key
+			}, options), nil
+		},
+		UpdateStructObjectFunc: func(structName string, key, value []byte) error {
+			table.objects[string(key)] = value
+			return nil
+		},
+		DeleteStructObjectFunc: func(structName string, key []byte) error {
+			delete(table.objects, string(key))
+			return nil
+		},
+		AcquireStructReadLockFunc: func(structNames ...string) error {
+			table.locks = append(table.locks, "struct read")
+			return nil
+		},
+		AcquireStructWriteLockFunc: func(structNames ...string) error {
+			table.locks = append(table.locks, "struct write")
+			return nil
+		},
+		AcquireStructObjectReadLockFunc: func(structName string, keys ...[]byte) error {
+			table.locks = append(table.locks, "object read")
+			return nil
+		},
+		AcquireStructObjectWriteLockFunc: func(structName string, keys ...[]byte) error {
+			table.locks = append(table.locks, "object write")
+			return nil
+		},
+	}
+	return table, mock
+}
+
+func sortedKeys(m map[string][]byte) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func ids(t *testing.T, v any) []int {
+	t.Helper()
+	result := []int{}
+	for _, object := range v.([]map[string]any) {
+		result = append(result, object["treeId"].(int))
+	}
+	return result
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name string
+
+		query      Query
+		params     map[string]any
+		indexReady bool
+
+		expected      any
+		expectedIDs   []int
+		expectedLocks []string
+		scanned       bool
+		indexed       bool
+	}{
+		{
+			name:          "first by primary key",
+			query:         Query{Struct: "Tree", Action: ActionFirst},
+			params:        map[string]any{ParamWhere: map[string]any{"treeId": 2}},
+			expectedIDs:   []int{2},
+			expectedLocks: []string{"object read"},
+		},
+		{
+			name:          "first by primary key missing",
+			query:         Query{Struct: "Tree", Action: ActionFirst},
+			params:        map[string]any{ParamWhere: map[string]any{"treeId": 4}},
+			expected:      map[string]any(nil),
+			expectedLocks: []string{"object read"},
+		},
+		{
+			name:          "all by index",
+			query:         Query{Struct: "Tree", Action: ActionAll},
+			params:        map[string]any{ParamWhere: map[string]any{"planterName": "astrid"}},
+			indexReady:    true,
+			expectedIDs:   []int{1, 3},
+			expectedLocks: []string{"struct read"},
+			indexed:       true,
+		},
+		{
+			name:          "all by scan when index not ready",
+			query:         Query{Struct: "Tree", Action: ActionAll},
+			params:        map[string]any{ParamWhere: map[string]any{"planterName": "astrid"}},
+			expectedIDs:   []int{1, 3},
+			expectedLocks: []string{"struct read"},
+			scanned:       true,
+		},
+		{
+			name:          "count null field",
+			query:         Query{Struct: "Tree", Action: ActionCount},
+			params:        map[string]any{ParamWhere: map[string]any{"planterAge": nil}},
+			expected:      2,
+			expectedLocks: []string{"struct read"},
+			scanned:       true,
+		},
+		{
+			name:          "count everything",
+			query:         Query{Struct: "Tree", Action: ActionCount},
+			params:        map[string]any{},
+			expected:      3,
+			expectedLocks: []string{"struct read"},
+			scanned:       true,
+		},
+		{
+			name:          "delete with write lock",
+			query:         Query{Struct: "Tree", Action: ActionDelete, Write: true},
+			params:        map[string]any{ParamWhere: map[string]any{"treeId": 1}},
+			expected:      1,
+			expectedLocks: []string{"object write"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, mock := newTestTable(t)
+			table.indexReady = tt.indexReady
+			v, err := Run(mock, &Locks{}, tt.query, tt.params)
+			require.NoError(t, err)
+			switch {
+			case tt.expectedIDs != nil && tt.query.Action == ActionFirst:
+				assert.Equal(t, tt.expectedIDs[0], v.(map[string]any)["treeId"])
+			case tt.expectedIDs != nil:
+				assert.Equal(t, tt.expectedIDs, ids(t, v))
+			default:
+				assert.Equal(t, tt.expected, v)
+			}
+			assert.Equal(t, tt.expectedLocks, table.locks)
+			assert.Equal(t, tt.scanned, table.scanned)
+			assert.Equal(t, tt.indexed, table.indexLookups != 0)
+		})
+	}
+}
+
+func TestRun_update(t *testing.T) {
+	table, mock := newTestTable(t)
+	v, err := Run(mock, &Locks{}, Query{Struct: "Tree", Action: ActionUpdate, Write: true}, map[string]any{
+		ParamWhere: map[string]any{"planterName": "astrid"},
+		ParamSet:   map[string]any{"planterName": "someone", "planterAge": nil},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, v)
+	assert.Equal(t, encodeTree(t, 1, "someone", nil), table.objects[encodeKey(t, 1)])
+	assert.Equal(t, encodeTree(t, 2, "jake", nil), table.objects[encodeKey(t, 2)])
+	assert.Equal(t, encodeTree(t, 3, "someone", nil), table.objects[encodeKey(t, 3)])
+}
+
+func TestRun_updatePrimaryKey(t *testing.T) {
+	_, mock := newTestTable(t)
+	_, err := Run(mock, &Locks{}, Query{Struct: "Tree", Action: ActionUpdate, Write: true}, map[string]any{
+		ParamSet: map[string]any{"treeId": 5},
+	})
+	assert.EqualError(t, err, "the primary key of a object cannot be updated")
+}
+
+func TestLocks(t *testing.T) {
+	table, mock := newTestTable(t)
+	locks := &Locks{}
+	require.NoError(t, locks.structLock(mock, "Tree", false))
+	require.NoError(t, locks.structLock(mock, "Tree", false))
+	require.NoError(t, locks.structLock(mock, "Forest", true))
+	require.NoError(t, locks.structLock(mock, "Forest", false))
+	require.NoError(t, locks.objectLock(mock, "Tree", []byte("a"), true))
+	require.NoError(t, locks.objectLock(mock, "Tree", []byte("a"), false))
+	require.NoError(t, locks.objectLock(mock, "Tree", []byte("b"), false))
+	assert.Equal(t, []string{"struct read", "struct write", "object write", "object read"}, table.locks)
+
+	// Read locks are never upgraded since another session could change the values which were read
+	// between the read lock being released and the write lock being acquired.
+	assert.Equal(t, ErrLockUpgrade, locks.structLock(mock, "Tree", true))
+	assert.Equal(t, ErrLockUpgrade, locks.objectLock(mock, "Tree", []byte("b"), true))
+	assert.Equal(t, []string{"struct read", "struct write", "object write", "object read"}, table.locks)
+}
+
+func TestRun_pagination(t *testing.T) {
+	tests := []struct {
+		name string
+
+		query  Query
+		params map[string]any
+
+		expectedIDs []int
+	}{
+		{
+			name:        "order by field",
+			query:       Query{Struct: "Tree", Action: ActionAll, OrderBy: "planterName"},
+			params:      map[string]any{},
+			expectedIDs: []int{1, 3, 2},
+		},
+		{
+			name:        "order by field descending",
+			query:       Query{Struct: "Tree", Action: ActionAll, OrderBy: "planterName", Descending: true},
+			params:      map[string]any{},
+			expectedIDs: []int{2, 3, 1},
+		},
+		{
+			name:        "order by field with conditions",
+			query:       Query{Struct: "Tree", Action: ActionAll, OrderBy: "planterName", Descending: true},
+			params:      map[string]any{ParamWhere: map[string]any{"planterName": "astrid"}},
+			expectedIDs: []int{3, 1},
+		},
+		{
+			name:        "offset and limit",
+			query:       Query{Struct: "Tree", Action: ActionAll, OrderBy: "planterName"},
+			params:      map[string]any{ParamOffset: 1, ParamLimit: 1},
+			expectedIDs: []int{3},
+		},
+		{
+			name:        "limit by key",
+			query:       Query{Struct: "Tree", Action: ActionAll},
+			params:      map[string]any{ParamLimit: 2},
+			expectedIDs: []int{1, 2},
+		},
+		{
+			name:        "zero limit",
+			query:       Query{Struct: "Tree", Action: ActionAll},
+			params:      map[string]any{ParamLimit: 0},
+			expectedIDs: []int{},
+		},
+	}
+	for _, tt := range tests {
+		for _, indexReady := range []bool{false, true} {
+			t.Run(tt.name, func(t *testing.T) {
+				table, mock := newTestTable(t)
+				table.indexReady = indexReady
+				v, err := Run(mock, &Locks{}, tt.query, tt.params)
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedIDs, ids(t, v))
+			})
+		}
+	}
+}
+
+func TestRun_cursor(t *testing.T) {
+	for _, query := range []Query{
+		{Struct: "Tree", OrderBy: "planterName"},
+		{Struct: "Tree", OrderBy: "planterName", Descending: true},
+		{Struct: "Tree"},
+	} {
+		for _, indexReady := range []bool{false, true} {
+			table, mock := newTestTable(t)
+			table.indexReady = indexReady
+
+			// Get every object in order.
+			query.Action = ActionAll
+			v, err := Run(mock, &Locks{}, query, map[string]any{})
+			require.NoError(t, err)
+			expected := ids(t, v)
+
+			// Page through the objects one at a time.
+			got := []int{}
+			var token *string
+			for i := 0; i < 5; i++ {
+				params := map[string]any{ParamLimit: 1, ParamAfter: token}
+				query.Action = ActionAll
+				v, err := Run(mock, &Locks{}, query, params)
+				require.NoError(t, err)
+				got = append(got, ids(t, v)...)
+
+				query.Action = ActionNextCursor
+				v, err = Run(mock, &Locks{}, query, params)
+				require.NoError(t, err)
+				token = v.(*string)
+				if token == nil {
+					break
+				}
+			}
+			assert.Equal(t, expected, got)
+			assert.Nil(t, token)
+		}
+	}
+}
+
+func TestRun_invalidCursor(t *testing.T) {
+	_, mock := newTestTable(t)
+	token := encodeCursor("", position{key: []byte(encodeKey(t, 1))})
+	for _, after := range []string{"!", "", token} {
+		_, err := Run(mock, &Locks{}, Query{Struct: "Tree", Action: ActionAll, OrderBy: "planterName"}, map[string]any{
+			ParamAfter: after,
+		})
+		assert.Equal(t, ErrInvalidCursor, err)
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func TestRun_aggregate(t *testing.T) {
+	tests := []struct {
+		name string
+
+		query  Query
+		params map[string]any
+
+		expected    any
+		expectedErr string
+	}{
+		{
+			name:     "sum",
+			query:    Query{Struct: "Tree", Action: ActionSum, Field: "planterAge"},
+			expected: 50,
+		},
+		{
+			name:     "sum with no values",
+			query:    Query{Struct: "Tree", Action: ActionSum, Field: "planterAge"},
+			params:   map[string]any{ParamWhere: map[string]any{"planterName": "nobody"}},
+			expected: 0,
+		},
+		{
+			name:     "avg",
+			query:    Query{Struct: "Tree", Action: ActionAvg, Field: "planterAge"},
+			expected: ptr(25.0),
+		},
+		{
+			name:     "avg of nulls",
+			query:    Query{Struct: "Tree", Action: ActionAvg, Field: "planterAge"},
+			params:   map[string]any{ParamWhere: map[string]any{"treeId": 2}},
+			expected: (*float64)(nil),
+		},
+		{
+			name:     "min",
+			query:    Query{Struct: "Tree", Action: ActionMin, Field: "planterAge"},
+			expected: ptr(20),
+		},
+		{
+			name:     "max string",
+			query:    Query{Struct: "Tree", Action: ActionMax, Field: "planterName"},
+			expected: ptr("jake"),
+		},
+		{
+			name:     "max with limit",
+			query:    Query{Struct: "Tree", Action: ActionMax, Field: "planterAge"},
+			params:   map[string]any{ParamLimit: 1},
+			expected: ptr(30),
+		},
+		{
+			name:  "grouped count",
+			query: Query{Struct: "Tree", Action: ActionCount, GroupBy: "planterName"},
+			expected: []map[string]any{
+				{"planterName": "astrid", "count": 2},
+				{"planterName": "jake", "count": 2},
+			},
+		},
+		{
+			name:  "grouped max",
+			query: Query{Struct: "Tree", Action: ActionMax, Field: "planterAge", GroupBy: "planterName"},
+			expected: []map[string]any{
+				{"planterName": "astrid", "max": ptr(30)},
+				{"planterName": "jake", "max": ptr(20)},
+			},
+		},
+		{
+			name:  "grouped by optional field",
+			query: Query{Struct: "Tree", Action: ActionCount, GroupBy: "planterAge"},
+			expected: []map[string]any{
+				{"planterAge": (*int)(nil), "count": 2},
+				{"planterAge": ptr(20), "count": 1},
+				{"planterAge": ptr(30), "count": 1},
+			},
+		},
+		{
+			name:        "sum of string",
+			query:       Query{Struct: "Tree", Action: ActionSum, Field: "planterName"},
+			expectedErr: "cannot sum the field planterName of Tree",
+		},
+		{
+			name:        "grouped all",
+			query:       Query{Struct: "Tree", Action: ActionAll, GroupBy: "planterName"},
+			expectedErr: "only aggregates can be grouped",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, mock := newTestTable(t)
+			age := 20
+			table.objects[encodeKey(t, 4)] = encodeTree(t, 4, "jake", &age)
+			v, err := Run(mock, &Locks{}, tt.query, tt.params)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, v)
+		})
+	}
+}
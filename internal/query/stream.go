@@ -0,0 +1,56 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package query
+
+import (
+	"errors"
+
+	"remixdb.io/internal/engine"
+	"remixdb.io/internal/rqltypes"
+)
+
+// Stream is used to read the objects matched by a query one at a time. The objects are read from
+// storage as they are needed where the query allows it, so the locks taken by the query are held
+// until the session is closed.
+type Stream struct {
+	res        *results
+	structType rqltypes.Type
+	resolve    rqltypes.StructResolver
+}
+
+// Open is used to open a stream of the objects matched by a query with ActionAll. This takes the
+// same parameters as Run.
+func Open(s engine.Session, locks *Locks, q Query, params map[string]any) (*Stream, error) {
+	if q.Action != ActionAll {
+		return nil, errors.New("only queries which get all of the objects can be streamed")
+	}
+	structToken, _, p, err := prepare(s, q, params)
+	if err != nil {
+		return nil, err
+	}
+	res, err := find(s, locks, q, structToken, p)
+	if err != nil {
+		return nil, err
+	}
+	return &Stream{
+		res:        res,
+		structType: rqltypes.Type{Name: structToken.Name},
+		resolve:    rqltypes.SessionResolver(s),
+	}, nil
+}
+
+// Next is used to get the RemixDB encoding of the next object. Returns nil once there are no more
+// objects. The object is decoded and encoded again with the latest version of the struct, so the
+// bytes sent are the same as if the object was returned by Run.
+func (s *Stream) Next() ([]byte, error) {
+	m, ok, err := s.res.next()
+	if err != nil || !ok {
+		return nil, err
+	}
+	v, err := rqltypes.Decode(s.structType, m.object, s.resolve)
+	if err != nil {
+		return nil, err
+	}
+	return rqltypes.Encode(s.structType, v, s.resolve)
+}
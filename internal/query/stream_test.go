@@ -0,0 +1,79 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"remixdb.io/internal/rqltypes"
+)
+
+func TestOpen(t *testing.T) {
+	for _, query := range []Query{
+		{Struct: "Tree", Action: ActionAll},
+		{Struct: "Tree", Action: ActionAll, OrderBy: "planterName", Descending: true},
+	} {
+		for _, params := range []map[string]any{
+			{},
+			{ParamLimit: 2},
+			{ParamWhere: map[string]any{"treeId": 3}},
+		} {
+			table, mock := newTestTable(t)
+
+			// Stream the objects.
+			stream, err := Open(mock, &Locks{}, query, params)
+			require.NoError(t, err)
+			got := [][]byte{}
+			for {
+				b, err := stream.Next()
+				require.NoError(t, err)
+				if b == nil {
+					break
+				}
+				got = append(got, b)
+			}
+
+			// Check they are the same as running the query.
+			v, err := Run(mock, &Locks{}, query, params)
+			require.NoError(t, err)
+			expected := [][]byte{}
+			for _, id := range ids(t, v) {
+				expected = append(expected, table.objects[encodeKey(t, id)])
+			}
+			assert.Equal(t, expected, got)
+		}
+	}
+}
+
+func TestOpen_notAll(t *testing.T) {
+	_, mock := newTestTable(t)
+	_, err := Open(mock, &Locks{}, Query{Struct: "Tree", Action: ActionCount}, map[string]any{})
+	assert.EqualError(t, err, "only queries which get all of the objects can be streamed")
+}
+
+func TestOpen_reencodes(t *testing.T) {
+	// Store a object with a field which is no longer in the struct and without the optional field.
+	table, mock := newTestTable(t)
+	id, err := rqltypes.Encode(rqltypes.Type{Name: rqltypes.Int}, 1, nil)
+	require.NoError(t, err)
+	name, err := rqltypes.Encode(rqltypes.Type{Name: rqltypes.String}, "astrid", nil)
+	require.NoError(t, err)
+	table.objects = map[string][]byte{
+		encodeKey(t, 1): rqltypes.EncodeStructFields("Tree", map[string][]byte{
+			"treeId": id, "planterName": name, "height": id,
+		}),
+	}
+
+	// The object is streamed with the fields of the latest version of the struct.
+	stream, err := Open(mock, &Locks{}, Query{Struct: "Tree", Action: ActionAll}, map[string]any{})
+	require.NoError(t, err)
+	b, err := stream.Next()
+	require.NoError(t, err)
+	assert.Equal(t, encodeTree(t, 1, "astrid", nil), b)
+	b, err = stream.Next()
+	require.NoError(t, err)
+	assert.Nil(t, b)
+}
@@ -39,6 +39,15 @@ var filledStructure = &structure.Base{
 				},
 			},
 		},
+		"EnumField": {
+			Comment: "used to test a enum field",
+			Fields: map[string]structure.StructField{
+				"colour": {
+					Comment: "used to test a enum field",
+					Type:    "Colour",
+				},
+			},
+		},
 		"ErrorWithAllFields": {
 			Comment:   "used to test a error with all fields",
 			Exception: true,
@@ -54,6 +63,12 @@ var filledStructure = &structure.Base{
 			},
 		},
 	},
+	Enums: map[string]structure.Enum{
+		"Colour": {
+			Comment: "used to test a enum",
+			Values:  []string{"red", "Green", "light_blue"},
+		},
+	},
 	Methods: map[string]structure.Method{
 		"VoidInput": {
 			Comment: "used to test a void input",
@@ -92,6 +107,17 @@ var filledStructure = &structure.Base{
 			Output:         "OneField",
 			OutputOptional: true,
 		},
+		"EnumOutput": {
+			Comment:   "used to test a enum input and output",
+			Input:     "Colour",
+			InputName: "EnumOutputInput",
+			Output:    "Colour",
+		},
+		"OptionalEnumOutput": {
+			Comment:        "used to test a optional enum output",
+			Output:         "Colour",
+			OutputOptional: true,
+		},
 		"StructCursorOutput": {
 			Comment:         "used to test a struct cursor output",
 			Output:          "OneField",
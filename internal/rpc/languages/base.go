@@ -251,6 +251,13 @@ func processGoTemplate(root *structure.Base, name, tmpl string, data any, variab
 				}
 			}
 
+			// Enums are decoded by their own template since the name is not a struct.
+			if _, ok := root.Enums[nameVar]; ok {
+				for i, v := range possibilities {
+					possibilities[i] = strings.Replace(v, "."+nameVar, ".enum", 1)
+				}
+			}
+
 			// Loop through the possibilities and check if they exist.
 			for _, v := range possibilities {
 				if tmpl, ok := subtemplates[v]; ok {
@@ -7,6 +7,7 @@ import (
 	_ "embed"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/iancoleman/strcase"
@@ -35,7 +36,7 @@ func orderedMapStringKeys[V any](m map[string]V) []string {
 
 var methodsAnchorRegex = regexp.MustCompile(`([ \t]+)\/\/ AUTO-GENERATION MARKER: methods`)
 
-func handleJsStruct(structName string, structure structure.Struct, spacing string) string {
+func handleJsStruct(base *structure.Base, structName string, structure structure.Struct, spacing string) string {
 	// Defines the struct.
 	struct_ := ""
 
@@ -53,7 +54,7 @@ func handleJsStruct(structName string, structure structure.Struct, spacing strin
 	jsBlob := "{"
 	for _, fieldName := range orderedMapStringKeys(structure.Fields) {
 		field := structure.Fields[fieldName]
-		x := jsRpcType(field.Type, field.Optional)
+		x := jsRpcType(base, field.Type, field.Optional)
 		if field.Array {
 			x = "[" + x + "]"
 		}
@@ -67,13 +68,39 @@ func handleJsStruct(structName string, structure structure.Struct, spacing strin
 	return struct_ + classTemplate
 }
 
+// Gets the key used for a enum value within a object. Values which start with a number are
+// quoted since they are not valid identifiers.
+func jsEnumKey(value string) string {
+	if value != "" && value[0] >= '0' && value[0] <= '9' {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+func handleJsEnum(enumName string, enum structure.Enum, spacing string) string {
+	// Handle comments.
+	enum_ := ""
+	comment := strings.TrimSpace(enum.Comment)
+	if comment != "" {
+		enum_ += "// " + strings.ReplaceAll(comment, "\n", "\n// ") + "\n"
+	}
+
+	// Enums are frozen objects of their values since they are sent as strings.
+	enum_ += "const " + enumName + " = Object.freeze({"
+	for _, value := range enum.Values {
+		enum_ += "\n" + spacing + jsEnumKey(value) + ": " + strconv.Quote(value) + ","
+	}
+	return enum_ + "\n});"
+}
+
 func handleJsStructures(base *structure.Base, spacing string) string {
-	structs := make([]string, len(base.Structs))
-	i := 0
+	structs := make([]string, 0, len(base.Enums)+len(base.Structs))
+	for _, enumName := range orderedMapStringKeys(base.Enums) {
+		structs = append(structs, handleJsEnum(enumName, base.Enums[enumName], spacing))
+	}
 	for _, structName := range orderedMapStringKeys(base.Structs) {
 		structure := base.Structs[structName]
-		structs[i] = handleJsStruct(structName, structure, spacing)
-		i++
+		structs = append(structs, handleJsStruct(base, structName, structure, spacing))
 	}
 	return strings.Join(structs, "\n\n")
 }
@@ -87,7 +114,11 @@ func prefixJsComments(comments, prefix string) string {
 	) + "\n"
 }
 
-func jsRpcType(i string, nullable bool) string {
+func jsRpcType(base *structure.Base, i string, nullable bool) string {
+	// Enums are sent as strings.
+	if _, ok := base.Enums[i]; ok {
+		i = "string"
+	}
 	switch i {
 	case "string":
 		i = "String"
@@ -135,7 +166,7 @@ func generateJsMethods(base *structure.Base, spacing string) string {
 
 		// Validate the input.
 		if method.Input != "" {
-			jsFuncs += spacing2 + "_validateType(" + method.InputName + ", " + jsRpcType(method.Input, method.InputOptional) + ");\n"
+			jsFuncs += spacing2 + "_validateType(" + method.InputName + ", " + jsRpcType(base, method.Input, method.InputOptional) + ");\n"
 		}
 
 		// Encode the body if it is present.
@@ -154,7 +185,7 @@ func generateJsMethods(base *structure.Base, spacing string) string {
 		if outputType == "" {
 			outputType = "null"
 		} else {
-			outputType = jsRpcType(outputType, method.OutputOptional)
+			outputType = jsRpcType(base, outputType, method.OutputOptional)
 		}
 		if method.OutputBehaviour == structure.OutputBehaviourArray {
 			outputType = "[" + outputType + "]"
@@ -196,6 +227,9 @@ func jsGen(base *structure.Base, isNode, isEsm bool) string {
 
 	// Deal with the exports object.
 	exports := ""
+	for _, enumName := range orderedMapStringKeys(base.Enums) {
+		exports += "\n" + spacing + enumName + ","
+	}
 	for _, structName := range orderedMapStringKeys(base.Structs) {
 		exports += "\n" + spacing + structName + ","
 	}
@@ -215,8 +249,25 @@ func jsGen(base *structure.Base, isNode, isEsm bool) string {
 }
 
 func jsDtsGen(base *structure.Base, isNode, isEsm bool) string {
-	// TODO
-	return jsDtsTemplate
+	// Add the enums as string unions before the structs.
+	enums := ""
+	for _, enumName := range orderedMapStringKeys(base.Enums) {
+		enum := base.Enums[enumName]
+		enums += prefixJsComments(enum.Comment, "")
+		values := make([]string, len(enum.Values))
+		for i, value := range enum.Values {
+			values[i] = strconv.Quote(value)
+		}
+		enums += "export type " + enumName + " = " + strings.Join(values, " | ") + ";\n"
+		enums += "export const " + enumName + ": {"
+		for i, value := range enum.Values {
+			enums += "\n  readonly " + jsEnumKey(value) + ": " + values[i] + ";"
+		}
+		enums += "\n};\n\n"
+	}
+
+	// TODO: Handle the rest.
+	return strings.Replace(jsDtsTemplate, "// AUTO-GENERATION MARKER: structs", enums+"// AUTO-GENERATION MARKER: structs", 1)
 }
 
 func js(base *structure.Base, opts map[string]string) (map[Extension]string, error) {
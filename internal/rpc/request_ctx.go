@@ -57,6 +57,10 @@ func Cursor(hn func() ([]byte, error), cleanup func()) *Response {
 	}
 }
 
+// IsCursor is used to check if the response is a cursor. The cleanup function of a cursor is
+// responsible for releasing anything it uses.
+func (r *Response) IsCursor() bool { return r.cursorHn != nil }
+
 // RemixDBException is used to return a RemixDB exception.
 func RemixDBException(httpCode int, code, message string) *Response {
 	return &Response{
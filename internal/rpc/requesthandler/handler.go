@@ -4,10 +4,12 @@
 package requesthandler
 
 import (
+	"errors"
 	"reflect"
 
 	"remixdb.io/internal/compiler"
 	"remixdb.io/internal/engine"
+	"remixdb.io/internal/query"
 	"remixdb.io/internal/rpc"
 )
 
@@ -102,10 +104,37 @@ func (e partitionHn) do(ctx *rpc.RequestCtx) (*rpc.Response, error) {
 	resValues := reflectValue.Call([]reflect.Value{reflect.ValueOf(pluginRpcStructure)})
 	err, _ = resValues[0].Interface().(error)
 	if err != nil {
-		_ = e.s.Close()
+		_ = pluginRpcStructure.Close()
+
+		// Turn unique constraint errors into a exception the client can handle.
+		var uniqueErr engine.UniqueConstraintError
+		if errors.As(err, &uniqueErr) {
+			return rpc.RemixDBException(
+				409, "unique_constraint_violation",
+				"The value of "+uniqueErr.Struct+"."+uniqueErr.Field+" must be unique.",
+			), nil
+		}
+
+		// Turn values which are not within a enum into a exception the client can handle.
+		var enumErr engine.EnumValueError
+		if errors.As(err, &enumErr) {
+			return rpc.RemixDBException(400, "invalid_enum_value", enumErr.Error()+"."), nil
+		}
+
+		// Turn invalid cursor tokens into a exception the client can handle.
+		if err == query.ErrInvalidCursor {
+			return rpc.RemixDBException(400, "invalid_cursor", "The cursor token is invalid."), nil
+		}
 		return nil, err
 	}
 
+	// Close the session unless a cursor is still using it. The cursor closes it once the client
+	// is done with it.
+	resp := pluginRpcStructure.resp
+	if resp == nil || !resp.IsCursor() {
+		_ = pluginRpcStructure.Close()
+	}
+
 	// Return the response.
-	return pluginRpcStructure.resp, nil
+	return resp, nil
 }
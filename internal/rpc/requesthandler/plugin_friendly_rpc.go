@@ -5,17 +5,32 @@ package requesthandler
 
 import (
 	"context"
+	"encoding/json"
 
 	"remixdb.io/internal/engine"
+	"remixdb.io/internal/query"
 	"remixdb.io/internal/rpc"
+	"remixdb.io/internal/rqltypes"
 )
 
 type pluginFriendlyRpc struct {
 	engine.Session
 
-	req   *rpc.RequestCtx
-	perms []string
-	resp  *rpc.Response
+	req    *rpc.RequestCtx
+	perms  []string
+	resp   *rpc.Response
+	locks  query.Locks
+	closed bool
+}
+
+// Close is used to close the session. This can be called more than once since both the contract
+// and the handler may close it.
+func (r *pluginFriendlyRpc) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	return r.Session.Close()
 }
 
 // Permissions is used to return the permissions fetched during authentication.
@@ -27,6 +42,15 @@ func (r pluginFriendlyRpc) Context() context.Context { return r.req.Context }
 // Body is used to return the body from RequestCtx.
 func (r pluginFriendlyRpc) Body() []byte { return r.req.Body }
 
+// ParseRemixDBBody is used to decode the RemixDB encoded body from RequestCtx into the Go representation of the type.
+func (r pluginFriendlyRpc) ParseRemixDBBody(type_ string) (any, error) {
+	t, err := rqltypes.ResolveEnums(r.Session, rqltypes.Parse(type_))
+	if err != nil {
+		return nil, err
+	}
+	return rqltypes.Decode(t, r.req.Body, rqltypes.SessionResolver(r.Session))
+}
+
 // RespondWithCursor is used to respond with a cursor. If this isn't the first usage, it will replace the previous response.
 func (r *pluginFriendlyRpc) RespondWithCursor(hn func() ([]byte, error)) {
 	r.resp = rpc.Cursor(hn, func() { _ = r.Close() })
@@ -35,6 +59,20 @@ func (r *pluginFriendlyRpc) RespondWithCursor(hn func() ([]byte, error)) {
 // RespondWithRemixDBBytes is used to respond with RemixDB bytes. If this isn't the first usage, it will replace the previous response.
 func (r *pluginFriendlyRpc) RespondWithRemixDBBytes(data []byte) { r.resp = rpc.RemixDBBytes(data) }
 
+// RespondWithRemixDBValue is used to respond with a value encoded as the type specified. If this isn't the first usage, it will replace the previous response.
+func (r *pluginFriendlyRpc) RespondWithRemixDBValue(type_ string, value any) error {
+	t, err := rqltypes.ResolveEnums(r.Session, rqltypes.Parse(type_))
+	if err != nil {
+		return err
+	}
+	b, err := rqltypes.Encode(t, value, rqltypes.SessionResolver(r.Session))
+	if err != nil {
+		return err
+	}
+	r.resp = rpc.RemixDBBytes(b)
+	return nil
+}
+
 // RespondWithRemixDBException is used to respond with a RemixDB exception. If this isn't the first usage, it will replace the previous response.
 func (r *pluginFriendlyRpc) RespondWithRemixDBException(httpCode int, code, message string) {
 	r.resp = rpc.RemixDBException(httpCode, code, message)
@@ -44,3 +82,25 @@ func (r *pluginFriendlyRpc) RespondWithRemixDBException(httpCode int, code, mess
 func (r *pluginFriendlyRpc) RespondWithCustomException(code int, exceptionName string, body any) {
 	r.resp = rpc.CustomException(code, exceptionName, body)
 }
+
+// RunQuery is used to run a query built by the compiler. The query is the JSON encoding of a query.Query.
+func (r *pluginFriendlyRpc) RunQuery(q string, params map[string]any) (any, error) {
+	var x query.Query
+	if err := json.Unmarshal([]byte(q), &x); err != nil {
+		return nil, err
+	}
+	return query.Run(r.Session, &r.locks, x, params)
+}
+
+// QueryCursor is used to open a stream of the objects matched by a query built by the compiler. The result is the handler for RespondWithCursor.
+func (r *pluginFriendlyRpc) QueryCursor(q string, params map[string]any) (func() ([]byte, error), error) {
+	var x query.Query
+	if err := json.Unmarshal([]byte(q), &x); err != nil {
+		return nil, err
+	}
+	stream, err := query.Open(r.Session, &r.locks, x, params)
+	if err != nil {
+		return nil, err
+	}
+	return stream.Next, nil
+}
@@ -170,6 +170,7 @@ func (s *Server) handleRpc(r sharedRequest) {
 		ws, ok := r.(websocketRequest)
 		if !ok {
 			// Someone tried to use a cursor on a non-websocket request. Return a 400.
+			resp.cursorHn.cleanup()
 			_ = r.ReturnRemixDBException(400, "non_cursor_request", "This request type does not support cursors.")
 			return
 		}
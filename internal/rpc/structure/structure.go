@@ -10,7 +10,7 @@ type StructField struct {
 
 	// Type is used to define the type of the field. Built-in types are
 	// "string", "uint", "int", "float", "bigint", "timestamp", "bool", and "bytes".
-	// If the type is not built-in, it is a structure.
+	// If the type is not built-in, it is a enum or a structure.
 	Type string `json:"type"`
 
 	// Array is used to define if the field is an array.
@@ -34,6 +34,15 @@ type Struct struct {
 	Fields map[string]StructField `json:"fields"`
 }
 
+// Enum is used to define a enum within the RPC. Enums are sent as strings.
+type Enum struct {
+	// Comment is used to define the comment. Can be blank.
+	Comment string `json:"comment"`
+
+	// Values is used to define the values of the enum in the order they were declared.
+	Values []string `json:"values"`
+}
+
 // OutputBehaviour is used to define the behaviour of the output.
 type OutputBehaviour string
 
@@ -55,8 +64,8 @@ type Method struct {
 
 	// Input is used to define the input structure. Built-in types are
 	// "string", "uint", "int", "float", "bigint", "timestamp", "bool", and "bytes".
-	// If the type is not built-in, it is a structure. If it is blank, there is
-	// no input.
+	// If the type is not built-in, it is a enum or a structure. If it is blank,
+	// there is no input.
 	Input string `json:"input"`
 
 	// InputName is used to define the name of the input structure. Required if
@@ -68,8 +77,8 @@ type Method struct {
 
 	// Output is used to define the output structure. Built-in types are
 	// "string", "uint", "int", "float", "bigint", "timestamp", "bool", and "bytes".
-	// If the type is not built-in, it is a structure. If it is blank, there is
-	// no output.
+	// If the type is not built-in, it is a enum or a structure. If it is blank,
+	// there is no output.
 	Output string `json:"output"`
 
 	// OutputOptional is used to define if the output is optional.
@@ -86,6 +95,10 @@ type Base struct {
 	// Structs is used to define the structures within the language.
 	Structs map[string]Struct `json:"structs"`
 
+	// Enums is used to define the enums within the language. Fields and methods use the name
+	// of the enum as their type.
+	Enums map[string]Enum `json:"enums"`
+
 	// Methods is used to define the methods within the language.
 	Methods map[string]Method `json:"methods"`
 
@@ -0,0 +1,121 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package rpc
+
+import (
+	"strings"
+
+	"remixdb.io/ast"
+	"remixdb.io/internal/engine"
+	"remixdb.io/internal/rpc/structure"
+	"remixdb.io/internal/rqltypes"
+)
+
+// Turns a RemixDB type into a structure field.
+func typeToField(t rqltypes.Type) structure.StructField {
+	f := structure.StructField{Optional: t.Optional}
+	if t.Elem != nil {
+		f.Array = true
+		t = *t.Elem
+	}
+	f.Type = t.Name
+	return f
+}
+
+// Turns the field tokens within a struct or exception into structure fields.
+func structFields(tokens []any) map[string]structure.StructField {
+	fields := map[string]structure.StructField{}
+	for _, token := range tokens {
+		if field, ok := token.(ast.FieldToken); ok {
+			f := typeToField(rqltypes.Parse(field.Type))
+			f.Comment = field.Comment
+			fields[field.Name] = f
+		}
+	}
+	return fields
+}
+
+// Turns a contract into a method.
+func contractToMethod(contract *ast.ContractToken) structure.Method {
+	m := structure.Method{Comment: contract.Comment, OutputBehaviour: structure.OutputBehaviourSingle}
+
+	// Handle the input.
+	if contract.Argument != nil {
+		t := rqltypes.Parse(contract.Argument.Type)
+		m.Input = t.NonOptional().String()
+		m.InputName = contract.Argument.Name
+		m.InputOptional = t.Optional
+	}
+
+	// Handle the output. Cursors and arrays are output behaviours rather than part of the type.
+	returnType := contract.ReturnType
+	if strings.HasPrefix(returnType, "Cursor<") && strings.HasSuffix(returnType, ">") {
+		m.OutputBehaviour = structure.OutputBehaviourCursor
+		returnType = returnType[len("Cursor<") : len(returnType)-1]
+	}
+	if returnType != rqltypes.Void {
+		t := rqltypes.Parse(returnType)
+		if t.Elem != nil && m.OutputBehaviour == structure.OutputBehaviourSingle {
+			m.OutputBehaviour = structure.OutputBehaviourArray
+			t = *t.Elem
+		}
+		m.Output = t.NonOptional().String()
+		m.OutputOptional = t.Optional
+	}
+	return m
+}
+
+// BuildStructure is used to build the RPC structure for a partition from the structs, exceptions,
+// enums, and contracts within the session. Exceptions are emitted as structs with Exception set.
+func BuildStructure(s engine.Session) (*structure.Base, error) {
+	base := &structure.Base{
+		Structs: map[string]structure.Struct{},
+		Enums:   map[string]structure.Enum{},
+		Methods: map[string]structure.Method{},
+	}
+
+	// Add the enums.
+	enums, err := s.Enums()
+	if err != nil {
+		return nil, err
+	}
+	for _, enum := range enums {
+		base.Enums[enum.Name] = structure.Enum{Comment: enum.Comment, Values: rqltypes.EnumValues(enum)}
+	}
+
+	// Add the structs.
+	structs, err := s.Structs()
+	if err != nil {
+		return nil, err
+	}
+	for _, structToken := range structs {
+		base.Structs[structToken.Name] = structure.Struct{
+			Comment: structToken.Comment,
+			Fields:  structFields(structToken.Fields),
+		}
+	}
+
+	// Add the exceptions.
+	exceptions, err := s.Exceptions()
+	if err != nil {
+		return nil, err
+	}
+	for _, exception := range exceptions {
+		base.Structs[exception.Name] = structure.Struct{
+			Comment:   exception.Comment,
+			Exception: true,
+			Fields:    structFields(exception.Fields),
+		}
+	}
+
+	// Add the contracts.
+	contracts, err := s.Contracts()
+	if err != nil {
+		return nil, err
+	}
+	for _, contract := range contracts {
+		base.Methods[contract.Name] = contractToMethod(contract)
+	}
+	return base, nil
+}
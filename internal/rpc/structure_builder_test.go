@@ -0,0 +1,142 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package rpc_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"remixdb.io/ast"
+	"remixdb.io/internal/compiler/mocksession"
+	"remixdb.io/internal/rpc"
+	"remixdb.io/internal/rpc/structure"
+)
+
+func TestBuildStructure(t *testing.T) {
+	tokens, perr := ast.Parse(`// The kind of tree.
+enum Kind {
+    Oak, Pine
+}
+
+// A tree in the forest.
+// Trees have a kind.
+struct Tree {
+    // The ID of the tree.
+    @primary
+    id: int
+    tags: string[]?
+    kind: Kind
+}
+
+@status(404)
+exception NotFound {
+    // Shown to the user.
+    message: string
+    id: int?
+}
+
+// Gets a tree by its ID.
+contract GetTree(id: int) -> Tree throws NotFound {
+    return
+}
+
+contract ListTrees() -> Tree[] {
+    return
+}
+
+contract StreamTrees(after: int?) -> Cursor<Tree> {
+    return
+}
+
+contract TreeKind(kind: Kind) -> Kind {
+    return
+}
+
+contract Nothing() -> void {
+    return
+}
+`)
+	require.Nil(t, perr)
+
+	// Create a session with everything that was parsed.
+	var (
+		structs    []*ast.StructToken
+		exceptions []*ast.ExceptionToken
+		enums      []*ast.EnumToken
+		contracts  []*ast.ContractToken
+	)
+	for _, token := range tokens {
+		switch x := token.(type) {
+		case ast.StructToken:
+			structs = append(structs, &x)
+		case ast.ExceptionToken:
+			exceptions = append(exceptions, &x)
+		case ast.EnumToken:
+			enums = append(enums, &x)
+		case ast.ContractToken:
+			contracts = append(contracts, &x)
+		}
+	}
+	s := &mocksession.SessionMock{
+		StructsFunc:    func() ([]*ast.StructToken, error) { return structs, nil },
+		ExceptionsFunc: func() ([]*ast.ExceptionToken, error) { return exceptions, nil },
+		EnumsFunc:      func() ([]*ast.EnumToken, error) { return enums, nil },
+		ContractsFunc:  func() ([]*ast.ContractToken, error) { return contracts, nil },
+	}
+
+	base, err := rpc.BuildStructure(s)
+	require.NoError(t, err)
+	assert.Equal(t, &structure.Base{
+		Structs: map[string]structure.Struct{
+			"Tree": {
+				Comment: "A tree in the forest.\nTrees have a kind.",
+				Fields: map[string]structure.StructField{
+					"id":   {Comment: "The ID of the tree.", Type: "int"},
+					"tags": {Type: "string", Array: true, Optional: true},
+					"kind": {Type: "Kind"},
+				},
+			},
+			"NotFound": {
+				Exception: true,
+				Fields: map[string]structure.StructField{
+					"message": {Comment: "Shown to the user.", Type: "string"},
+					"id":      {Type: "int", Optional: true},
+				},
+			},
+		},
+		Enums: map[string]structure.Enum{
+			"Kind": {Comment: "The kind of tree.", Values: []string{"Oak", "Pine"}},
+		},
+		Methods: map[string]structure.Method{
+			"GetTree": {
+				Comment:         "Gets a tree by its ID.",
+				Input:           "int",
+				InputName:       "id",
+				Output:          "Tree",
+				OutputBehaviour: structure.OutputBehaviourSingle,
+			},
+			"ListTrees": {
+				Output:          "Tree",
+				OutputBehaviour: structure.OutputBehaviourArray,
+			},
+			"StreamTrees": {
+				Input:           "int",
+				InputName:       "after",
+				InputOptional:   true,
+				Output:          "Tree",
+				OutputBehaviour: structure.OutputBehaviourCursor,
+			},
+			"TreeKind": {
+				Input:           "Kind",
+				InputName:       "kind",
+				Output:          "Kind",
+				OutputBehaviour: structure.OutputBehaviourSingle,
+			},
+			"Nothing": {
+				OutputBehaviour: structure.OutputBehaviourSingle,
+			},
+		},
+	}, base)
+}
@@ -0,0 +1,352 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package rqltypes
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"time"
+
+	"remixdb.io/internal/engine"
+)
+
+// ErrUnexpectedEOF is returned when the data ends before the value does.
+var ErrUnexpectedEOF = errors.New("unexpected end of data")
+
+// Reads a length prefixed or root value which is either bytes or a string.
+func readBytesLike(b []byte, root bool) ([]byte, int, error) {
+	if root {
+		return b[1:], len(b), nil
+	}
+	if len(b) < 5 {
+		return nil, 0, ErrUnexpectedEOF
+	}
+	l := int(binary.LittleEndian.Uint32(b[1:]))
+	if len(b) < 5+l {
+		return nil, 0, ErrUnexpectedEOF
+	}
+	return b[5 : 5+l], 5 + l, nil
+}
+
+// Reads 8 bytes after the packet type.
+func readUint64(b []byte) (uint64, error) {
+	if len(b) < 9 {
+		return 0, ErrUnexpectedEOF
+	}
+	return binary.LittleEndian.Uint64(b[1:]), nil
+}
+
+// Wraps the value in a pointer if this is a optional type that requires it.
+func wrapOptional(t Type, v any) any {
+	if !t.Optional || t.Nilable() {
+		return v
+	}
+	ptr := reflect.New(reflect.TypeOf(v))
+	ptr.Elem().Set(reflect.ValueOf(v))
+	return ptr.Interface()
+}
+
+// Decodes a value from the byte slice. Returns the value and how many bytes were consumed.
+func decodeValue(b []byte, t Type, resolve StructResolver, root bool) (any, int, error) {
+	if len(b) == 0 {
+		return nil, 0, ErrUnexpectedEOF
+	}
+
+	// Handle null values.
+	if b[0] == 0x00 {
+		if !t.Optional {
+			return nil, 0, errors.New("null value specified for non-optional type " + t.String())
+		}
+		return reflect.Zero(t.GoType()).Interface(), 1, nil
+	}
+	unexpected := errors.New("unexpected packet type for " + t.String())
+
+	// Handle arrays.
+	if t.Elem != nil {
+		if b[0] != 0x07 {
+			return nil, 0, unexpected
+		}
+		if len(b) < 5 {
+			return nil, 0, ErrUnexpectedEOF
+		}
+		l := int(binary.LittleEndian.Uint32(b[1:]))
+		if l > len(b) {
+			return nil, 0, ErrUnexpectedEOF
+		}
+		slice := reflect.MakeSlice(reflect.SliceOf(t.Elem.GoType()), l, l)
+		n := 5
+		for i := 0; i < l; i++ {
+			v, consumed, err := decodeValue(b[n:], *t.Elem, resolve, false)
+			if err != nil {
+				return nil, 0, err
+			}
+			if v != nil {
+				slice.Index(i).Set(reflect.ValueOf(v))
+			}
+			n += consumed
+		}
+		return wrapOptional(t, slice.Interface()), n, nil
+	}
+
+	// Handle the built-in types.
+	var v any
+	n := 1
+	switch t.Name {
+	case String:
+		switch b[0] {
+		case 0x04:
+			v = ""
+		case 0x06:
+			x, consumed, err := readBytesLike(b, root)
+			if err != nil {
+				return nil, 0, err
+			}
+			v, n = string(x), consumed
+		default:
+			return nil, 0, unexpected
+		}
+	case Bytes:
+		switch b[0] {
+		case 0x03:
+			v = []byte{}
+		case 0x05:
+			x, consumed, err := readBytesLike(b, root)
+			if err != nil {
+				return nil, 0, err
+			}
+			v, n = append([]byte(nil), x...), consumed
+		default:
+			return nil, 0, unexpected
+		}
+	case Bool:
+		switch b[0] {
+		case 0x01:
+			v = false
+		case 0x02:
+			v = true
+		default:
+			return nil, 0, unexpected
+		}
+	case Int:
+		switch {
+		case b[0] == 0x0a:
+			x, err := readUint64(b)
+			if err != nil {
+				return nil, 0, err
+			}
+			v, n = int(int64(x)), 9
+		case b[0] >= 0x10 && b[0] <= 0x1f:
+			v = int(b[0] - 0x10)
+		case b[0] >= 0x20 && b[0] <= 0x2f:
+			v = -1 - int(b[0]-0x20)
+		default:
+			return nil, 0, unexpected
+		}
+	case Uint:
+		switch {
+		case b[0] == 0x0e:
+			x, err := readUint64(b)
+			if err != nil {
+				return nil, 0, err
+			}
+			v, n = uint(x), 9
+		case b[0] >= 0x30 && b[0] <= 0x3f:
+			v = uint(b[0] - 0x30)
+		default:
+			return nil, 0, unexpected
+		}
+	case Float:
+		switch {
+		case b[0] == 0x0b:
+			x, err := readUint64(b)
+			if err != nil {
+				return nil, 0, err
+			}
+			v, n = math.Float64frombits(x), 9
+		case b[0] >= 0x60 && b[0] <= 0x6f:
+			v = float64(b[0] - 0x60)
+		case b[0] >= 0x70 && b[0] <= 0x7f:
+			v = -1 - float64(b[0]-0x70)
+		default:
+			return nil, 0, unexpected
+		}
+	case Timestamp:
+		if b[0] != 0x0c {
+			return nil, 0, unexpected
+		}
+		x, err := readUint64(b)
+		if err != nil {
+			return nil, 0, err
+		}
+		v, n = time.UnixMilli(int64(x)).UTC(), 9
+	case Bigint:
+		switch {
+		case b[0] == 0x0d:
+			x, consumed, err := readBytesLike(b, root)
+			if err != nil {
+				return nil, 0, err
+			}
+			i, ok := new(big.Int).SetString(string(x), 10)
+			if !ok {
+				return nil, 0, errors.New("invalid bigint")
+			}
+			v, n = i, consumed
+		case b[0] >= 0x40 && b[0] <= 0x4f:
+			v = big.NewInt(int64(b[0] - 0x40))
+		case b[0] >= 0x50 && b[0] <= 0x5f:
+			v = big.NewInt(-1 - int64(b[0]-0x50))
+		default:
+			return nil, 0, unexpected
+		}
+	default:
+		if !t.IsEnum() {
+			return decodeStruct(b, t, resolve)
+		}
+
+		// Enums are encoded as strings.
+		x, consumed, err := decodeValue(b, Type{Name: String}, resolve, root)
+		if err != nil {
+			return nil, 0, errors.New("unexpected packet type for " + t.String())
+		}
+		if !t.HasEnumValue(x.(string)) {
+			return nil, 0, engine.EnumValueError{Enum: t.Name, Value: x.(string)}
+		}
+		v, n = x, consumed
+	}
+	return wrapOptional(t, v), n, nil
+}
+
+// Defines a field within a encoded struct.
+type rawField struct {
+	key   string
+	value []byte
+}
+
+// Reads the name and fields of a struct from the byte slice without decoding the values.
+func readStruct(b []byte) (name string, fields []rawField, n int, err error) {
+	if len(b) == 0 || b[0] != 0x09 {
+		return "", nil, 0, errors.New("unexpected packet type for struct")
+	}
+
+	// Read the struct name.
+	if len(b) < 2 || len(b) < 4+int(b[1]) {
+		return "", nil, 0, ErrUnexpectedEOF
+	}
+	nameLen := int(b[1])
+	name = string(b[2 : 2+nameLen])
+	n = 2 + nameLen
+	count := int(binary.LittleEndian.Uint16(b[n:]))
+	n += 2
+
+	// Read each field.
+	fields = make([]rawField, count)
+	for i := 0; i < count; i++ {
+		if len(b) < n+2 {
+			return "", nil, 0, ErrUnexpectedEOF
+		}
+		keyLen := int(binary.LittleEndian.Uint16(b[n:]))
+		n += 2
+		if len(b) < n+keyLen+4 {
+			return "", nil, 0, ErrUnexpectedEOF
+		}
+		key := string(b[n : n+keyLen])
+		n += keyLen
+		valueLen := int(binary.LittleEndian.Uint32(b[n:]))
+		n += 4
+		if len(b) < n+valueLen {
+			return "", nil, 0, ErrUnexpectedEOF
+		}
+		fields[i] = rawField{key: key, value: b[n : n+valueLen]}
+		n += valueLen
+	}
+	return name, fields, n, nil
+}
+
+// Decodes a struct from the byte slice.
+func decodeStruct(b []byte, t Type, resolve StructResolver) (any, int, error) {
+	if b[0] != 0x09 {
+		return nil, 0, errors.New("unexpected packet type for " + t.String())
+	}
+
+	// Read the struct and make sure the name matches.
+	name, rawFields, n, err := readStruct(b)
+	if err != nil {
+		return nil, 0, err
+	}
+	if name != t.Name {
+		return nil, 0, errors.New("expected struct " + t.Name + ", got " + name)
+	}
+	fields, err := resolve(t.Name)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Decode each field.
+	m := make(map[string]any, len(rawFields))
+	for _, raw := range rawFields {
+		// Ignore any fields which are not in the struct.
+		f, ok := fields[raw.key]
+		if !ok {
+			continue
+		}
+		v, _, err := decodeValue(raw.value, f, resolve, true)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s.%s: %w", t.Name, raw.key, err)
+		}
+		m[raw.key] = v
+	}
+
+	// Make sure all non-optional fields are present and set any missing optional fields
+	// to null so that every field is always present in the map.
+	for k, f := range fields {
+		if _, ok := m[k]; !ok {
+			if !f.Optional {
+				return nil, 0, errors.New(t.Name + "." + k + ": field is not optional but is missing")
+			}
+			m[k] = reflect.Zero(f.GoType()).Interface()
+		}
+	}
+	return m, n, nil
+}
+
+// StructFields is used to get the encoded value of each field within a encoded struct without
+// decoding them. Fields which are null are not present in the map. This is useful when the
+// encoded values need to be compared, since the encoding of a value is always the same.
+func StructFields(b []byte) (map[string][]byte, error) {
+	_, rawFields, n, err := readStruct(b)
+	if err != nil {
+		return nil, err
+	}
+	if n != len(b) {
+		return nil, errors.New("unexpected trailing data")
+	}
+	m := make(map[string][]byte, len(rawFields))
+	for _, raw := range rawFields {
+		m[raw.key] = raw.value
+	}
+	return m, nil
+}
+
+// StructName is used to get the name of the struct within a encoded struct.
+func StructName(b []byte) (string, error) {
+	name, _, _, err := readStruct(b)
+	return name, err
+}
+
+// Decode is used to decode RemixDB RPC bytes into the Go representation of the type
+// specified.
+func Decode(t Type, b []byte, resolve StructResolver) (any, error) {
+	v, n, err := decodeValue(b, t, resolve, true)
+	if err != nil {
+		return nil, err
+	}
+	if n != len(b) {
+		return nil, errors.New("unexpected trailing data")
+	}
+	return v, nil
+}
@@ -0,0 +1,251 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package rqltypes
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"time"
+
+	"remixdb.io/internal/engine"
+)
+
+// Handles unwrapping a value into its non-optional form. Returns nil if the value is null.
+func unwrapOptional(t Type, v any) any {
+	if v == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice:
+		if rv.IsNil() {
+			return nil
+		}
+	}
+	if t.Optional && !t.Nilable() && rv.Kind() == reflect.Pointer {
+		return rv.Elem().Interface()
+	}
+	return v
+}
+
+// Appends a uint32 length and then the data.
+func appendLengthPrefixed(b, data []byte) []byte {
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(data)))
+	return append(b, data...)
+}
+
+// Appends a string or byte value. Root values do not have a length prefix.
+func appendBytesLike(b []byte, packetType byte, data []byte, root bool) []byte {
+	b = append(b, packetType)
+	if root {
+		return append(b, data...)
+	}
+	return appendLengthPrefixed(b, data)
+}
+
+// Appends a value to the byte slice.
+func appendValue(b []byte, t Type, v any, resolve StructResolver, root bool) ([]byte, error) {
+	// Handle null values.
+	v = unwrapOptional(t, v)
+	if v == nil {
+		if !t.Optional {
+			return nil, errors.New("null value specified for non-optional type " + t.String())
+		}
+		return append(b, 0x00), nil
+	}
+
+	// Handle arrays.
+	if t.Elem != nil {
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice {
+			return nil, errors.New("expected a slice for type " + t.String())
+		}
+		b = append(b, 0x07)
+		b = binary.LittleEndian.AppendUint32(b, uint32(rv.Len()))
+		for i := 0; i < rv.Len(); i++ {
+			var err error
+			b, err = appendValue(b, *t.Elem, rv.Index(i).Interface(), resolve, false)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+
+	// Handle the built-in types.
+	invalidType := errors.New("value is not a valid " + t.String())
+	switch t.Name {
+	case String:
+		s, ok := v.(string)
+		if !ok {
+			return nil, invalidType
+		}
+		if s == "" {
+			return append(b, 0x04), nil
+		}
+		return appendBytesLike(b, 0x06, []byte(s), root), nil
+	case Bytes:
+		x, ok := v.([]byte)
+		if !ok {
+			return nil, invalidType
+		}
+		if len(x) == 0 {
+			return append(b, 0x03), nil
+		}
+		return appendBytesLike(b, 0x05, x, root), nil
+	case Bool:
+		x, ok := v.(bool)
+		if !ok {
+			return nil, invalidType
+		}
+		if x {
+			return append(b, 0x02), nil
+		}
+		return append(b, 0x01), nil
+	case Int:
+		x, ok := v.(int)
+		if !ok {
+			return nil, invalidType
+		}
+		switch {
+		case x >= 0 && x <= 15:
+			return append(b, 0x10+byte(x)), nil
+		case x < 0 && x >= -16:
+			return append(b, 0x20+byte(-1-x)), nil
+		}
+		b = append(b, 0x0a)
+		return binary.LittleEndian.AppendUint64(b, uint64(x)), nil
+	case Uint:
+		x, ok := v.(uint)
+		if !ok {
+			return nil, invalidType
+		}
+		if x <= 15 {
+			return append(b, 0x30+byte(x)), nil
+		}
+		b = append(b, 0x0e)
+		return binary.LittleEndian.AppendUint64(b, uint64(x)), nil
+	case Float:
+		x, ok := v.(float64)
+		if !ok {
+			return nil, invalidType
+		}
+		switch {
+		case x == math.Trunc(x) && x >= 0 && x <= 15 && !math.Signbit(x):
+			return append(b, 0x60+byte(x)), nil
+		case x == math.Trunc(x) && x < 0 && x >= -16:
+			return append(b, 0x70+byte(-1-x)), nil
+		}
+		b = append(b, 0x0b)
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(x)), nil
+	case Timestamp:
+		x, ok := v.(time.Time)
+		if !ok {
+			return nil, invalidType
+		}
+		b = append(b, 0x0c)
+		return binary.LittleEndian.AppendUint64(b, uint64(x.UnixMilli())), nil
+	case Bigint:
+		x, ok := v.(*big.Int)
+		if !ok {
+			return nil, invalidType
+		}
+		if x.IsInt64() {
+			i := x.Int64()
+			switch {
+			case i >= 0 && i <= 15:
+				return append(b, 0x40+byte(i)), nil
+			case i < 0 && i >= -16:
+				return append(b, 0x50+byte(-1-i)), nil
+			}
+		}
+		return appendBytesLike(b, 0x0d, []byte(x.String()), root), nil
+	}
+
+	// Handle enums. These are encoded as strings.
+	if t.IsEnum() {
+		x, ok := v.(string)
+		if !ok {
+			return nil, invalidType
+		}
+		if !t.HasEnumValue(x) {
+			return nil, engine.EnumValueError{Enum: t.Name, Value: x}
+		}
+		return appendValue(b, Type{Name: String}, x, resolve, root)
+	}
+
+	// Anything else is a struct.
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, invalidType
+	}
+	fields, err := resolve(t.Name)
+	if err != nil {
+		return nil, err
+	}
+	if len(t.Name) > math.MaxUint8 {
+		return nil, errors.New("struct name is too long")
+	}
+	b = append(b, 0x09, byte(len(t.Name)))
+	b = append(b, t.Name...)
+
+	// Get the keys which are not null in a consistent order.
+	keys := make([]string, 0, len(m))
+	for k, v := range m {
+		if _, ok := fields[k]; ok && unwrapOptional(fields[k], v) != nil {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(keys)))
+
+	// Write each key and value.
+	for _, k := range keys {
+		b = binary.LittleEndian.AppendUint16(b, uint16(len(k)))
+		b = append(b, k...)
+		value, err := appendValue(nil, fields[k], m[k], resolve, true)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", t.Name, k, err)
+		}
+		b = appendLengthPrefixed(b, value)
+	}
+
+	// Check all non-optional fields were present.
+	for k, f := range fields {
+		if !f.Optional && unwrapOptional(f, m[k]) == nil {
+			return nil, errors.New(t.Name + "." + k + ": field is not optional but is missing")
+		}
+	}
+	return b, nil
+}
+
+// Encode is used to encode a value of the type specified into RemixDB RPC bytes. The
+// value should be in the Go representation documented on Type.
+func Encode(t Type, v any, resolve StructResolver) ([]byte, error) {
+	return appendValue(nil, t, v, resolve, true)
+}
+
+// EncodeStructFields is used to encode a struct from the encoded values of its fields. This is the
+// inverse of StructFields. Fields which are null should not be present in the map.
+func EncodeStructFields(name string, fields map[string][]byte) []byte {
+	b := []byte{0x09, byte(len(name))}
+	b = append(b, name...)
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(keys)))
+	for _, k := range keys {
+		b = binary.LittleEndian.AppendUint16(b, uint16(len(k)))
+		b = append(b, k...)
+		b = appendLengthPrefixed(b, fields[k])
+	}
+	return b
+}
@@ -0,0 +1,265 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package rqltypes
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"remixdb.io/ast"
+	"remixdb.io/internal/engine"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func testResolver(name string) (map[string]Type, error) {
+	return map[string]Type{
+		"name":   Parse("string"),
+		"age":    Parse("int?"),
+		"tags":   Parse("string[]"),
+		"parent": Parse("Person?"),
+	}, nil
+}
+
+func TestEncode_roundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+
+		type_    string
+		value    any
+		expected []byte
+		decoded  any
+	}{
+		{name: "null", type_: "int?", value: (*int)(nil), expected: []byte{0x00}},
+		{name: "true", type_: "bool", value: true, expected: []byte{0x02}},
+		{name: "optional false", type_: "bool?", value: ptr(false), expected: []byte{0x01}},
+		{name: "empty string", type_: "string", value: "", expected: []byte{0x04}},
+		{name: "root string", type_: "string", value: "hi", expected: []byte{0x06, 'h', 'i'}},
+		{name: "small int", type_: "int", value: 15, expected: []byte{0x1f}},
+		{name: "small negative int", type_: "int", value: -16, expected: []byte{0x2f}},
+		{
+			name: "large int", type_: "int", value: 256,
+			expected: []byte{0x0a, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		},
+		{name: "small uint", type_: "uint", value: uint(3), expected: []byte{0x33}},
+		{name: "small float", type_: "float", value: float64(-1), expected: []byte{0x70}},
+		{name: "small bigint", type_: "bigint", value: big.NewInt(2), expected: []byte{0x42}},
+		{name: "large bigint", type_: "bigint", value: big.NewInt(100), expected: []byte{0x0d, '1', '0', '0'}},
+		{
+			name: "timestamp", type_: "timestamp", value: time.UnixMilli(1).UTC(),
+			expected: []byte{0x0c, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		},
+		{
+			name: "array", type_: "string[]", value: []string{"", "a"},
+			expected: []byte{0x07, 0x02, 0x00, 0x00, 0x00, 0x04, 0x06, 0x01, 0x00, 0x00, 0x00, 'a'},
+		},
+		{
+			name: "struct", type_: "Person",
+			value: map[string]any{"name": "a", "tags": []string{}},
+			expected: []byte{
+				0x09, 0x06, 'P', 'e', 'r', 's', 'o', 'n', 0x02, 0x00,
+				0x04, 0x00, 'n', 'a', 'm', 'e', 0x02, 0x00, 0x00, 0x00, 0x06, 'a',
+				0x04, 0x00, 't', 'a', 'g', 's', 0x05, 0x00, 0x00, 0x00, 0x07, 0x00, 0x00, 0x00, 0x00,
+			},
+			decoded: map[string]any{
+				"name": "a", "tags": []string{}, "age": (*int)(nil), "parent": map[string]any(nil),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			type_ := Parse(tt.type_)
+			b, err := Encode(type_, tt.value, testResolver)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, b)
+
+			decoded := tt.decoded
+			if decoded == nil {
+				decoded = tt.value
+			}
+			v, err := Decode(type_, b, testResolver)
+			assert.NoError(t, err)
+			assert.Equal(t, decoded, v)
+		})
+	}
+}
+
+func TestEncode_missingField(t *testing.T) {
+	_, err := Encode(Parse("Person"), map[string]any{"tags": []string{}}, testResolver)
+	assert.EqualError(t, err, "Person.name: field is not optional but is missing")
+}
+
+func TestEncode_enum(t *testing.T) {
+	status := Type{Name: "Status", Enum: []string{"Active", "Paused"}}
+	b, err := Encode(status, "Paused", nil)
+	assert.NoError(t, err)
+	v, err := Decode(status, b, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "Paused", v)
+
+	// Values which are not within the enum are rejected either way.
+	_, err = Encode(status, "Deleted", nil)
+	assert.Equal(t, engine.EnumValueError{Enum: "Status", Value: "Deleted"}, err)
+	b, err = Encode(Parse("string"), "Deleted", nil)
+	assert.NoError(t, err)
+	_, err = Decode(status, b, nil)
+	assert.Equal(t, engine.EnumValueError{Enum: "Status", Value: "Deleted"}, err)
+
+	// The error is wrapped when the enum is within a struct.
+	_, err = Encode(Parse("Task"), map[string]any{"status": "Deleted"}, func(string) (map[string]Type, error) {
+		return map[string]Type{"status": status}, nil
+	})
+	assert.EqualError(t, err, `Task.status: "Deleted" is not a value of the enum Status`)
+	assert.ErrorAs(t, err, &engine.EnumValueError{})
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Type
+	}{
+		{input: "int", expected: Type{Name: "int"}},
+		{input: "int?", expected: Type{Name: "int", Optional: true}},
+		{input: "int[]", expected: Type{Elem: &Type{Name: "int"}}},
+		{input: "int?[]?", expected: Type{Elem: &Type{Name: "int", Optional: true}, Optional: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			x := Parse(tt.input)
+			assert.Equal(t, tt.expected, x)
+			assert.Equal(t, tt.input, x.String())
+		})
+	}
+}
+
+func TestStructFields(t *testing.T) {
+	b, err := Encode(Parse("Person"), map[string]any{
+		"name": "Jeff",
+		"tags": []string{"a"},
+		"age":  (*int)(nil),
+	}, testResolver)
+	assert.NoError(t, err)
+	fields, err := StructFields(b)
+	assert.NoError(t, err)
+
+	name, err := Encode(Parse("string"), "Jeff", testResolver)
+	assert.NoError(t, err)
+	assert.Equal(t, name, fields["name"])
+	_, ok := fields["age"]
+	assert.False(t, ok)
+}
+
+func TestEncodeStructFields(t *testing.T) {
+	b, err := Encode(Parse("Person"), map[string]any{"name": "Jeff", "tags": []string{"a", "b"}}, testResolver)
+	assert.NoError(t, err)
+	fields, err := StructFields(b)
+	assert.NoError(t, err)
+	assert.Equal(t, b, EncodeStructFields("Person", fields))
+}
+
+func TestParseLiteral(t *testing.T) {
+	tests := []struct {
+		type_    string
+		literal  string
+		expected any
+		err      string
+	}{
+		{type_: "string", literal: `"hello\n"`, expected: "hello\n"},
+		{type_: "string", literal: `'it\'s "here"'`, expected: `it's "here"`},
+		{type_: "string?", literal: "null", expected: nil},
+		{type_: "string", literal: "null", err: "null value specified for non-optional type string"},
+		{type_: "int", literal: "-5", expected: -5},
+		{type_: "int?", literal: "5", expected: ptr(5)},
+		{type_: "uint", literal: "-5", err: "-5 is not a valid uint"},
+		{type_: "float", literal: "1.5", expected: 1.5},
+		{type_: "bigint", literal: "123456789012345678901234567890", expected: func() *big.Int {
+			x, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+			return x
+		}()},
+		{type_: "bool", literal: "true", expected: true},
+		{type_: "Person", literal: "{}", err: "{} is not a valid Person"},
+		{type_: "Status", literal: "'Active'", expected: "Active"},
+		{type_: "Status?", literal: "'Paused'", expected: ptr("Paused")},
+		{type_: "Status", literal: "'Deleted'", err: "'Deleted' is not a valid Status"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.type_+" "+tt.literal, func(t *testing.T) {
+			type_ := Parse(tt.type_)
+			if type_.Name == "Status" {
+				type_.Enum = []string{"Active", "Paused"}
+			}
+			v, err := ParseLiteral(type_, tt.literal)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, v)
+		})
+	}
+}
+
+func TestLiteralValue(t *testing.T) {
+	tests := []struct {
+		type_    string
+		token    any
+		expected any
+		err      string
+	}{
+		{type_: "string", token: ast.StringLiteralToken{Value: "hello"}, expected: "hello"},
+		{type_: "bytes", token: ast.StringLiteralToken{Value: "hi"}, expected: []byte("hi")},
+		{type_: "string?", token: ast.NullLiteralToken{}, expected: nil},
+		{type_: "int", token: ast.NullLiteralToken{}, err: "null value specified for non-optional type int"},
+		{type_: "int", token: ast.NumberLiteralToken{Value: -5}, expected: -5},
+		{type_: "uint?", token: ast.NumberLiteralToken{Value: 5}, expected: ptr(uint(5))},
+		{type_: "uint", token: ast.NumberLiteralToken{Value: -5}, err: "-5 is not a valid uint"},
+		{type_: "int", token: ast.FloatLiteralToken{Value: 1.5}, err: "1.5 is not a valid int"},
+		{type_: "int", token: ast.StringLiteralToken{Value: "1"}, err: "'1' is not a valid int"},
+		{type_: "float", token: ast.NumberLiteralToken{Value: 2}, expected: float64(2)},
+		{type_: "bigint", token: ast.BigIntLiteralToken{Value: "123456789012345678901234567890"}, expected: func() *big.Int {
+			x, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+			return x
+		}()},
+		{type_: "bool", token: ast.BooleanLiteralToken{Value: false}, expected: false},
+		{type_: "int[]", token: ast.ArrayLiteralToken{Values: []any{}}, err: "[] is not a valid int[]"},
+		{type_: "Status", token: ast.StringLiteralToken{Value: "Active"}, expected: "Active"},
+		{type_: "Status", token: ast.ReferenceToken{Name: "Active"}, err: "Active is not a valid Status"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.type_+" "+ast.FormatValue(tt.token), func(t *testing.T) {
+			type_ := Parse(tt.type_)
+			if type_.Name == "Status" {
+				type_.Enum = []string{"Active", "Paused"}
+			}
+			v, err := LiteralValue(type_, tt.token)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, v)
+		})
+	}
+}
+
+func TestDecoratorValue(t *testing.T) {
+	// Values are used when they are set.
+	v, err := DecoratorValue(Parse("int"), ast.DecoratorToken{
+		Method: "status", Arguments: "404", Values: []any{ast.NumberLiteralToken{Value: 404}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 404, v)
+
+	// Otherwise the arguments are parsed.
+	v, err = DecoratorValue(Parse("string"), ast.DecoratorToken{Method: "renamedFrom", Arguments: `"name"`})
+	assert.NoError(t, err)
+	assert.Equal(t, "name", v)
+
+	_, err = DecoratorValue(Parse("int"), ast.DecoratorToken{Method: "length", Arguments: "1, 64"})
+	assert.EqualError(t, err, "expected a single argument")
+	_, err = DecoratorValue(Parse("int"), ast.DecoratorToken{Method: "default", Arguments: "now()"})
+	assert.EqualError(t, err, "decorator arguments must be literals or references")
+}
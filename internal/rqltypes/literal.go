@@ -0,0 +1,212 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package rqltypes
+
+import (
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"remixdb.io/ast"
+)
+
+// Unquotes a string literal which is either single or double quoted.
+func unquoteString(literal string) (string, error) {
+	if len(literal) < 2 {
+		return "", errors.New("expected a string literal")
+	}
+	switch {
+	case literal[0] == '"' && literal[len(literal)-1] == '"':
+		return strconv.Unquote(literal)
+	case literal[0] == '\'' && literal[len(literal)-1] == '\'':
+		// Turn it into a double quoted string so that Go can handle the escapes.
+		inner := strings.ReplaceAll(literal[1:len(literal)-1], `\'`, `'`)
+		inner = strings.ReplaceAll(inner, `"`, `\"`)
+		return strconv.Unquote(`"` + inner + `"`)
+	}
+	return "", errors.New("expected a string literal")
+}
+
+// ParseLiteral is used to parse a RQL literal such as one used within a decorator into the Go
+// representation of the type specified. Strings, numbers, booleans, enum values and null are
+// supported.
+func ParseLiteral(t Type, literal string) (any, error) {
+	literal = strings.TrimSpace(literal)
+
+	// Handle null.
+	if literal == "null" {
+		if !t.Optional {
+			return nil, errors.New("null value specified for non-optional type " + t.String())
+		}
+		return nil, nil
+	}
+
+	// Handle the built-in types.
+	invalidLiteral := errors.New(literal + " is not a valid " + t.String())
+	if t.Elem != nil {
+		return nil, invalidLiteral
+	}
+	var v any
+	switch t.Name {
+	case String:
+		s, err := unquoteString(literal)
+		if err != nil {
+			return nil, invalidLiteral
+		}
+		v = s
+	case Bytes:
+		s, err := unquoteString(literal)
+		if err != nil {
+			return nil, invalidLiteral
+		}
+		v = []byte(s)
+	case Bool:
+		switch literal {
+		case "true":
+			v = true
+		case "false":
+			v = false
+		default:
+			return nil, invalidLiteral
+		}
+	case Int:
+		x, err := strconv.ParseInt(literal, 10, strconv.IntSize)
+		if err != nil {
+			return nil, invalidLiteral
+		}
+		v = int(x)
+	case Uint:
+		x, err := strconv.ParseUint(literal, 10, strconv.IntSize)
+		if err != nil {
+			return nil, invalidLiteral
+		}
+		v = uint(x)
+	case Float:
+		x, err := strconv.ParseFloat(literal, 64)
+		if err != nil {
+			return nil, invalidLiteral
+		}
+		v = x
+	case Bigint:
+		x, ok := new(big.Int).SetString(literal, 10)
+		if !ok {
+			return nil, invalidLiteral
+		}
+		return x, nil
+	default:
+		// Enums use the string literal of the value.
+		if !t.IsEnum() {
+			return nil, invalidLiteral
+		}
+		s, err := unquoteString(literal)
+		if err != nil || !t.HasEnumValue(s) {
+			return nil, invalidLiteral
+		}
+		v = s
+	}
+	return wrapOptional(t, v), nil
+}
+
+// LiteralValue is used to turn a literal token, such as one within the Values of a decorator,
+// into the Go representation of the type specified. Strings, numbers, booleans, enum values and
+// null are supported. Unlike ParseLiteral, escapes within strings are handled by the parser the
+// same way as they are within contracts.
+func LiteralValue(t Type, token any) (any, error) {
+	return literalValue(t, token, ast.FormatValue(token))
+}
+
+// DecoratorValue is used to get the single argument of a decorator as the Go representation of
+// the type specified. See LiteralValue for what is supported.
+func DecoratorValue(t Type, decorator ast.DecoratorToken) (any, error) {
+	values, perr := decorator.ArgumentValues()
+	if perr != nil {
+		return nil, errors.New(perr.Message)
+	}
+	if len(values) != 1 {
+		return nil, errors.New("expected a single argument")
+	}
+	return LiteralValue(t, values[0])
+}
+
+// Turns a literal token into the Go representation of the type. The source is used within errors.
+func literalValue(t Type, token any, source string) (any, error) {
+	// Handle null.
+	if _, ok := token.(ast.NullLiteralToken); ok {
+		if !t.Optional {
+			return nil, errors.New("null value specified for non-optional type " + t.String())
+		}
+		return nil, nil
+	}
+
+	// Get the text of numbers so that they can be parsed as the type.
+	invalidLiteral := errors.New(source + " is not a valid " + t.String())
+	if t.Elem != nil {
+		return nil, invalidLiteral
+	}
+	number, isNumber := "", true
+	switch x := token.(type) {
+	case ast.NumberLiteralToken:
+		number = strconv.Itoa(x.Value)
+	case ast.BigIntLiteralToken:
+		number = x.Value
+	case ast.FloatLiteralToken:
+		number = strconv.FormatFloat(x.Value, 'g', -1, 64)
+	default:
+		isNumber = false
+	}
+	str, isString := token.(ast.StringLiteralToken)
+
+	// Handle the built-in types.
+	var v any
+	switch t.Name {
+	case String:
+		if !isString {
+			return nil, invalidLiteral
+		}
+		v = str.Value
+	case Bytes:
+		if !isString {
+			return nil, invalidLiteral
+		}
+		v = []byte(str.Value)
+	case Bool:
+		b, ok := token.(ast.BooleanLiteralToken)
+		if !ok {
+			return nil, invalidLiteral
+		}
+		v = b.Value
+	case Int:
+		x, err := strconv.ParseInt(number, 10, strconv.IntSize)
+		if !isNumber || err != nil {
+			return nil, invalidLiteral
+		}
+		v = int(x)
+	case Uint:
+		x, err := strconv.ParseUint(number, 10, strconv.IntSize)
+		if !isNumber || err != nil {
+			return nil, invalidLiteral
+		}
+		v = uint(x)
+	case Float:
+		x, err := strconv.ParseFloat(number, 64)
+		if !isNumber || err != nil {
+			return nil, invalidLiteral
+		}
+		v = x
+	case Bigint:
+		x, ok := new(big.Int).SetString(number, 10)
+		if !isNumber || !ok {
+			return nil, invalidLiteral
+		}
+		return x, nil
+	default:
+		// Enums use the string literal of the value.
+		if !t.IsEnum() || !isString || !t.HasEnumValue(str.Value) {
+			return nil, invalidLiteral
+		}
+		v = str.Value
+	}
+	return wrapOptional(t, v), nil
+}
@@ -0,0 +1,104 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package rqltypes
+
+import (
+	"remixdb.io/ast"
+	"remixdb.io/internal/engine"
+)
+
+// Gets the field tokens within a struct or exception as types.
+func fieldsFromTokens(tokens []any) map[string]Type {
+	fields := map[string]Type{}
+	for _, f := range tokens {
+		if field, ok := f.(ast.FieldToken); ok {
+			fields[field.Name] = Parse(field.Type)
+		}
+	}
+	return fields
+}
+
+// FieldsFromStruct is used to get the fields of a struct token as types.
+func FieldsFromStruct(s *ast.StructToken) map[string]Type {
+	return fieldsFromTokens(s.Fields)
+}
+
+// FieldsFromException is used to get the fields of a exception token as types.
+func FieldsFromException(e *ast.ExceptionToken) map[string]Type {
+	return fieldsFromTokens(e.Fields)
+}
+
+// EnumValues is used to get the values of a enum token.
+func EnumValues(e *ast.EnumToken) []string {
+	values := []string{}
+	for _, v := range e.Values {
+		if value, ok := v.(ast.EnumValueToken); ok {
+			values = append(values, value.Name)
+		}
+	}
+	return values
+}
+
+// ResolveEnums is used to set the values of the type (or the element of the type if it is a
+// array) if it refers to a enum within the session. Types which are not enums are returned as is.
+func ResolveEnums(s engine.Session, t Type) (Type, error) {
+	if t.Elem != nil {
+		elem, err := ResolveEnums(s, *t.Elem)
+		if err != nil {
+			return Type{}, err
+		}
+		t.Elem = &elem
+		return t, nil
+	}
+	if t.IsBuiltin() || t.Name == Void || t.Name == Null {
+		return t, nil
+	}
+	e, err := s.GetEnumByKey(t.Name)
+	if err != nil {
+		if err == engine.ErrNotExists {
+			return t, nil
+		}
+		return Type{}, err
+	}
+	t.Enum = EnumValues(e)
+	return t, nil
+}
+
+// Resolves the enums within the fields.
+func resolveFieldEnums(s engine.Session, fields map[string]Type) (map[string]Type, error) {
+	for k, t := range fields {
+		t, err := ResolveEnums(s, t)
+		if err != nil {
+			return nil, err
+		}
+		fields[k] = t
+	}
+	return fields, nil
+}
+
+// FieldsFromSession is used to get the fields of a struct token as types with any enums resolved
+// from the session.
+func FieldsFromSession(s engine.Session, structToken *ast.StructToken) (map[string]Type, error) {
+	return resolveFieldEnums(s, FieldsFromStruct(structToken))
+}
+
+// SessionResolver is used to create a struct resolver which uses the latest version of
+// each struct within the session. Exceptions are resolved as well so that caught exceptions
+// can be encoded, and enums within the fields are resolved so their values are checked.
+func SessionResolver(s engine.Session) StructResolver {
+	return func(name string) (map[string]Type, error) {
+		history, err := s.GetStructByKey(name)
+		if err == engine.ErrNotExists {
+			e, err := s.GetExceptionByKey(name)
+			if err != nil {
+				return nil, err
+			}
+			return resolveFieldEnums(s, FieldsFromException(e))
+		}
+		if err != nil {
+			return nil, err
+		}
+		return FieldsFromSession(s, history[len(history)-1])
+	}
+}
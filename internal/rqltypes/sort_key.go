@@ -0,0 +1,93 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package rqltypes
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/big"
+	"time"
+)
+
+// Orderable is used to check if values of the type can be ordered. Only the built-in scalar types
+// and enums can be ordered. Enums are ordered by their string value.
+func Orderable(t Type) bool { return t.IsBuiltin() || t.IsEnum() }
+
+// Appends bytes which keep their order when followed by other data. Zero bytes are escaped as
+// 0x00 0xff and the end is marked with 0x00 0x01.
+func appendOrderedBytes(b, data []byte) []byte {
+	for _, c := range data {
+		if c == 0x00 {
+			b = append(b, 0x00, 0xff)
+		} else {
+			b = append(b, c)
+		}
+	}
+	return append(b, 0x00, 0x01)
+}
+
+// SortKey is used to turn the RemixDB encoding of a value into bytes which compare in the same
+// order as the values. Null sorts before everything else. Sort keys mark their own end, so they
+// can be followed by other data without changing the order. The type must be Orderable.
+func SortKey(t Type, b []byte) ([]byte, error) {
+	if !Orderable(t) {
+		return nil, errors.New("values of type " + t.String() + " cannot be ordered")
+	}
+	v, err := Decode(t, b, nil)
+	if err != nil {
+		return nil, err
+	}
+	v = unwrapOptional(t, v)
+	if v == nil {
+		return []byte{0x00}, nil
+	}
+
+	key := []byte{0x01}
+	switch x := v.(type) {
+	case bool:
+		if x {
+			return append(key, 0x01), nil
+		}
+		return append(key, 0x00), nil
+	case int:
+		return binary.BigEndian.AppendUint64(key, uint64(x)^(1<<63)), nil
+	case uint:
+		return binary.BigEndian.AppendUint64(key, uint64(x)), nil
+	case float64:
+		// Flip the sign bit of positive numbers and every bit of negative numbers.
+		bits := math.Float64bits(x)
+		if bits&(1<<63) == 0 {
+			bits ^= 1 << 63
+		} else {
+			bits = ^bits
+		}
+		return binary.BigEndian.AppendUint64(key, bits), nil
+	case time.Time:
+		return binary.BigEndian.AppendUint64(key, uint64(x.UnixMilli())^(1<<63)), nil
+	case string:
+		return appendOrderedBytes(key, []byte(x)), nil
+	case []byte:
+		return appendOrderedBytes(key, x), nil
+	case *big.Int:
+		// Order by the sign, then the length of the magnitude, then the magnitude. The length and
+		// magnitude are inverted for negative numbers so that larger magnitudes sort first.
+		magnitude := x.Bytes()
+		length := binary.BigEndian.AppendUint32(nil, uint32(len(magnitude)))
+		switch x.Sign() {
+		case 0:
+			return append(key, 0x01), nil
+		case 1:
+			key = append(key, 0x02)
+			key = append(key, length...)
+			return append(key, magnitude...), nil
+		}
+		key = append(key, 0x00)
+		for _, c := range append(length, magnitude...) {
+			key = append(key, ^c)
+		}
+		return key, nil
+	}
+	return nil, errors.New("values of type " + t.String() + " cannot be ordered")
+}
@@ -0,0 +1,74 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package rqltypes
+
+import (
+	"bytes"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortKey(t *testing.T) {
+	tests := []struct {
+		name string
+
+		type_  string
+		values []any
+	}{
+		{name: "bool", type_: "bool?", values: []any{(*bool)(nil), ptr(false), ptr(true)}},
+		{name: "int", type_: "int", values: []any{math.MinInt, -300, -16, -1, 0, 15, 16, 300, math.MaxInt}},
+		{name: "uint", type_: "uint", values: []any{uint(0), uint(15), uint(16), uint(math.MaxUint)}},
+		{
+			name: "float", type_: "float",
+			values: []any{math.Inf(-1), -2.5, -1.0, 0.0, 0.5, 1.0, 16.0, math.Inf(1)},
+		},
+		{
+			name: "timestamp", type_: "timestamp",
+			values: []any{time.UnixMilli(-1000), time.UnixMilli(0), time.UnixMilli(1), time.UnixMilli(100000)},
+		},
+		{name: "string", type_: "string", values: []any{"", "\x00", "\x00a", "a", "a\x00", "ab", "b"}},
+		{name: "bytes", type_: "bytes", values: []any{[]byte{}, []byte{0}, []byte{0, 0}, []byte{1}}},
+		{
+			name: "bigint", type_: "bigint?",
+			values: []any{
+				(*big.Int)(nil), big.NewInt(-1000), big.NewInt(-999), big.NewInt(-17), big.NewInt(-1),
+				big.NewInt(0), big.NewInt(1), big.NewInt(16), big.NewInt(999), big.NewInt(1000),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			type_ := Parse(tt.type_)
+			var last []byte
+			for i, v := range tt.values {
+				b, err := Encode(type_, v, nil)
+				require.NoError(t, err)
+				key, err := SortKey(type_, b)
+				require.NoError(t, err)
+				if i != 0 {
+					assert.Equal(t, -1, bytes.Compare(last, key), "value %d should sort after value %d", i, i-1)
+				}
+				last = key
+			}
+		})
+	}
+}
+
+func TestSortKey_followedByData(t *testing.T) {
+	a, err := SortKey(Parse("string"), []byte{0x06, 'a'})
+	require.NoError(t, err)
+	ab, err := SortKey(Parse("string"), []byte{0x06, 'a', 'b'})
+	require.NoError(t, err)
+	assert.Equal(t, -1, bytes.Compare(append(a, 0xff), append(ab, 0x00)))
+}
+
+func TestSortKey_notOrderable(t *testing.T) {
+	_, err := SortKey(Parse("string[]"), []byte{0x07, 0x00, 0x00, 0x00, 0x00})
+	assert.EqualError(t, err, "values of type string[] cannot be ordered")
+}
@@ -0,0 +1,231 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package rqltypes
+
+import (
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Defines the names of the built-in types.
+const (
+	Void      = "void"
+	Null      = "null"
+	String    = "string"
+	Int       = "int"
+	Uint      = "uint"
+	Float     = "float"
+	Bigint    = "bigint"
+	Timestamp = "timestamp"
+	Bool      = "bool"
+	Bytes     = "bytes"
+)
+
+// Builtins is used to define all the built-in types that can be used as a field.
+var Builtins = map[string]struct{}{
+	String:    {},
+	Int:       {},
+	Uint:      {},
+	Float:     {},
+	Bigint:    {},
+	Timestamp: {},
+	Bool:      {},
+	Bytes:     {},
+}
+
+// Type is used to define a type within RQL. The Go representation of each type is the
+// following:
+//
+//   - string: string
+//   - int: int
+//   - uint: uint
+//   - float: float64
+//   - bigint: *big.Int
+//   - timestamp: time.Time
+//   - bool: bool
+//   - bytes: []byte
+//   - enums: string
+//   - structs: map[string]any with every field of the struct present
+//   - arrays: a slice of the element type
+//
+// Optional types are a pointer to the type unless the type is already nilable (structs
+// and bigints), in which case nil is used to represent null.
+type Type struct {
+	// Name is the name of the type. This is blank if this is an array.
+	Name string
+
+	// Elem is the type of the elements if this is an array.
+	Elem *Type
+
+	// Optional defines if the type can be null.
+	Optional bool
+
+	// Enum is the values of the enum if the name refers to a enum. This is not set by Parse since
+	// the name alone does not say if it is a enum, so use ResolveEnums to set it.
+	Enum []string
+}
+
+// Parse is used to parse a type string such as "string[]?" into a Type.
+func Parse(s string) Type {
+	// Handle the optional suffix.
+	if strings.HasSuffix(s, "?") {
+		t := Parse(s[:len(s)-1])
+		t.Optional = true
+		return t
+	}
+
+	// Handle the array suffix.
+	if strings.HasSuffix(s, "[]") {
+		elem := Parse(s[:len(s)-2])
+		return Type{Elem: &elem}
+	}
+
+	// Return the named type.
+	return Type{Name: s}
+}
+
+// String is used to turn the type back into the string representation.
+func (t Type) String() string {
+	s := t.Name
+	if t.Elem != nil {
+		s = t.Elem.String() + "[]"
+	}
+	if t.Optional {
+		s += "?"
+	}
+	return s
+}
+
+// IsArray is used to check if the type is an array.
+func (t Type) IsArray() bool { return t.Elem != nil }
+
+// IsBuiltin is used to check if the type is a built-in scalar type.
+func (t Type) IsBuiltin() bool {
+	if t.Elem != nil {
+		return false
+	}
+	_, ok := Builtins[t.Name]
+	return ok
+}
+
+// IsStruct is used to check if the type refers to a struct.
+func (t Type) IsStruct() bool {
+	return t.Elem == nil && t.Name != Void && t.Name != Null && !t.IsBuiltin() && !t.IsEnum()
+}
+
+// IsEnum is used to check if the type refers to a enum. The type must be resolved with
+// ResolveEnums for this to be true.
+func (t Type) IsEnum() bool { return t.Elem == nil && t.Enum != nil }
+
+// HasEnumValue is used to check if the value is within the enum.
+func (t Type) HasEnumValue(value string) bool {
+	for _, v := range t.Enum {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Nilable is used to check if the Go representation of the type can already be nil
+// without wrapping it in a pointer.
+func (t Type) Nilable() bool {
+	return t.Elem == nil && (t.Name == Bigint || t.IsStruct())
+}
+
+// NonOptional returns a copy of the type which is not optional.
+func (t Type) NonOptional() Type {
+	t.Optional = false
+	return t
+}
+
+// Equal is used to check if two types are identical.
+func (t Type) Equal(other Type) bool {
+	if t.Optional != other.Optional || t.Name != other.Name {
+		return false
+	}
+	if t.Elem == nil || other.Elem == nil {
+		return t.Elem == other.Elem
+	}
+	return t.Elem.Equal(*other.Elem)
+}
+
+// Turns any enums which are being compared with strings (including within arrays) into strings.
+func enumsAsStrings(a, b Type) (Type, Type) {
+	if a.Elem != nil && b.Elem != nil {
+		elemA, elemB := enumsAsStrings(*a.Elem, *b.Elem)
+		a.Elem, b.Elem = &elemA, &elemB
+		return a, b
+	}
+	if a.IsEnum() && b.Elem == nil && b.Name == String {
+		a = Type{Name: String, Optional: a.Optional}
+	} else if b.IsEnum() && a.Elem == nil && a.Name == String {
+		b = Type{Name: String, Optional: b.Optional}
+	}
+	return a, b
+}
+
+// AssignableTo is used to check if a value of this type can be assigned to the type
+// specified. Since enums are strings, enums can be assigned to strings and strings can be
+// assigned to enums. The value is checked against the enum when it is written.
+func (t Type) AssignableTo(other Type) bool {
+	// Null can be assigned to anything optional.
+	if t.Name == Null && t.Elem == nil {
+		return other.Optional
+	}
+	t, other = enumsAsStrings(t, other)
+
+	// Non-optional values can be assigned to optional types.
+	if other.Optional && !t.Optional {
+		return t.Equal(other.NonOptional())
+	}
+	return t.Equal(other)
+}
+
+var (
+	bigIntType = reflect.TypeOf((*big.Int)(nil))
+	timeType   = reflect.TypeOf(time.Time{})
+	structType = reflect.TypeOf(map[string]any(nil))
+)
+
+// GoType is used to get the Go type that is used to represent this type.
+func (t Type) GoType() reflect.Type {
+	var x reflect.Type
+	if t.Elem != nil {
+		x = reflect.SliceOf(t.Elem.GoType())
+	} else {
+		switch t.Name {
+		case String:
+			x = reflect.TypeOf("")
+		case Int:
+			x = reflect.TypeOf(0)
+		case Uint:
+			x = reflect.TypeOf(uint(0))
+		case Float:
+			x = reflect.TypeOf(float64(0))
+		case Bigint:
+			x = bigIntType
+		case Timestamp:
+			x = timeType
+		case Bool:
+			x = reflect.TypeOf(false)
+		case Bytes:
+			x = reflect.TypeOf([]byte(nil))
+		default:
+			x = structType
+			if t.IsEnum() {
+				x = reflect.TypeOf("")
+			}
+		}
+	}
+	if t.Optional && !t.Nilable() {
+		x = reflect.PointerTo(x)
+	}
+	return x
+}
+
+// StructResolver is used to resolve the fields of a struct by its name.
+type StructResolver func(name string) (map[string]Type, error)
@@ -0,0 +1,317 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package schema
+
+import (
+	"strings"
+
+	"remixdb.io/ast"
+	"remixdb.io/internal/rqltypes"
+)
+
+// Defines the kinds of declarations which share the type namespace.
+const (
+	kindStruct    = "struct"
+	kindException = "exception"
+	kindEnum      = "enum"
+)
+
+// Defines a variable scope within a contract. Blocks get their own scope, so variables assigned
+// within them are not visible after the block. This matches the compiler.
+type scope struct {
+	parent    *scope
+	variables map[string]struct{}
+}
+
+// Checks if the variable is within the scope or any of its parents.
+func (s *scope) has(name string) bool {
+	for ; s != nil; s = s.parent {
+		if _, ok := s.variables[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Creates a child scope.
+func (s *scope) child() *scope {
+	return &scope{parent: s, variables: map[string]struct{}{}}
+}
+
+// Defines the state of the semantic analysis.
+type checker struct {
+	errs ErrorList
+	file int
+
+	// types maps the name of each struct, exception and enum to its kind.
+	types map[string]string
+}
+
+// Adds a error at the position within the current file.
+func (c *checker) errorAt(position int, message string) {
+	c.errs = append(c.errs, Error{File: c.file, Message: message, Position: position})
+}
+
+// Finds the declarations within the files and reports any names which are used twice. Structs
+// defined twice are reported by ResolveExtends.
+func (c *checker) declare(files [][]any) {
+	contracts := map[string]struct{}{}
+	mappings := map[string]struct{}{}
+	for file, tokens := range files {
+		c.file = file
+		for _, t := range tokens {
+			var name, kind string
+			var position int
+			switch x := t.(type) {
+			case ast.StructToken:
+				name, kind, position = x.Name, kindStruct, x.Position
+			case ast.ExceptionToken:
+				name, kind, position = x.Name, kindException, x.Position
+			case ast.EnumToken:
+				name, kind, position = x.Name, kindEnum, x.Position
+			case ast.ContractToken:
+				if _, ok := contracts[x.Name]; ok {
+					c.errorAt(x.Position, "contract "+x.Name+" is already defined")
+				}
+				contracts[x.Name] = struct{}{}
+				continue
+			case ast.MappingToken:
+				if _, ok := mappings[x.Name]; ok {
+					c.errorAt(x.Position, "mapping "+x.Name+" is already defined")
+				}
+				mappings[x.Name] = struct{}{}
+				continue
+			default:
+				continue
+			}
+			if existing, ok := c.types[name]; ok {
+				if existing != kindStruct || kind != kindStruct {
+					c.errorAt(position, kind+" "+name+" cannot be defined since there is already a "+
+						existing+" with the same name")
+				}
+				continue
+			}
+			c.types[name] = kind
+		}
+	}
+}
+
+// Checks that a type and anything within it exists. Structs, exceptions and enums are allowed.
+func (c *checker) checkType(type_ string, position int) {
+	t := rqltypes.Parse(type_)
+	for t.Elem != nil {
+		t = *t.Elem
+	}
+	if t.IsBuiltin() {
+		return
+	}
+	if _, ok := c.types[t.Name]; !ok {
+		c.errorAt(position, "unknown type "+t.Name)
+	}
+}
+
+// Checks the types of the fields within a struct or exception. References within structs must
+// be to other structs.
+func (c *checker) checkFields(fields []any) {
+	for _, f := range fields {
+		switch x := f.(type) {
+		case ast.FieldToken:
+			c.checkType(x.Type, x.Position)
+		case ast.ReferenceToken:
+			if c.types[x.Name] != kindStruct {
+				c.errorAt(x.Position, "unknown struct "+x.Name)
+			}
+		}
+	}
+}
+
+// Checks the references to variables within a expression. References which are not variables are
+// allowed if they start with the name of a struct, since that is a query.
+func (c *checker) checkExpression(sc *scope, t any) {
+	ast.Inspect(t, func(token any) bool {
+		switch x := token.(type) {
+		case ast.ReferenceToken:
+			c.checkVariable(sc, x.Name, x.Position)
+			return false
+		case ast.MethodCallToken:
+			// Calls without a dot are methods rather than variables.
+			if strings.Contains(x.Name, ".") {
+				c.checkVariable(sc, x.Name, x.Position)
+			}
+
+			// Chained calls are on the result, so only their arguments are checked.
+			for next, ok := x, true; ok; next, ok = next.ChainedCall.(ast.MethodCallToken) {
+				for _, arg := range next.Arguments {
+					c.checkExpression(sc, arg)
+				}
+			}
+			return false
+		}
+		return true
+	})
+}
+
+// Checks that the start of a reference is a variable or a struct.
+func (c *checker) checkVariable(sc *scope, name string, position int) {
+	name = strings.SplitN(name, ".", 2)[0]
+	if !sc.has(name) && c.types[name] != kindStruct {
+		c.errorAt(position, "undefined variable "+name)
+	}
+}
+
+// Checks the statements within a block. The scope is the scope of the block.
+func (c *checker) checkStatements(sc *scope, statements []any) {
+	for _, t := range statements {
+		c.checkStatement(sc, t)
+	}
+}
+
+// Checks a else chain. Conditions are within the scope outside of the if statement.
+func (c *checker) checkElse(sc *scope, else_ *ast.ElseToken) {
+	for ; else_ != nil; else_ = else_.Next {
+		if else_.Condition != nil {
+			c.checkExpression(sc, else_.Condition)
+		}
+		c.checkStatements(sc.child(), else_.Statements)
+	}
+}
+
+// Checks a single statement within a contract.
+func (c *checker) checkStatement(sc *scope, t any) {
+	switch x := t.(type) {
+	case nil, ast.CommentToken:
+	case ast.AssignmentToken:
+		c.checkExpression(sc, x.Value)
+		if !sc.has(x.Name) {
+			sc.variables[x.Name] = struct{}{}
+		}
+	case ast.IfToken:
+		c.checkExpression(sc, x.Condition)
+		c.checkStatements(sc.child(), x.Statements)
+		c.checkElse(sc, x.Else)
+	case ast.UnlessToken:
+		c.checkExpression(sc, x.Condition)
+		c.checkStatements(sc.child(), x.Statements)
+		c.checkElse(sc, x.Else)
+	case ast.ForToken:
+		forScope := sc.child()
+		c.checkStatement(forScope, x.Assignment)
+		if x.Condition != nil {
+			c.checkExpression(forScope, x.Condition)
+		}
+		c.checkStatement(forScope, x.Increment)
+		c.checkStatements(forScope.child(), x.Statements)
+	case ast.WhileToken:
+		c.checkExpression(sc, x.Condition)
+		c.checkStatements(sc.child(), x.Statements)
+	case ast.InlineIfToken:
+		c.checkExpression(sc, x.Condition)
+		c.checkStatement(sc.child(), x.Token)
+	case ast.InlineUnlessToken:
+		c.checkExpression(sc, x.Condition)
+		c.checkStatement(sc.child(), x.Token)
+	case ast.SwitchToken:
+		c.checkExpression(sc, x.Condition)
+		for _, case_ := range x.Cases {
+			c.checkExpression(sc, case_.Name)
+			c.checkStatements(sc.child(), case_.Statements)
+		}
+	case ast.TryToken:
+		c.checkStatements(sc.child(), x.Statements)
+		for catch := x.Catch; catch != nil; catch = catch.Next {
+			catchScope := sc.child()
+			// Exception catches everything, so it does not need to be defined.
+			if catch.Exception != "" && catch.Exception != "Exception" {
+				c.checkThrowable(catch.Exception, catch.Position)
+			}
+			if catch.Variable != "" {
+				catchScope.variables[catch.Variable] = struct{}{}
+			}
+			c.checkStatements(catchScope, catch.Statements)
+		}
+	case ast.ReturnToken:
+		if x.Token != nil {
+			c.checkExpression(sc, x.Token)
+		}
+	case ast.ThrowLiteralToken:
+		if call, ok := x.Token.(ast.MethodCallToken); ok && !strings.Contains(call.Name, ".") {
+			c.checkThrowable(call.Name, call.Position)
+		}
+		c.checkExpression(sc, x.Token)
+	default:
+		c.checkExpression(sc, t)
+	}
+}
+
+// Checks that the name is a exception or a struct, since both can be thrown.
+func (c *checker) checkThrowable(name string, position int) {
+	if kind := c.types[name]; kind != kindException && kind != kindStruct {
+		c.errorAt(position, "unknown exception "+name)
+	}
+}
+
+// Checks the argument, return type, throws and statements of a contract.
+func (c *checker) checkContract(contract ast.ContractToken) {
+	root := &scope{variables: map[string]struct{}{}}
+	if contract.Argument != nil {
+		c.checkType(contract.Argument.Type, contract.Argument.TypeIndex)
+		root.variables[contract.Argument.Name] = struct{}{}
+	}
+	returnType, returnTypeIndex := contract.ReturnType, contract.ReturnTypeIndex
+	if strings.HasPrefix(returnType, "Cursor<") && strings.HasSuffix(returnType, ">") {
+		returnType = returnType[len("Cursor<") : len(returnType)-1]
+		returnTypeIndex += len("Cursor<")
+	}
+	if returnType != rqltypes.Void {
+		c.checkType(returnType, returnTypeIndex)
+	}
+	for _, throw := range contract.Throws {
+		c.checkThrowable(throw.Name, throw.Position)
+	}
+	c.checkStatements(root, contract.Statements)
+}
+
+// Check is used to do semantic analysis on the tokens from each file and resolve any extends.
+// Every error is returned at once along with the file and position it is at. The checks are:
+//   - everything which is checked by ResolveExtends
+//   - structs, exceptions and enums do not share a name, and neither do contracts or mappings
+//   - the types of fields and contract arguments and return types exist
+//   - references within structs and the structs used by mappings are structs
+//   - thrown and caught exceptions are exceptions or structs
+//   - variables are assigned before they are used within contracts
+//
+// Types are only resolved from the files, so the files should be the whole schema. The resolved
+// tokens are returned and are ready to be written with Write if there are no errors.
+func Check(files ...[]any) ([]any, ErrorList) {
+	tokens, errs := ResolveExtends(files...)
+	c := &checker{errs: errs, types: map[string]string{}}
+	c.declare(files)
+
+	for file, fileTokens := range files {
+		c.file = file
+		for _, t := range fileTokens {
+			switch x := t.(type) {
+			case ast.StructToken:
+				c.checkFields(x.Fields)
+			case ast.ExceptionToken:
+				c.checkFields(x.Fields)
+			case ast.ExtendsToken:
+				if s, ok := x.Token.(ast.StructToken); ok {
+					c.checkFields(s.Fields)
+				}
+			case ast.ContractToken:
+				c.checkContract(x)
+			case ast.MappingToken:
+				for _, name := range x.Using {
+					if c.types[name] != kindStruct {
+						c.errorAt(x.Position, "unknown struct "+name)
+					}
+				}
+			}
+		}
+	}
+
+	c.errs.sort()
+	return tokens, c.errs
+}
@@ -0,0 +1,109 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package schema_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"remixdb.io/ast"
+	"remixdb.io/internal/schema"
+)
+
+func TestCheck(t *testing.T) {
+	tokens, errs := schema.Check(parse(t, `enum Kind {
+    Oak
+}
+
+exception NotFound {
+    message: string
+}
+
+struct Tree {
+    @primary
+    id: int
+    kind: Kind
+    neighbours: Tree[]
+}
+
+contract GetTree(id: int) -> Tree? throws NotFound {
+    if id < 0 {
+        throw NotFound({ message = 'not found' })
+    }
+    try {
+        count = Tree.count()
+    } catch NotFound -> e {
+        return e.message
+    }
+    return Tree.get(id)
+}
+
+contract ListTrees() -> Cursor<Tree> {
+    for i = 0; i < 10; i = i + 1 {
+        x = i
+    }
+    return Tree.all()
+}
+`), parse(t, `extends struct Tree {
+    name: string
+}
+`))
+	require.Nil(t, errs)
+	require.Len(t, tokens, 5)
+	assert.Len(t, tokens[2].(ast.StructToken).Fields, 4)
+}
+
+func TestCheck_errors(t *testing.T) {
+	const file0 = `struct Tree {
+    forest: Forest
+    planter
+}
+
+enum Tree {
+    Oak
+}
+
+contract Grow(x: Soil) -> Leaf {
+    if x {
+        y = 1
+    }
+    return y
+}
+
+contract Grow() -> void throws Wind {
+    try {
+        z = 1
+    } catch Rain {
+        return
+    }
+}
+
+contract List() -> Cursor<Bark> {
+    return
+}
+`
+	const file1 = `extends struct Tree {
+    height: Metres
+}
+`
+	_, errs := schema.Check(parse(t, file0), parse(t, file1))
+	at := func(s, sub string) int {
+		return strings.Index(s, sub)
+	}
+	assert.Equal(t, schema.ErrorList{
+		{File: 0, Message: "unknown type Forest", Position: at(file0, "forest")},
+		{File: 0, Message: "unknown struct planter", Position: at(file0, "planter")},
+		{File: 0, Message: "enum Tree cannot be defined since there is already a struct with the same name", Position: at(file0, "enum")},
+		{File: 0, Message: "unknown type Soil", Position: at(file0, " Soil")},
+		{File: 0, Message: "unknown type Leaf", Position: at(file0, "Leaf")},
+		{File: 0, Message: "undefined variable y", Position: at(file0, "y\n}")},
+		{File: 0, Message: "contract Grow is already defined", Position: at(file0, "contract Grow()")},
+		{File: 0, Message: "unknown exception Wind", Position: at(file0, "Wind")},
+		{File: 0, Message: "unknown exception Rain", Position: at(file0, "try")},
+		{File: 0, Message: "unknown type Bark", Position: at(file0, "Bark")},
+		{File: 1, Message: "unknown type Metres", Position: at(file1, "height")},
+	}, errs)
+}
@@ -0,0 +1,51 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package schema
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Error is used to define a error within a schema.
+type Error struct {
+	// File is the index of the file the error is in. This is the index of the token list which was
+	// passed in.
+	File int
+
+	// Message is the error message.
+	Message string
+
+	// Position is the position of the error within the file.
+	Position int
+}
+
+// Error is used to return the error message.
+func (e Error) Error() string {
+	return e.Message + " (file " + strconv.Itoa(e.File) + ", position " + strconv.Itoa(e.Position) + ")"
+}
+
+// ErrorList is used to define all of the errors found within a schema. This is nil if there
+// are no errors.
+type ErrorList []Error
+
+// Error is used to return every error message on its own line.
+func (e ErrorList) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Sorts the errors by file and then position so the order does not depend on map iteration.
+func (e ErrorList) sort() {
+	sort.SliceStable(e, func(i, j int) bool {
+		if e[i].File != e[j].File {
+			return e[i].File < e[j].File
+		}
+		return e[i].Position < e[j].Position
+	})
+}
@@ -0,0 +1,139 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package schema
+
+import "remixdb.io/ast"
+
+// Gets the name and position of a entry within a struct. Returns a blank name for comments.
+func structEntry(t any) (name string, position int) {
+	switch x := t.(type) {
+	case ast.FieldToken:
+		return x.Name, x.Position
+	case ast.ReferenceToken:
+		return x.Name, x.Position
+	}
+	return "", 0
+}
+
+// Defines where a struct is within the resolved tokens.
+type resolvedStruct struct {
+	index int
+	token ast.StructToken
+	names map[string]struct{}
+}
+
+// Merges the extension into the struct. Any conflicts are added to the errors.
+func (s *resolvedStruct) extend(file int, extension ast.StructToken, errs *ErrorList) {
+	// Use the doc comment of the extension if the struct does not have one.
+	if s.token.Comment == "" {
+		s.token.Comment = extension.Comment
+	}
+
+	// Add the decorators which are not already on the struct.
+	for _, decorator := range extension.Decorators {
+		duplicate := false
+		for _, existing := range s.token.Decorators {
+			if existing.Method == decorator.Method {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			*errs = append(*errs, Error{
+				File:     file,
+				Message:  "struct " + s.token.Name + " already has the decorator @" + decorator.Method,
+				Position: decorator.Position,
+			})
+			continue
+		}
+		s.token.Decorators = append(s.token.Decorators, decorator)
+	}
+
+	// Add the fields which do not conflict with the ones already on the struct.
+	for _, field := range extension.Fields {
+		if name, position := structEntry(field); name != "" {
+			if _, ok := s.names[name]; ok {
+				*errs = append(*errs, Error{
+					File:     file,
+					Message:  "struct " + s.token.Name + " already has a field named " + name,
+					Position: position,
+				})
+				continue
+			}
+			s.names[name] = struct{}{}
+		}
+		s.token.Fields = append(s.token.Fields, field)
+	}
+}
+
+// ResolveExtends is used to merge every `extends struct` within the files into the struct it
+// extends, so that only the final struct is left. The files are the tokens from parsing each
+// file, and the structs being extended can be in any of them. The tokens are returned in the
+// order of the files with the extends tokens removed. The tokens passed in are not modified.
+// Errors are returned for extends of unknown structs, structs which are defined more than once,
+// and fields or decorators which are already on the struct.
+func ResolveExtends(files ...[]any) ([]any, ErrorList) {
+	var errs ErrorList
+	tokens := []any{}
+	structs := map[string]*resolvedStruct{}
+
+	// Add everything other than the extends tokens and find the structs.
+	for file, fileTokens := range files {
+		for _, t := range fileTokens {
+			if _, ok := t.(ast.ExtendsToken); ok {
+				continue
+			}
+			if x, ok := t.(ast.StructToken); ok {
+				if _, exists := structs[x.Name]; exists {
+					errs = append(errs, Error{
+						File:     file,
+						Message:  "struct " + x.Name + " is already defined",
+						Position: x.Position,
+					})
+					continue
+				}
+
+				// Copy the slices so that merging does not change the tokens passed in.
+				x.Decorators = append([]ast.DecoratorToken{}, x.Decorators...)
+				x.Fields = append([]any{}, x.Fields...)
+				s := &resolvedStruct{index: len(tokens), token: x, names: map[string]struct{}{}}
+				for _, field := range x.Fields {
+					if name, _ := structEntry(field); name != "" {
+						s.names[name] = struct{}{}
+					}
+				}
+				structs[x.Name] = s
+			}
+			tokens = append(tokens, t)
+		}
+	}
+
+	// Merge each extends into the struct.
+	for file, fileTokens := range files {
+		for _, t := range fileTokens {
+			extends, ok := t.(ast.ExtendsToken)
+			if !ok {
+				continue
+			}
+			extension := extends.Token.(ast.StructToken)
+			s, ok := structs[extension.Name]
+			if !ok {
+				errs = append(errs, Error{
+					File:     file,
+					Message:  "cannot extend the struct " + extension.Name + " since it is not defined",
+					Position: extends.Position,
+				})
+				continue
+			}
+			s.extend(file, extension, &errs)
+		}
+	}
+
+	// Put the merged structs into the tokens.
+	for _, s := range structs {
+		tokens[s.index] = s.token
+	}
+	errs.sort()
+	return tokens, errs
+}
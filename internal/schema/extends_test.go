@@ -0,0 +1,225 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package schema_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"remixdb.io/ast"
+	"remixdb.io/internal/compiler/mocksession"
+	"remixdb.io/internal/engine/localfs/acid"
+	"remixdb.io/internal/engine/localfs/session"
+	"remixdb.io/internal/schema"
+)
+
+func parse(t *testing.T, s string) []any {
+	t.Helper()
+	tokens, perr := ast.Parse(s)
+	require.Nil(t, perr)
+	return tokens
+}
+
+func TestResolveExtends(t *testing.T) {
+	base := parse(t, `struct Tree {
+    @primary
+    id: int
+}
+
+contract GetTree() -> void {
+    return
+}
+`)
+	extension := parse(t, `// A tree which can be named.
+@notable
+extends struct Tree {
+    // The name of the tree.
+    name: string
+}
+
+extends struct Tree {
+    planter
+}
+`)
+	tokens, errs := schema.ResolveExtends(base, extension)
+	require.Nil(t, errs)
+	require.Len(t, tokens, 3)
+	assert.Equal(t, ast.StructToken{
+		Name:     "Tree",
+		Position: 0,
+		Decorators: []ast.DecoratorToken{
+			{Method: "notable", Position: 30},
+		},
+		Fields: []any{
+			ast.FieldToken{
+				Name:       "id",
+				Type:       "int",
+				Position:   31,
+				Decorators: []ast.DecoratorToken{{Method: "primary", Position: 18}},
+			},
+			ast.CommentToken{Comment: " The name of the tree.", Position: 65},
+			ast.FieldToken{
+				Name:       "name",
+				Type:       "string",
+				Position:   94,
+				Decorators: []ast.DecoratorToken{},
+				Comment:    "The name of the tree.",
+			},
+			ast.ReferenceToken{Name: "planter", Position: 136, Decorators: []ast.DecoratorToken{}},
+		},
+		Comment: "A tree which can be named.",
+	}, tokens[0])
+	assert.IsType(t, ast.ContractToken{}, tokens[1])
+	assert.IsType(t, ast.CommentToken{}, tokens[2])
+
+	// Make sure the tokens passed in were not changed.
+	assert.Len(t, base[0].(ast.StructToken).Fields, 1)
+}
+
+func TestResolveExtends_errors(t *testing.T) {
+	base := parse(t, `@notable
+struct Tree {
+    id: int
+}
+
+struct Tree {
+    other: int
+}
+`)
+	extension := parse(t, `@notable
+extends struct Tree {
+    id: string
+    name: string
+}
+
+extends struct Forest {
+    name: string
+}
+`)
+	tokens, errs := schema.ResolveExtends(base, extension)
+	assert.Equal(t, schema.ErrorList{
+		{File: 0, Message: "struct Tree is already defined", Position: 38},
+		{File: 1, Message: "struct Tree already has the decorator @notable", Position: 0},
+		{File: 1, Message: "struct Tree already has a field named id", Position: 35},
+		{File: 1, Message: "cannot extend the struct Forest since it is not defined", Position: 66},
+	}, errs)
+
+	// The fields which did not conflict are still merged.
+	require.Len(t, tokens, 1)
+	assert.Len(t, tokens[0].(ast.StructToken).Fields, 2)
+}
+
+func TestWrite(t *testing.T) {
+	base := parse(t, `enum Kind {
+    Oak
+}
+
+struct Tree {
+    kind: Kind
+}
+
+contract Nothing() -> void {
+    return
+}
+`)
+	extension := parse(t, `extends struct Tree {
+    name: string
+}
+`)
+
+	var order []string
+	s := &mocksession.SessionMock{
+		WriteEnumFunc: func(enum *ast.EnumToken) error {
+			order = append(order, "enum "+enum.Name)
+			return nil
+		},
+		WriteStructFunc: func(structToken *ast.StructToken, force bool) error {
+			assert.Len(t, structToken.Fields, 2)
+			order = append(order, "struct "+structToken.Name)
+			return nil
+		},
+		WriteContractFunc: func(contract *ast.ContractToken) error {
+			order = append(order, "contract "+contract.Name)
+			return nil
+		},
+	}
+	require.NoError(t, schema.Write(s, false, base, extension))
+	assert.Equal(t, []string{"enum Kind", "struct Tree", "contract Nothing"}, order)
+
+	// Nothing is written if the extends cannot be resolved.
+	order = nil
+	err := schema.Write(s, false, base, parse(t, `extends struct Bush {
+    name: string
+}
+`))
+	assert.Equal(t, schema.ErrorList{
+		{File: 1, Message: "cannot extend the struct Bush since it is not defined", Position: 0},
+	}, err)
+	assert.Nil(t, order)
+
+	// Nothing is written if the schema does not pass the checks either.
+	err = schema.Write(s, false, base, parse(t, `extends struct Tree {
+    height: Metres
+}
+`))
+	assert.Equal(t, schema.ErrorList{
+		{File: 1, Message: "unknown type Metres", Position: 26},
+	}, err)
+	assert.Nil(t, order)
+}
+
+func TestWrite_session(t *testing.T) {
+	dataFolder := t.TempDir()
+	relativePath := filepath.Join("partitions", "test")
+	require.NoError(t, os.MkdirAll(filepath.Join(dataFolder, relativePath), 0755))
+	newSession := func() *session.Session {
+		return &session.Session{
+			Logger:          zap.NewNop().Sugar(),
+			Transaction:     acid.New(dataFolder),
+			Cache:           &session.Cache{},
+			PartitionName:   "test",
+			DataFolder:      dataFolder,
+			RelativePath:    relativePath,
+			SchemaWriteLock: true,
+			Unlocker:        func() {},
+		}
+	}
+
+	// Write a struct which is extended in another file.
+	s := newSession()
+	require.NoError(t, schema.Write(s, false, parse(t, `struct Tree {
+    @primary
+    id: int
+}
+`), parse(t, `extends struct Tree {
+    name: string
+}
+`)))
+	require.NoError(t, s.Commit())
+	require.NoError(t, s.Close())
+
+	// The struct which is stored has the fields from both files.
+	s = newSession()
+	defer s.Close()
+	history, err := s.GetStructByKey("Tree")
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, ast.StructToken{
+		Name:       "Tree",
+		Decorators: []ast.DecoratorToken{},
+		Fields: []any{
+			ast.FieldToken{
+				Name:       "id",
+				Type:       "int",
+				Position:   31,
+				Decorators: []ast.DecoratorToken{{Method: "primary", Position: 18}},
+			},
+			ast.FieldToken{Name: "name", Type: "string", Position: 26, Decorators: []ast.DecoratorToken{}},
+		},
+	}, *history[0])
+}
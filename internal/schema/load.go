@@ -0,0 +1,162 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package schema
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"remixdb.io/ast"
+)
+
+// File is used to define a file which was loaded as part of a schema.
+type File struct {
+	// Path is the path of the file within the file system.
+	Path string
+
+	// Text is the contents of the file. The positions within the tokens and errors for this file
+	// are byte offsets into this.
+	Text string
+
+	// Tokens are the tokens parsed from the file, including the imports.
+	Tokens []any
+}
+
+// Project is used to define a schema which is made of several files.
+type Project struct {
+	// Files are the files within the schema in the order they were loaded. The File within each
+	// error is the index of a file within this.
+	Files []File
+
+	// Tokens are the resolved tokens from every file with the imports and extends removed. These
+	// are ready to be written with Write if there are no errors.
+	Tokens []any
+}
+
+// Describe is used to return the error with the path, line, and column it is at instead of the
+// index of the file and the position. Lines and columns start at 1.
+func (p *Project) Describe(e Error) string {
+	if e.File < 0 || e.File >= len(p.Files) {
+		return e.Error()
+	}
+	f := p.Files[e.File]
+	pos := min(max(e.Position, 0), len(f.Text))
+	start := strings.LastIndexByte(f.Text[:pos], '\n') + 1
+	line := strings.Count(f.Text[:start], "\n") + 1
+	column := utf8.RuneCountInString(f.Text[start:pos]) + 1
+	return f.Path + ":" + strconv.Itoa(line) + ":" + strconv.Itoa(column) + ": " + e.Message
+}
+
+// Defines the state of loading the files within a project.
+type loader struct {
+	fsys    fs.FS
+	project *Project
+	errs    ErrorList
+
+	// indexes maps the path of each file which was loaded to its index.
+	indexes map[string]int
+
+	// stack is the paths of the files which are being loaded, which is used to find cycles.
+	stack []string
+}
+
+// Adds a error at the position within the file.
+func (l *loader) errorAt(file, position int, message string) {
+	l.errs = append(l.errs, Error{File: file, Message: message, Position: position})
+}
+
+// Loads the file at the path and then every file it imports. from and position are where the
+// import is, or -1 if this is the first file.
+func (l *loader) load(name string, from, position int) {
+	// Handle files which were already loaded. These are a cycle if they are still being loaded.
+	if _, ok := l.indexes[name]; ok {
+		for i, loading := range l.stack {
+			if loading == name {
+				cycle := append(append([]string{}, l.stack[i:]...), name)
+				l.errorAt(from, position, "import cycle: "+strings.Join(cycle, " -> "))
+				break
+			}
+		}
+		return
+	}
+
+	// Read the file.
+	b, err := fs.ReadFile(l.fsys, name)
+	if err != nil {
+		message := "cannot read " + name + ": " + err.Error()
+		if errors.Is(err, fs.ErrNotExist) {
+			message = "cannot import " + name + " since it does not exist"
+		}
+		if from == -1 {
+			// Add the file so the error has somewhere to point.
+			l.project.Files = append(l.project.Files, File{Path: name, Tokens: []any{}})
+			from, position = 0, 0
+		}
+		l.errorAt(from, position, message)
+		return
+	}
+
+	// Parse the file.
+	index := len(l.project.Files)
+	l.indexes[name] = index
+	text := string(b)
+	tokens, perrs := ast.ParseAll(text)
+	l.project.Files = append(l.project.Files, File{Path: name, Text: text, Tokens: tokens})
+	for _, perr := range perrs {
+		l.errorAt(index, perr.Position, perr.Message)
+	}
+
+	// Load the imports. Paths are relative to the directory of the file.
+	l.stack = append(l.stack, name)
+	for _, t := range tokens {
+		if x, ok := t.(ast.ImportToken); ok {
+			target := path.Join(path.Dir(name), x.Path)
+			if path.IsAbs(x.Path) || !fs.ValidPath(target) {
+				l.errorAt(index, x.Position, "cannot import "+x.Path+" since it is outside of the schema")
+				continue
+			}
+			l.load(target, index, x.Position)
+		}
+	}
+	l.stack = l.stack[:len(l.stack)-1]
+}
+
+// Load is used to load the schema file at the path within the file system along with every file
+// it imports. Imports are relative to the directory of the file which has them, and each file is
+// only loaded once even if it is imported several times. The files are in the order they were
+// first imported, starting with the file at the path.
+//
+// Every file shares the same namespace, so the tokens from all of them are checked together with
+// Check and then resolved into a single schema. Errors are returned for files which cannot be read
+// or parsed, imports which form a cycle, and everything which is checked by Check, including
+// declarations which are within more than one file. The project is returned even if there are
+// errors so that they can be shown with Describe.
+func Load(fsys fs.FS, name string) (*Project, ErrorList) {
+	l := &loader{fsys: fsys, project: &Project{}, indexes: map[string]int{}}
+	l.load(path.Clean(name), -1, 0)
+
+	// Check the tokens from every file and remove the imports.
+	files := make([][]any, len(l.project.Files))
+	for i, f := range l.project.Files {
+		files[i] = f.Tokens
+	}
+	tokens, errs := Check(files...)
+	l.project.Tokens = []any{}
+	for _, t := range tokens {
+		if _, ok := t.(ast.ImportToken); !ok {
+			l.project.Tokens = append(l.project.Tokens, t)
+		}
+	}
+
+	l.errs = append(l.errs, errs...)
+	if len(l.errs) == 0 {
+		return l.project, nil
+	}
+	l.errs.sort()
+	return l.project, l.errs
+}
@@ -0,0 +1,181 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package schema_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"remixdb.io/ast"
+	"remixdb.io/internal/schema"
+)
+
+// Creates a file system with the files.
+func files(contents map[string]string) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for name, content := range contents {
+		fsys[name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	return fsys
+}
+
+func TestLoad(t *testing.T) {
+	fsys := files(map[string]string{
+		"schema.rql": `import "models/tree.rql"
+import "enums.rql"
+
+extends struct Tree {
+    name: string
+}
+
+contract GetTree(id: int) -> Tree {
+    return Tree.get(id)
+}
+`,
+		"models/tree.rql": `import "../enums.rql"
+
+struct Tree {
+    @primary
+    id: int
+    kind: Kind
+}
+`,
+		"enums.rql": `enum Kind {
+    Oak
+}
+`,
+	})
+
+	project, errs := schema.Load(fsys, "./schema.rql")
+	require.Nil(t, errs)
+
+	// Files are in the order they were first imported.
+	var paths []string
+	for _, f := range project.Files {
+		paths = append(paths, f.Path)
+	}
+	assert.Equal(t, []string{"schema.rql", "models/tree.rql", "enums.rql"}, paths)
+	assert.IsType(t, ast.ImportToken{}, project.Files[0].Tokens[0])
+
+	// The tokens are resolved with the imports removed.
+	require.Len(t, project.Tokens, 3)
+	assert.IsType(t, ast.ContractToken{}, project.Tokens[0])
+	tree := project.Tokens[1].(ast.StructToken)
+	assert.Len(t, tree.Fields, 3)
+	assert.IsType(t, ast.EnumToken{}, project.Tokens[2])
+}
+
+func TestLoad_errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string
+	}{
+		{
+			name: "cycle",
+			files: map[string]string{
+				"schema.rql": `import "a.rql"
+`,
+				"a.rql": `import "b.rql"
+`,
+				"b.rql": `
+
+import "a.rql"
+`,
+			},
+			want: []string{"b.rql:3:1: import cycle: a.rql -> b.rql -> a.rql"},
+		},
+		{
+			name: "self import",
+			files: map[string]string{
+				"schema.rql": `import "./schema.rql"
+`,
+			},
+			want: []string{"schema.rql:1:1: import cycle: schema.rql -> schema.rql"},
+		},
+		{
+			name: "missing file",
+			files: map[string]string{
+				"schema.rql": `struct Tree {
+    id: int
+}
+
+import "missing.rql"
+`,
+			},
+			want: []string{"schema.rql:5:1: cannot import missing.rql since it does not exist"},
+		},
+		{
+			name: "outside of the schema",
+			files: map[string]string{
+				"schema.rql": `import "../other.rql"
+`,
+			},
+			want: []string{"schema.rql:1:1: cannot import ../other.rql since it is outside of the schema"},
+		},
+		{
+			name:  "missing root",
+			files: map[string]string{},
+			want:  []string{"schema.rql:1:1: cannot import schema.rql since it does not exist"},
+		},
+		{
+			name: "duplicate declarations",
+			files: map[string]string{
+				"schema.rql": `import "a.rql"
+
+enum Kind {
+    Oak
+}
+
+contract GetTree() -> void {
+    return
+}
+`,
+				"a.rql": `struct Kind {
+    id: int
+}
+
+contract GetTree() -> void {
+    return
+}
+`,
+			},
+			want: []string{
+				"a.rql:1:1: struct Kind cannot be defined since there is already a enum with the same name",
+				"a.rql:5:1: contract GetTree is already defined",
+			},
+		},
+		{
+			name: "parse and check errors",
+			files: map[string]string{
+				"schema.rql": `import "a.rql"
+
+struct Tree {
+    kind: Kind
+}
+`,
+				"a.rql": `
+enum Status {}
+`,
+			},
+			want: []string{
+				"schema.rql:4:5: unknown type Kind",
+				"a.rql:2:1: unexpected empty enum - enums must have at least one value",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project, errs := schema.Load(files(tt.files), "schema.rql")
+			require.NotNil(t, errs)
+			var got []string
+			for _, err := range errs {
+				got = append(got, project.Describe(err))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+// RemixDB. Copyright (C) 2023 Web Scale Software Ltd.
+// Author: Astrid Gealer <astrid@gealer.email>
+
+package schema
+
+import (
+	"remixdb.io/ast"
+	"remixdb.io/internal/engine"
+)
+
+// Write is used to write the tokens from each file to the session. The files are checked with
+// Check first, which also resolves the extends, and the ErrorList is returned without writing
+// anything if there are errors. Since types are only resolved from the files, they should be the
+// whole schema. Enums are written first since structs and exceptions can use them, and contracts
+// are written last. The session must be a schema write session. Force is passed through to
+// WriteStruct.
+func Write(s engine.Session, force bool, files ...[]any) error {
+	tokens, errs := Check(files...)
+	if errs != nil {
+		return errs
+	}
+
+	var (
+		enums      []ast.EnumToken
+		exceptions []ast.ExceptionToken
+		structs    []ast.StructToken
+		contracts  []ast.ContractToken
+	)
+	for _, t := range tokens {
+		switch x := t.(type) {
+		case ast.EnumToken:
+			enums = append(enums, x)
+		case ast.ExceptionToken:
+			exceptions = append(exceptions, x)
+		case ast.StructToken:
+			structs = append(structs, x)
+		case ast.ContractToken:
+			contracts = append(contracts, x)
+		}
+	}
+
+	// Write everything in order.
+	for i := range enums {
+		if err := s.WriteEnum(&enums[i]); err != nil {
+			return err
+		}
+	}
+	for i := range exceptions {
+		if err := s.WriteException(&exceptions[i]); err != nil {
+			return err
+		}
+	}
+	for i := range structs {
+		if err := s.WriteStruct(&structs[i], force); err != nil {
+			return err
+		}
+	}
+	for i := range contracts {
+		if err := s.WriteContract(&contracts[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -20,6 +20,9 @@ type NamedLock struct {
 // Lock is used to acquire a lock on the given resource.
 func (n *NamedLock) Lock(name string) {
 	n.locksMu.Lock()
+	if n.locks == nil {
+		n.locks = map[string]*lock{}
+	}
 	l, ok := n.locks[name]
 	if !ok {
 		l = &lock{}
@@ -33,6 +36,9 @@ func (n *NamedLock) Lock(name string) {
 // RLock is used to acquire a read lock on the given resource.
 func (n *NamedLock) RLock(name string) {
 	n.locksMu.Lock()
+	if n.locks == nil {
+		n.locks = map[string]*lock{}
+	}
 	l, ok := n.locks[name]
 	if !ok {
 		l = &lock{}